@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+// ANSI colors.
+const (
+	colorRed   = "\x1b[31m"
+	colorReset = "\x1b[0m"
+)
+
+// serviceLevelSummary is a service level on the list command.
+type serviceLevelSummary struct {
+	Namespace string     `json:"namespace"`
+	Name      string     `json:"name"`
+	Created   time.Time  `json:"created"`
+	SLOs      []sloState `json:"slos"`
+}
+
+// runList lists the service levels, with the state of their SLOs if a source is set.
+func runList(args []string) error {
+	o := &options{}
+	fs := newFlagSet("list", "[flags]", o)
+	fs.Parse(args)
+	if err := o.validate(); err != nil {
+		return err
+	}
+
+	sls, err := o.listServiceLevels()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+
+	// Without source only the service levels are listed.
+	withState := o.operatorAddress != "" || o.prometheusAddress != ""
+	summaries := []serviceLevelSummary{}
+	for _, sl := range sls {
+		s := serviceLevelSummary{Namespace: sl.Namespace, Name: sl.Name, Created: sl.CreationTimestamp.Time}
+		if withState {
+			s.SLOs = o.currentStates(ctx, sl)
+		} else {
+			s.SLOs = newSLOStates(sl, "")
+		}
+		summaries = append(summaries, s)
+	}
+
+	if o.outputFmt == outputJSON {
+		return writeJSON(os.Stdout, summaries)
+	}
+	return writeListTable(os.Stdout, summaries, withState, o.color())
+}
+
+// runStatus shows the current availability of the SLOs of a service level.
+func runStatus(args []string) error {
+	o := &options{}
+	fs := newFlagSet("status", "<service-level> [flags]", o)
+	sl, err := parseServiceLevelArgs(fs, args, o)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+	states := o.currentStates(ctx, sl)
+
+	if o.outputFmt == outputJSON {
+		return writeJSON(os.Stdout, states)
+	}
+	return writeStatusTable(os.Stdout, states, o.color())
+}
+
+// runBudget shows the error budget of the SLOs of a service level.
+func runBudget(args []string) error {
+	o := &options{}
+	fs := newFlagSet("budget", "<service-level> [flags]", o)
+	window := fs.String("window", defBudgetWindow, "the error budget time window (e.g. 7d, 30d) when using Prometheus")
+	sl, err := parseServiceLevelArgs(fs, args, o)
+	if err != nil {
+		return err
+	}
+	if _, err := model.ParseDuration(*window); err != nil {
+		return fmt.Errorf("invalid window: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+	states := o.budgetStates(ctx, sl, *window)
+
+	if o.outputFmt == outputJSON {
+		return writeJSON(os.Stdout, states)
+	}
+	return writeBudgetTable(os.Stdout, states, o.color())
+}
+
+// parseServiceLevelArgs parses the arguments of the commands that receive a service
+// level name and gets the service level, the flags can be after the name like kubectl.
+func parseServiceLevelArgs(fs *flag.FlagSet, args []string, o *options) (*monitoringv1alpha1.ServiceLevel, error) {
+	var name string
+	if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		name, args = args[0], args[1:]
+	}
+	fs.Parse(args)
+	if name == "" && len(fs.Args()) > 0 {
+		name = fs.Args()[0]
+	}
+	if name == "" {
+		fs.Usage()
+		return nil, fmt.Errorf("the service level name is required")
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	return o.getServiceLevel(name)
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// tableRow is a row of a table, the cells are separated by tabs.
+type tableRow struct {
+	cells    string
+	breached bool
+}
+
+// writeTable writes an aligned table, the rows of the breached SLOs are colored.
+func writeTable(w io.Writer, header string, rows []tableRow, color bool) error {
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, header)
+	for _, r := range rows {
+		fmt.Fprintln(tw, r.cells)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// The colors are set once aligned so they don't count on the cell widths.
+	lines := strings.SplitAfter(b.String(), "\n")
+	for i, line := range lines {
+		if i > 0 && i <= len(rows) && color && rows[i-1].breached {
+			line = colorRed + strings.TrimSuffix(line, "\n") + colorReset + "\n"
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeListTable(w io.Writer, summaries []serviceLevelSummary, withState, color bool) error {
+	header := "NAMESPACE\tNAME\tSLOS\tAGE"
+	if withState {
+		header = "NAMESPACE\tNAME\tSLOS\tBREACHED\tERRORS\tAGE"
+	}
+	rows := []tableRow{}
+	for _, s := range summaries {
+		age := formatAge(s.Created)
+		if !withState {
+			rows = append(rows, tableRow{cells: fmt.Sprintf("%s\t%s\t%d\t%s", s.Namespace, s.Name, len(s.SLOs), age)})
+			continue
+		}
+
+		breached, errs := 0, 0
+		for _, st := range s.SLOs {
+			if st.Breached {
+				breached++
+			}
+			if st.Error != "" {
+				errs++
+			}
+		}
+		rows = append(rows, tableRow{
+			cells:    fmt.Sprintf("%s\t%s\t%d\t%d\t%d\t%s", s.Namespace, s.Name, len(s.SLOs), breached, errs, age),
+			breached: breached > 0,
+		})
+	}
+	return writeTable(w, header, rows, color)
+}
+
+func writeStatusTable(w io.Writer, states []sloState, color bool) error {
+	rows := []tableRow{}
+	for _, s := range states {
+		availability := "-"
+		if s.AvailabilityRatio != nil {
+			availability = fmt.Sprintf("%.4f%%", *s.AvailabilityRatio*100)
+		}
+		lastEval := "-"
+		if s.LastEvaluation != nil {
+			lastEval = formatAge(*s.LastEvaluation) + " ago"
+		}
+		rows = append(rows, tableRow{
+			cells:    fmt.Sprintf("%s\t%g%%\t%s\t%s\t%s", s.SLO, s.ObjectivePercent, availability, stateStatus(s, "breached"), lastEval),
+			breached: s.Breached,
+		})
+	}
+	return writeTable(w, "SLO\tOBJECTIVE\tAVAILABILITY\tSTATUS\tLAST EVALUATION", rows, color)
+}
+
+func writeBudgetTable(w io.Writer, states []sloState, color bool) error {
+	rows := []tableRow{}
+	for _, s := range states {
+		availability, remaining := "-", "-"
+		if s.AvailabilityRatio != nil {
+			availability = fmt.Sprintf("%.4f%%", *s.AvailabilityRatio*100)
+		}
+		if s.RemainingErrorBudgetRatio != nil {
+			remaining = fmt.Sprintf("%.2f%%", *s.RemainingErrorBudgetRatio*100)
+		}
+		rows = append(rows, tableRow{
+			cells:    fmt.Sprintf("%s\t%g%%\t%s\t%s\t%s\t%s", s.SLO, s.ObjectivePercent, s.Window, availability, remaining, stateStatus(s, "exhausted")),
+			breached: s.Breached,
+		})
+	}
+	return writeTable(w, "SLO\tOBJECTIVE\tWINDOW\tAVAILABILITY\tREMAINING BUDGET\tSTATUS", rows, color)
+}
+
+func stateStatus(s sloState, breached string) string {
+	switch {
+	case s.Disabled:
+		return "disabled"
+	case s.Error != "":
+		return "error: " + s.Error
+	case s.AvailabilityRatio == nil:
+		return "unknown"
+	case s.Breached:
+		return breached
+	}
+	return "ok"
+}
+
+// formatAge formats the time since t like kubectl does.
+func formatAge(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var states = []sloState{
+	{SLO: "slo0", ObjectivePercent: 99, Window: "30d", AvailabilityRatio: float64P(0.995), RemainingErrorBudgetRatio: float64P(0.5)},
+	{SLO: "slo1", ObjectivePercent: 99.9, Window: "30d", AvailabilityRatio: float64P(0.998), RemainingErrorBudgetRatio: float64P(-1), Breached: true},
+	{SLO: "slo2", ObjectivePercent: 99, Window: "30d", Disabled: true},
+	{SLO: "slo3", ObjectivePercent: 99, Window: "30d", Error: "wanted error"},
+	{SLO: "slo4", ObjectivePercent: 99, Window: "30d"},
+}
+
+func TestWriteStatusTable(t *testing.T) {
+	tests := map[string]struct {
+		color  bool
+		expOut string
+	}{
+		"The SLOs should be written with their availability and status.": {
+			expOut: `SLO   OBJECTIVE  AVAILABILITY  STATUS               LAST EVALUATION
+slo0  99%        99.5000%      ok                   -
+slo1  99.9%      99.8000%      breached             -
+slo2  99%        -             disabled             -
+slo3  99%        -             error: wanted error  -
+slo4  99%        -             unknown              -
+`,
+		},
+
+		"The breached SLOs should be colored.": {
+			color: true,
+			expOut: `SLO   OBJECTIVE  AVAILABILITY  STATUS               LAST EVALUATION
+slo0  99%        99.5000%      ok                   -
+` + colorRed + `slo1  99.9%      99.8000%      breached             -` + colorReset + `
+slo2  99%        -             disabled             -
+slo3  99%        -             error: wanted error  -
+slo4  99%        -             unknown              -
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			require.NoError(t, writeStatusTable(&b, states, test.color))
+			assert.Equal(t, test.expOut, b.String())
+		})
+	}
+}
+
+func TestWriteBudgetTable(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, writeBudgetTable(&b, states, false))
+
+	exp := `SLO   OBJECTIVE  WINDOW  AVAILABILITY  REMAINING BUDGET  STATUS
+slo0  99%        30d     99.5000%      50.00%            ok
+slo1  99.9%      30d     99.8000%      -100.00%          exhausted
+slo2  99%        30d     -             -                 disabled
+slo3  99%        30d     -             -                 error: wanted error
+slo4  99%        30d     -             -                 unknown
+`
+	assert.Equal(t, exp, b.String())
+}
+
+func TestWriteListTable(t *testing.T) {
+	summaries := []serviceLevelSummary{
+		{Namespace: "ns0", Name: "sl0", SLOs: states},
+		{Namespace: "ns1", Name: "sl1", SLOs: states[:1]},
+	}
+
+	tests := map[string]struct {
+		withState bool
+		color     bool
+		expOut    string
+	}{
+		"Without state only the service levels should be written.": {
+			expOut: `NAMESPACE  NAME  SLOS  AGE
+ns0        sl0   5     -
+ns1        sl1   1     -
+`,
+		},
+
+		"With state the breached and failed SLOs should be counted.": {
+			withState: true,
+			color:     true,
+			expOut: `NAMESPACE  NAME  SLOS  BREACHED  ERRORS  AGE
+` + colorRed + `ns0        sl0   5     1         1       -` + colorReset + `
+ns1        sl1   1     0         0       -
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			require.NoError(t, writeListTable(&b, summaries, test.withState, test.color))
+			assert.Equal(t, test.expOut, b.String())
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Version is the version of the plugin, set on build time.
+var Version = "dev"
+
+const (
+	pluginName = "kubectl slo"
+	// plugin defaults.
+	defBudgetWindow = "30d"
+)
+
+// command is a subcommand of the plugin, it receives the arguments after the
+// subcommand name.
+type command func(args []string) error
+
+var commands = map[string]command{
+	"list":    runList,
+	"status":  runStatus,
+	"budget":  runBudget,
+	"version": runVersion,
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands: %s\n", pluginName, strings.Join(names, ", "))
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(1)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		if os.Args[1] != "-h" && os.Args[1] != "--help" && os.Args[1] != "help" {
+			fmt.Fprintf(os.Stderr, "error: unknown %q command\n", os.Args[1])
+		}
+		usage()
+		os.Exit(1)
+	}
+
+	err := cmd(os.Args[2:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func runVersion(_ []string) error {
+	fmt.Println(Version)
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	crdcli "github.com/spotahome/service-level-operator/pkg/k8sautogen/client/clientset/versioned"
+	kubernetesclifactory "github.com/spotahome/service-level-operator/pkg/service/client/kubernetes"
+	promclifactory "github.com/spotahome/service-level-operator/pkg/service/client/prometheus"
+)
+
+// output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// options are the flags shared by all the commands.
+type options struct {
+	kubeconfig        string
+	kubeContext       string
+	namespace         string
+	allNamespaces     bool
+	outputFmt         string
+	prometheusAddress string
+	operatorAddress   string
+	cluster           string
+	noColor           bool
+	timeout           time.Duration
+
+	// promCliFactory is the Prometheus clients factory, by default one with
+	// the Prometheus address as the default client.
+	promCliFactory promclifactory.ClientFactory
+}
+
+// newFlagSet returns the flag set of a command with the shared flags.
+func newFlagSet(name, usage string, o *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.StringVar(&o.kubeconfig, "kubeconfig", "", "path to the kubeconfig file, by default the kubectl one")
+	fs.StringVar(&o.kubeContext, "context", "", "the kubeconfig context to use")
+	fs.StringVar(&o.namespace, "n", "", "the namespace of the service levels, by default the context one")
+	fs.StringVar(&o.namespace, "namespace", "", "the namespace of the service levels, by default the context one")
+	fs.BoolVar(&o.allNamespaces, "A", false, "use the service levels of all the namespaces")
+	fs.BoolVar(&o.allNamespaces, "all-namespaces", false, "use the service levels of all the namespaces")
+	fs.StringVar(&o.outputFmt, "o", outputTable, "the output format, table or json")
+	fs.StringVar(&o.prometheusAddress, "prometheus-address", "", "the address of the Prometheus used by the SLIs without address and that has the operator metrics")
+	fs.StringVar(&o.operatorAddress, "operator-address", "", "the address of the operator API (e.g. a port-forward to http://127.0.0.1:8080), if set the operator status is used instead of Prometheus")
+	fs.StringVar(&o.cluster, "cluster", "", "the cluster of the service levels on the operator status, when the operator watches multiple clusters")
+	fs.BoolVar(&o.noColor, "no-color", false, "disable the colored output")
+	fs.DurationVar(&o.timeout, "timeout", 30*time.Second, "the timeout of the command")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s %s\n\nFlags:\n", pluginName, name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func (o *options) validate() error {
+	if o.outputFmt != outputTable && o.outputFmt != outputJSON {
+		return fmt.Errorf("unknown %q output format, should be one of: %s, %s", o.outputFmt, outputTable, outputJSON)
+	}
+	return nil
+}
+
+// color returns true if the table output should be colored.
+func (o *options) color() bool {
+	if o.noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// prometheusFactory returns the Prometheus clients factory of the command, the
+// SLIs without address use the Prometheus address.
+func (o *options) prometheusFactory() (promclifactory.ClientFactory, error) {
+	if o.promCliFactory != nil {
+		return o.promCliFactory, nil
+	}
+	f := promclifactory.NewBaseFactory()
+	if o.prometheusAddress != "" {
+		if err := f.WithDefaultV1APIClient(o.prometheusAddress); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// serviceLevelClient returns the service levels client and the namespace of the
+// command, empty if all the namespaces. The configuration is loaded like kubectl does.
+func (o *options) serviceLevelClient() (crdcli.Interface, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if o.kubeconfig != "" {
+		rules.ExplicitPath = o.kubeconfig
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: o.kubeContext}
+	overrides.Context.Namespace = o.namespace
+	cc := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+
+	cfg, err := cc.ClientConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("could not load kubernetes configuration: %s", err)
+	}
+	ns, _, err := cc.Namespace()
+	if err != nil {
+		return nil, "", err
+	}
+	if o.allNamespaces {
+		ns = metav1.NamespaceAll
+	}
+
+	cli, err := kubernetesclifactory.NewFactory(cfg).GetCRDClient()
+	if err != nil {
+		return nil, "", err
+	}
+	return cli, ns, nil
+}
+
+// listServiceLevels lists the service levels of the command namespace.
+func (o *options) listServiceLevels() ([]*monitoringv1alpha1.ServiceLevel, error) {
+	cli, ns, err := o.serviceLevelClient()
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := cli.MonitoringV1alpha1().ServiceLevels(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	sls := make([]*monitoringv1alpha1.ServiceLevel, 0, len(list.Items))
+	for i := range list.Items {
+		sls = append(sls, &list.Items[i])
+	}
+	return sls, nil
+}
+
+// getServiceLevel gets a service level of the command namespace.
+func (o *options) getServiceLevel(name string) (*monitoringv1alpha1.ServiceLevel, error) {
+	if o.allNamespaces {
+		return nil, fmt.Errorf("a service level can't be got from all the namespaces, set the namespace")
+	}
+	cli, ns, err := o.serviceLevelClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return cli.MonitoringV1alpha1().ServiceLevels(ns).Get(name, metav1.GetOptions{})
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/eval"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+	"github.com/spotahome/service-level-operator/pkg/web"
+)
+
+// state sources
+const (
+	sourceOperator   = "operator"
+	sourcePrometheus = "prometheus"
+)
+
+// sloState is the state of an SLO combining the service level with the current
+// values of Prometheus or the operator.
+type sloState struct {
+	Namespace        string  `json:"namespace"`
+	ServiceLevel     string  `json:"serviceLevel"`
+	SLO              string  `json:"slo"`
+	Description      string  `json:"description,omitempty"`
+	ObjectivePercent float64 `json:"objectivePercent"`
+	Disabled         bool    `json:"disabled,omitempty"`
+	Source           string  `json:"source,omitempty"`
+	// AvailabilityRatio is the current availability, nil if unknown.
+	AvailabilityRatio *float64 `json:"availabilityRatio,omitempty"`
+	// RemainingErrorBudgetRatio is the remaining error budget of the window, nil if unknown.
+	RemainingErrorBudgetRatio *float64 `json:"remainingErrorBudgetRatio,omitempty"`
+	// Window is the time window of the error budget.
+	Window         string     `json:"window,omitempty"`
+	LastEvaluation *time.Time `json:"lastEvaluation,omitempty"`
+	// Breached is true when the objective is not met or the error budget is exhausted.
+	Breached bool   `json:"breached"`
+	Error    string `json:"error,omitempty"`
+}
+
+func newSLOStates(sl *monitoringv1alpha1.ServiceLevel, source string) []sloState {
+	states := make([]sloState, 0, len(sl.Spec.ServiceLevelObjectives))
+	for _, slo := range sl.Spec.ServiceLevelObjectives {
+		states = append(states, sloState{
+			Namespace:        sl.Namespace,
+			ServiceLevel:     sl.Name,
+			SLO:              slo.Name,
+			Description:      slo.Description,
+			ObjectivePercent: slo.AvailabilityObjectivePercent,
+			Disabled:         slo.Disable,
+			Source:           source,
+		})
+	}
+	return states
+}
+
+// operatorSLOs returns the operator status of the service level SLOs by name.
+func (o *options) operatorSLOs(ctx context.Context, sl *monitoringv1alpha1.ServiceLevel) (map[string]status.SLOStatus, error) {
+	st, err := web.NewAPIClient(o.operatorAddress, nil).GetServiceLevel(ctx, o.cluster, sl.Namespace, sl.Name)
+	if err != nil {
+		return nil, err
+	}
+	slos := map[string]status.SLOStatus{}
+	for _, s := range st.SLOs {
+		slos[s.Name] = s
+	}
+	return slos, nil
+}
+
+// currentStates returns the current availability of the SLOs, from the last
+// evaluation of the operator or evaluating the SLOs against Prometheus.
+func (o *options) currentStates(ctx context.Context, sl *monitoringv1alpha1.ServiceLevel) []sloState {
+	if o.operatorAddress != "" {
+		return o.currentOperatorStates(ctx, sl)
+	}
+	return o.currentPrometheusStates(ctx, sl)
+}
+
+func (o *options) currentOperatorStates(ctx context.Context, sl *monitoringv1alpha1.ServiceLevel) []sloState {
+	states := newSLOStates(sl, sourceOperator)
+	slos, err := o.operatorSLOs(ctx, sl)
+	for i := range states {
+		s := &states[i]
+		if s.Disabled {
+			continue
+		}
+		if err != nil {
+			s.Error = err.Error()
+			continue
+		}
+
+		st, ok := slos[s.SLO]
+		if !ok {
+			s.Error = "SLO not handled by the operator"
+			continue
+		}
+		s.LastEvaluation = st.LastEvaluation
+		s.Error = st.LastError
+		if st.LastResult != nil {
+			availability := st.LastResult.AvailabilityRatio
+			s.AvailabilityRatio = &availability
+			s.Breached = availability*100 < s.ObjectivePercent
+		}
+	}
+	return states
+}
+
+func (o *options) currentPrometheusStates(ctx context.Context, sl *monitoringv1alpha1.ServiceLevel) []sloState {
+	states := newSLOStates(sl, sourcePrometheus)
+	f, err := o.prometheusFactory()
+	if err != nil {
+		return statesWithError(states, err)
+	}
+	retrieverFact := sli.NewRetrieverFactory(sli.NewPrometheus(f, noop.NewTracerProvider().Tracer(pluginName), log.Dummy))
+
+	now := time.Now()
+	results, err := eval.Run(ctx, retrieverFact, sl, "")
+	if err != nil {
+		return statesWithError(states, err)
+	}
+	byName := map[string]eval.SLOResult{}
+	for _, r := range results {
+		byName[r.SLO] = r
+	}
+
+	for i := range states {
+		s := &states[i]
+		r, ok := byName[s.SLO]
+		if !ok || s.Disabled {
+			continue
+		}
+		s.LastEvaluation = &now
+		if r.Error != "" {
+			s.Error = r.Error
+			continue
+		}
+		availability := r.AvailabilityRatio
+		s.AvailabilityRatio = &availability
+		s.Breached = !r.ObjectiveMet
+	}
+	return states
+}
+
+// budgetStates returns the error budget of the SLOs, from the counters of the
+// operator or from the operator metrics stored in Prometheus for the window.
+func (o *options) budgetStates(ctx context.Context, sl *monitoringv1alpha1.ServiceLevel, window string) []sloState {
+	if o.operatorAddress != "" {
+		return o.budgetOperatorStates(ctx, sl)
+	}
+	return o.budgetPrometheusStates(ctx, sl, window)
+}
+
+func (o *options) budgetOperatorStates(ctx context.Context, sl *monitoringv1alpha1.ServiceLevel) []sloState {
+	states := newSLOStates(sl, sourceOperator)
+	slos, err := o.operatorSLOs(ctx, sl)
+	for i := range states {
+		s := &states[i]
+		// The operator counters are accumulated since it started.
+		s.Window = "operator uptime"
+		if s.Disabled {
+			continue
+		}
+		if err != nil {
+			s.Error = err.Error()
+			continue
+		}
+
+		st, ok := slos[s.SLO]
+		if !ok {
+			s.Error = "SLO not handled by the operator"
+			continue
+		}
+		s.LastEvaluation = st.LastEvaluation
+		availability, remaining, ok := st.ErrorBudget()
+		if !ok {
+			s.Error = "no SLI results yet"
+			continue
+		}
+		s.AvailabilityRatio = &availability
+		s.RemainingErrorBudgetRatio = &remaining
+		s.Breached = output.ErrorBudgetExhausted(remaining)
+	}
+	return states
+}
+
+func (o *options) budgetPrometheusStates(ctx context.Context, sl *monitoringv1alpha1.ServiceLevel, window string) []sloState {
+	states := newSLOStates(sl, sourcePrometheus)
+	for i := range states {
+		states[i].Window = window
+	}
+	if o.prometheusAddress == "" {
+		return statesWithError(states, fmt.Errorf("the prometheus address with the operator metrics is required"))
+	}
+	f, err := o.prometheusFactory()
+	if err != nil {
+		return statesWithError(states, err)
+	}
+	cli, err := f.GetV1APIClient(o.prometheusAddress)
+	if err != nil {
+		return statesWithError(states, err)
+	}
+
+	for i := range states {
+		s := &states[i]
+		if s.Disabled {
+			continue
+		}
+
+		matchers := []string{
+			fmt.Sprintf("namespace=%q", s.Namespace),
+			fmt.Sprintf("service_level=%q", s.ServiceLevel),
+			fmt.Sprintf("slo=%q", s.SLO),
+		}
+		if o.cluster != "" {
+			matchers = append(matchers, fmt.Sprintf("cluster=%q", o.cluster))
+		}
+		sel := strings.Join(matchers, ",")
+
+		errSum, err := queryValue(ctx, cli, fmt.Sprintf("sum(increase(service_level_sli_result_error_ratio_total{%s}[%s]))", sel, window))
+		if err != nil {
+			s.Error = err.Error()
+			continue
+		}
+		count, err := queryValue(ctx, cli, fmt.Sprintf("sum(increase(service_level_sli_result_count_total{%s}[%s]))", sel, window))
+		if err != nil {
+			s.Error = err.Error()
+			continue
+		}
+
+		counters := output.SLOCounters{ErrorRatioSum: errSum, Count: count, Objective: s.ObjectivePercent / 100}
+		availability, remaining, ok := counters.ErrorBudget()
+		if !ok {
+			s.Error = "no SLI results on the window"
+			continue
+		}
+		s.AvailabilityRatio = &availability
+		s.RemainingErrorBudgetRatio = &remaining
+		s.Breached = output.ErrorBudgetExhausted(remaining)
+	}
+	return states
+}
+
+// queryValue returns the value of a single series query, 0 if there isn't any series.
+func queryValue(ctx context.Context, cli promv1.API, query string) (float64, error) {
+	val, _, err := cli.Query(ctx, query, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	vec, ok := val.(model.Vector)
+	if !ok {
+		return 0, fmt.Errorf("received metric needs to be a vector, received: %s", val.Type())
+	}
+	if len(vec) == 0 {
+		return 0, nil
+	}
+	return float64(vec[0].Value), nil
+}
+
+func statesWithError(states []sloState, err error) []sloState {
+	for i := range states {
+		if !states[i].Disabled {
+			states[i].Error = err.Error()
+		}
+	}
+	return states
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	mpromv1 "github.com/spotahome/service-level-operator/mocks/github.com/prometheus/client_golang/api/prometheus/v1"
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	promclifactory "github.com/spotahome/service-level-operator/pkg/service/client/prometheus"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+	"github.com/spotahome/service-level-operator/pkg/web"
+)
+
+var sl0 = &monitoringv1alpha1.ServiceLevel{
+	ObjectMeta: metav1.ObjectMeta{Namespace: "ns0", Name: "sl0"},
+	Spec: monitoringv1alpha1.ServiceLevelSpec{
+		ServiceLevelObjectives: []monitoringv1alpha1.SLO{
+			{Name: "slo0", AvailabilityObjectivePercent: 99},
+			{Name: "slo1", AvailabilityObjectivePercent: 99.9},
+			{Name: "slo2", AvailabilityObjectivePercent: 99, Disable: true},
+		},
+	},
+}
+
+func float64P(f float64) *float64 {
+	return &f
+}
+
+// vector returns a single sample vector.
+func vector(v float64) model.Vector {
+	return model.Vector{&model.Sample{Value: model.SampleValue(v)}}
+}
+
+// isQuery matches the queries of an SLO metric.
+func isQuery(metric, slo string) interface{} {
+	return mock.MatchedBy(func(q string) bool {
+		return strings.Contains(q, metric+"{") && strings.Contains(q, `slo="`+slo+`"`)
+	})
+}
+
+func TestBudgetPrometheusStates(t *testing.T) {
+	tests := map[string]struct {
+		address   string
+		cluster   string
+		mock      func(m *mpromv1.API)
+		expStates []sloState
+	}{
+		"Without Prometheus address the SLOs should have an error.": {
+			mock: func(m *mpromv1.API) {},
+			expStates: []sloState{
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo0", ObjectivePercent: 99, Source: sourcePrometheus, Window: "30d", Error: "the prometheus address with the operator metrics is required"},
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo1", ObjectivePercent: 99.9, Source: sourcePrometheus, Window: "30d", Error: "the prometheus address with the operator metrics is required"},
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo2", ObjectivePercent: 99, Disabled: true, Source: sourcePrometheus, Window: "30d"},
+			},
+		},
+
+		"The error budget should be calculated from the operator counters of the window.": {
+			address: "http://prometheus:9090",
+			mock: func(m *mpromv1.API) {
+				m.On("Query", mock.Anything, isQuery("service_level_sli_result_error_ratio_total", "slo0"), mock.Anything).Return(vector(0.5), nil, nil)
+				m.On("Query", mock.Anything, isQuery("service_level_sli_result_count_total", "slo0"), mock.Anything).Return(vector(100), nil, nil)
+				m.On("Query", mock.Anything, isQuery("service_level_sli_result_error_ratio_total", "slo1"), mock.Anything).Return(vector(0.2), nil, nil)
+				m.On("Query", mock.Anything, isQuery("service_level_sli_result_count_total", "slo1"), mock.Anything).Return(vector(100), nil, nil)
+			},
+			expStates: []sloState{
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo0", ObjectivePercent: 99, Source: sourcePrometheus, Window: "30d", AvailabilityRatio: float64P(0.995), RemainingErrorBudgetRatio: float64P(0.5)},
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo1", ObjectivePercent: 99.9, Source: sourcePrometheus, Window: "30d", AvailabilityRatio: float64P(0.998), RemainingErrorBudgetRatio: float64P(-1), Breached: true},
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo2", ObjectivePercent: 99, Disabled: true, Source: sourcePrometheus, Window: "30d"},
+			},
+		},
+
+		"The SLOs without results or failing queries should have an error.": {
+			address: "http://prometheus:9090",
+			mock: func(m *mpromv1.API) {
+				m.On("Query", mock.Anything, isQuery("service_level_sli_result_error_ratio_total", "slo0"), mock.Anything).Return(model.Vector{}, nil, nil)
+				m.On("Query", mock.Anything, isQuery("service_level_sli_result_count_total", "slo0"), mock.Anything).Return(model.Vector{}, nil, nil)
+				m.On("Query", mock.Anything, isQuery("service_level_sli_result_error_ratio_total", "slo1"), mock.Anything).Return(nil, nil, errors.New("wanted error"))
+			},
+			expStates: []sloState{
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo0", ObjectivePercent: 99, Source: sourcePrometheus, Window: "30d", Error: "no SLI results on the window"},
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo1", ObjectivePercent: 99.9, Source: sourcePrometheus, Window: "30d", Error: "wanted error"},
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo2", ObjectivePercent: 99, Disabled: true, Source: sourcePrometheus, Window: "30d"},
+			},
+		},
+
+		"The queries should select the metrics of the cluster.": {
+			address: "http://prometheus:9090",
+			cluster: "cluster0",
+			mock: func(m *mpromv1.API) {
+				isCluster := mock.MatchedBy(func(q string) bool { return strings.Contains(q, `cluster="cluster0"`) })
+				m.On("Query", mock.Anything, isCluster, mock.Anything).Return(vector(0), nil, nil)
+			},
+			expStates: []sloState{
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo0", ObjectivePercent: 99, Source: sourcePrometheus, Window: "30d", Error: "no SLI results on the window"},
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo1", ObjectivePercent: 99.9, Source: sourcePrometheus, Window: "30d", Error: "no SLI results on the window"},
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo2", ObjectivePercent: 99, Disabled: true, Source: sourcePrometheus, Window: "30d"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			mapi := &mpromv1.API{}
+			test.mock(mapi)
+			o := &options{
+				prometheusAddress: test.address,
+				cluster:           test.cluster,
+				promCliFactory:    &promclifactory.MockFactory{Cli: mapi},
+			}
+
+			states := o.budgetPrometheusStates(context.Background(), sl0, "30d")
+			for i, s := range states {
+				// Avoid the float precision errors.
+				if s.AvailabilityRatio != nil {
+					assert.InDelta(*test.expStates[i].AvailabilityRatio, *s.AvailabilityRatio, 1e-9)
+					assert.InDelta(*test.expStates[i].RemainingErrorBudgetRatio, *s.RemainingErrorBudgetRatio, 1e-9)
+					states[i].AvailabilityRatio = test.expStates[i].AvailabilityRatio
+					states[i].RemainingErrorBudgetRatio = test.expStates[i].RemainingErrorBudgetRatio
+				}
+			}
+			assert.Equal(test.expStates, states)
+			mapi.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCurrentOperatorStates(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	store := status.NewMemory(time.Minute)
+	store.SetServiceLevel("", sl0)
+	store.RecordSLOEvaluation(status.SLOEvaluation{
+		ServiceLevel: sl0,
+		SLO:          &sl0.Spec.ServiceLevelObjectives[0],
+		Time:         now,
+		Result:       &sli.Result{TotalQ: 1000, ErrorQ: 5},
+	})
+	store.RecordSLOEvaluation(status.SLOEvaluation{
+		ServiceLevel: sl0,
+		SLO:          &sl0.Spec.ServiceLevelObjectives[1],
+		Time:         now,
+		Result:       &sli.Result{TotalQ: 1000, ErrorQ: 5},
+	})
+	srv := httptest.NewServer(web.NewAPIHandler(store, log.Dummy))
+	defer srv.Close()
+
+	// A service level with an SLO the operator doesn't know.
+	sl := sl0.DeepCopy()
+	sl.Spec.ServiceLevelObjectives = append(sl.Spec.ServiceLevelObjectives, monitoringv1alpha1.SLO{Name: "slo3", AvailabilityObjectivePercent: 99})
+
+	tests := map[string]struct {
+		address   string
+		cluster   string
+		expStates []sloState
+	}{
+		"The SLOs should have the last result of the operator.": {
+			address: srv.URL,
+			expStates: []sloState{
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo0", ObjectivePercent: 99, Source: sourceOperator, AvailabilityRatio: float64P(0.995), LastEvaluation: &now},
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo1", ObjectivePercent: 99.9, Source: sourceOperator, AvailabilityRatio: float64P(0.995), LastEvaluation: &now, Breached: true},
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo2", ObjectivePercent: 99, Disabled: true, Source: sourceOperator},
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo3", ObjectivePercent: 99, Source: sourceOperator, Error: "SLO not handled by the operator"},
+			},
+		},
+
+		"The SLOs of a service level missing on the operator should have an error.": {
+			address: srv.URL,
+			cluster: "cluster1",
+			expStates: []sloState{
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo0", ObjectivePercent: 99, Source: sourceOperator, Error: "operator API error: service level not found"},
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo1", ObjectivePercent: 99.9, Source: sourceOperator, Error: "operator API error: service level not found"},
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo2", ObjectivePercent: 99, Disabled: true, Source: sourceOperator},
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo3", ObjectivePercent: 99, Source: sourceOperator, Error: "operator API error: service level not found"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			o := &options{operatorAddress: test.address, cluster: test.cluster}
+			states := o.currentOperatorStates(context.Background(), sl)
+			for i := range states {
+				if states[i].LastEvaluation != nil {
+					lastEval := states[i].LastEvaluation.UTC()
+					states[i].LastEvaluation = &lastEval
+				}
+			}
+			assert.Equal(test.expStates, states)
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/backtest"
+	promclifactory "github.com/spotahome/service-level-operator/pkg/service/client/prometheus"
+	"github.com/spotahome/service-level-operator/pkg/service/manifest"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
+)
+
+// backtest defaults
+const (
+	defBacktestRange    = "30d"
+	defBacktestInterval = defResyncSeconds * time.Second
+)
+
+// output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// runBacktest runs the backtest subcommand, it replays the SLOs of the service
+// level manifests over a past time range.
+func runBacktest(args []string) error {
+	var (
+		files     stringsFlag
+		slo       string
+		rangeS    string
+		startS    string
+		endS      string
+		interval  time.Duration
+		promAddr  string
+		outputFmt string
+		timeout   time.Duration
+	)
+	fs := flag.NewFlagSet("backtest", flag.ExitOnError)
+	fs.Var(&files, "f", "the service level manifest files or directories (can be repeated), - reads from stdin")
+	fs.StringVar(&slo, "slo", "", "the SLO to backtest, by default all the SLOs of the service levels")
+	fs.StringVar(&rangeS, "range", defBacktestRange, "the backtested time range until the end (e.g. 12h, 7d, 30d), ignored if the start is set")
+	fs.StringVar(&startS, "start", "", "the start of the backtested time range in RFC3339 format")
+	fs.StringVar(&endS, "end", "", "the end of the backtested time range in RFC3339 format, by default now")
+	fs.DurationVar(&interval, "interval", defBacktestInterval, "the SLO evaluation interval, should be the resync interval of the operator")
+	fs.StringVar(&promAddr, "prometheus-address", "", "the address of the Prometheus used by the SLIs without address")
+	fs.StringVar(&outputFmt, "o", outputTable, "the output format, table or json")
+	fs.DurationVar(&timeout, "timeout", 5*time.Minute, "the timeout of the whole backtest")
+	fs.Usage = commandUsage("backtest", "-f <manifests> [flags]", fs.PrintDefaults)
+	fs.Parse(args)
+
+	if len(files) == 0 {
+		return fmt.Errorf("at least one service level manifest is required")
+	}
+	if outputFmt != outputTable && outputFmt != outputJSON {
+		return fmt.Errorf("unknown %q output format, should be one of: %s, %s", outputFmt, outputTable, outputJSON)
+	}
+
+	end := time.Now()
+	if endS != "" {
+		t, err := time.Parse(time.RFC3339, endS)
+		if err != nil {
+			return fmt.Errorf("invalid end: %s", err)
+		}
+		end = t
+	}
+	var start time.Time
+	if startS != "" {
+		t, err := time.Parse(time.RFC3339, startS)
+		if err != nil {
+			return fmt.Errorf("invalid start: %s", err)
+		}
+		start = t
+	} else {
+		r, err := model.ParseDuration(rangeS)
+		if err != nil {
+			return fmt.Errorf("invalid range: %s", err)
+		}
+		start = end.Add(-time.Duration(r))
+	}
+
+	docs, err := manifest.ReadFiles(files...)
+	if err != nil {
+		return err
+	}
+	sls := manifest.ServiceLevels(docs)
+	if len(sls) == 0 {
+		return fmt.Errorf("no service levels found")
+	}
+
+	promCliFactory := promclifactory.NewBaseFactory()
+	if promAddr != "" {
+		err := promCliFactory.WithDefaultV1APIClient(promAddr)
+		if err != nil {
+			return err
+		}
+	}
+	retriever := sli.NewPrometheusRange(promCliFactory, noop.NewTracerProvider().Tracer(serviceName), log.Dummy)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cfg := backtest.Config{Start: start, End: end, Interval: interval, SLO: slo}
+	results := []backtest.SLOResult{}
+	for _, sl := range sls {
+		res, err := backtest.Run(ctx, retriever, sl, cfg)
+		if err != nil {
+			return err
+		}
+		results = append(results, res...)
+	}
+
+	if outputFmt == outputJSON {
+		return writeBacktestJSON(os.Stdout, cfg, results)
+	}
+	return writeBacktestTable(os.Stdout, results)
+}
+
+func writeBacktestJSON(w io.Writer, cfg backtest.Config, results []backtest.SLOResult) error {
+	out := struct {
+		Start    time.Time            `json:"start"`
+		End      time.Time            `json:"end"`
+		Interval string               `json:"interval"`
+		SLOs     []backtest.SLOResult `json:"slos"`
+	}{
+		Start:    cfg.Start,
+		End:      cfg.End,
+		Interval: cfg.Interval.String(),
+		SLOs:     results,
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func writeBacktestTable(w io.Writer, results []backtest.SLOResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tSERVICE LEVEL\tSLO\tOBJECTIVE\tEVALUATIONS\tFAILED\tAVAILABILITY\tERROR BUDGET\tSTATUS")
+	for _, r := range results {
+		status := "met"
+		switch {
+		case r.Error != "":
+			status = "error: " + r.Error
+		case r.Disabled:
+			status = "disabled"
+		case r.Evaluations == 0:
+			status = "no data"
+		case !r.ObjectiveMet:
+			status = "not met"
+		}
+
+		availability, budget := "-", "-"
+		if r.Evaluations > 0 {
+			availability = fmt.Sprintf("%.4f%%", r.AvailabilityRatio*100)
+			budget = fmt.Sprintf("%.2f%%", r.RemainingErrorBudgetRatio*100)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%g%%\t%d\t%d\t%s\t%s\t%s\n",
+			r.Namespace, r.ServiceLevel, r.SLO, r.ObjectivePercent, r.Evaluations, r.FailedEvaluations, availability, budget, status)
+	}
+	return tw.Flush()
+}
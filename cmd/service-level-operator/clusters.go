@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/spotahome/service-level-operator/pkg/operator"
+	kubernetesclifactory "github.com/spotahome/service-level-operator/pkg/service/client/kubernetes"
+	kubernetesservice "github.com/spotahome/service-level-operator/pkg/service/kubernetes"
+)
+
+// createClusters returns the clusters where the service levels will be watched. If
+// additional clusters are set, the cluster where the operator runs will only be
+// watched if it has a name.
+func (m *Main) createClusters(k8sstdcli kubernetes.Interface, k8ssvc kubernetesservice.Service) ([]operator.Cluster, error) {
+	contexts := splitList(m.flags.kubeContexts)
+	secrets := splitList(m.flags.kubeconfigSecrets)
+	if len(contexts) == 0 && len(secrets) == 0 {
+		return []operator.Cluster{{Name: m.flags.clusterName, Service: k8ssvc}}, nil
+	}
+
+	if m.flags.fake {
+		return nil, fmt.Errorf("multiple clusters can't be used in fake mode")
+	}
+
+	var clusters []operator.Cluster
+	if m.flags.clusterName != "" {
+		clusters = append(clusters, operator.Cluster{Name: m.flags.clusterName, Service: k8ssvc})
+	}
+
+	for _, kctx := range contexts {
+		cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: m.flags.kubeConfig},
+			&clientcmd.ConfigOverrides{CurrentContext: kctx},
+		).ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("could not load %s context configuration: %s", kctx, err)
+		}
+
+		c, err := m.createCluster(kctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, c)
+	}
+
+	for _, secret := range secrets {
+		nsName := strings.SplitN(secret, "/", 2)
+		if len(nsName) != 2 || nsName[0] == "" || nsName[1] == "" {
+			return nil, fmt.Errorf("kubeconfig secrets must be in namespace/name format")
+		}
+
+		s, err := k8sstdcli.CoreV1().Secrets(nsName[0]).Get(nsName[1], metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("could not get %s kubeconfig secret: %s", secret, err)
+		}
+		data, ok := s.Data[m.flags.kubeconfigSecretKey]
+		if !ok {
+			return nil, fmt.Errorf("%s kubeconfig secret doesn't have the %s key", secret, m.flags.kubeconfigSecretKey)
+		}
+		cfg, err := clientcmd.RESTConfigFromKubeConfig(data)
+		if err != nil {
+			return nil, fmt.Errorf("could not load %s kubeconfig secret configuration: %s", secret, err)
+		}
+
+		c, err := m.createCluster(nsName[1], cfg)
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, c)
+	}
+
+	return clusters, nil
+}
+
+// createCluster creates the services of an additional cluster.
+func (m *Main) createCluster(name string, cfg *rest.Config) (operator.Cluster, error) {
+	// Set better cli rate limiter.
+	cfg.QPS = kubeCliQPS
+	cfg.Burst = kubeCliBurst
+
+	stdcli, crdcli, aexcli, err := getKubernetesClients(kubernetesclifactory.NewFactory(cfg))
+	if err != nil {
+		return operator.Cluster{}, fmt.Errorf("could not create %s cluster clients: %s", name, err)
+	}
+
+	logger := m.logger.With("cluster", name)
+	return operator.Cluster{
+		Name:    name,
+		Service: kubernetesservice.New(stdcli, crdcli, aexcli, logger),
+	}, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// command is a subcommand of the app, it receives the arguments after the
+// subcommand name.
+type command func(args []string) error
+
+// commands are the subcommands of the app, without a subcommand the app runs
+// the operator.
+var commands = map[string]command{
+	"backtest":  runBacktest,
+	"dashboard": runDashboard,
+	"eval":      runEval,
+	"export":    runExport,
+	"gate":      runGate,
+	"import":    runImport,
+	"lint":      runLint,
+	"report":    runReport,
+}
+
+// runCommand runs the subcommand of the arguments, false if the arguments
+// don't have a subcommand.
+func runCommand(args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return false, nil
+	}
+
+	return true, cmd(args[1:])
+}
+
+// exitError is an error of a subcommand with a specific exit code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+// exitCode returns the exit code of a subcommand error, 1 by default.
+func exitCode(err error) int {
+	if ee, ok := err.(*exitError); ok {
+		return ee.code
+	}
+	return 1
+}
+
+// stringsFlag is a flag that can be set multiple times.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// commandUsage returns the usage func of a subcommand flag set.
+func commandUsage(name, usage string, printDefaults func()) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s %s\n\nFlags:\n", os.Args[0], name, usage)
+		printDefaults()
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/spotahome/service-level-operator/pkg/service/dashboard"
+	"github.com/spotahome/service-level-operator/pkg/service/manifest"
+)
+
+// dashboard outputs
+const (
+	outputConfigMap = "configmap"
+)
+
+// runDashboard runs the dashboard subcommand, it generates the Grafana dashboards
+// of the service levels offline, the same ones the operator publishes.
+func runDashboard(args []string) error {
+	var (
+		files     stringsFlag
+		window    string
+		cluster   string
+		output    string
+		namespace string
+		lbls      string
+	)
+	fs := flag.NewFlagSet("dashboard", flag.ExitOnError)
+	fs.Var(&files, "f", "the service level manifest files or directories (can be repeated), - reads from stdin")
+	fs.StringVar(&window, "window", "", "the time window (Prometheus duration) of the availability and error budget, by default 30d")
+	fs.StringVar(&cluster, "cluster", "", "the cluster name of the service levels, the same as the operator --cluster-name")
+	fs.StringVar(&output, "o", outputJSON, "the output format, json (a single service level) or configmap")
+	fs.StringVar(&namespace, "namespace", "", "the namespace of the configmaps, by default the namespace of the service levels")
+	fs.StringVar(&lbls, "labels", defDashboardsLabels, "the labels (comma separated, in key=value format) of the configmaps")
+	fs.Usage = commandUsage("dashboard", "-f <manifests> [flags]", fs.PrintDefaults)
+	fs.Parse(args)
+
+	files = append(files, fs.Args()...)
+	if len(files) == 0 {
+		return fmt.Errorf("at least one service level manifest is required")
+	}
+	if output != outputJSON && output != outputConfigMap {
+		return fmt.Errorf("unknown %q output, should be one of: %s, %s", output, outputJSON, outputConfigMap)
+	}
+
+	docs, err := manifest.ReadFiles(files...)
+	if err != nil {
+		return err
+	}
+	sls := manifest.ServiceLevels(docs)
+	if len(sls) == 0 {
+		return fmt.Errorf("no service levels found")
+	}
+	cfg := dashboard.Cfg{Window: window}
+	if output == outputJSON {
+		if len(sls) > 1 {
+			return fmt.Errorf("the json output requires a single service level, found %d, use the configmap output", len(sls))
+		}
+		js, err := dashboard.JSON(cluster, sls[0], cfg)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(os.Stdout, "%s\n", js)
+		return err
+	}
+
+	cmLabels, err := labels.ConvertSelectorToLabelsMap(lbls)
+	if err != nil {
+		return fmt.Errorf("invalid labels: %s", err)
+	}
+	cmCfg := dashboard.ConfigMapCfg{Dashboard: cfg, Namespace: namespace, Labels: cmLabels}
+	objs := make([]interface{}, 0, len(sls))
+	for _, sl := range sls {
+		cm, err := dashboard.ConfigMap(cluster, sl, cmCfg)
+		if err != nil {
+			return err
+		}
+		objs = append(objs, cm)
+	}
+	return manifest.WriteObjects(os.Stdout, objs)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/spotahome/service-level-operator/pkg/log"
+	promclifactory "github.com/spotahome/service-level-operator/pkg/service/client/prometheus"
+	"github.com/spotahome/service-level-operator/pkg/service/configuration"
+	"github.com/spotahome/service-level-operator/pkg/service/eval"
+	"github.com/spotahome/service-level-operator/pkg/service/manifest"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
+)
+
+// runEval runs the eval subcommand, it evaluates the SLOs of the service level
+// manifests once against the SLI sources, without Kubernetes.
+func runEval(args []string) error {
+	var (
+		files         stringsFlag
+		slo           string
+		promAddr      string
+		defSLISrcPath string
+		outputFmt     string
+		timeout       time.Duration
+	)
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	fs.Var(&files, "f", "the service level manifest files or directories (can be repeated), - reads from stdin")
+	fs.StringVar(&slo, "slo", "", "the SLO to evaluate, by default all the SLOs of the service levels")
+	fs.StringVar(&promAddr, "prometheus-address", "", "the address of the Prometheus used by the SLIs without address")
+	fs.StringVar(&defSLISrcPath, "def-sli-source-path", "", "the path to the default SLI source configuration file, like the operator flag")
+	fs.StringVar(&outputFmt, "o", outputTable, "the output format, table or json")
+	fs.DurationVar(&timeout, "timeout", 30*time.Second, "the timeout of the whole evaluation")
+	fs.Usage = commandUsage("eval", "-f <manifests> [flags]", fs.PrintDefaults)
+	fs.Parse(args)
+
+	if len(files) == 0 {
+		return fmt.Errorf("at least one service level manifest is required")
+	}
+	if outputFmt != outputTable && outputFmt != outputJSON {
+		return fmt.Errorf("unknown %q output format, should be one of: %s, %s", outputFmt, outputTable, outputJSON)
+	}
+	if promAddr != "" && defSLISrcPath != "" {
+		return fmt.Errorf("prometheus address and default SLI source path can't be set at the same time")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// The default SLI source is loaded like the operator does.
+	if defSLISrcPath != "" {
+		f, err := os.Open(defSLISrcPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		cfg, err := configuration.YAMLLoader{}.LoadDefaultSLISource(ctx, f)
+		if err != nil {
+			return fmt.Errorf("could not load default SLI source: %s", err)
+		}
+		promAddr = cfg.Prometheus.Address
+	}
+
+	docs, err := manifest.ReadFiles(files...)
+	if err != nil {
+		return err
+	}
+	sls := manifest.ServiceLevels(docs)
+	if len(sls) == 0 {
+		return fmt.Errorf("no service levels found")
+	}
+
+	promCliFactory := promclifactory.NewBaseFactory()
+	if promAddr != "" {
+		err := promCliFactory.WithDefaultV1APIClient(promAddr)
+		if err != nil {
+			return err
+		}
+	}
+	promRetriever := sli.NewPrometheus(promCliFactory, noop.NewTracerProvider().Tracer(serviceName), log.Dummy)
+	retrieverFact := sli.NewRetrieverFactory(promRetriever)
+
+	results := []eval.SLOResult{}
+	for _, sl := range sls {
+		res, err := eval.Run(ctx, retrieverFact, sl, slo)
+		if err != nil {
+			return err
+		}
+		results = append(results, res...)
+	}
+
+	if outputFmt == outputJSON {
+		err = writeEvalJSON(os.Stdout, results)
+	} else {
+		err = writeEvalTable(os.Stdout, results)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		if r.Error != "" {
+			return fmt.Errorf("some SLOs could not be evaluated")
+		}
+	}
+	return nil
+}
+
+func writeEvalJSON(w io.Writer, results []eval.SLOResult) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+func writeEvalTable(w io.Writer, results []eval.SLOResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tSERVICE LEVEL\tSLO\tTOTAL\tERRORS\tERROR RATIO\tAVAILABILITY\tOBJECTIVE\tLATENCY\tSTATUS")
+	for _, r := range results {
+		status := "met"
+		switch {
+		case r.Error != "":
+			status = "error: " + r.Error
+		case r.Disabled:
+			status = "disabled"
+		case !r.ObjectiveMet:
+			status = "not met"
+		}
+
+		total, errs, errRatio, availability, latency := "-", "-", "-", "-", "-"
+		if !r.Disabled {
+			latency = time.Duration(r.LatencySeconds * float64(time.Second)).Round(time.Millisecond).String()
+		}
+		if !r.Disabled && r.Error == "" {
+			total = fmt.Sprintf("%g", r.TotalQ)
+			errs = fmt.Sprintf("%g", r.ErrorQ)
+			errRatio = fmt.Sprintf("%.6f", r.ErrorRatio)
+			availability = fmt.Sprintf("%.4f%%", r.AvailabilityRatio*100)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%g%%\t%s\t%s\n",
+			r.Namespace, r.ServiceLevel, r.SLO, total, errs, errRatio, availability, r.ObjectivePercent, latency, status)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		for _, warn := range r.Warnings {
+			fmt.Fprintf(w, "warning: %s/%s/%s: %s\n", r.Namespace, r.ServiceLevel, r.SLO, warn)
+		}
+	}
+	return nil
+}
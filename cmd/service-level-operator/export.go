@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/spotahome/service-level-operator/pkg/service/manifest"
+	"github.com/spotahome/service-level-operator/pkg/service/openslo"
+)
+
+// runExport runs the export subcommand, it converts the service level manifests
+// to OpenSLO documents. What can't be represented on OpenSLO is printed as
+// warnings.
+func runExport(args []string) error {
+	var (
+		files      stringsFlag
+		timeWindow string
+	)
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fs.Var(&files, "f", "the service level manifest files or directories (can be repeated), - reads from stdin")
+	fs.StringVar(&timeWindow, "time-window", "30d", "the rolling time window of the exported SLOs")
+	fs.Usage = commandUsage("export", "-f <manifests> [flags]", fs.PrintDefaults)
+	fs.Parse(args)
+
+	files = append(files, fs.Args()...)
+	if len(files) == 0 {
+		return fmt.Errorf("at least one service level manifest is required")
+	}
+
+	docs, err := manifest.ReadFiles(files...)
+	if err != nil {
+		return err
+	}
+	sls := manifest.ServiceLevels(docs)
+	if len(sls) == 0 {
+		return fmt.Errorf("no service levels found")
+	}
+
+	objs, issues, err := openslo.Export(sls, openslo.ExportConfig{TimeWindow: timeWindow})
+	if err != nil {
+		return err
+	}
+	for _, i := range issues {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", i)
+	}
+
+	return openslo.Encode(os.Stdout, objs)
+}
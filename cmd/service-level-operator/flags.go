@@ -2,13 +2,25 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/util/homedir"
 
 	"github.com/spotahome/service-level-operator/pkg/operator"
+	"github.com/spotahome/service-level-operator/pkg/service/alertmanager"
+	"github.com/spotahome/service-level-operator/pkg/service/configuration"
+	"github.com/spotahome/service-level-operator/pkg/service/dashboard"
+	"github.com/spotahome/service-level-operator/pkg/service/gate"
+	"github.com/spotahome/service-level-operator/pkg/service/metrics"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/report"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
 )
 
 // defaults
@@ -17,22 +29,71 @@ const (
 	defListenAddress = ":8080"
 	defResyncSeconds = 5
 	defWorkers       = 10
+
+	defSLISourceReloadSeconds = 10
+	defSLISourceConfigMapKey  = "config.json"
+
+	defKubeconfigSecretKey = "kubeconfig"
+
+	defLivenessResyncPeriods = 10
+
+	defDashboardsLabels = "grafana_dashboard=1"
+
+	defAlertmanagerBudgetThresholds  = "50,75,100"
+	defAlertmanagerBurnRate          = 14.4
+	defAlertmanagerBurnRateWindowSec = 3600
 )
 
 type cmdFlags struct {
 	fs *flag.FlagSet
 
-	kubeConfig    string
-	resyncSeconds int
-	workers       int
-	metricsPath   string
-	listenAddress string
-	labelSelector string
-	namespace     string
-	defSLISourcePath string
-	debug         bool
-	development   bool
-	fake          bool
+	configPath                string
+	kubeConfig                string
+	clusterName               string
+	kubeContexts              string
+	kubeconfigSecrets         string
+	kubeconfigSecretKey       string
+	resyncSeconds             int
+	workers                   int
+	metricsPath               string
+	listenAddress             string
+	labelSelector             string
+	namespaces                string
+	namespaceSelector         string
+	defSLISourcePath          string
+	defSLISourceConfigMap     string
+	defSLISourceConfigMapKey  string
+	defSLISourceReloadSeconds int
+	sliCBFailures             int
+	sliCBOpenSeconds          int
+	promOutputExpireSeconds   int
+	otlpEndpoint              string
+	livenessResyncPeriods     int
+	backfillMaxWindowSeconds  int
+	backfillCheckpointPath    string
+	backfillCheckpointCM      string
+	dashboardsNamespace       string
+	dashboardsLabels          string
+	dashboardsWindow          string
+	dashboards                bool
+	reportDir                 string
+	reportFormats             string
+	reportPromAddress         string
+	alertmanagerURLs          string
+	alertmanagerThresholds    string
+	alertmanagerBurnRate      float64
+	alertmanagerBurnRateWin   int
+	gateMinErrorBudget        float64
+	gateAllowUnknown          bool
+	sloMetrics                bool
+	sloMetricsMax             int
+	otlpInsecure              bool
+	checkCRDOnly              bool
+	disableEvents             bool
+	debug                     bool
+	development               bool
+	fake                      bool
+	fakeScenarioPath          string
 }
 
 func newCmdFlags() *cmdFlags {
@@ -48,17 +109,53 @@ func (c *cmdFlags) init() {
 
 	kubehome := filepath.Join(homedir.HomeDir(), ".kube", "config")
 	// register flags
-	c.fs.StringVar(&c.kubeConfig, "kubeconfig", kubehome, "kubernetes configuration path, only used when development mode enabled")
+	c.fs.StringVar(&c.configPath, "config", "", "the path to the operator configuration file, the flags set explicitly override the configuration file values")
+	c.fs.StringVar(&c.kubeConfig, "kubeconfig", kubehome, "kubernetes configuration path, used when development mode enabled and to load the kubernetes contexts")
+	c.fs.StringVar(&c.clusterName, "cluster-name", "", "the name of the cluster where the operator runs, if set the service levels of this cluster will be identified by a cluster label")
+	c.fs.StringVar(&c.kubeContexts, "kube-contexts", "", "the kubeconfig contexts (comma separated) of the additional clusters to watch, the context name is used as the cluster name")
+	c.fs.StringVar(&c.kubeconfigSecrets, "kubeconfig-secrets", "", "the secrets (comma separated, in namespace/name format) with the kubeconfigs of the additional clusters to watch, the secret name is used as the cluster name")
+	c.fs.StringVar(&c.kubeconfigSecretKey, "kubeconfig-secret-key", defKubeconfigSecretKey, "the key of the kubeconfig secrets that has the kubeconfig")
 	c.fs.StringVar(&c.metricsPath, "metrics-path", defMetricsPath, "the path where the metrics will be served")
 	c.fs.StringVar(&c.listenAddress, "listen-addr", defListenAddress, "the address where the metrics will be exposed")
 	c.fs.StringVar(&c.labelSelector, "selector", "", "selector (label query) to filter on, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
-	c.fs.StringVar(&c.namespace, "namespace", "", "the namespace to filter on, by default all")
+	c.fs.StringVar(&c.namespaces, "namespace", "", "the namespaces to filter on (comma separated), by default all")
+	c.fs.StringVar(&c.namespaceSelector, "namespace-selector", "", "selector (label query) of the namespaces to filter on, the namespaces are tracked while the operator is running (e.g. slo.enabled=true)")
 	c.fs.StringVar(&c.defSLISourcePath, "def-sli-source-path", "", "the path to the default sli sources configuration file")
+	c.fs.StringVar(&c.defSLISourceConfigMap, "def-sli-source-configmap", "", "the configmap (in namespace/name format) with the default sli sources configuration, can't be used with the configuration file")
+	c.fs.StringVar(&c.defSLISourceConfigMapKey, "def-sli-source-configmap-key", defSLISourceConfigMapKey, "the key of the default sli sources configmap that has the configuration")
+	c.fs.IntVar(&c.defSLISourceReloadSeconds, "def-sli-source-reload-seconds", defSLISourceReloadSeconds, "the number of seconds between checks of default sli sources configuration changes, 0 disables the reload")
 	c.fs.IntVar(&c.resyncSeconds, "resync-seconds", defResyncSeconds, "the number of seconds for the SLO calculation interval")
 	c.fs.IntVar(&c.workers, "workers", defWorkers, "the number of concurrent workers per controller handling events")
+	c.fs.IntVar(&c.sliCBFailures, "sli-circuit-breaker-failures", sli.DefCircuitBreakerFailureThreshold, "the number of consecutive failures of an SLI source that will open its circuit, 0 disables the circuit breaker")
+	c.fs.IntVar(&c.promOutputExpireSeconds, "prometheus-output-expire-seconds", 0, "the number of seconds an SLO prometheus output metric will expire if not refreshed, by default 90")
+	c.fs.IntVar(&c.sliCBOpenSeconds, "sli-circuit-breaker-open-seconds", int(sli.DefCircuitBreakerOpenDuration/time.Second), "the number of seconds an SLI source circuit will be open before probing the SLI source again")
+	c.fs.IntVar(&c.livenessResyncPeriods, "liveness-resync-periods", defLivenessResyncPeriods, "the number of resync periods without handling any service level that will make the operator not alive, 0 disables the check")
+	c.fs.IntVar(&c.backfillMaxWindowSeconds, "backfill-max-window-seconds", 0, "the maximum number of seconds of missed SLO evaluations (operator downtime or SLI source outages) that will be backfilled, 0 disables the backfill")
+	c.fs.StringVar(&c.backfillCheckpointPath, "backfill-checkpoint-path", "", "the path to the file where the last SLO evaluation times are persisted to backfill the operator downtime")
+	c.fs.StringVar(&c.backfillCheckpointCM, "backfill-checkpoint-configmap", "", "the configmap (in namespace/name format) where the last SLO evaluation times are persisted to backfill the operator downtime")
+	c.fs.BoolVar(&c.dashboards, "dashboards", false, "publish the Grafana dashboard of every service level as a configmap, to be loaded by the Grafana sidecar")
+	c.fs.StringVar(&c.dashboardsNamespace, "dashboards-namespace", "", "the namespace of the dashboard configmaps, by default the namespace of the service levels")
+	c.fs.StringVar(&c.dashboardsLabels, "dashboards-labels", defDashboardsLabels, "the labels (comma separated, in key=value format) of the dashboard configmaps, they should match the Grafana sidecar label")
+	c.fs.StringVar(&c.dashboardsWindow, "dashboards-window", "", "the time window (Prometheus duration) of the availability and error budget of the dashboards, by default 30d")
+	c.fs.StringVar(&c.reportDir, "report-dir", "", "the directory where the monthly SLO compliance reports are written, if empty the reports are disabled")
+	c.fs.StringVar(&c.reportFormats, "report-formats", report.FormatMarkdown, "the formats (comma separated) of the monthly SLO compliance reports, markdown, html or csv")
+	c.fs.StringVar(&c.reportPromAddress, "report-prometheus-address", "", "the address of the Prometheus with the operator metrics used by the reports, by default the default SLI source")
+	c.fs.StringVar(&c.alertmanagerURLs, "alertmanager-url", "", "the URLs (comma separated) of the Alertmanagers where the SLO alerts are sent, if empty the alerts are disabled")
+	c.fs.StringVar(&c.alertmanagerThresholds, "alertmanager-budget-thresholds", defAlertmanagerBudgetThresholds, "the consumed error budget percents (comma separated) that fire an SLO alert")
+	c.fs.Float64Var(&c.alertmanagerBurnRate, "alertmanager-burn-rate", defAlertmanagerBurnRate, "the error budget burn rate that fires an SLO alert, 0 disables the burn rate alerts")
+	c.fs.IntVar(&c.alertmanagerBurnRateWin, "alertmanager-burn-rate-window-seconds", defAlertmanagerBurnRateWindowSec, "the number of seconds of the error budget burn rate window")
+	c.fs.Float64Var(&c.gateMinErrorBudget, "gate-min-error-budget-percent", 0, "the minimum remaining error budget percent of the SLOs to allow changes on the gate API, by default 0 (the SLOs with their error budget exhausted deny)")
+	c.fs.BoolVar(&c.gateAllowUnknown, "gate-allow-unknown", false, "allow changes on the gate API for the SLOs whose error budget is not known yet")
+	c.fs.StringVar(&c.otlpEndpoint, "otlp-endpoint", "", "the OTLP HTTP endpoint (host:port) where the traces will be exported, if empty tracing is disabled")
+	c.fs.BoolVar(&c.otlpInsecure, "otlp-insecure", false, "export the traces to the OTLP endpoint without TLS")
+	c.fs.BoolVar(&c.sloMetrics, "slo-metrics", false, "enable the per SLO operator metrics (evaluation duration, last success, consecutive failures and query samples)")
+	c.fs.IntVar(&c.sloMetricsMax, "slo-metrics-max", 0, "the maximum number of SLOs with per SLO operator metrics, the rest will not be measured, by default 1000")
 	c.fs.BoolVar(&c.development, "development", false, "development flag will allow to run the operator outside a kubernetes cluster")
+	c.fs.BoolVar(&c.checkCRDOnly, "check-crd-only", false, "only check the CRD is present instead of creating or updating it, this way the operator can run without cluster wide permissions")
+	c.fs.BoolVar(&c.disableEvents, "disable-events", false, "disable recording the SLO failures, breaches and recoveries as Kubernetes events on the service levels")
 	c.fs.BoolVar(&c.debug, "debug", false, "enable debug mode")
 	c.fs.BoolVar(&c.fake, "fake", false, "enable faked mode, in faked node external services/dependencies are not needed")
+	c.fs.StringVar(&c.fakeScenarioPath, "fake-scenario", "", "the path to the scenario file with the faked service levels and query values, enables the faked mode")
 
 	// Parse flags
 	c.fs.Parse(os.Args[1:])
@@ -66,9 +163,164 @@ func (c *cmdFlags) init() {
 
 func (c *cmdFlags) toOperatorConfig() operator.Config {
 	return operator.Config{
-		ResyncPeriod:     time.Duration(c.resyncSeconds) * time.Second,
-		ConcurretWorkers: c.workers,
-		LabelSelector:    c.labelSelector,
-		Namespace:        c.namespace,
+		ResyncPeriod:           time.Duration(c.resyncSeconds) * time.Second,
+		ConcurretWorkers:       c.workers,
+		LabelSelector:          c.labelSelector,
+		Namespaces:             splitList(c.namespaces),
+		NamespaceLabelSelector: c.namespaceSelector,
+		CheckCRDOnly:           c.checkCRDOnly,
+		LivenessResyncPeriods:  c.livenessResyncPeriods,
+		SLICircuitBreaker: sli.CircuitBreakerCfg{
+			Disable:          c.sliCBFailures == 0,
+			FailureThreshold: c.sliCBFailures,
+			OpenDuration:     time.Duration(c.sliCBOpenSeconds) * time.Second,
+		},
+		PrometheusOutput: output.PrometheusCfg{
+			ExpireDuration: time.Duration(c.promOutputExpireSeconds) * time.Second,
+		},
+		Dashboards: c.dashboards,
+		DashboardsConfigMap: dashboard.ConfigMapCfg{
+			Dashboard: dashboard.Cfg{Window: c.dashboardsWindow},
+			Namespace: c.dashboardsNamespace,
+		},
+		DisableEvents: c.disableEvents,
+	}
+}
+
+func (c *cmdFlags) toGatePolicy() gate.Policy {
+	return gate.Policy{
+		MinErrorBudgetPercent: c.gateMinErrorBudget,
+		AllowUnknown:          c.gateAllowUnknown,
+	}
+}
+
+func (c *cmdFlags) toAlertmanagerConfig() (alertmanager.Cfg, error) {
+	var thresholds []float64
+	for _, v := range splitList(c.alertmanagerThresholds) {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return alertmanager.Cfg{}, fmt.Errorf("invalid %q alertmanager budget threshold: %s", v, err)
+		}
+		thresholds = append(thresholds, t)
+	}
+	return alertmanager.Cfg{
+		BudgetThresholds: thresholds,
+		DisableBurnRate:  c.alertmanagerBurnRate == 0,
+		BurnRate:         c.alertmanagerBurnRate,
+		BurnRateWindow:   time.Duration(c.alertmanagerBurnRateWin) * time.Second,
+	}, nil
+}
+
+func (c *cmdFlags) toMetricsConfig() metrics.PrometheusCfg {
+	return metrics.PrometheusCfg{
+		SLOMetrics: c.sloMetrics,
+		MaxSLOs:    c.sloMetricsMax,
+	}
+}
+
+// applyConfiguration sets the configuration file values on the flags that have
+// not been set explicitly, this way flags have priority over the configuration file.
+func (c *cmdFlags) applyConfiguration(cfg *configuration.Configuration) {
+	set := map[string]bool{}
+	c.fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	setString := func(name string, dst *string, value string) {
+		if !set[name] && value != "" {
+			*dst = value
+		}
+	}
+	setInt := func(name string, dst *int, value int) {
+		if !set[name] && value != 0 {
+			*dst = value
+		}
+	}
+	setSeconds := func(name string, dst *int, value time.Duration) {
+		setInt(name, dst, int(value/time.Second))
+	}
+
+	setSeconds("resync-seconds", &c.resyncSeconds, cfg.ResyncPeriod)
+	setInt("workers", &c.workers, cfg.Workers)
+	setString("cluster-name", &c.clusterName, cfg.Clusters.Name)
+	setString("kube-contexts", &c.kubeContexts, strings.Join(cfg.Clusters.KubeContexts, ","))
+	setString("kubeconfig-secrets", &c.kubeconfigSecrets, strings.Join(cfg.Clusters.KubeconfigSecrets, ","))
+	setString("namespace", &c.namespaces, strings.Join(cfg.Namespaces, ","))
+	setString("namespace-selector", &c.namespaceSelector, cfg.NamespaceSelector)
+	setString("selector", &c.labelSelector, cfg.LabelSelector)
+	setString("listen-addr", &c.listenAddress, cfg.Server.ListenAddress)
+	setString("metrics-path", &c.metricsPath, cfg.Server.MetricsPath)
+	setSeconds("prometheus-output-expire-seconds", &c.promOutputExpireSeconds, cfg.PrometheusOutput.ExpireDuration)
+	setInt("sli-circuit-breaker-failures", &c.sliCBFailures, cfg.SLICircuitBreaker.FailureThreshold)
+	setSeconds("sli-circuit-breaker-open-seconds", &c.sliCBOpenSeconds, cfg.SLICircuitBreaker.OpenDuration)
+	setInt("slo-metrics-max", &c.sloMetricsMax, cfg.OperatorMetrics.MaxSLOs)
+	if !set["slo-metrics"] && cfg.OperatorMetrics.SLOMetrics {
+		c.sloMetrics = true
+	}
+	setInt("liveness-resync-periods", &c.livenessResyncPeriods, cfg.Health.LivenessResyncPeriods)
+	setSeconds("backfill-max-window-seconds", &c.backfillMaxWindowSeconds, cfg.Backfill.MaxWindow)
+	setString("backfill-checkpoint-path", &c.backfillCheckpointPath, cfg.Backfill.CheckpointPath)
+	setString("backfill-checkpoint-configmap", &c.backfillCheckpointCM, cfg.Backfill.CheckpointConfigMap)
+	if !set["dashboards"] && cfg.Dashboards.Enabled {
+		c.dashboards = true
+	}
+	setString("dashboards-namespace", &c.dashboardsNamespace, cfg.Dashboards.Namespace)
+	setString("dashboards-labels", &c.dashboardsLabels, labels.Set(cfg.Dashboards.Labels).String())
+	setString("dashboards-window", &c.dashboardsWindow, cfg.Dashboards.Window)
+	setString("report-dir", &c.reportDir, cfg.Reports.Dir)
+	setString("report-formats", &c.reportFormats, strings.Join(cfg.Reports.Formats, ","))
+	setString("report-prometheus-address", &c.reportPromAddress, cfg.Reports.PrometheusAddress)
+	setString("alertmanager-url", &c.alertmanagerURLs, strings.Join(cfg.Alertmanager.URLs, ","))
+	setString("alertmanager-budget-thresholds", &c.alertmanagerThresholds, joinFloats(cfg.Alertmanager.BudgetThresholds))
+	if !set["alertmanager-burn-rate"] && cfg.Alertmanager.BurnRate != 0 {
+		c.alertmanagerBurnRate = cfg.Alertmanager.BurnRate
+	}
+	if !set["alertmanager-burn-rate"] && cfg.Alertmanager.DisableBurnRate {
+		c.alertmanagerBurnRate = 0
+	}
+	setSeconds("alertmanager-burn-rate-window-seconds", &c.alertmanagerBurnRateWin, cfg.Alertmanager.BurnRateWindow)
+	if !set["gate-min-error-budget-percent"] && cfg.Gate.MinErrorBudgetPercent != 0 {
+		c.gateMinErrorBudget = cfg.Gate.MinErrorBudgetPercent
+	}
+	if !set["gate-allow-unknown"] && cfg.Gate.AllowUnknown {
+		c.gateAllowUnknown = true
+	}
+	setString("otlp-endpoint", &c.otlpEndpoint, cfg.Tracing.OTLPEndpoint)
+	if !set["otlp-insecure"] && cfg.Tracing.Insecure {
+		c.otlpInsecure = true
+	}
+	if !set["check-crd-only"] && cfg.CheckCRDOnly {
+		c.checkCRDOnly = true
+	}
+	if !set["disable-events"] && cfg.DisableEvents {
+		c.disableEvents = true
+	}
+	if !set["sli-circuit-breaker-failures"] && cfg.SLICircuitBreaker.Disable {
+		c.sliCBFailures = 0
+	}
+
+	// The default SLI sources are loaded (and reloaded) from the configuration file
+	// if there isn't any other default SLI source set.
+	if cfg.DefaultSLISource.Prometheus.Address != "" && c.defSLISourcePath == "" && c.defSLISourceConfigMap == "" {
+		c.defSLISourcePath = c.configPath
+	}
+}
+
+// joinFloats joins a list of numbers as a comma separated list.
+func joinFloats(fs []float64) string {
+	res := make([]string, 0, len(fs))
+	for _, f := range fs {
+		res = append(res, strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	return strings.Join(res, ",")
+}
+
+// splitList splits a comma separated list.
+func splitList(s string) []string {
+	var res []string
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			res = append(res, v)
+		}
 	}
+	return res
 }
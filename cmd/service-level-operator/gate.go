@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spotahome/service-level-operator/pkg/service/gate"
+	"github.com/spotahome/service-level-operator/pkg/web"
+)
+
+// gate subcommand exit codes.
+const (
+	gateExitDenied = 1
+	gateExitError  = 2
+)
+
+// runGate runs the gate subcommand, it asks the error budget gate of a running
+// operator if a service level allows changes. It exits with 0 when allowed, 1
+// when denied and 2 when the decision could not be made, so it can be used as
+// a step of the CI/CD pipelines.
+func runGate(args []string) error {
+	var (
+		operatorAddr string
+		cluster      string
+		outputFmt    string
+		timeout      time.Duration
+		allowOnError bool
+	)
+	fs := flag.NewFlagSet("gate", flag.ExitOnError)
+	fs.StringVar(&operatorAddr, "operator-address", "", "the address of the operator API (e.g. http://service-level-operator.monitoring:8080)")
+	fs.StringVar(&cluster, "cluster", "", "the cluster of the service level when the operator handles multiple clusters")
+	fs.StringVar(&outputFmt, "o", outputTable, "the output format, table or json")
+	fs.DurationVar(&timeout, "timeout", 10*time.Second, "the timeout of the decision request")
+	fs.BoolVar(&allowOnError, "allow-on-error", false, "allow when the decision could not be made (e.g. the operator is not reachable)")
+	fs.Usage = commandUsage("gate", "-operator-address <address> [flags] <namespace>/<service level>", fs.PrintDefaults)
+	fs.Parse(args)
+
+	if operatorAddr == "" {
+		return &exitError{code: gateExitError, err: fmt.Errorf("the operator address is required")}
+	}
+	if outputFmt != outputTable && outputFmt != outputJSON {
+		return &exitError{code: gateExitError, err: fmt.Errorf("unknown %q output format, should be one of: %s, %s", outputFmt, outputTable, outputJSON)}
+	}
+	if fs.NArg() != 1 {
+		return &exitError{code: gateExitError, err: fmt.Errorf("a single <namespace>/<service level> argument is required")}
+	}
+	parts := strings.Split(fs.Arg(0), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return &exitError{code: gateExitError, err: fmt.Errorf("invalid %q service level, should be <namespace>/<service level>", fs.Arg(0))}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cli := web.NewAPIClient(operatorAddr, &http.Client{Timeout: timeout})
+	d, err := cli.Gate(ctx, cluster, parts[0], parts[1])
+	if err != nil {
+		if allowOnError {
+			fmt.Fprintf(os.Stderr, "warning: allowed without a decision: %s\n", err)
+			return nil
+		}
+		return &exitError{code: gateExitError, err: err}
+	}
+
+	if outputFmt == outputJSON {
+		err = writeGateJSON(os.Stdout, d)
+	} else {
+		err = writeGateTable(os.Stdout, d)
+	}
+	if err != nil {
+		return &exitError{code: gateExitError, err: err}
+	}
+
+	if !d.Allowed {
+		return &exitError{code: gateExitDenied, err: fmt.Errorf("the %s/%s service level denies changes: %s", d.Namespace, d.ServiceLevel, strings.Join(d.Reasons, ", "))}
+	}
+	return nil
+}
+
+func writeGateJSON(w io.Writer, d gate.Decision) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+func writeGateTable(w io.Writer, d gate.Decision) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SLO\tERROR BUDGET REMAINING\tMIN ERROR BUDGET\tDECISION\tREASON")
+	for _, slo := range d.SLOs {
+		decision := "allow"
+		switch {
+		case slo.Ignored:
+			decision = "ignored"
+		case !slo.Allowed:
+			decision = "deny"
+		}
+
+		remaining := "-"
+		if slo.ErrorBudgetRemainingPercent != nil {
+			pct := *slo.ErrorBudgetRemainingPercent
+			// Avoid printing the exhausted budgets that round to zero as -0.000%.
+			if math.Abs(pct) < 0.0005 {
+				pct = 0
+			}
+			remaining = fmt.Sprintf("%.3f%%", pct)
+		}
+		reason := slo.Reason
+		if reason == "" {
+			reason = "-"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%g%%\t%s\t%s\n", slo.Name, remaining, slo.MinErrorBudgetPercent, decision, reason)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	decision := "allowed"
+	if !d.Allowed {
+		decision = "denied"
+	}
+	_, err := fmt.Fprintf(w, "%s/%s: %s\n", d.Namespace, d.ServiceLevel, decision)
+	return err
+}
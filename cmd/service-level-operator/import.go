@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/service/manifest"
+	"github.com/spotahome/service-level-operator/pkg/service/openslo"
+	"github.com/spotahome/service-level-operator/pkg/service/sloth"
+)
+
+// import formats
+const (
+	formatAuto    = "auto"
+	formatOpenSLO = "openslo"
+	formatSloth   = "sloth"
+)
+
+// runImport runs the import subcommand, it converts OpenSLO and Sloth specs to
+// service level manifests. What is not imported is printed as warnings.
+func runImport(args []string) error {
+	var (
+		files     stringsFlag
+		from      string
+		namespace string
+		window    string
+	)
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	fs.Var(&files, "f", "the OpenSLO or Sloth spec files or directories (can be repeated), - reads from stdin")
+	fs.StringVar(&from, "from", formatAuto, "the format of the specs, auto, openslo or sloth")
+	fs.StringVar(&namespace, "namespace", "", "the namespace of the service levels, the Sloth CRDs and the exported OpenSLO specs keep their namespace")
+	fs.StringVar(&window, "window", "", "the range that replaces the Sloth templated windows and gets the increase of the OpenSLO counters (e.g. 2m)")
+	fs.Usage = commandUsage("import", "-f <specs> [flags]", fs.PrintDefaults)
+	fs.Parse(args)
+
+	files = append(files, fs.Args()...)
+	if len(files) == 0 {
+		return fmt.Errorf("at least one spec is required")
+	}
+	if from != formatAuto && from != formatOpenSLO && from != formatSloth {
+		return fmt.Errorf("unknown %q format, should be one of: %s, %s, %s", from, formatAuto, formatOpenSLO, formatSloth)
+	}
+
+	paths, err := manifest.Files(files...)
+	if err != nil {
+		return err
+	}
+
+	// The OpenSLO objects reference each other so they are imported at once.
+	var (
+		opensloObjs []interface{}
+		slothSpecs  []sloth.Spec
+	)
+	for _, path := range paths {
+		data, err := readSpecFile(path)
+		if err != nil {
+			return err
+		}
+		if path == "-" {
+			path = "<stdin>"
+		}
+		format := from
+		if format == formatAuto {
+			format, err = detectSpecFormat(data)
+			if err != nil {
+				return fmt.Errorf("%s: %s", path, err)
+			}
+		}
+
+		switch format {
+		case formatOpenSLO:
+			objs, err := openslo.Read(bytes.NewReader(data))
+			if err != nil {
+				return fmt.Errorf("%s: %s", path, err)
+			}
+			opensloObjs = append(opensloObjs, objs...)
+		case formatSloth:
+			specs, err := sloth.Read(bytes.NewReader(data))
+			if err != nil {
+				return fmt.Errorf("%s: %s", path, err)
+			}
+			slothSpecs = append(slothSpecs, specs...)
+		}
+	}
+
+	var sls []*monitoringv1alpha1.ServiceLevel
+	if len(opensloObjs) > 0 {
+		res, issues, err := openslo.Import(opensloObjs, openslo.ImportConfig{Namespace: namespace, Window: window})
+		if err != nil {
+			return err
+		}
+		for _, i := range issues {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", i)
+		}
+		sls = append(sls, res...)
+	}
+	if len(slothSpecs) > 0 {
+		res, issues, err := sloth.Import(slothSpecs, sloth.ImportConfig{Namespace: namespace, Window: window})
+		if err != nil {
+			return err
+		}
+		for _, i := range issues {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", i)
+		}
+		sls = append(sls, res...)
+	}
+	if len(sls) == 0 {
+		return fmt.Errorf("no SLOs found")
+	}
+
+	return manifest.Write(os.Stdout, sls)
+}
+
+func readSpecFile(path string) ([]byte, error) {
+	if path == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(path)
+}
+
+// detectSpecFormat returns the format of the specs based on the first document.
+func detectSpecFormat(data []byte) (string, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var header struct {
+			Version    string `yaml:"version"`
+			APIVersion string `yaml:"apiVersion"`
+		}
+		err := dec.Decode(&header)
+		if err == io.EOF {
+			return "", fmt.Errorf("empty specs")
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case header.APIVersion == openslo.APIVersion:
+			return formatOpenSLO, nil
+		case header.Version == sloth.SpecVersion, header.APIVersion == sloth.KubernetesAPIVersion:
+			return formatSloth, nil
+		case header.Version == "" && header.APIVersion == "":
+			// Empty document.
+			continue
+		}
+		return "", fmt.Errorf("unknown spec format, set it with --from")
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/spotahome/service-level-operator/pkg/service/lint"
+	"github.com/spotahome/service-level-operator/pkg/service/manifest"
+)
+
+// lint output formats
+const (
+	outputText  = "text"
+	outputJUnit = "junit"
+)
+
+// runLint runs the lint subcommand, it checks the service level manifests
+// without a cluster and fails if there is any error.
+func runLint(args []string) error {
+	var (
+		files     stringsFlag
+		outputFmt string
+	)
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	fs.Var(&files, "f", "the service level manifest files or directories (can be repeated), - reads from stdin")
+	fs.StringVar(&outputFmt, "o", outputText, "the output format, text, json or junit")
+	fs.Usage = commandUsage("lint", "[flags] <manifests>...", fs.PrintDefaults)
+	fs.Parse(args)
+
+	files = append(files, fs.Args()...)
+	if len(files) == 0 {
+		return fmt.Errorf("at least one service level manifest is required")
+	}
+	if outputFmt != outputText && outputFmt != outputJSON && outputFmt != outputJUnit {
+		return fmt.Errorf("unknown %q output format, should be one of: %s, %s, %s", outputFmt, outputText, outputJSON, outputJUnit)
+	}
+
+	paths, err := manifest.Files(files...)
+	if err != nil {
+		return err
+	}
+
+	// The files that can't be read are diagnostics, the rest of files are
+	// linted anyway.
+	diags := []lint.Diagnostic{}
+	docs := []manifest.Document{}
+	for _, path := range paths {
+		d, err := manifest.ReadFile(path)
+		if err != nil {
+			diags = append(diags, lint.ReadError(path, err))
+			continue
+		}
+		docs = append(docs, d...)
+	}
+	diags = append(diags, lint.Lint(docs)...)
+	lint.Sort(diags)
+
+	switch outputFmt {
+	case outputJSON:
+		err = writeLintJSON(os.Stdout, diags)
+	case outputJUnit:
+		err = writeLintJUnit(os.Stdout, paths, docs, diags)
+	default:
+		err = writeLintText(os.Stdout, diags)
+	}
+	if err != nil {
+		return err
+	}
+
+	if lint.HasErrors(diags) {
+		return fmt.Errorf("the service level manifests have errors")
+	}
+	return nil
+}
+
+func writeLintText(w io.Writer, diags []lint.Diagnostic) error {
+	for _, d := range diags {
+		if _, err := fmt.Fprintln(w, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeLintJSON(w io.Writer, diags []lint.Diagnostic) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(diags)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeLintJUnit writes a test suite per file and a test case per service level,
+// the errors are failures and the warnings are on the test case output.
+func writeLintJUnit(w io.Writer, paths []string, docs []manifest.Document, diags []lint.Diagnostic) error {
+	type caseID struct{ path, name string }
+	suites := map[string][]caseID{}
+	for _, p := range paths {
+		if p == "-" {
+			p = "<stdin>"
+		}
+		suites[p] = nil
+	}
+	cases := map[caseID][]lint.Diagnostic{}
+	addCase := func(id caseID) {
+		if _, ok := cases[id]; !ok {
+			cases[id] = nil
+			suites[id.path] = append(suites[id.path], id)
+		}
+	}
+	for _, doc := range docs {
+		id := caseID{path: doc.Path, name: doc.Position()}
+		if id.path == "" {
+			id.path = "<stdin>"
+		}
+		if sl := doc.ServiceLevel; sl.Name != "" {
+			id.name = sl.Name
+			if sl.Namespace != "" {
+				id.name = sl.Namespace + "/" + sl.Name
+			}
+		}
+		addCase(id)
+	}
+	for _, d := range diags {
+		id := caseID{path: d.Path, name: d.ServiceLevel}
+		if id.name == "" {
+			id.name = fmt.Sprintf("%s:%d", d.Path, d.Line)
+		}
+		addCase(id)
+		cases[id] = append(cases[id], d)
+	}
+
+	names := make([]string, 0, len(suites))
+	for name := range suites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := junitTestSuites{}
+	for _, name := range names {
+		suite := junitTestSuite{Name: name}
+		for _, id := range suites[name] {
+			tc := junitTestCase{Name: id.name, Classname: name}
+			var failures, warnings string
+			for _, d := range cases[id] {
+				if d.Severity == lint.SeverityError {
+					failures += d.String() + "\n"
+					continue
+				}
+				warnings += d.String() + "\n"
+			}
+			if failures != "" {
+				tc.Failure = &junitFailure{Message: "service level has errors", Type: "lint", Text: failures}
+				suite.Failures++
+			}
+			tc.SystemOut = warnings
+			suite.Cases = append(suite.Cases, tc)
+			suite.Tests++
+		}
+		out.Suites = append(out.Suites, suite)
+		out.Tests += suite.Tests
+		out.Failures += suite.Failures
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
@@ -6,13 +6,21 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/oklog/run"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 	apiextensionscli "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
 	"k8s.io/client-go/rest"
@@ -21,27 +29,69 @@ import (
 	crdcli "github.com/spotahome/service-level-operator/pkg/k8sautogen/client/clientset/versioned"
 	"github.com/spotahome/service-level-operator/pkg/log"
 	"github.com/spotahome/service-level-operator/pkg/operator"
+	"github.com/spotahome/service-level-operator/pkg/service/alertmanager"
+	"github.com/spotahome/service-level-operator/pkg/service/backfill"
 	kubernetesclifactory "github.com/spotahome/service-level-operator/pkg/service/client/kubernetes"
 	promclifactory "github.com/spotahome/service-level-operator/pkg/service/client/prometheus"
 	"github.com/spotahome/service-level-operator/pkg/service/configuration"
+	"github.com/spotahome/service-level-operator/pkg/service/gate"
+	"github.com/spotahome/service-level-operator/pkg/service/health"
 	kubernetesservice "github.com/spotahome/service-level-operator/pkg/service/kubernetes"
 	"github.com/spotahome/service-level-operator/pkg/service/metrics"
+	"github.com/spotahome/service-level-operator/pkg/service/notify"
+	"github.com/spotahome/service-level-operator/pkg/service/report"
+	"github.com/spotahome/service-level-operator/pkg/service/scenario"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+	"github.com/spotahome/service-level-operator/pkg/web"
 )
 
 const (
-	kubeCliQPS   = 100
-	kubeCliBurst = 100
-	gracePeriod  = 2 * time.Second
+	kubeCliQPS          = 100
+	kubeCliBurst        = 100
+	gracePeriod         = 2 * time.Second
+	alertmanagerTimeout = 10 * time.Second
+	notificationTimeout = 10 * time.Second
+	serviceName         = "service-level-operator"
 )
 
 // Main has the main logic of the app.
 type Main struct {
-	flags  *cmdFlags
-	logger log.Logger
+	flags    *cmdFlags
+	scenario *scenario.Scenario
+	logger   log.Logger
 }
 
 // Run runs the main program.
 func (m *Main) Run() error {
+	// Load the configuration file, flags have priority.
+	if m.flags.configPath != "" {
+		f, err := os.Open(m.flags.configPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		cfg, err := configuration.YAMLLoader{}.LoadConfiguration(context.Background(), f)
+		if err != nil {
+			return fmt.Errorf("could not load %s configuration file: %s", m.flags.configPath, err)
+		}
+		m.flags.applyConfiguration(cfg)
+	}
+
+	// The fake scenario replaces the builtin faked objects.
+	if m.flags.fakeScenarioPath != "" {
+		f, err := os.Open(m.flags.fakeScenarioPath)
+		if err != nil {
+			return fmt.Errorf("could not open %s fake scenario file: %s", m.flags.fakeScenarioPath, err)
+		}
+		defer f.Close()
+		m.scenario, err = scenario.Load(f)
+		if err != nil {
+			return fmt.Errorf("could not load %s fake scenario file: %s", m.flags.fakeScenarioPath, err)
+		}
+		m.flags.fake = true
+	}
+
 	// Prepare the logger with the correct settings.
 	jsonLog := true
 	if m.flags.development {
@@ -59,7 +109,22 @@ func (m *Main) Run() error {
 
 	// Create prometheus registry and metrics service to expose and measure with metrics.
 	promReg := prometheus.NewRegistry()
-	metricssvc := metrics.NewPrometheus(promReg)
+	metricssvc := metrics.NewPrometheus(m.flags.toMetricsConfig(), promReg)
+
+	// Create the store of the service levels state, used by the operator and the API.
+	cfg := m.flags.toOperatorConfig()
+	statusStore := status.NewMemory(cfg.ResyncPeriod)
+
+	// Create the error budget gate, it decides with the state of the service levels
+	// and their policy annotations.
+	gatePolicies := gate.NewPolicies(statusStore)
+	gater, err := gate.NewGate(m.flags.toGatePolicy(), statusStore, gatePolicies)
+	if err != nil {
+		return fmt.Errorf("invalid gate policy: %s", err)
+	}
+
+	// Create the health checks, the checks are registered by the components.
+	healthChecks := health.NewChecks()
 
 	// Create services
 	k8sstdcli, k8scrdcli, k8saexcli, err := m.createKubernetesClients()
@@ -68,6 +133,13 @@ func (m *Main) Run() error {
 	}
 	k8ssvc := kubernetesservice.New(k8sstdcli, k8scrdcli, k8saexcli, m.logger)
 
+	// Create the tracer, the spans are flushed when the app exits.
+	tracer, shutdownTracer, err := m.createTracer()
+	if err != nil {
+		return err
+	}
+	defer shutdownTracer()
+
 	// Prepare our run entrypoints.
 	var g run.Group
 
@@ -95,7 +167,7 @@ func (m *Main) Run() error {
 
 	// Metrics.
 	{
-		s := m.createHTTPServer(promReg)
+		s := m.createHTTPServer(promReg, statusStore, gater, healthChecks)
 		g.Add(
 			func() error {
 				m.logger.Infof("metrics server listening on %s", m.flags.listenAddress)
@@ -115,29 +187,148 @@ func (m *Main) Run() error {
 
 	// Operator.
 	{
+		// Create SLI source client factories.
+		promCliFactory, reloader, err := m.createPrometheusCliFactory(k8sstdcli, metricssvc)
+		if err != nil {
+			return err
+		}
 
-		// Load configuration.
-		var cfgSLISrc *configuration.DefaultSLISource
-		if m.flags.defSLISourcePath != "" {
-			f, err := os.Open(m.flags.defSLISourcePath)
+		// The default SLI source needs to be reachable to evaluate the SLOs, it's
+		// only checked while it has an address, it can be set or removed on reloads.
+		if reloader != nil {
+			healthChecks.RegisterReadiness("default-sli-source", health.CheckerFunc(func(ctx context.Context) error {
+				if cfg := reloader.Current(); cfg == nil || cfg.Prometheus.Address == "" {
+					return nil
+				}
+				return promclifactory.CheckV1APIClient(ctx, promCliFactory, "")
+			}))
+		}
+
+		// Default SLI source configuration reloader.
+		if reloader != nil && m.flags.defSLISourceReloadSeconds > 0 {
+			stopC := make(chan struct{})
+			g.Add(
+				func() error {
+					return reloader.Run(stopC)
+				},
+				func(_ error) {
+					close(stopC)
+				},
+			)
+		}
+
+		// Missed evaluations backfill, the checkpoints are persisted periodically.
+		var backfiller backfill.Backfiller = backfill.Dummy
+		rangeBackfiller, err := m.createBackfiller(k8sstdcli, promCliFactory, cfg.ResyncPeriod, tracer)
+		if err != nil {
+			return err
+		}
+		if rangeBackfiller != nil {
+			err := rangeBackfiller.Load(context.Background())
 			if err != nil {
 				return err
 			}
-			defer f.Close()
-			cfgSLISrc, err = configuration.JSONLoader{}.LoadDefaultSLISource(context.Background(), f)
+			backfiller = rangeBackfiller
+
+			stopC := make(chan struct{})
+			g.Add(
+				func() error {
+					return rangeBackfiller.Run(stopC)
+				},
+				func(_ error) {
+					close(stopC)
+				},
+			)
+		}
+
+		// Monthly SLO compliance reports of the handled service levels.
+		if m.flags.reportDir != "" {
+			promCli, err := promCliFactory.GetV1APIClient(m.flags.reportPromAddress)
+			if err != nil {
+				return fmt.Errorf("could not create the reports prometheus client: %s", err)
+			}
+			scheduler, err := report.NewScheduler(report.SchedulerCfg{
+				Dir:     m.flags.reportDir,
+				Formats: splitList(m.flags.reportFormats),
+			}, promCli, statusStore, m.logger.With("report", "scheduler"))
 			if err != nil {
 				return err
 			}
+
+			stopC := make(chan struct{})
+			g.Add(
+				func() error {
+					return scheduler.Run(stopC)
+				},
+				func(_ error) {
+					close(stopC)
+				},
+			)
 		}
 
-		// Create SLI source client factories.
-		promCliFactory, err := m.createPrometheusCliFactory(cfgSLISrc)
+		clusters, err := m.createClusters(k8sstdcli, k8ssvc)
 		if err != nil {
 			return err
 		}
 
-		cfg := m.flags.toOperatorConfig()
-		op, err := operator.New(cfg, promReg, promCliFactory, k8ssvc, metricssvc, m.logger)
+		// The dashboard configmaps are discovered by the Grafana sidecar by their labels.
+		if cfg.Dashboards {
+			cfg.DashboardsConfigMap.Labels, err = labels.ConvertSelectorToLabelsMap(m.flags.dashboardsLabels)
+			if err != nil {
+				return fmt.Errorf("invalid dashboards labels: %s", err)
+			}
+		}
+
+		// The SLO alerts are sent to Alertmanager from the recorded evaluations.
+		var recorder status.Recorder = gatePolicies
+		if urls := splitList(m.flags.alertmanagerURLs); len(urls) > 0 {
+			amCfg, err := m.flags.toAlertmanagerConfig()
+			if err != nil {
+				return err
+			}
+			amCli, err := alertmanager.NewClient(urls, &http.Client{Timeout: alertmanagerTimeout})
+			if err != nil {
+				return err
+			}
+			notifier, err := alertmanager.NewNotifier(amCfg, amCli, recorder, m.logger.With("alertmanager", "notifier"))
+			if err != nil {
+				return fmt.Errorf("invalid alertmanager configuration: %s", err)
+			}
+			recorder = notifier
+
+			stopC := make(chan struct{})
+			g.Add(
+				func() error {
+					return notifier.Run(stopC)
+				},
+				func(_ error) {
+					close(stopC)
+				},
+			)
+		}
+
+		// The SLO state changes are notified to the notifications of the service levels.
+		{
+			secrets := notify.ClusterSecrets{}
+			for _, c := range clusters {
+				secrets[c.Name] = c.Service
+			}
+			sender := notify.NewWebhookSender(secrets, &http.Client{Timeout: notificationTimeout})
+			notifier := notify.NewNotifier(notify.Cfg{}, sender, recorder, metricssvc, m.logger.With("notify", "notifier"))
+			recorder = notifier
+
+			stopC := make(chan struct{})
+			g.Add(
+				func() error {
+					return notifier.Run(stopC)
+				},
+				func(_ error) {
+					close(stopC)
+				},
+			)
+		}
+
+		op, err := operator.NewMultiCluster(cfg, promReg, promCliFactory, clusters, recorder, backfiller, metricssvc, healthChecks, tracer, m.logger)
 		if err != nil {
 			return err
 		}
@@ -185,9 +376,12 @@ func (m *Main) loadKubernetesConfig() (*rest.Config, error) {
 func (m *Main) createKubernetesClients() (kubernetes.Interface, crdcli.Interface, apiextensionscli.Interface, error) {
 	var factory kubernetesclifactory.ClientFactory
 
-	if m.flags.fake {
+	switch {
+	case m.scenario != nil:
+		factory = m.scenario.KubernetesFactory()
+	case m.flags.fake:
 		factory = kubernetesclifactory.NewFake()
-	} else {
+	default:
 		config, err := m.loadKubernetesConfig()
 		if err != nil {
 			return nil, nil, nil, err
@@ -195,6 +389,10 @@ func (m *Main) createKubernetesClients() (kubernetes.Interface, crdcli.Interface
 		factory = kubernetesclifactory.NewFactory(config)
 	}
 
+	return getKubernetesClients(factory)
+}
+
+func getKubernetesClients(factory kubernetesclifactory.ClientFactory) (kubernetes.Interface, crdcli.Interface, apiextensionscli.Interface, error) {
 	stdcli, err := factory.GetSTDClient()
 	if err != nil {
 		return nil, nil, nil, err
@@ -213,39 +411,160 @@ func (m *Main) createKubernetesClients() (kubernetes.Interface, crdcli.Interface
 	return stdcli, crdcli, aexcli, nil
 }
 
-func (m *Main) createPrometheusCliFactory(cfg *configuration.DefaultSLISource) (promclifactory.ClientFactory, error) {
+func (m *Main) createPrometheusCliFactory(k8sstdcli kubernetes.Interface, metricssvc metrics.Service) (promclifactory.ClientFactory, *configuration.DefaultSLISourceReloader, error) {
+	if m.scenario != nil {
+		return m.scenario.PrometheusFactory(time.Now()), nil, nil
+	}
 	if m.flags.fake {
-		return promclifactory.NewFakeFactory(), nil
+		return promclifactory.NewFakeFactory(), nil, nil
 	}
 
 	f := promclifactory.NewBaseFactory()
-	if cfg != nil && cfg.Prometheus.Address != "" {
-		err := f.WithDefaultV1APIClient(cfg.Prometheus.Address)
+
+	src, err := m.defSLISource(k8sstdcli)
+	if err != nil {
+		return nil, nil, err
+	}
+	if src == nil {
+		return f, nil, nil
+	}
+
+	// The default SLI source will be set, and swapped on every reload.
+	logger := m.logger.With("config", "default-sli-source")
+	reloader, err := configuration.NewDefaultSLISourceReloader(configuration.DefaultSLISourceReloaderCfg{
+		Interval: time.Duration(m.flags.defSLISourceReloadSeconds) * time.Second,
+		Source:   src,
+		OnReload: func(cfg *configuration.DefaultSLISource) error {
+			// Without address the previous default SLI source is not used anymore.
+			if cfg.Prometheus.Address == "" {
+				f.WithoutDefaultV1APIClient()
+				logger.Warnf("prometheus default SLI source not set")
+				return nil
+			}
+			err := f.WithDefaultV1APIClient(cfg.Prometheus.Address)
+			if err != nil {
+				return err
+			}
+			logger.Infof("prometheus default SLI source set to: %s", cfg.Prometheus.Address)
+			return nil
+		},
+	}, metricssvc, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Initial load, at this point the configuration must be valid.
+	_, err = reloader.Reload(context.Background())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return f, reloader, nil
+}
+
+// defSLISource returns the source of the default SLI source configuration, nil if not set.
+func (m *Main) defSLISource(k8sstdcli kubernetes.Interface) (configuration.Source, error) {
+	switch {
+	case m.flags.defSLISourcePath != "" && m.flags.defSLISourceConfigMap != "":
+		return nil, fmt.Errorf("default sli source can't be loaded from a file and a configmap at the same time")
+	case m.flags.defSLISourcePath != "":
+		return configuration.FileSource{Path: m.flags.defSLISourcePath}, nil
+	case m.flags.defSLISourceConfigMap != "":
+		nsName := strings.SplitN(m.flags.defSLISourceConfigMap, "/", 2)
+		if len(nsName) != 2 || nsName[0] == "" || nsName[1] == "" {
+			return nil, fmt.Errorf("default sli source configmap must be in namespace/name format")
+		}
+		return configuration.ConfigMapSource{
+			Client:    k8sstdcli,
+			Namespace: nsName[0],
+			Name:      nsName[1],
+			Key:       m.flags.defSLISourceConfigMapKey,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// createBackfiller returns the backfiller of the missed evaluations, nil if the
+// backfill is disabled.
+func (m *Main) createBackfiller(k8sstdcli kubernetes.Interface, promCliFactory promclifactory.ClientFactory, interval time.Duration, tracer trace.Tracer) (*backfill.RangeBackfiller, error) {
+	if m.flags.backfillMaxWindowSeconds <= 0 {
+		return nil, nil
+	}
+
+	var store backfill.CheckpointStore
+	switch {
+	case m.flags.backfillCheckpointPath != "" && m.flags.backfillCheckpointCM != "":
+		return nil, fmt.Errorf("backfill checkpoints can't be persisted on a file and a configmap at the same time")
+	case m.flags.backfillCheckpointPath != "":
+		store = backfill.FileStore{Path: m.flags.backfillCheckpointPath}
+	case m.flags.backfillCheckpointCM != "":
+		nsName := strings.SplitN(m.flags.backfillCheckpointCM, "/", 2)
+		if len(nsName) != 2 || nsName[0] == "" || nsName[1] == "" {
+			return nil, fmt.Errorf("backfill checkpoint configmap must be in namespace/name format")
+		}
+		store = backfill.ConfigMapStore{
+			Client:    k8sstdcli,
+			Namespace: nsName[0],
+			Name:      nsName[1],
+		}
+	default:
+		m.logger.Warnf("backfill checkpoints are not persisted, the operator downtime will not be backfilled")
+	}
+
+	logger := m.logger.With("backfill", "prometheus")
+	retriever := sli.NewPrometheusRange(promCliFactory, tracer, logger)
+	return backfill.NewRangeBackfiller(backfill.Cfg{
+		Interval:  interval,
+		MaxWindow: time.Duration(m.flags.backfillMaxWindowSeconds) * time.Second,
+		Store:     store,
+	}, retriever, logger)
+}
+
+// createTracer creates the tracer of the app, if the OTLP endpoint is not set the
+// tracer will be a no-op tracer. The returned func will flush and stop the tracer.
+func (m *Main) createTracer() (trace.Tracer, func(), error) {
+	if m.flags.otlpEndpoint == "" {
+		return noop.NewTracerProvider().Tracer(serviceName), func() {}, nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(m.flags.otlpEndpoint)}
+	if m.flags.otlpInsecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create OTLP trace exporter: %s", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+	)
+	m.logger.Infof("exporting traces to %s", m.flags.otlpEndpoint)
+
+	shutdown := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+		defer cancel()
+		err := tp.Shutdown(ctx)
 		if err != nil {
-			return nil, err
+			m.logger.Errorf("error while flushing the traces: %s", err)
 		}
-		m.logger.Infof("prometheus default SLI source set to: %s", cfg.Prometheus.Address)
 	}
 
-	return f, nil
+	return tp.Tracer(serviceName), shutdown, nil
 }
 
-// createHTTPServer creates the http server that serves prometheus metrics and healthchecks.
-func (m *Main) createHTTPServer(promReg *prometheus.Registry) http.Server {
+// createHTTPServer creates the http server that serves prometheus metrics, the UI, the API, the gate API and healthchecks.
+func (m *Main) createHTTPServer(promReg *prometheus.Registry, statusReader status.Reader, decider web.Decider, healthReporter health.Reporter) http.Server {
 	h := promhttp.HandlerFor(promReg, promhttp.HandlerOpts{})
 	mux := http.NewServeMux()
 	mux.Handle(m.flags.metricsPath, h)
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte(`<html>
-			<head><title>Service level operator</title></head>
-			<body>
-			<h1>Service level operator</h1>
-			<p><a href="` + m.flags.metricsPath + `">Metrics</a></p>
-			</body>
-			</html>`))
-	})
-	mux.HandleFunc("/healthz/ready", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(`ready`)) })
-	mux.HandleFunc("/healthz/live", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(`live`)) })
+	mux.Handle(web.APIPrefix, web.NewAPIHandler(statusReader, m.logger.WithField("http", "api")))
+	mux.Handle(web.GatePrefix, web.NewGateHandler(decider, m.logger.WithField("http", "gate")))
+	mux.Handle("/", web.NewUIHandler(statusReader, m.flags.metricsPath, m.logger.WithField("http", "ui")))
+	mux.Handle(web.ReadyPath, web.NewReadyHandler(healthReporter, m.logger.WithField("http", "health")))
+	mux.Handle(web.LivePath, web.NewLiveHandler(healthReporter, m.logger.WithField("http", "health")))
 
 	return http.Server{
 		Handler: mux,
@@ -254,6 +573,15 @@ func (m *Main) createHTTPServer(promReg *prometheus.Registry) http.Server {
 }
 
 func main() {
+	// Subcommands don't run the operator.
+	if ok, err := runCommand(os.Args[1:]); ok {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(exitCode(err))
+		}
+		os.Exit(0)
+	}
+
 	m := &Main{flags: newCmdFlags()}
 
 	// Party time!
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	promclifactory "github.com/spotahome/service-level-operator/pkg/service/client/prometheus"
+	"github.com/spotahome/service-level-operator/pkg/service/manifest"
+	"github.com/spotahome/service-level-operator/pkg/service/report"
+)
+
+const (
+	monthFormat = "2006-01"
+	dayFormat   = "2006-01-02"
+)
+
+// runReport runs the report subcommand, it generates the compliance report of
+// the SLOs of the service level manifests on a period from the operator metrics
+// stored on Prometheus.
+func runReport(args []string) error {
+	var (
+		files     stringsFlag
+		month     string
+		startS    string
+		endS      string
+		cluster   string
+		promAddr  string
+		outputFmt string
+		worstDays int
+		timeout   time.Duration
+	)
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	fs.Var(&files, "f", "the service level manifest files or directories (can be repeated), - reads from stdin")
+	fs.StringVar(&month, "month", "", "the reported month in YYYY-MM format (UTC), by default the previous month")
+	fs.StringVar(&startS, "start", "", "the first reported day in YYYY-MM-DD format (UTC), instead of the month")
+	fs.StringVar(&endS, "end", "", "the last reported day in YYYY-MM-DD format (UTC), by default yesterday")
+	fs.StringVar(&cluster, "cluster", "", "the cluster name of the service levels, the same as the operator --cluster-name")
+	fs.StringVar(&promAddr, "prometheus-address", "", "the address of the Prometheus with the operator metrics")
+	fs.StringVar(&outputFmt, "o", report.FormatMarkdown, fmt.Sprintf("the output format, %s or %s", strings.Join(report.Formats, ", "), outputJSON))
+	fs.IntVar(&worstDays, "worst-days", 3, "the number of days with the lowest availability of every SLO")
+	fs.DurationVar(&timeout, "timeout", 5*time.Minute, "the timeout of the whole report")
+	fs.Usage = commandUsage("report", "-f <manifests> --prometheus-address <address> [flags]", fs.PrintDefaults)
+	fs.Parse(args)
+
+	files = append(files, fs.Args()...)
+	if len(files) == 0 {
+		return fmt.Errorf("at least one service level manifest is required")
+	}
+	if promAddr == "" {
+		return fmt.Errorf("the prometheus address with the operator metrics is required")
+	}
+	if outputFmt != outputJSON && !isReportFormat(outputFmt) {
+		return fmt.Errorf("unknown %q output format, should be one of: %s, %s", outputFmt, strings.Join(report.Formats, ", "), outputJSON)
+	}
+
+	start, end, err := reportPeriod(time.Now().UTC(), month, startS, endS)
+	if err != nil {
+		return err
+	}
+
+	docs, err := manifest.ReadFiles(files...)
+	if err != nil {
+		return err
+	}
+	var sls []report.ServiceLevel
+	for _, sl := range manifest.ServiceLevels(docs) {
+		sls = append(sls, report.ServiceLevel{Cluster: cluster, ServiceLevel: sl})
+	}
+	if len(sls) == 0 {
+		return fmt.Errorf("no service levels found")
+	}
+
+	cli, err := promclifactory.NewBaseFactory().GetV1APIClient(promAddr)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	r, err := report.Generate(ctx, cli, sls, report.Config{Start: start, End: end, WorstDays: worstDays})
+	if err != nil {
+		return err
+	}
+
+	if outputFmt == outputJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	return report.Write(os.Stdout, r, outputFmt)
+}
+
+func isReportFormat(format string) bool {
+	for _, f := range report.Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// reportPeriod returns the reported period, a month or a range of days, the
+// end is not included.
+func reportPeriod(now time.Time, month, startS, endS string) (start, end time.Time, err error) {
+	if month != "" && (startS != "" || endS != "") {
+		return start, end, fmt.Errorf("the month can't be used with the start and end")
+	}
+
+	if startS == "" {
+		m, _ := report.Month(now)
+		m = m.AddDate(0, -1, 0)
+		if month != "" {
+			m, err = time.Parse(monthFormat, month)
+			if err != nil {
+				return start, end, fmt.Errorf("invalid month: %s", err)
+			}
+		}
+		start, end = report.Month(m)
+		return start, end, nil
+	}
+
+	start, err = time.Parse(dayFormat, startS)
+	if err != nil {
+		return start, end, fmt.Errorf("invalid start: %s", err)
+	}
+	end = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if endS != "" {
+		last, err := time.Parse(dayFormat, endS)
+		if err != nil {
+			return start, end, fmt.Errorf("invalid end: %s", err)
+		}
+		end = last.AddDate(0, 0, 1)
+	}
+	return start, end, nil
+}
@@ -2,6 +2,7 @@
 
 package slo
 
+import context "context"
 import mock "github.com/stretchr/testify/mock"
 
 import sli "github.com/spotahome/service-level-operator/pkg/service/sli"
@@ -12,13 +13,13 @@ type Output struct {
 	mock.Mock
 }
 
-// Create provides a mock function with given fields: serviceLevel, _a1, result
-func (_m *Output) Create(serviceLevel *v1alpha1.ServiceLevel, _a1 *v1alpha1.SLO, result *sli.Result) error {
-	ret := _m.Called(serviceLevel, _a1, result)
+// Create provides a mock function with given fields: ctx, cluster, serviceLevel, _a3, result
+func (_m *Output) Create(ctx context.Context, cluster string, serviceLevel *v1alpha1.ServiceLevel, _a3 *v1alpha1.SLO, result *sli.Result) error {
+	ret := _m.Called(ctx, cluster, serviceLevel, _a3, result)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*v1alpha1.ServiceLevel, *v1alpha1.SLO, *sli.Result) error); ok {
-		r0 = rf(serviceLevel, _a1, result)
+	if rf, ok := ret.Get(0).(func(context.Context, string, *v1alpha1.ServiceLevel, *v1alpha1.SLO, *sli.Result) error); ok {
+		r0 = rf(ctx, cluster, serviceLevel, _a3, result)
 	} else {
 		r0 = ret.Error(0)
 	}
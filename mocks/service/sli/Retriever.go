@@ -2,6 +2,7 @@
 
 package sli
 
+import context "context"
 import mock "github.com/stretchr/testify/mock"
 import sli "github.com/spotahome/service-level-operator/pkg/service/sli"
 import v1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
@@ -11,20 +12,20 @@ type Retriever struct {
 	mock.Mock
 }
 
-// Retrieve provides a mock function with given fields: _a0
-func (_m *Retriever) Retrieve(_a0 *v1alpha1.SLI) (sli.Result, error) {
-	ret := _m.Called(_a0)
+// Retrieve provides a mock function with given fields: _a0, _a1
+func (_m *Retriever) Retrieve(_a0 context.Context, _a1 *v1alpha1.SLI) (sli.Result, error) {
+	ret := _m.Called(_a0, _a1)
 
 	var r0 sli.Result
-	if rf, ok := ret.Get(0).(func(*v1alpha1.SLI) sli.Result); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, *v1alpha1.SLI) sli.Result); ok {
+		r0 = rf(_a0, _a1)
 	} else {
 		r0 = ret.Get(0).(sli.Result)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*v1alpha1.SLI) error); ok {
-		r1 = rf(_a0)
+	if rf, ok := ret.Get(1).(func(context.Context, *v1alpha1.SLI) error); ok {
+		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
 	}
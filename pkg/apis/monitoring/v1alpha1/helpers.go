@@ -1,6 +1,22 @@
 package v1alpha1
 
-import "fmt"
+import (
+	"fmt"
+	"text/template"
+)
+
+// WebhookTemplateFuncs are the names of the functions available on the webhook
+// notification templates, besides the Go template ones.
+var WebhookTemplateFuncs = []string{"json", "percent"}
+
+// ReservedOutputLabels are the labels set by the operator on the SLO output
+// metrics, they can't be used as SLO output labels.
+var ReservedOutputLabels = map[string]bool{
+	"namespace":     true,
+	"service_level": true,
+	"slo":           true,
+	"cluster":       true,
+}
 
 // Validate validates and sets defaults on the ServiceLevel
 // Kubernetes resource object.
@@ -18,6 +34,69 @@ func (s *ServiceLevel) Validate() error {
 		}
 	}
 
+	names := map[string]bool{}
+	for _, n := range s.Spec.Notifications {
+		if names[n.Name] {
+			return fmt.Errorf("the %s notification is repeated", n.Name)
+		}
+		names[n.Name] = true
+		err := s.validateNotification(&n)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *ServiceLevel) validateNotification(n *Notification) error {
+	if n.Name == "" {
+		return fmt.Errorf("a notification must have a name")
+	}
+
+	for _, name := range n.SLOs {
+		found := false
+		for _, slo := range s.Spec.ServiceLevelObjectives {
+			if slo.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("the %s notification has the %s SLO that doesn't exist", n.Name, name)
+		}
+	}
+
+	for _, e := range n.Events {
+		switch e {
+		case NotificationEventBreached, NotificationEventRecovered, NotificationEventBudgetReset:
+		default:
+			return fmt.Errorf("the %s notification has the unknown %q event", n.Name, e)
+		}
+	}
+
+	if n.MinInterval != nil && n.MinInterval.Duration < 0 {
+		return fmt.Errorf("the %s notification min interval can't be negative", n.Name)
+	}
+
+	// Check destinations.
+	if n.Webhook == nil {
+		return fmt.Errorf("the %s notification must have a destination", n.Name)
+	}
+	if n.Webhook.URLSecretRef.Name == "" || n.Webhook.URLSecretRef.Key == "" {
+		return fmt.Errorf("the %s notification webhook must have the secret name and key of the URL", n.Name)
+	}
+	if n.Webhook.Template != "" {
+		// Only the template syntax is checked, the functions don't run.
+		funcs := template.FuncMap{}
+		for _, name := range WebhookTemplateFuncs {
+			funcs[name] = func(...interface{}) string { return "" }
+		}
+		if _, err := template.New("message").Funcs(funcs).Parse(n.Webhook.Template); err != nil {
+			return fmt.Errorf("the %s notification webhook template is not valid: %s", n.Name, err)
+		}
+	}
+
 	return nil
 }
 
@@ -39,6 +118,11 @@ func (s *ServiceLevel) validateSLO(slo *SLO) error {
 	if slo.Output.Prometheus == nil {
 		return fmt.Errorf("the %s SLO must have at least one output source", slo.Name)
 	}
+	for name := range slo.Output.Prometheus.Labels {
+		if ReservedOutputLabels[name] {
+			return fmt.Errorf("the %s SLO output label %q is already set by the operator", slo.Name, name)
+		}
+	}
 
 	return nil
 }
@@ -47,6 +47,35 @@ func TestServiceLevelValidation(t *testing.T) {
 	slSLOWithoutSLI.Spec.ServiceLevelObjectives[0].ServiceLevelIndicator.Prometheus = nil
 	slSLOWithoutOutput := goodSL.DeepCopy()
 	slSLOWithoutOutput.Spec.ServiceLevelObjectives[0].Output.Prometheus = nil
+	slSLOWithReservedOutputLabel := goodSL.DeepCopy()
+	slSLOWithReservedOutputLabel.Spec.ServiceLevelObjectives[0].Output.Prometheus.Labels = map[string]string{"cluster": "cluster0"}
+	slWithNotification := goodSL.DeepCopy()
+	slWithNotification.Spec.Notifications = []monitoringv1alpha1.Notification{
+		{
+			Name:   "chat",
+			SLOs:   []string{"fake_slo0"},
+			Events: []string{"breached", "recovered"},
+			Webhook: &monitoringv1alpha1.WebhookNotification{
+				URLSecretRef: monitoringv1alpha1.SecretKeyRef{Name: "chat-webhook", Key: "url"},
+			},
+		},
+	}
+	slNotificationWithoutDestination := slWithNotification.DeepCopy()
+	slNotificationWithoutDestination.Spec.Notifications[0].Webhook = nil
+	slNotificationWithoutSecretKey := slWithNotification.DeepCopy()
+	slNotificationWithoutSecretKey.Spec.Notifications[0].Webhook.URLSecretRef.Key = ""
+	slNotificationWithUnknownSLO := slWithNotification.DeepCopy()
+	slNotificationWithUnknownSLO.Spec.Notifications[0].SLOs = []string{"fake_slo1"}
+	slNotificationWithUnknownEvent := slWithNotification.DeepCopy()
+	slNotificationWithUnknownEvent.Spec.Notifications[0].Events = []string{"exploded"}
+	slNotificationWithTemplate := slWithNotification.DeepCopy()
+	slNotificationWithTemplate.Spec.Notifications[0].Webhook.Template = `{"text": {{ printf "%s %s" .SLO (percent .AvailabilityRatio) | json }}}`
+	slNotificationWithInvalidTemplate := slWithNotification.DeepCopy()
+	slNotificationWithInvalidTemplate.Spec.Notifications[0].Webhook.Template = `{"text": {{ .Summary }`
+	slNotificationWithUnknownTemplateFunc := slWithNotification.DeepCopy()
+	slNotificationWithUnknownTemplateFunc.Spec.Notifications[0].Webhook.Template = `{"text": {{ yaml .Summary }}}`
+	slRepeatedNotification := slWithNotification.DeepCopy()
+	slRepeatedNotification.Spec.Notifications = append(slRepeatedNotification.Spec.Notifications, slRepeatedNotification.Spec.Notifications[0])
 
 	tests := []struct {
 		name         string
@@ -83,6 +112,56 @@ func TestServiceLevelValidation(t *testing.T) {
 			serviceLevel: slSLOWithoutOutput,
 			expErr:       true,
 		},
+		{
+			name:         "A ServiceLevel with an SLO output label set by the operator shouldn't be valid.",
+			serviceLevel: slSLOWithReservedOutputLabel,
+			expErr:       true,
+		},
+		{
+			name:         "A ServiceLevel with a valid notification should be valid.",
+			serviceLevel: slWithNotification,
+			expErr:       false,
+		},
+		{
+			name:         "A ServiceLevel with a notification without destination shouldn't be valid.",
+			serviceLevel: slNotificationWithoutDestination,
+			expErr:       true,
+		},
+		{
+			name:         "A ServiceLevel with a notification without the webhook secret key shouldn't be valid.",
+			serviceLevel: slNotificationWithoutSecretKey,
+			expErr:       true,
+		},
+		{
+			name:         "A ServiceLevel with a notification template should be valid.",
+			serviceLevel: slNotificationWithTemplate,
+			expErr:       false,
+		},
+		{
+			name:         "A ServiceLevel with an invalid notification template shouldn't be valid.",
+			serviceLevel: slNotificationWithInvalidTemplate,
+			expErr:       true,
+		},
+		{
+			name:         "A ServiceLevel with a notification template with an unknown function shouldn't be valid.",
+			serviceLevel: slNotificationWithUnknownTemplateFunc,
+			expErr:       true,
+		},
+		{
+			name:         "A ServiceLevel with a notification of an unknown SLO shouldn't be valid.",
+			serviceLevel: slNotificationWithUnknownSLO,
+			expErr:       true,
+		},
+		{
+			name:         "A ServiceLevel with a notification of an unknown event shouldn't be valid.",
+			serviceLevel: slNotificationWithUnknownEvent,
+			expErr:       true,
+		},
+		{
+			name:         "A ServiceLevel with repeated notifications shouldn't be valid.",
+			serviceLevel: slRepeatedNotification,
+			expErr:       true,
+		},
 	}
 
 	for _, test := range tests {
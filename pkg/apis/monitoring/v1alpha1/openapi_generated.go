@@ -29,15 +29,80 @@ import (
 
 func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
 	return map[string]common.OpenAPIDefinition{
+		"github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.Notification":           schema_pkg_apis_monitoring_v1alpha1_Notification(ref),
 		"github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.Output":                 schema_pkg_apis_monitoring_v1alpha1_Output(ref),
 		"github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.PrometheusOutputSource": schema_pkg_apis_monitoring_v1alpha1_PrometheusOutputSource(ref),
 		"github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.PrometheusSLISource":    schema_pkg_apis_monitoring_v1alpha1_PrometheusSLISource(ref),
 		"github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.SLI":                    schema_pkg_apis_monitoring_v1alpha1_SLI(ref),
 		"github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.SLISource":              schema_pkg_apis_monitoring_v1alpha1_SLISource(ref),
 		"github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.SLO":                    schema_pkg_apis_monitoring_v1alpha1_SLO(ref),
+		"github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.SecretKeyRef":           schema_pkg_apis_monitoring_v1alpha1_SecretKeyRef(ref),
 		"github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.ServiceLevel":           schema_pkg_apis_monitoring_v1alpha1_ServiceLevel(ref),
 		"github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.ServiceLevelList":       schema_pkg_apis_monitoring_v1alpha1_ServiceLevelList(ref),
 		"github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.ServiceLevelSpec":       schema_pkg_apis_monitoring_v1alpha1_ServiceLevelSpec(ref),
+		"github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.WebhookNotification":    schema_pkg_apis_monitoring_v1alpha1_WebhookNotification(ref),
+	}
+}
+
+func schema_pkg_apis_monitoring_v1alpha1_Notification(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "Notification is a notification of the SLO state changes (breached, recovered and error budget reset).",
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the notification.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"slos": {
+						SchemaProps: spec.SchemaProps{
+							Description: "SLOs are the names of the notified SLOs, by default all of them.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"events": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Events are the notified events (breached, recovered and budgetReset), by default all of them.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"minInterval": {
+						SchemaProps: spec.SchemaProps{
+							Description: "MinInterval is the minimum interval between the messages of an SLO, the state changes in between are coalesced. By default 5m.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Duration"),
+						},
+					},
+					"webhook": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Webhook is the chat webhook notification.",
+							Ref:         ref("github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.WebhookNotification"),
+						},
+					},
+				},
+				Required: []string{"name"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.WebhookNotification", "k8s.io/apimachinery/pkg/apis/meta/v1.Duration"},
 	}
 }
 
@@ -218,6 +283,34 @@ func schema_pkg_apis_monitoring_v1alpha1_SLO(ref common.ReferenceCallback) commo
 	}
 }
 
+func schema_pkg_apis_monitoring_v1alpha1_SecretKeyRef(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "SecretKeyRef is a key of a secret.",
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name is the name of the secret.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"key": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Key is the key of the secret.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"name", "key"},
+			},
+		},
+		Dependencies: []string{},
+	}
+}
+
 func schema_pkg_apis_monitoring_v1alpha1_ServiceLevel(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -323,10 +416,51 @@ func schema_pkg_apis_monitoring_v1alpha1_ServiceLevelSpec(ref common.ReferenceCa
 							},
 						},
 					},
+					"notifications": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Notifications are the notifications of the SLO state changes.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.Notification"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.Notification", "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.SLO"},
+	}
+}
+
+func schema_pkg_apis_monitoring_v1alpha1_WebhookNotification(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "WebhookNotification sends the notifications to a chat webhook (e.g. Slack or Mattermost incoming webhooks).",
+				Properties: map[string]spec.Schema{
+					"urlSecretRef": {
+						SchemaProps: spec.SchemaProps{
+							Description: "URLSecretRef is the key of the secret with the webhook URL, the secret must be on the service level namespace.",
+							Ref:         ref("github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.SecretKeyRef"),
+						},
+					},
+					"template": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Template is the Go template of the message body, by default a Slack compatible JSON message.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
 				},
+				Required: []string{"urlSecretRef"},
 			},
 		},
 		Dependencies: []string{
-			"github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.SLO"},
+			"github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1.SecretKeyRef"},
 	}
 }
@@ -28,6 +28,9 @@ type ServiceLevelSpec struct {
 	// ServiceLevelObjectives is the list of SLOs of a service/app.
 	// +optional
 	ServiceLevelObjectives []SLO `json:"serviceLevelObjectives,omitempty"`
+	// Notifications are the notifications of the SLO state changes.
+	// +optional
+	Notifications []Notification `json:"notifications,omitempty"`
 }
 
 // SLO represents a SLO.
@@ -84,6 +87,58 @@ type PrometheusOutputSource struct {
 	Labels map[string]string `json:"labels,omitempty"`
 }
 
+// Notification events.
+const (
+	// NotificationEventBreached is notified when the error budget of an SLO is exhausted.
+	NotificationEventBreached = "breached"
+	// NotificationEventRecovered is notified when a breached SLO has error budget again.
+	NotificationEventRecovered = "recovered"
+	// NotificationEventBudgetReset is notified when the error budget of an SLO is
+	// reset (the output counters have been reset).
+	NotificationEventBudgetReset = "budgetReset"
+)
+
+// Notification is a notification of the SLO state changes (breached, recovered
+// and error budget reset).
+type Notification struct {
+	// Name of the notification.
+	Name string `json:"name"`
+	// SLOs are the names of the notified SLOs, by default all of them.
+	// +optional
+	SLOs []string `json:"slos,omitempty"`
+	// Events are the notified events (breached, recovered and budgetReset), by
+	// default all of them.
+	// +optional
+	Events []string `json:"events,omitempty"`
+	// MinInterval is the minimum interval between the messages of an SLO, the
+	// state changes in between are coalesced. By default 5m.
+	// +optional
+	MinInterval *metav1.Duration `json:"minInterval,omitempty"`
+	// Webhook is the chat webhook notification.
+	// +optional
+	Webhook *WebhookNotification `json:"webhook,omitempty"`
+}
+
+// WebhookNotification sends the notifications to a chat webhook (e.g. Slack or
+// Mattermost incoming webhooks).
+type WebhookNotification struct {
+	// URLSecretRef is the key of the secret with the webhook URL, the secret must
+	// be on the service level namespace.
+	URLSecretRef SecretKeyRef `json:"urlSecretRef"`
+	// Template is the Go template of the message body, by default a Slack
+	// compatible JSON message.
+	// +optional
+	Template string `json:"template,omitempty"`
+}
+
+// SecretKeyRef is a key of a secret.
+type SecretKeyRef struct {
+	// Name is the name of the secret.
+	Name string `json:"name"`
+	// Key is the key of the secret.
+	Key string `json:"key"`
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // ServiceLevelList is a list of ServiceLevel resources
@@ -13,17 +13,24 @@ import (
 
 // serviceLevelCRD is the crd release.
 type serviceLevelCRD struct {
-	cfg     Config
-	service kubernetes.Service
-	logger  log.Logger
+	cfg        Config
+	namespaces namespaceSource
+	service    kubernetes.Service
+	state      *crdState
+	logger     log.Logger
 }
 
-func newServiceLevelCRD(cfg Config, service kubernetes.Service, logger log.Logger) *serviceLevelCRD {
+// newServiceLevelCRD returns a new service level CRD. If namespaces is nil the
+// service levels will be watched on all namespaces or on a single namespace
+// (based on the configuration).
+func newServiceLevelCRD(cfg Config, namespaces namespaceSource, service kubernetes.Service, logger log.Logger) *serviceLevelCRD {
 	logger = logger.With("crd", "servicelevel")
 	return &serviceLevelCRD{
-		cfg:     cfg,
-		service: service,
-		logger:  logger,
+		cfg:        cfg,
+		namespaces: namespaces,
+		service:    service,
+		state:      &crdState{},
+		logger:     logger,
 	}
 }
 
@@ -39,21 +46,47 @@ func (s *serviceLevelCRD) Initialize() error {
 		EnableStatusSubresource: true,
 	}
 
-	return s.service.EnsurePresentCRD(crd)
+	// The CRD could be managed by someone else.
+	ensure := s.service.EnsurePresentCRD
+	if s.cfg.CheckCRDOnly {
+		ensure = s.service.CheckPresentCRD
+	}
+
+	err := ensure(crd)
+	if err != nil {
+		return err
+	}
+	s.state.setInitialized()
+
+	return nil
 }
 
 // GetListerWatcher satisfies resource.crd interface (and retrieve.Retriever).
 func (s *serviceLevelCRD) GetListerWatcher() cache.ListerWatcher {
-	return &cache.ListWatch{
+	if s.namespaces != nil {
+		return syncTrackingListerWatcher{
+			ListerWatcher: newMultiNamespaceListerWatcher(s.namespaces, s.service, s.cfg.LabelSelector),
+			state:         s.state,
+		}
+	}
+
+	// All namespaces or a single one.
+	ns := ""
+	if len(s.cfg.Namespaces) == 1 {
+		ns = s.cfg.Namespaces[0]
+	}
+	lw := &cache.ListWatch{
 		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
 			options.LabelSelector = s.cfg.LabelSelector
-			return s.service.ListServiceLevels(s.cfg.Namespace, options)
+			return s.service.ListServiceLevels(ns, options)
 		},
 		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
 			options.LabelSelector = s.cfg.LabelSelector
-			return s.service.WatchServiceLevels(s.cfg.Namespace, options)
+			return s.service.WatchServiceLevels(ns, options)
 		},
 	}
+
+	return syncTrackingListerWatcher{ListerWatcher: lw, state: s.state}
 }
 
 // GetObject satisfies resource.crd interface (and retrieve.Retriever).
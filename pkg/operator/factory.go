@@ -1,19 +1,28 @@
 package operator
 
 import (
+	"context"
+	"fmt"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	kmetrics "github.com/spotahome/kooper/monitoring/metrics"
 	"github.com/spotahome/kooper/operator"
 	"github.com/spotahome/kooper/operator/controller"
+	"github.com/spotahome/kooper/operator/resource"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/backfill"
 	promcli "github.com/spotahome/service-level-operator/pkg/service/client/prometheus"
+	"github.com/spotahome/service-level-operator/pkg/service/dashboard"
+	"github.com/spotahome/service-level-operator/pkg/service/events"
+	"github.com/spotahome/service-level-operator/pkg/service/health"
 	"github.com/spotahome/service-level-operator/pkg/service/kubernetes"
 	"github.com/spotahome/service-level-operator/pkg/service/metrics"
 	"github.com/spotahome/service-level-operator/pkg/service/output"
 	"github.com/spotahome/service-level-operator/pkg/service/sli"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
 )
 
 const (
@@ -29,47 +38,196 @@ type Config struct {
 	ConcurretWorkers int
 	// LabelSelector is the label selector to filter Kubernetes resources by labels.
 	LabelSelector string
-	// Namespace is the namespace to filter Kubernetes resources by a single namespace.
-	Namespace string
+	// Namespaces are the namespaces to filter Kubernetes resources, if empty all namespaces
+	// will be used.
+	Namespaces []string
+	// NamespaceLabelSelector is the label selector to filter the namespaces where the Kubernetes
+	// resources will be watched, it can't be used with Namespaces.
+	NamespaceLabelSelector string
+	// CheckCRDOnly will only check that the CRD is present instead of creating or updating
+	// it, this way the operator doesn't require cluster wide permissions on the CRDs.
+	CheckCRDOnly bool
+	// SLICircuitBreaker is the configuration of the circuit breaker of the SLI sources.
+	SLICircuitBreaker sli.CircuitBreakerCfg
+	// PrometheusOutput is the configuration of the Prometheus SLO output.
+	PrometheusOutput output.PrometheusCfg
+	// LivenessResyncPeriods is the number of resync periods without handling any
+	// service level that will make the operator not alive, 0 disables the check.
+	LivenessResyncPeriods int
+	// Dashboards enables publishing the Grafana dashboard of every service level
+	// as a configmap, to be loaded by the Grafana sidecar.
+	Dashboards bool
+	// DashboardsConfigMap is the configuration of the dashboard configmaps.
+	DashboardsConfigMap dashboard.ConfigMapCfg
+	// DisableEvents disables recording the lifecycle and the failures of the SLOs
+	// as Kubernetes events on the service levels.
+	DisableEvents bool
+}
+
+// Cluster is a Kubernetes cluster where the service levels will be watched.
+type Cluster struct {
+	// Name is the name of the cluster, it will be used to identify the service
+	// levels of the cluster. It can be empty only when a single cluster is used.
+	Name string
+	// Service is the Kubernetes service of the cluster.
+	Service kubernetes.Service
 }
 
 // New returns pod terminator operator.
-func New(cfg Config, promreg *prometheus.Registry, promCliFactory promcli.ClientFactory, k8ssvc kubernetes.Service, metricssvc metrics.Service, logger log.Logger) (operator.Operator, error) {
+func New(cfg Config, promreg *prometheus.Registry, promCliFactory promcli.ClientFactory, k8ssvc kubernetes.Service, recorder status.Recorder, backfiller backfill.Backfiller, metricssvc metrics.Service, healthreg health.Registerer, tracer trace.Tracer, logger log.Logger) (operator.Operator, error) {
+	return NewMultiCluster(cfg, promreg, promCliFactory, []Cluster{{Service: k8ssvc}}, recorder, backfiller, metricssvc, healthreg, tracer, logger)
+}
 
-	// Create crd.
-	ptCRD := newServiceLevelCRD(cfg, k8ssvc, logger)
+// NewMultiCluster returns an operator that watches the service levels of multiple
+// clusters, the SLIs and the outputs are shared by all the clusters. The readiness
+// (CRD initialized and service levels synced) and liveness (controller handling the
+// service levels) checks of every cluster are registered on the health registerer.
+func NewMultiCluster(cfg Config, promreg *prometheus.Registry, promCliFactory promcli.ClientFactory, clusters []Cluster, recorder status.Recorder, backfiller backfill.Backfiller, metricssvc metrics.Service, healthreg health.Registerer, tracer trace.Tracer, logger log.Logger) (operator.Operator, error) {
+	if len(cfg.Namespaces) > 0 && cfg.NamespaceLabelSelector != "" {
+		return nil, fmt.Errorf("namespaces and namespace label selector can't be used at the same time")
+	}
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("at least one cluster is required")
+	}
+	if len(clusters) > 1 {
+		names := map[string]bool{}
+		for _, c := range clusters {
+			if c.Name == "" {
+				return nil, fmt.Errorf("clusters require a name when using multiple clusters")
+			}
+			if names[c.Name] {
+				return nil, fmt.Errorf("%s cluster name is duplicated", c.Name)
+			}
+			names[c.Name] = true
+		}
+	}
 
 	// Create services.
-	promRetriever := sli.NewPrometheus(promCliFactory, logger.WithField("sli-retriever", "prometheus"))
+	promRetriever := sli.NewPrometheus(promCliFactory, tracer, logger.WithField("sli-retriever", "prometheus"))
 	retrieverFact := sli.NewRetrieverFactory(
-		sli.NewMetricsMiddleware(metricssvc, "prometheus", promRetriever),
+		sli.NewCircuitBreakerMiddleware(cfg.SLICircuitBreaker, metricssvc, "prometheus",
+			sli.NewMetricsMiddleware(metricssvc, "prometheus", promRetriever),
+			logger.WithField("sli-retriever", "prometheus")),
 	)
 
-	promOutput := output.NewPrometheus(output.PrometheusCfg{}, promreg, logger.WithField("slo-output", "prometheus"))
+	promOutput := output.NewPrometheus(cfg.PrometheusOutput, promreg, logger.WithField("slo-output", "prometheus"))
 	outputFact := output.NewFactory(
-		output.NewMetricsMiddleware(metricssvc, "prometheus", promOutput),
+		output.NewTracingMiddleware(tracer, "prometheus",
+			output.NewMetricsMiddleware(metricssvc, "prometheus", promOutput)),
 	)
 
-	// Create handler.
-	handler := NewHandler(outputFact, retrieverFact, logger)
+	// The recorded SLO states have the counters of the output.
+	if counters, ok := promOutput.(output.CounterGetter); ok {
+		recorder = status.NewCountersMiddleware(counters, recorder)
+	}
+
+	ctrlMetrics := kmetrics.NewPrometheus(promreg)
+
+	// Create a CRD and a controller for each cluster.
+	var crds []resource.CRD
+	var ctrls []controller.Controller
+	var nsTrackers []*namespaceTracker
+	for _, c := range clusters {
+		clusterLogger := logger
+		ctrlName := operatorName
+		if c.Name != "" {
+			clusterLogger = logger.With("cluster", c.Name)
+			ctrlName = fmt.Sprintf("%s-%s", operatorName, c.Name)
+		}
+
+		// Select the namespaces to watch.
+		var nsSource namespaceSource
+		switch {
+		case cfg.NamespaceLabelSelector != "":
+			nsTracker := newNamespaceTracker(cfg.NamespaceLabelSelector, c.Service, clusterLogger)
+			nsTrackers = append(nsTrackers, nsTracker)
+			nsSource = nsTracker
+		case len(cfg.Namespaces) > 1:
+			nsSource = staticNamespaces(cfg.Namespaces)
+		}
+
+		// Create crd.
+		slCRD := newServiceLevelCRD(cfg, nsSource, c.Service, clusterLogger)
+
+		// Create the dashboard publisher, the dashboards are published on every cluster.
+		var dashboards dashboard.Publisher = dashboard.Dummy
+		if cfg.Dashboards {
+			p, err := dashboard.NewConfigMapPublisher(cfg.DashboardsConfigMap, c.Service, clusterLogger.WithField("dashboards", "configmap"))
+			if err != nil {
+				return nil, err
+			}
+			dashboards = p
+		}
 
-	// Create controller.
-	ctrlCfg := &controller.Config{
-		Name:                 operatorName,
-		ConcurrentWorkers:    cfg.ConcurretWorkers,
-		ResyncInterval:       cfg.ResyncPeriod,
-		ProcessingJobRetries: jobRetries,
+		// The events are recorded on the service levels of every cluster.
+		clusterRecorder := recorder
+		if !cfg.DisableEvents {
+			clusterRecorder = events.NewRecorder(events.Cfg{}, c.Service, recorder, clusterLogger.WithField("events", "kubernetes"))
+		}
+
+		// Create handler.
+		handler := NewClusterHandler(c.Name, outputFact, retrieverFact, clusterRecorder, backfiller, dashboards, metricssvc, tracer, clusterLogger)
+
+		// Create controller.
+		ctrlCfg := &controller.Config{
+			Name:                 ctrlName,
+			ConcurrentWorkers:    cfg.ConcurretWorkers,
+			ResyncInterval:       cfg.ResyncPeriod,
+			ProcessingJobRetries: jobRetries,
+		}
+
+		ctrl := controller.New(
+			ctrlCfg,
+			handler,
+			slCRD,
+			nil,
+			nil,
+			ctrlMetrics,
+			clusterLogger)
+
+		// Register the health checks of the cluster.
+		checkName := func(name string) string {
+			if c.Name == "" {
+				return name
+			}
+			return fmt.Sprintf("%s-%s", name, c.Name)
+		}
+		healthreg.RegisterReadiness(checkName("crd"), health.CheckerFunc(slCRD.state.checkInitialized))
+		healthreg.RegisterReadiness(checkName("servicelevels-sync"), health.CheckerFunc(slCRD.state.checkSynced))
+		if cfg.LivenessResyncPeriods > 0 {
+			maxInactivity := time.Duration(cfg.LivenessResyncPeriods) * cfg.ResyncPeriod
+			healthreg.RegisterLiveness(checkName("controller"), health.CheckerFunc(func(_ context.Context) error {
+				return handler.CheckActivity(maxInactivity)
+			}))
+		}
+
+		crds = append(crds, slCRD)
+		ctrls = append(ctrls, ctrl)
+	}
+
+	// Assemble CRDs and controllers to create the operator.
+	op := operator.NewMultiOperator(crds, ctrls, logger)
+	if len(nsTrackers) > 0 {
+		op = &namespaceTrackingOperator{Operator: op, trackers: nsTrackers}
 	}
 
-	ctrl := controller.New(
-		ctrlCfg,
-		handler,
-		ptCRD,
-		nil,
-		nil,
-		kmetrics.NewPrometheus(promreg),
-		logger)
-
-	// Assemble CRD and controller to create the operator.
-	return operator.NewOperator(ptCRD, ctrl, logger), nil
+	return op, nil
+}
+
+// namespaceTrackingOperator is an operator that needs to track the watched
+// namespaces before running.
+type namespaceTrackingOperator struct {
+	operator.Operator
+	trackers []*namespaceTracker
+}
+
+// Run satisfies operator.Operator interface.
+func (n *namespaceTrackingOperator) Run(stopC <-chan struct{}) error {
+	for _, tracker := range n.trackers {
+		err := tracker.Run(stopC)
+		if err != nil {
+			return err
+		}
+	}
+	return n.Operator.Run(stopC)
 }
@@ -4,44 +4,90 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
 
 	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
 	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/backfill"
+	"github.com/spotahome/service-level-operator/pkg/service/dashboard"
+	"github.com/spotahome/service-level-operator/pkg/service/metrics"
 	"github.com/spotahome/service-level-operator/pkg/service/output"
 	"github.com/spotahome/service-level-operator/pkg/service/sli"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
 )
 
 // Handler is the Operator handler.
 type Handler struct {
+	cluster       string
 	outputerFact  output.Factory
 	retrieverFact sli.RetrieverFactory
+	recorder      status.Recorder
+	backfiller    backfill.Backfiller
+	dashboards    dashboard.Publisher
+	metricssvc    metrics.Service
+	tracer        trace.Tracer
+	activity      *activityTracker
 	logger        log.Logger
 }
 
 // NewHandler returns a new project handler
-func NewHandler(outputerFact output.Factory, retrieverFact sli.RetrieverFactory, logger log.Logger) *Handler {
+func NewHandler(outputerFact output.Factory, retrieverFact sli.RetrieverFactory, recorder status.Recorder, backfiller backfill.Backfiller, dashboards dashboard.Publisher, metricssvc metrics.Service, tracer trace.Tracer, logger log.Logger) *Handler {
+	return NewClusterHandler("", outputerFact, retrieverFact, recorder, backfiller, dashboards, metricssvc, tracer, logger)
+}
+
+// NewClusterHandler returns a new handler for the service levels of a named cluster,
+// the handled service levels will be identified with the cluster name.
+func NewClusterHandler(cluster string, outputerFact output.Factory, retrieverFact sli.RetrieverFactory, recorder status.Recorder, backfiller backfill.Backfiller, dashboards dashboard.Publisher, metricssvc metrics.Service, tracer trace.Tracer, logger log.Logger) *Handler {
 	return &Handler{
+		cluster:       cluster,
 		outputerFact:  outputerFact,
 		retrieverFact: retrieverFact,
+		recorder:      recorder,
+		backfiller:    backfiller,
+		dashboards:    dashboards,
+		metricssvc:    metricssvc,
+		tracer:        tracer,
+		activity:      newActivityTracker(),
 		logger:        logger,
 	}
 }
 
 // Add will ensure the the ci builds and jobs are persisted.
-func (h *Handler) Add(_ context.Context, obj runtime.Object) error {
+func (h *Handler) Add(ctx context.Context, obj runtime.Object) error {
 	sl, ok := obj.(*monitoringv1alpha1.ServiceLevel)
 	if !ok {
 		return fmt.Errorf("can't handle received object, it's not a service level object")
 	}
 
+	// Every handling (failed or not) is activity of the controller.
+	defer h.activity.handled(fmt.Sprintf("%s/%s", sl.Namespace, sl.Name))
+
 	slc := sl.DeepCopy()
 
+	ctx, span := h.tracer.Start(ctx, "Handler.Add", trace.WithAttributes(serviceLevelAttributes(h.cluster, slc)...))
+	defer span.End()
+
 	err := slc.Validate()
 	if err != nil {
+		if vr, ok := h.recorder.(status.ValidationRecorder); ok {
+			vr.RecordValidationError(h.cluster, slc, err)
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
+	h.recorder.SetServiceLevel(h.cluster, slc)
+
+	// The dashboard is not required to measure the SLOs.
+	if err := h.dashboards.Publish(ctx, h.cluster, slc); err != nil {
+		h.logger.With("sl", sl.Name).Errorf("error publishing dashboard: %s", err)
+	}
 
 	var wg sync.WaitGroup
 	wg.Add(len(slc.Spec.ServiceLevelObjectives))
@@ -52,11 +98,17 @@ func (h *Handler) Add(_ context.Context, obj runtime.Object) error {
 
 		go func() {
 			defer wg.Done()
-			err := h.processSLO(slc, &slo)
+			err := h.processSLO(ctx, slc, &slo)
 			// Don't stop if one of the SLOs errors, the rest should
 			// be processed independently.
 			if err != nil {
-				h.logger.With("sl", sl.Name).With("slo", slo.Name).Errorf("error processing SLO: %s", err)
+				logger := h.logger.With("sl", sl.Name).With("slo", slo.Name)
+				// Skipped SLOs due to unhealthy SLI sources are not failures of the SLO itself.
+				if sli.IsCircuitOpenError(err) {
+					logger.With("skipped", true).Warnf("SLO not processed: %s", err)
+					return
+				}
+				logger.Errorf("error processing SLO: %s", err)
 			}
 		}()
 	}
@@ -65,28 +117,77 @@ func (h *Handler) Add(_ context.Context, obj runtime.Object) error {
 	return nil
 }
 
-func (h *Handler) processSLO(sl *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO) error {
+func (h *Handler) processSLO(ctx context.Context, sl *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO) (err error) {
+	ctx, span := h.tracer.Start(ctx, "Handler.processSLO", trace.WithAttributes(serviceLevelAttributes(h.cluster, sl)...))
+	span.SetAttributes(attribute.String("slo", slo.Name))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
 	if slo.Disable {
+		span.SetAttributes(attribute.Bool("slo.disabled", true))
 		h.logger.Debugf("ignoring SLO %s", slo.Name)
 		return nil
 	}
 
+	// Skipped SLOs are not evaluated so they are not measured.
+	var res *sli.Result
+	evalTime := time.Now()
+	defer func(t time.Time) {
+		skipped := sli.IsCircuitOpenError(err)
+		if !skipped {
+			h.metricssvc.ObserveSLOEvaluation(h.cluster, sl, slo, t, err)
+		}
+		h.recorder.RecordSLOEvaluation(status.SLOEvaluation{
+			Cluster:      h.cluster,
+			ServiceLevel: sl,
+			SLO:          slo,
+			Time:         t,
+			Duration:     time.Since(t),
+			Result:       res,
+			Err:          err,
+			Skipped:      skipped,
+		})
+	}(evalTime)
+
 	retriever, err := h.retrieverFact.GetStrategy(&slo.ServiceLevelIndicator)
 	if err != nil {
 		return err
 	}
 
-	res, err := retriever.Retrieve(&slo.ServiceLevelIndicator)
+	result, err := retriever.Retrieve(ctx, &slo.ServiceLevelIndicator)
 	if err != nil {
 		return err
 	}
+	res = &result
+	h.metricssvc.SetSLOQueryResultSamples(h.cluster, sl, slo, "total", res.TotalQSamples)
+	h.metricssvc.SetSLOQueryResultSamples(h.cluster, sl, slo, "error", res.ErrorQSamples)
 
 	outputer, err := h.outputerFact.GetStrategy(slo)
 	if err != nil {
 		return err
 	}
 
-	err = outputer.Create(sl, slo, &res)
+	// The intervals missed since the last evaluation (operator downtime or SLI
+	// source outages) are evaluated before the current one. If they can't be
+	// the current interval is evaluated anyway and the missed ones are lost,
+	// retrying them later would evaluate the current one twice.
+	n, err := h.backfiller.Backfill(ctx, h.cluster, sl, slo, evalTime, outputer)
+	if err != nil {
+		span.RecordError(err)
+		h.logger.With("sl", sl.Name).With("slo", slo.Name).Errorf("could not backfill the missed evaluations: %s", err)
+	}
+	if n > 0 {
+		span.SetAttributes(attribute.Int("slo.backfilled", n))
+	}
+
+	// The SLO is evaluated even if the result is invalid, so it's not backfilled.
+	h.backfiller.Evaluated(h.cluster, sl, slo, evalTime)
+	err = outputer.Create(ctx, h.cluster, sl, slo, res)
 	if err != nil {
 		return err
 	}
@@ -94,8 +195,38 @@ func (h *Handler) processSLO(sl *monitoringv1alpha1.ServiceLevel, slo *monitorin
 	return nil
 }
 
-// Delete handles the deletion of a release.
-func (h *Handler) Delete(_ context.Context, name string) error {
+// CheckActivity returns an error if the handler has service levels but hasn't
+// handled any of them for longer than the max inactivity, this means that the
+// controller loop is stalled because the service levels are handled on every resync.
+func (h *Handler) CheckActivity(maxInactivity time.Duration) error {
+	return h.activity.check(maxInactivity)
+}
+
+// serviceLevelAttributes returns the tracing attributes that identify a service level of a cluster.
+func serviceLevelAttributes(cluster string, sl *monitoringv1alpha1.ServiceLevel) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("namespace", sl.Namespace),
+		attribute.String("service_level", sl.Name),
+	}
+	if cluster != "" {
+		attrs = append(attrs, attribute.String("cluster", cluster))
+	}
+	return attrs
+}
+
+// Delete handles the deletion of a service level.
+func (h *Handler) Delete(ctx context.Context, name string) error {
 	h.logger.Debugf("delete received")
+	defer h.activity.deleted(name)
+
+	ns, n, err := cache.SplitMetaNamespaceKey(name)
+	if err != nil {
+		return err
+	}
+	h.recorder.DeleteServiceLevel(h.cluster, ns, n)
+	if err := h.dashboards.Delete(ctx, ns, n); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -2,19 +2,33 @@ package operator_test
 
 import (
 	"context"
+	"errors"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 
 	moutput "github.com/spotahome/service-level-operator/mocks/service/output"
 	msli "github.com/spotahome/service-level-operator/mocks/service/sli"
 	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
 	"github.com/spotahome/service-level-operator/pkg/log"
 	"github.com/spotahome/service-level-operator/pkg/operator"
+	"github.com/spotahome/service-level-operator/pkg/service/backfill"
+	"github.com/spotahome/service-level-operator/pkg/service/dashboard"
+	"github.com/spotahome/service-level-operator/pkg/service/events"
+	"github.com/spotahome/service-level-operator/pkg/service/kubernetes"
+	"github.com/spotahome/service-level-operator/pkg/service/metrics"
 	"github.com/spotahome/service-level-operator/pkg/service/output"
 	"github.com/spotahome/service-level-operator/pkg/service/sli"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
 )
 
 var (
@@ -139,11 +153,11 @@ func TestHandler(t *testing.T) {
 			mretf := sli.MockRetrieverFactory{Mock: mret}
 
 			if test.processTimes > 0 {
-				mout.On("Create", mock.Anything, mock.Anything, mock.Anything).Times(test.processTimes).Return(nil)
-				mret.On("Retrieve", mock.Anything).Times(test.processTimes).Return(sli.Result{}, nil)
+				mout.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Times(test.processTimes).Return(nil)
+				mret.On("Retrieve", mock.Anything, mock.Anything).Times(test.processTimes).Return(sli.Result{}, nil)
 			}
 
-			h := operator.NewHandler(moutf, mretf, log.Dummy)
+			h := operator.NewHandler(moutf, mretf, status.Dummy, backfill.Dummy, dashboard.Dummy, metrics.Dummy, noop.NewTracerProvider().Tracer(""), log.Dummy)
 			err := h.Add(context.Background(), test.serviceLevel)
 
 			if test.expErr {
@@ -155,3 +169,247 @@ func TestHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestClusterHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	// Mocks.
+	mout := &moutput.Output{}
+	moutf := output.MockFactory{Mock: mout}
+	mret := &msli.Retriever{}
+	mretf := sli.MockRetrieverFactory{Mock: mret}
+
+	// The outputs should receive the service levels identified by the cluster.
+	mout.On("Create", mock.Anything, "cluster0", sl1, mock.Anything, mock.Anything).Times(3).Return(nil)
+	mret.On("Retrieve", mock.Anything, mock.Anything).Times(3).Return(sli.Result{}, nil)
+
+	h := operator.NewClusterHandler("cluster0", moutf, mretf, status.Dummy, backfill.Dummy, dashboard.Dummy, metrics.Dummy, noop.NewTracerProvider().Tracer(""), log.Dummy)
+	err := h.Add(context.Background(), sl1)
+
+	if assert.NoError(err) {
+		mout.AssertExpectations(t)
+		mret.AssertExpectations(t)
+	}
+}
+
+func TestHandlerTracing(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := tp.Tracer("test")
+
+	// Mocks.
+	mout := &moutput.Output{}
+	moutf := output.MockFactory{Mock: output.NewTracingMiddleware(tracer, "test", mout)}
+	mret := &msli.Retriever{}
+	mretf := sli.MockRetrieverFactory{Mock: mret}
+	mout.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mret.On("Retrieve", mock.Anything, mock.Anything).Return(sli.Result{}, nil)
+
+	h := operator.NewHandler(moutf, mretf, status.Dummy, backfill.Dummy, dashboard.Dummy, metrics.Dummy, tracer, log.Dummy)
+	err := h.Add(context.Background(), sl1)
+	require.NoError(err)
+
+	// The SLO processing and the outputs should be children of the handling span.
+	spans := exporter.GetSpans()
+	names := map[string]int{}
+	var addSpan tracetest.SpanStub
+	for _, span := range spans {
+		names[span.Name]++
+		if span.Name == "Handler.Add" {
+			addSpan = span
+		}
+	}
+	assert.Equal(map[string]int{"Handler.Add": 1, "Handler.processSLO": 4, "Output.Create": 3}, names)
+
+	for _, span := range spans {
+		switch span.Name {
+		case "Handler.processSLO":
+			assert.Equal(addSpan.SpanContext.SpanID(), span.Parent.SpanID())
+		case "Output.Create":
+			assert.Equal(addSpan.SpanContext.TraceID(), span.Parent.TraceID())
+			assert.NotEqual(addSpan.SpanContext.SpanID(), span.Parent.SpanID())
+		}
+	}
+}
+
+func TestHandlerActivity(t *testing.T) {
+	assert := assert.New(t)
+
+	// Mocks.
+	mout := &moutput.Output{}
+	moutf := output.MockFactory{Mock: mout}
+	mret := &msli.Retriever{}
+	mretf := sli.MockRetrieverFactory{Mock: mret}
+	mout.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mret.On("Retrieve", mock.Anything, mock.Anything).Return(sli.Result{}, nil)
+
+	h := operator.NewHandler(moutf, mretf, status.Dummy, backfill.Dummy, dashboard.Dummy, metrics.Dummy, noop.NewTracerProvider().Tracer(""), log.Dummy)
+
+	// Without service levels the controller can't be stalled.
+	assert.NoError(h.CheckActivity(0))
+
+	err := h.Add(context.Background(), sl1)
+	assert.NoError(err)
+	assert.NoError(h.CheckActivity(time.Hour))
+	time.Sleep(time.Millisecond)
+	assert.Error(h.CheckActivity(time.Nanosecond), "without recent activity the controller should be stalled")
+
+	// Deleted service levels are not handled anymore.
+	err = h.Delete(context.Background(), "fake/fake-service0")
+	assert.NoError(err)
+	time.Sleep(time.Millisecond)
+	assert.NoError(h.CheckActivity(time.Nanosecond))
+}
+
+// fakeRangeRetriever returns a valid result at every step of the range, or
+// the error if set.
+type fakeRangeRetriever struct {
+	err error
+}
+
+func (f fakeRangeRetriever) RetrieveRange(_ context.Context, _ *monitoringv1alpha1.SLI, start, end time.Time, step time.Duration) ([]sli.TimedResult, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	res := []sli.TimedResult{}
+	for t := start; !t.After(end); t = t.Add(step) {
+		res = append(res, sli.TimedResult{Time: t})
+	}
+	return res, nil
+}
+
+func TestHandlerBackfill(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	backfiller, err := backfill.NewRangeBackfiller(backfill.Cfg{Interval: 10 * time.Second, MaxWindow: time.Hour}, fakeRangeRetriever{}, log.Dummy)
+	require.NoError(err)
+
+	// Mocks.
+	mout := &moutput.Output{}
+	moutf := output.MockFactory{Mock: mout}
+	mret := &msli.Retriever{}
+	mretf := sli.MockRetrieverFactory{Mock: mret}
+	mout.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mret.On("Retrieve", mock.Anything, mock.Anything).Return(sli.Result{}, nil)
+
+	h := operator.NewHandler(moutf, mretf, status.Dummy, backfiller, dashboard.Dummy, metrics.Dummy, noop.NewTracerProvider().Tracer(""), log.Dummy)
+
+	// The SLOs were last evaluated a minute ago, 5 evaluations per SLO were missed.
+	for _, slo := range sl1.Spec.ServiceLevelObjectives {
+		slo := slo
+		backfiller.Evaluated("", sl1, &slo, time.Now().Add(-time.Minute))
+	}
+	err = h.Add(context.Background(), sl1)
+	require.NoError(err)
+	assert.True(mout.AssertNumberOfCalls(t, "Create", 3+3*5))
+
+	// Once backfilled only the current evaluations should be created.
+	err = h.Add(context.Background(), sl1)
+	require.NoError(err)
+	assert.True(mout.AssertNumberOfCalls(t, "Create", 3+3*5+3))
+}
+
+func TestHandlerBackfillError(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	backfiller, err := backfill.NewRangeBackfiller(backfill.Cfg{Interval: 10 * time.Second, MaxWindow: time.Hour}, fakeRangeRetriever{err: errors.New("wanted error")}, log.Dummy)
+	require.NoError(err)
+
+	// Mocks.
+	mout := &moutput.Output{}
+	moutf := output.MockFactory{Mock: mout}
+	mret := &msli.Retriever{}
+	mretf := sli.MockRetrieverFactory{Mock: mret}
+	mout.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mret.On("Retrieve", mock.Anything, mock.Anything).Return(sli.Result{}, nil)
+
+	h := operator.NewHandler(moutf, mretf, status.Dummy, backfiller, dashboard.Dummy, metrics.Dummy, noop.NewTracerProvider().Tracer(""), log.Dummy)
+
+	for _, slo := range sl1.Spec.ServiceLevelObjectives {
+		slo := slo
+		backfiller.Evaluated("", sl1, &slo, time.Now().Add(-time.Minute))
+	}
+
+	// The current evaluations should be created even if the missed ones can't be backfilled.
+	err = h.Add(context.Background(), sl1)
+	require.NoError(err)
+	assert.True(mout.AssertNumberOfCalls(t, "Create", 3))
+}
+
+func TestHandlerDashboards(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Mocks.
+	mout := &moutput.Output{}
+	moutf := output.MockFactory{Mock: mout}
+	mret := &msli.Retriever{}
+	mretf := sli.MockRetrieverFactory{Mock: mret}
+	mout.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mret.On("Retrieve", mock.Anything, mock.Anything).Return(sli.Result{}, nil)
+
+	cli := fake.NewSimpleClientset()
+	dashboards, err := dashboard.NewConfigMapPublisher(dashboard.ConfigMapCfg{}, kubernetes.NewConfigMap(cli, log.Dummy), log.Dummy)
+	require.NoError(err)
+	h := operator.NewHandler(moutf, mretf, status.Dummy, backfill.Dummy, dashboards, metrics.Dummy, noop.NewTracerProvider().Tracer(""), log.Dummy)
+
+	// Handled service levels should have their dashboard published.
+	err = h.Add(context.Background(), sl1)
+	require.NoError(err)
+	cm, err := cli.CoreV1().ConfigMaps("fake").Get(dashboard.ConfigMapName("fake", "fake-service0"), metav1.GetOptions{})
+	require.NoError(err)
+	assert.Equal("1", cm.Labels["grafana_dashboard"])
+
+	// Deleted service levels should have their dashboard deleted.
+	err = h.Delete(context.Background(), "fake/fake-service0")
+	require.NoError(err)
+	cms, err := cli.CoreV1().ConfigMaps("fake").List(metav1.ListOptions{})
+	require.NoError(err)
+	assert.Empty(cms.Items)
+}
+
+func TestHandlerEvents(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Mocks.
+	mout := &moutput.Output{}
+	moutf := output.MockFactory{Mock: mout}
+	mret := &msli.Retriever{}
+	mretf := sli.MockRetrieverFactory{Mock: mret}
+	mret.On("Retrieve", mock.Anything, mock.Anything).Return(sli.Result{}, errors.New("wanted error"))
+
+	cli := fake.NewSimpleClientset()
+	recorder := events.NewRecorder(events.Cfg{}, kubernetes.NewEvent(cli, log.Dummy), status.Dummy, log.Dummy)
+	h := operator.NewHandler(moutf, mretf, recorder, backfill.Dummy, dashboard.Dummy, metrics.Dummy, noop.NewTracerProvider().Tracer(""), log.Dummy)
+
+	// The invalid service levels and the failed SLOs should be recorded as events on the service levels.
+	invalid := sl1.DeepCopy()
+	invalid.Name = "fake-service1"
+	invalid.Spec.ServiceLevelObjectives[0].AvailabilityObjectivePercent = 0
+	err := h.Add(context.Background(), invalid)
+	require.Error(err)
+	err = h.Add(context.Background(), sl1)
+	require.NoError(err)
+
+	reasons := func() map[string]int {
+		evs, err := cli.CoreV1().Events("fake").List(metav1.ListOptions{})
+		require.NoError(err)
+		got := map[string]int{}
+		for _, ev := range evs.Items {
+			got[ev.InvolvedObject.Kind+"/"+ev.InvolvedObject.Name+":"+ev.Reason]++
+		}
+		return got
+	}
+	exp := map[string]int{
+		"ServiceLevel/fake-service1:InvalidServiceLevel": 1,
+		"ServiceLevel/fake-service0:SLOEvaluationFailed": 3,
+	}
+	assert.Eventually(func() bool { return reflect.DeepEqual(exp, reasons()) }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(exp, reasons())
+}
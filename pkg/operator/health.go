@@ -0,0 +1,116 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+// activityTracker tracks the handling activity of a controller.
+type activityTracker struct {
+	mu   sync.Mutex
+	last time.Time
+	keys map[string]bool
+}
+
+func newActivityTracker() *activityTracker {
+	return &activityTracker{keys: map[string]bool{}}
+}
+
+// handled marks the object key as handled.
+func (a *activityTracker) handled(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.last = time.Now()
+	a.keys[key] = true
+}
+
+// deleted marks the object key as deleted, it will not be handled anymore.
+func (a *activityTracker) deleted(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.last = time.Now()
+	delete(a.keys, key)
+}
+
+// check returns an error if there are handled objects and there hasn't been
+// activity for longer than the max inactivity. Without objects there isn't
+// activity, so the controller can't be considered stalled.
+func (a *activityTracker) check(maxInactivity time.Duration) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.keys) == 0 {
+		return nil
+	}
+
+	inactivity := time.Since(a.last)
+	if inactivity > maxInactivity {
+		return fmt.Errorf("no service level handled in %s (%d service levels, max inactivity %s)", inactivity.Round(time.Second), len(a.keys), maxInactivity)
+	}
+
+	return nil
+}
+
+// crdState is the state of the initialization of a CRD and the initial list of
+// its resources.
+type crdState struct {
+	mu          sync.Mutex
+	initialized bool
+	synced      bool
+}
+
+func (c *crdState) setInitialized() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.initialized = true
+}
+
+func (c *crdState) setSynced() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.synced = true
+}
+
+// checkInitialized satisfies health.CheckerFunc.
+func (c *crdState) checkInitialized(_ context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.initialized {
+		return fmt.Errorf("CRD not initialized")
+	}
+	return nil
+}
+
+// checkSynced satisfies health.CheckerFunc.
+func (c *crdState) checkSynced(_ context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.synced {
+		return fmt.Errorf("service levels not synced")
+	}
+	return nil
+}
+
+// syncTrackingListerWatcher is a ListerWatcher that marks the resources as
+// synced once they have been listed, this is when the informer starts having
+// the resources in its cache.
+type syncTrackingListerWatcher struct {
+	cache.ListerWatcher
+	state *crdState
+}
+
+// List satisfies cache.ListerWatcher interface.
+func (s syncTrackingListerWatcher) List(options metav1.ListOptions) (runtime.Object, error) {
+	obj, err := s.ListerWatcher.List(options)
+	if err != nil {
+		return nil, err
+	}
+	s.state.setSynced()
+	return obj, nil
+}
@@ -0,0 +1,349 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/kubernetes"
+)
+
+// namespaceSource knows the namespaces that need to be watched.
+type namespaceSource interface {
+	// Namespaces returns the namespaces to watch.
+	Namespaces() []string
+	// Changed returns a channel that will receive an event every time
+	// the namespaces change.
+	Changed() <-chan struct{}
+}
+
+// staticNamespaces are a fixed list of namespaces.
+type staticNamespaces []string
+
+func (s staticNamespaces) Namespaces() []string     { return s }
+func (s staticNamespaces) Changed() <-chan struct{} { return nil }
+
+// namespaceTracker tracks the namespaces that match a label selector.
+type namespaceTracker struct {
+	informer cache.SharedIndexInformer
+	changedC chan struct{}
+	logger   log.Logger
+}
+
+func newNamespaceTracker(selector string, service kubernetes.Namespace, logger log.Logger) *namespaceTracker {
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = selector
+			return service.ListNamespaces(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = selector
+			return service.WatchNamespaces(options)
+		},
+	}
+
+	n := &namespaceTracker{
+		informer: cache.NewSharedIndexInformer(lw, &corev1.Namespace{}, 0, cache.Indexers{}),
+		changedC: make(chan struct{}, 1),
+		logger:   logger.With("namespace-selector", selector),
+	}
+
+	// We only care about namespaces entering or leaving the selector.
+	n.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { n.notify(obj, "added") },
+		DeleteFunc: func(obj interface{}) { n.notify(obj, "removed") },
+	})
+
+	return n
+}
+
+func (n *namespaceTracker) notify(obj interface{}, action string) {
+	if ns, ok := obj.(*corev1.Namespace); ok {
+		n.logger.Infof("namespace %s %s from the watched namespaces", ns.Name, action)
+	}
+
+	select {
+	case n.changedC <- struct{}{}:
+	default:
+	}
+}
+
+// Run will run the tracker and wait until is synced.
+func (n *namespaceTracker) Run(stopC <-chan struct{}) error {
+	go n.informer.Run(stopC)
+	if !cache.WaitForCacheSync(stopC, n.informer.HasSynced) {
+		return fmt.Errorf("timed out waiting for the namespaces to be synced")
+	}
+	return nil
+}
+
+// Namespaces satisfies namespaceSource interface.
+func (n *namespaceTracker) Namespaces() []string {
+	nss := n.informer.GetStore().ListKeys()
+	sort.Strings(nss)
+	return nss
+}
+
+// Changed satisfies namespaceSource interface.
+func (n *namespaceTracker) Changed() <-chan struct{} {
+	return n.changedC
+}
+
+// multiNamespaceListerWatcher lists and watches service levels on multiple namespaces
+// using namespaced API calls only, so it doesn't require cluster wide permissions.
+// It tracks the resource version per namespace, so on rewatches the watch is resumed
+// on each namespace independently. When the watched namespaces change, the current
+// watch is stopped so the new namespaces start being watched and the objects of the
+// namespaces that are not watched anymore are deleted.
+type multiNamespaceListerWatcher struct {
+	namespaces namespaceSource
+	service    kubernetes.ServiceLevel
+	selector   string
+
+	mu               sync.Mutex
+	resourceVersions map[string]string
+	objects          map[string]map[string]struct{}
+}
+
+func newMultiNamespaceListerWatcher(namespaces namespaceSource, service kubernetes.ServiceLevel, selector string) *multiNamespaceListerWatcher {
+	return &multiNamespaceListerWatcher{
+		namespaces:       namespaces,
+		service:          service,
+		selector:         selector,
+		resourceVersions: map[string]string{},
+		objects:          map[string]map[string]struct{}{},
+	}
+}
+
+// List satisfies cache.ListerWatcher interface.
+func (m *multiNamespaceListerWatcher) List(options metav1.ListOptions) (runtime.Object, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	options.LabelSelector = m.selector
+	res := &monitoringv1alpha1.ServiceLevelList{}
+	m.resourceVersions = map[string]string{}
+	m.objects = map[string]map[string]struct{}{}
+	for _, ns := range m.namespaces.Namespaces() {
+		sls, err := m.service.ListServiceLevels(ns, options)
+		if err != nil {
+			return nil, fmt.Errorf("could not list %s namespace service levels: %s", ns, err)
+		}
+
+		m.resourceVersions[ns] = sls.ResourceVersion
+		m.objects[ns] = map[string]struct{}{}
+		for _, sl := range sls.Items {
+			m.objects[ns][sl.Name] = struct{}{}
+		}
+		res.Items = append(res.Items, sls.Items...)
+	}
+
+	return res, nil
+}
+
+// Watch satisfies cache.ListerWatcher interface. The received resource version is
+// ignored because the resource version is tracked per namespace.
+func (m *multiNamespaceListerWatcher) Watch(options metav1.ListOptions) (watch.Interface, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	options.LabelSelector = m.selector
+	mw := newMultiWatch(m.track)
+
+	// The service levels of the namespaces that are not watched anymore
+	// need to be deleted.
+	current := map[string]bool{}
+	for _, ns := range m.namespaces.Namespaces() {
+		current[ns] = true
+	}
+	var deleted []watch.Event
+	for ns, objs := range m.objects {
+		if current[ns] {
+			continue
+		}
+		for name := range objs {
+			deleted = append(deleted, watch.Event{
+				Type:   watch.Deleted,
+				Object: &monitoringv1alpha1.ServiceLevel{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}},
+			})
+		}
+		delete(m.objects, ns)
+		delete(m.resourceVersions, ns)
+	}
+	mw.sendEvents(deleted)
+
+	for ns := range current {
+		// New namespaces will not have resource version, in this case the watch
+		// will send the present objects as added events.
+		nsOpts := options
+		nsOpts.ResourceVersion = m.resourceVersions[ns]
+		w, err := m.service.WatchServiceLevels(ns, nsOpts)
+		if err != nil {
+			mw.Stop()
+			return nil, fmt.Errorf("could not watch %s namespace service levels: %s", ns, err)
+		}
+		if _, ok := m.objects[ns]; !ok {
+			m.objects[ns] = map[string]struct{}{}
+		}
+		mw.add(ns, w)
+	}
+
+	mw.stopOn(m.namespaces.Changed())
+
+	return mw, nil
+}
+
+// track tracks the resource versions and objects of the namespaces based on the watch events.
+func (m *multiNamespaceListerWatcher) track(ns string, e watch.Event) {
+	if e.Type == watch.Error {
+		return
+	}
+
+	acc, err := meta.Accessor(e.Object)
+	if err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	objs, ok := m.objects[ns]
+	if !ok {
+		return
+	}
+	m.resourceVersions[ns] = acc.GetResourceVersion()
+	switch e.Type {
+	case watch.Added, watch.Modified:
+		objs[acc.GetName()] = struct{}{}
+	case watch.Deleted:
+		delete(objs, acc.GetName())
+	}
+}
+
+// multiWatch multiplexes multiple watches in a single one. If any of the
+// watches finishes all of them will be stopped.
+type multiWatch struct {
+	resultC chan watch.Event
+	stopC   chan struct{}
+	wg      sync.WaitGroup
+	onEvent func(ns string, e watch.Event)
+
+	mu      sync.Mutex
+	stopped bool
+	watches []watch.Interface
+}
+
+func newMultiWatch(onEvent func(ns string, e watch.Event)) *multiWatch {
+	m := &multiWatch{
+		resultC: make(chan watch.Event),
+		stopC:   make(chan struct{}),
+		onEvent: onEvent,
+	}
+
+	go func() {
+		<-m.stopC
+		m.wg.Wait()
+		close(m.resultC)
+	}()
+
+	return m
+}
+
+func (m *multiWatch) sendEvents(events []watch.Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped {
+		return
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for _, e := range events {
+			select {
+			case <-m.stopC:
+				return
+			case m.resultC <- e:
+			}
+		}
+	}()
+}
+
+func (m *multiWatch) add(ns string, w watch.Interface) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped {
+		w.Stop()
+		return
+	}
+
+	m.watches = append(m.watches, w)
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		// If one of the watches ends, end all, the reflector will rewatch.
+		defer m.Stop()
+		for {
+			select {
+			case <-m.stopC:
+				return
+			case e, ok := <-w.ResultChan():
+				if !ok {
+					return
+				}
+				select {
+				case <-m.stopC:
+					return
+				case m.resultC <- e:
+					m.onEvent(ns, e)
+				}
+			}
+		}
+	}()
+}
+
+func (m *multiWatch) stopOn(c <-chan struct{}) {
+	if c == nil {
+		return
+	}
+	go func() {
+		select {
+		case <-m.stopC:
+		case <-c:
+			m.Stop()
+		}
+	}()
+}
+
+// Stop satisfies watch.Interface interface.
+func (m *multiWatch) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped {
+		return
+	}
+
+	m.stopped = true
+	close(m.stopC)
+	for _, w := range m.watches {
+		w.Stop()
+	}
+}
+
+// ResultChan satisfies watch.Interface interface.
+func (m *multiWatch) ResultChan() <-chan watch.Event {
+	return m.resultC
+}
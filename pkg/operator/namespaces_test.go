@@ -0,0 +1,127 @@
+package operator
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	crdclifake "github.com/spotahome/service-level-operator/pkg/k8sautogen/client/clientset/versioned/fake"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/kubernetes"
+)
+
+// testNamespaces is a namespace source that can change.
+type testNamespaces struct {
+	mu       sync.Mutex
+	nss      []string
+	changedC chan struct{}
+}
+
+func (t *testNamespaces) Namespaces() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.nss
+}
+func (t *testNamespaces) Changed() <-chan struct{} { return t.changedC }
+func (t *testNamespaces) set(nss ...string) {
+	t.mu.Lock()
+	t.nss = nss
+	t.mu.Unlock()
+	t.changedC <- struct{}{}
+}
+
+func newTestSL(ns, name string) *monitoringv1alpha1.ServiceLevel {
+	return &monitoringv1alpha1.ServiceLevel{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+}
+
+func slKeys(objs []runtime.Object) []string {
+	var keys []string
+	for _, obj := range objs {
+		sl := obj.(*monitoringv1alpha1.ServiceLevel)
+		keys = append(keys, sl.Namespace+"/"+sl.Name)
+	}
+	return keys
+}
+
+func nextEvent(t *testing.T, w watch.Interface) (watch.Event, bool) {
+	select {
+	case e, ok := <-w.ResultChan():
+		return e, ok
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout waiting for watch event")
+	}
+	return watch.Event{}, false
+}
+
+func TestMultiNamespaceListerWatcherList(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cli := crdclifake.NewSimpleClientset(
+		newTestSL("ns0", "sl0"),
+		newTestSL("ns1", "sl1"),
+		newTestSL("ns1", "sl2"),
+		newTestSL("ns2", "sl3"),
+	)
+	svc := kubernetes.NewServiceLevel(cli, log.Dummy)
+	lw := newMultiNamespaceListerWatcher(staticNamespaces{"ns0", "ns1"}, svc, "")
+
+	obj, err := lw.List(metav1.ListOptions{})
+	require.NoError(err)
+
+	sls := obj.(*monitoringv1alpha1.ServiceLevelList)
+	var objs []runtime.Object
+	for i := range sls.Items {
+		objs = append(objs, &sls.Items[i])
+	}
+	assert.ElementsMatch([]string{"ns0/sl0", "ns1/sl1", "ns1/sl2"}, slKeys(objs))
+}
+
+func TestMultiNamespaceListerWatcherWatch(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cli := crdclifake.NewSimpleClientset(
+		newTestSL("ns0", "sl0"),
+		newTestSL("ns1", "sl1"),
+	)
+	svc := kubernetes.NewServiceLevel(cli, log.Dummy)
+	nss := &testNamespaces{nss: []string{"ns0", "ns1"}, changedC: make(chan struct{}, 1)}
+	lw := newMultiNamespaceListerWatcher(nss, svc, "")
+
+	_, err := lw.List(metav1.ListOptions{})
+	require.NoError(err)
+	w, err := lw.Watch(metav1.ListOptions{})
+	require.NoError(err)
+
+	// Events of the watched namespaces should be received, the rest not.
+	_, err = cli.MonitoringV1alpha1().ServiceLevels("ns2").Create(newTestSL("ns2", "sl2"))
+	require.NoError(err)
+	_, err = cli.MonitoringV1alpha1().ServiceLevels("ns1").Create(newTestSL("ns1", "sl3"))
+	require.NoError(err)
+	e, ok := nextEvent(t, w)
+	require.True(ok)
+	assert.Equal(watch.Added, e.Type)
+	assert.Equal([]string{"ns1/sl3"}, slKeys([]runtime.Object{e.Object}))
+
+	// Changing the namespaces should end the watch.
+	nss.set("ns1")
+	_, ok = nextEvent(t, w)
+	assert.False(ok)
+
+	// The new watch should delete the objects of the not watched namespaces.
+	w, err = lw.Watch(metav1.ListOptions{})
+	require.NoError(err)
+	defer w.Stop()
+	e, ok = nextEvent(t, w)
+	require.True(ok)
+	assert.Equal(watch.Deleted, e.Type)
+	assert.Equal([]string{"ns0/sl0"}, slKeys([]runtime.Object{e.Object}))
+}
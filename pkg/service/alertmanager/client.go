@@ -0,0 +1,110 @@
+package alertmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const alertsPath = "/api/v2/alerts"
+
+// Alert is an alert of the Alertmanager v2 API.
+type Alert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt,omitempty"`
+	EndsAt       time.Time         `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// MarshalJSON satisfies json.Marshaler interface, the zero times are omitted
+// so Alertmanager sets them.
+func (a Alert) MarshalJSON() ([]byte, error) {
+	type alert Alert
+	aux := struct {
+		alert
+		StartsAt *time.Time `json:"startsAt,omitempty"`
+		EndsAt   *time.Time `json:"endsAt,omitempty"`
+	}{alert: alert(a)}
+	if !a.StartsAt.IsZero() {
+		aux.StartsAt = &a.StartsAt
+	}
+	if !a.EndsAt.IsZero() {
+		aux.EndsAt = &a.EndsAt
+	}
+	return json.Marshal(aux)
+}
+
+// Client knows how to send alerts to Alertmanager.
+type Client interface {
+	// PostAlerts sends the alerts to Alertmanager.
+	PostAlerts(ctx context.Context, alerts []Alert) error
+}
+
+type client struct {
+	urls []string
+	cli  *http.Client
+}
+
+// NewClient returns a new Alertmanager v2 API client. The alerts are sent to all
+// the Alertmanagers of the URLs (they should be replicas of the same cluster),
+// it only fails if none of them receives the alerts.
+func NewClient(urls []string, cli *http.Client) (Client, error) {
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("at least one Alertmanager URL is required")
+	}
+	if cli == nil {
+		cli = http.DefaultClient
+	}
+	c := &client{cli: cli}
+	for _, u := range urls {
+		c.urls = append(c.urls, strings.TrimSuffix(u, "/"))
+	}
+	return c, nil
+}
+
+// PostAlerts satisfies Client interface.
+func (c *client) PostAlerts(ctx context.Context, alerts []Alert) error {
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for _, u := range c.urls {
+		if err := c.post(ctx, u+alertsPath, body); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) == len(c.urls) {
+		return fmt.Errorf("could not send the alerts: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (c *client) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s: %s", url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,413 @@
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+)
+
+// Alert names.
+const (
+	// AlertBudgetConsumed fires when the consumed error budget of an SLO crosses
+	// a threshold, there is an alert per crossed threshold.
+	AlertBudgetConsumed = "SLOErrorBudgetConsumed"
+	// AlertBurnRate fires when the error budget of an SLO is consumed too fast.
+	AlertBurnRate = "SLOErrorBudgetBurnRate"
+)
+
+// ThresholdLabel is the label with the consumed error budget percent threshold
+// of the budget alerts.
+const ThresholdLabel = "threshold"
+
+const (
+	defBurnRate       = 14.4
+	defBurnRateWindow = time.Hour
+	defResendInterval = time.Minute
+	defFlushInterval  = 10 * time.Second
+	sendTimeout       = 10 * time.Second
+)
+
+var defBudgetThresholds = []float64{50, 75, 100}
+
+// Cfg is the configuration of the Alertmanager notifier.
+type Cfg struct {
+	// BudgetThresholds are the consumed error budget percents that fire an
+	// alert, by default 50, 75 and 100.
+	BudgetThresholds []float64
+	// DisableBurnRate disables the burn rate alerts.
+	DisableBurnRate bool
+	// BurnRate is the burn rate that fires an alert, by default 14.4 (2% of a
+	// 30d error budget consumed in 1h).
+	BurnRate float64
+	// BurnRateWindow is the window of the burn rate, by default 1h.
+	BurnRateWindow time.Duration
+	// ResendInterval is the interval the firing alerts are sent again, so
+	// Alertmanager doesn't resolve them. It should be lower than the Alertmanager
+	// resolve timeout, by default 1m.
+	ResendInterval time.Duration
+	// FlushInterval is the interval the changed alerts are sent, by default 10s.
+	FlushInterval time.Duration
+	// GeneratorURL is the URL of the alerts source (e.g. the operator UI).
+	GeneratorURL string
+}
+
+func (c *Cfg) defaults() error {
+	if len(c.BudgetThresholds) == 0 {
+		c.BudgetThresholds = defBudgetThresholds
+	}
+	for _, t := range c.BudgetThresholds {
+		if t <= 0 {
+			return fmt.Errorf("the budget thresholds must be positive")
+		}
+	}
+	if c.BurnRate == 0 {
+		c.BurnRate = defBurnRate
+	}
+	if c.BurnRate < 0 {
+		return fmt.Errorf("the burn rate must be positive")
+	}
+	if c.BurnRateWindow == 0 {
+		c.BurnRateWindow = defBurnRateWindow
+	}
+	if c.ResendInterval == 0 {
+		c.ResendInterval = defResendInterval
+	}
+	if c.FlushInterval == 0 {
+		c.FlushInterval = defFlushInterval
+	}
+	if c.BurnRateWindow < 0 || c.ResendInterval < 0 || c.FlushInterval < 0 {
+		return fmt.Errorf("the intervals can't be negative")
+	}
+	return nil
+}
+
+// alertState is the state of an alert of an SLO.
+type alertState struct {
+	alert  Alert
+	firing bool
+	// version is incremented on every change, so the changes while the alert is
+	// being sent are not lost.
+	version  int
+	sent     int
+	lastSent time.Time
+}
+
+// counterSample is a sample of the output counters of an SLO.
+type counterSample struct {
+	t        time.Time
+	counters output.SLOCounters
+}
+
+// sloState is the state of the alerts of an SLO.
+type sloState struct {
+	serviceLevel string
+	alerts       map[string]*alertState
+	samples      []counterSample
+}
+
+// Notifier is a status recorder middleware that sends alerts to Alertmanager
+// based on the recorded SLO evaluations. The error budget is the one of the
+// output counters (recorded by the counters middleware), and the burn rate is
+// measured from the counters samples of the burn rate window. The alerts are
+// resolved when the SLOs recover, and when they are deleted or disabled.
+type Notifier struct {
+	status.Recorder
+	cfg    Cfg
+	client Client
+	logger log.Logger
+	now    func() time.Time
+
+	mu   sync.Mutex
+	slos map[string]*sloState
+}
+
+// NewNotifier returns a new Alertmanager notifier that wraps a status recorder.
+func NewNotifier(cfg Cfg, client Client, next status.Recorder, logger log.Logger) (*Notifier, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, err
+	}
+	return &Notifier{
+		Recorder: next,
+		cfg:      cfg,
+		client:   client,
+		logger:   logger,
+		now:      time.Now,
+		slos:     map[string]*sloState{},
+	}, nil
+}
+
+// SetServiceLevel satisfies status.Recorder interface.
+func (n *Notifier) SetServiceLevel(cluster string, sl *monitoringv1alpha1.ServiceLevel) {
+	n.Recorder.SetServiceLevel(cluster, sl)
+
+	// The alerts of the removed and disabled SLOs are resolved.
+	enabled := map[string]bool{}
+	for i := range sl.Spec.ServiceLevelObjectives {
+		slo := &sl.Spec.ServiceLevelObjectives[i]
+		if !slo.Disable {
+			enabled[status.SLOID(cluster, sl, slo)] = true
+		}
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	slKey := status.ServiceLevelKey(cluster, sl.Namespace, sl.Name)
+	for id, st := range n.slos {
+		if st.serviceLevel == slKey && !enabled[id] {
+			n.resolveSLO(st)
+			st.samples = nil
+		}
+	}
+}
+
+// DeleteServiceLevel satisfies status.Recorder interface.
+func (n *Notifier) DeleteServiceLevel(cluster, namespace, name string) {
+	n.Recorder.DeleteServiceLevel(cluster, namespace, name)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	slKey := status.ServiceLevelKey(cluster, namespace, name)
+	for _, st := range n.slos {
+		if st.serviceLevel == slKey {
+			n.resolveSLO(st)
+			st.samples = nil
+		}
+	}
+}
+
+// RecordSLOEvaluation satisfies status.Recorder interface.
+func (n *Notifier) RecordSLOEvaluation(e status.SLOEvaluation) {
+	n.Recorder.RecordSLOEvaluation(e)
+
+	// Only the evaluations with counters change the error budget.
+	if e.Err != nil || e.Skipped || e.Counters == nil || e.SLO.Disable {
+		return
+	}
+	_, remaining, ok := e.Counters.ErrorBudget()
+	if !ok {
+		return
+	}
+	consumed := (1 - remaining) * 100
+	// A 100% objective doesn't have error budget to consume until it's exhausted.
+	if e.Counters.Objective >= 1 && !output.ErrorBudgetExhausted(remaining) {
+		consumed = 0
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	id := status.SLOID(e.Cluster, e.ServiceLevel, e.SLO)
+	st, ok := n.slos[id]
+	if !ok {
+		st = &sloState{alerts: map[string]*alertState{}}
+		n.slos[id] = st
+	}
+	st.serviceLevel = status.ServiceLevelKey(e.Cluster, e.ServiceLevel.Namespace, e.ServiceLevel.Name)
+
+	for _, t := range n.cfg.BudgetThresholds {
+		threshold := strconv.FormatFloat(t, 'f', -1, 64)
+		key := AlertBudgetConsumed + "/" + threshold
+		if consumed < t {
+			n.resolve(st, key)
+			continue
+		}
+
+		labels := alertLabels(e.Cluster, e.ServiceLevel, e.SLO, AlertBudgetConsumed)
+		labels[ThresholdLabel] = threshold
+		n.fire(st, key, Alert{
+			Labels: labels,
+			Annotations: map[string]string{
+				"summary":               fmt.Sprintf("The %s SLO has consumed more than %s%% of its error budget", e.SLO.Name, threshold),
+				"description":           fmt.Sprintf("The %s/%s %s SLO has consumed %.2f%% of its error budget.", e.ServiceLevel.Namespace, e.ServiceLevel.Name, e.SLO.Name, consumed),
+				"error_budget_consumed": fmt.Sprintf("%.2f%%", consumed),
+			},
+		})
+	}
+
+	if n.cfg.DisableBurnRate {
+		return
+	}
+	burnRate, ok := n.burnRate(st, e.Time, *e.Counters)
+	switch {
+	case !ok:
+	case burnRate >= n.cfg.BurnRate:
+		n.fire(st, AlertBurnRate, Alert{
+			Labels: alertLabels(e.Cluster, e.ServiceLevel, e.SLO, AlertBurnRate),
+			Annotations: map[string]string{
+				"summary":     fmt.Sprintf("The %s SLO error budget is being consumed too fast", e.SLO.Name),
+				"description": fmt.Sprintf("The %s/%s %s SLO error budget burn rate of the last %s is %.2f, the threshold is %g.", e.ServiceLevel.Namespace, e.ServiceLevel.Name, e.SLO.Name, n.cfg.BurnRateWindow, burnRate, n.cfg.BurnRate),
+				"burn_rate":   strconv.FormatFloat(burnRate, 'f', 2, 64),
+			},
+		})
+	default:
+		n.resolve(st, AlertBurnRate)
+	}
+}
+
+// burnRate returns the burn rate of the burn rate window, false if the counters
+// samples don't cover the window yet.
+func (n *Notifier) burnRate(st *sloState, t time.Time, c output.SLOCounters) (float64, bool) {
+	// The counters are reset when the output metrics expire.
+	if len(st.samples) > 0 && c.Count < st.samples[len(st.samples)-1].counters.Count {
+		st.samples = nil
+	}
+	st.samples = append(st.samples, counterSample{t: t, counters: c})
+
+	// The base sample is the newest one at the start of the window or before.
+	start := t.Add(-n.cfg.BurnRateWindow)
+	base := -1
+	for i, s := range st.samples {
+		if s.t.After(start) {
+			break
+		}
+		base = i
+	}
+	if base < 0 {
+		return 0, false
+	}
+	st.samples = st.samples[base:]
+
+	b := st.samples[0].counters
+	count := c.Count - b.Count
+	budget := 1 - c.Objective
+	if count <= 0 || budget <= 0 {
+		return 0, false
+	}
+	return (c.ErrorRatioSum - b.ErrorRatioSum) / count / budget, true
+}
+
+func (n *Notifier) fire(st *sloState, key string, alert Alert) {
+	alert.GeneratorURL = n.cfg.GeneratorURL
+	a, ok := st.alerts[key]
+	if ok && a.firing {
+		// Keep the start of the alert, the annotations are updated when it's sent again.
+		alert.StartsAt = a.alert.StartsAt
+		a.alert = alert
+		return
+	}
+
+	alert.StartsAt = n.now()
+	if !ok {
+		a = &alertState{}
+		st.alerts[key] = a
+	}
+	a.alert = alert
+	a.firing = true
+	a.version++
+}
+
+func (n *Notifier) resolve(st *sloState, key string) {
+	a, ok := st.alerts[key]
+	if !ok || !a.firing {
+		return
+	}
+	a.firing = false
+	a.alert.EndsAt = n.now()
+	a.version++
+}
+
+func (n *Notifier) resolveSLO(st *sloState) {
+	for key := range st.alerts {
+		n.resolve(st, key)
+	}
+}
+
+// Run sends the alerts periodically until stopped.
+func (n *Notifier) Run(stopC <-chan struct{}) error {
+	t := time.NewTicker(n.cfg.FlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			if err := n.Flush(ctx); err != nil {
+				n.logger.Errorf("%s", err)
+			}
+			cancel()
+		case <-stopC:
+			return nil
+		}
+	}
+}
+
+// Flush sends the new, resolved and changed alerts, and the firing alerts that
+// have not been sent for the resend interval. The alerts that can't be sent
+// are sent on the next flush.
+func (n *Notifier) Flush(ctx context.Context) error {
+	type pending struct {
+		state   *alertState
+		version int
+	}
+
+	n.mu.Lock()
+	now := n.now()
+	var alerts []Alert
+	var sending []pending
+	for _, st := range n.slos {
+		for _, a := range st.alerts {
+			if a.sent == a.version && (!a.firing || now.Sub(a.lastSent) < n.cfg.ResendInterval) {
+				continue
+			}
+			alerts = append(alerts, a.alert)
+			sending = append(sending, pending{state: a, version: a.version})
+		}
+	}
+	n.mu.Unlock()
+
+	if len(alerts) == 0 {
+		return nil
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alertID(alerts[i]) < alertID(alerts[j]) })
+	if err := n.client.PostAlerts(ctx, alerts); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, p := range sending {
+		p.state.sent = p.version
+		p.state.lastSent = now
+	}
+	// The resolved alerts are forgotten once sent.
+	for id, st := range n.slos {
+		for key, a := range st.alerts {
+			if !a.firing && a.sent == a.version {
+				delete(st.alerts, key)
+			}
+		}
+		if len(st.alerts) == 0 && st.samples == nil {
+			delete(n.slos, id)
+		}
+	}
+	return nil
+}
+
+// alertLabels returns the labels of an SLO alert, the output labels of the SLO
+// are added for the routing.
+func alertLabels(cluster string, sl *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO, name string) map[string]string {
+	labels := map[string]string{}
+	if slo.Output.Prometheus != nil {
+		for k, v := range slo.Output.Prometheus.Labels {
+			labels[k] = v
+		}
+	}
+	labels["alertname"] = name
+	labels["namespace"] = sl.Namespace
+	labels["service_level"] = sl.Name
+	labels["slo"] = slo.Name
+	if cluster != "" {
+		labels["cluster"] = cluster
+	}
+	return labels
+}
+
+func alertID(a Alert) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s/%s", a.Labels["alertname"], a.Labels["cluster"], a.Labels["namespace"], a.Labels["service_level"], a.Labels["slo"], a.Labels[ThresholdLabel])
+}
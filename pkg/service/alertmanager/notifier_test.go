@@ -0,0 +1,389 @@
+package alertmanager_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/alertmanager"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+)
+
+// fakeAlertmanager is an Alertmanager v2 API stand-in that stores the received alerts.
+type fakeAlertmanager struct {
+	*httptest.Server
+	mu       sync.Mutex
+	received [][]alertmanager.Alert
+	fail     bool
+}
+
+func newFakeAlertmanager() *fakeAlertmanager {
+	f := &fakeAlertmanager{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v2/alerts" {
+			http.NotFound(w, r)
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.fail {
+			http.Error(w, "wanted error", http.StatusInternalServerError)
+			return
+		}
+		var alerts []alertmanager.Alert
+		if err := json.NewDecoder(r.Body).Decode(&alerts); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.received = append(f.received, alerts)
+	}))
+	return f
+}
+
+// last returns the last received alerts by alert name and threshold.
+func (f *fakeAlertmanager) last() map[string]alertmanager.Alert {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	res := map[string]alertmanager.Alert{}
+	if len(f.received) == 0 {
+		return res
+	}
+	for _, a := range f.received[len(f.received)-1] {
+		res[a.Labels["alertname"]+a.Labels[alertmanager.ThresholdLabel]] = a
+	}
+	return res
+}
+
+func (f *fakeAlertmanager) setFail(fail bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fail = fail
+}
+
+func (f *fakeAlertmanager) requests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.received)
+}
+
+var sl0 = &monitoringv1alpha1.ServiceLevel{
+	ObjectMeta: metav1.ObjectMeta{Namespace: "ns0", Name: "sl0"},
+	Spec: monitoringv1alpha1.ServiceLevelSpec{
+		ServiceLevelObjectives: []monitoringv1alpha1.SLO{
+			{
+				Name:                         "slo0",
+				AvailabilityObjectivePercent: 99,
+				Output: monitoringv1alpha1.Output{
+					Prometheus: &monitoringv1alpha1.PrometheusOutputSource{
+						Labels: map[string]string{"team": "checkout", "slo": "overridden"},
+					},
+				},
+			},
+		},
+	},
+}
+
+func record(n *alertmanager.Notifier, cluster string, sl *monitoringv1alpha1.ServiceLevel, t time.Time, errSum, count float64) {
+	n.RecordSLOEvaluation(status.SLOEvaluation{
+		Cluster:      cluster,
+		ServiceLevel: sl,
+		SLO:          &sl.Spec.ServiceLevelObjectives[0],
+		Time:         t,
+		Counters:     &output.SLOCounters{ErrorRatioSum: errSum, Count: count, Objective: 0.99},
+	})
+}
+
+func TestNotifierBudgetAlerts(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	am := newFakeAlertmanager()
+	defer am.Close()
+	client, err := alertmanager.NewClient([]string{am.URL}, nil)
+	require.NoError(err)
+	n, err := alertmanager.NewNotifier(alertmanager.Cfg{
+		DisableBurnRate: true,
+		ResendInterval:  time.Hour,
+		GeneratorURL:    "http://slo-operator",
+	}, client, status.Dummy, log.Dummy)
+	require.NoError(err)
+
+	ctx := context.Background()
+	now := time.Now()
+	n.SetServiceLevel("cluster0", sl0)
+
+	// Without budget consumed nothing is sent.
+	record(n, "cluster0", sl0, now, 0, 100)
+	require.NoError(n.Flush(ctx))
+	assert.Equal(0, am.requests())
+
+	// 60% of the budget consumed fires the 50% alert.
+	record(n, "cluster0", sl0, now, 0.6, 100)
+	require.NoError(n.Flush(ctx))
+	require.Equal(1, am.requests())
+	alerts := am.last()
+	require.Len(alerts, 1)
+	a := alerts["SLOErrorBudgetConsumed50"]
+	assert.Equal(map[string]string{
+		"alertname":     "SLOErrorBudgetConsumed",
+		"namespace":     "ns0",
+		"service_level": "sl0",
+		"slo":           "slo0",
+		"cluster":       "cluster0",
+		"team":          "checkout",
+		"threshold":     "50",
+	}, a.Labels)
+	assert.Equal("60.00%", a.Annotations["error_budget_consumed"])
+	assert.Equal("http://slo-operator", a.GeneratorURL)
+	assert.False(a.StartsAt.IsZero())
+	assert.True(a.EndsAt.IsZero())
+
+	// Nothing changed, nothing is sent until the resend interval.
+	record(n, "cluster0", sl0, now, 0.62, 100)
+	require.NoError(n.Flush(ctx))
+	assert.Equal(1, am.requests())
+
+	// The exhausted budget fires the rest of thresholds.
+	record(n, "cluster0", sl0, now, 1.5, 100)
+	require.NoError(n.Flush(ctx))
+	require.Equal(2, am.requests())
+	alerts = am.last()
+	assert.Len(alerts, 2)
+	assert.Contains(alerts, "SLOErrorBudgetConsumed75")
+	assert.Contains(alerts, "SLOErrorBudgetConsumed100")
+
+	// Recovering resolves the alerts, only once.
+	record(n, "cluster0", sl0, now, 0.1, 100)
+	require.NoError(n.Flush(ctx))
+	require.Equal(3, am.requests())
+	alerts = am.last()
+	assert.Len(alerts, 3)
+	for _, a := range alerts {
+		assert.False(a.EndsAt.IsZero())
+	}
+	require.NoError(n.Flush(ctx))
+	assert.Equal(3, am.requests())
+}
+
+func TestNotifierFullObjectiveBudgetAlerts(t *testing.T) {
+	tests := map[string]struct {
+		errSum    float64
+		expAlerts []string
+	}{
+		"A 100% objective without errors shouldn't fire alerts.": {
+			errSum:    0,
+			expAlerts: []string{},
+		},
+		"A 100% objective with errors should fire all the alerts.": {
+			errSum:    0.1,
+			expAlerts: []string{"SLOErrorBudgetConsumed50", "SLOErrorBudgetConsumed75", "SLOErrorBudgetConsumed100"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			am := newFakeAlertmanager()
+			defer am.Close()
+			client, err := alertmanager.NewClient([]string{am.URL}, nil)
+			require.NoError(err)
+			n, err := alertmanager.NewNotifier(alertmanager.Cfg{DisableBurnRate: true}, client, status.Dummy, log.Dummy)
+			require.NoError(err)
+
+			n.SetServiceLevel("", sl0)
+			n.RecordSLOEvaluation(status.SLOEvaluation{
+				ServiceLevel: sl0,
+				SLO:          &sl0.Spec.ServiceLevelObjectives[0],
+				Time:         time.Now(),
+				Counters:     &output.SLOCounters{ErrorRatioSum: test.errSum, Count: 100, Objective: 1},
+			})
+			require.NoError(n.Flush(context.Background()))
+
+			gotAlerts := []string{}
+			for name := range am.last() {
+				gotAlerts = append(gotAlerts, name)
+			}
+			assert.ElementsMatch(test.expAlerts, gotAlerts)
+		})
+	}
+}
+
+func TestNotifierResolveDeleted(t *testing.T) {
+	tests := map[string]struct {
+		change func(n *alertmanager.Notifier, sl *monitoringv1alpha1.ServiceLevel)
+	}{
+		"Deleting the service level should resolve the alerts.": {
+			change: func(n *alertmanager.Notifier, sl *monitoringv1alpha1.ServiceLevel) {
+				n.DeleteServiceLevel("", sl.Namespace, sl.Name)
+			},
+		},
+		"Disabling the SLO should resolve the alerts.": {
+			change: func(n *alertmanager.Notifier, sl *monitoringv1alpha1.ServiceLevel) {
+				sl = sl.DeepCopy()
+				sl.Spec.ServiceLevelObjectives[0].Disable = true
+				n.SetServiceLevel("", sl)
+			},
+		},
+		"Removing the SLO should resolve the alerts.": {
+			change: func(n *alertmanager.Notifier, sl *monitoringv1alpha1.ServiceLevel) {
+				sl = sl.DeepCopy()
+				sl.Spec.ServiceLevelObjectives = nil
+				n.SetServiceLevel("", sl)
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			am := newFakeAlertmanager()
+			defer am.Close()
+			client, err := alertmanager.NewClient([]string{am.URL}, nil)
+			require.NoError(err)
+			n, err := alertmanager.NewNotifier(alertmanager.Cfg{DisableBurnRate: true, ResendInterval: time.Hour}, client, status.Dummy, log.Dummy)
+			require.NoError(err)
+
+			ctx := context.Background()
+			n.SetServiceLevel("", sl0)
+			record(n, "", sl0, time.Now(), 0.6, 100)
+			require.NoError(n.Flush(ctx))
+			require.Equal(1, am.requests())
+
+			test.change(n, sl0)
+			require.NoError(n.Flush(ctx))
+			require.Equal(2, am.requests())
+			alerts := am.last()
+			require.Len(alerts, 1)
+			assert.False(alerts["SLOErrorBudgetConsumed50"].EndsAt.IsZero())
+		})
+	}
+}
+
+func TestNotifierBurnRate(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	am := newFakeAlertmanager()
+	defer am.Close()
+	client, err := alertmanager.NewClient([]string{am.URL}, nil)
+	require.NoError(err)
+	n, err := alertmanager.NewNotifier(alertmanager.Cfg{
+		BudgetThresholds: []float64{1000},
+		BurnRate:         10,
+		BurnRateWindow:   time.Hour,
+		ResendInterval:   time.Hour,
+	}, client, status.Dummy, log.Dummy)
+	require.NoError(err)
+
+	ctx := context.Background()
+	start := time.Now()
+
+	// The burn rate is not known until the window is covered.
+	record(n, "", sl0, start, 0, 1000)
+	record(n, "", sl0, start.Add(30*time.Minute), 10, 1100)
+	require.NoError(n.Flush(ctx))
+	assert.Equal(0, am.requests())
+
+	// 100 results with a 0.2 error ratio on the window, 20 times the 1% budget.
+	record(n, "", sl0, start.Add(time.Hour), 20, 1100)
+	require.NoError(n.Flush(ctx))
+	require.Equal(1, am.requests())
+	a := am.last()["SLOErrorBudgetBurnRate"]
+	assert.Equal("SLOErrorBudgetBurnRate", a.Labels["alertname"])
+	assert.Equal("20.00", a.Annotations["burn_rate"])
+	assert.True(a.EndsAt.IsZero())
+
+	// Without errors on the window the alert is resolved.
+	record(n, "", sl0, start.Add(2*time.Hour), 20, 1200)
+	require.NoError(n.Flush(ctx))
+	require.Equal(2, am.requests())
+	a = am.last()["SLOErrorBudgetBurnRate"]
+	assert.False(a.EndsAt.IsZero())
+}
+
+func TestNotifierRetry(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	am := newFakeAlertmanager()
+	defer am.Close()
+	client, err := alertmanager.NewClient([]string{am.URL}, nil)
+	require.NoError(err)
+	n, err := alertmanager.NewNotifier(alertmanager.Cfg{DisableBurnRate: true, ResendInterval: time.Hour}, client, status.Dummy, log.Dummy)
+	require.NoError(err)
+
+	ctx := context.Background()
+	record(n, "", sl0, time.Now(), 0.6, 100)
+
+	// The alerts not sent are sent on the next flush.
+	am.setFail(true)
+	assert.Error(n.Flush(ctx))
+	am.setFail(false)
+	require.NoError(n.Flush(ctx))
+	assert.Equal(1, am.requests())
+	assert.Contains(am.last(), "SLOErrorBudgetConsumed50")
+}
+
+func TestClientMultipleAlertmanagers(t *testing.T) {
+	tests := map[string]struct {
+		failing []bool
+		expErr  bool
+	}{
+		"Sending to all the Alertmanagers should not fail.": {
+			failing: []bool{false, false},
+		},
+		"Sending to some of the Alertmanagers should not fail.": {
+			failing: []bool{true, false},
+		},
+		"Not sending to any Alertmanager should fail.": {
+			failing: []bool{true, true},
+			expErr:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var urls []string
+			var ams []*fakeAlertmanager
+			for _, fail := range test.failing {
+				am := newFakeAlertmanager()
+				defer am.Close()
+				am.setFail(fail)
+				ams = append(ams, am)
+				urls = append(urls, am.URL+"/")
+			}
+			client, err := alertmanager.NewClient(urls, nil)
+			require.NoError(err)
+
+			err = client.PostAlerts(context.Background(), []alertmanager.Alert{{Labels: map[string]string{"alertname": "test"}}})
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			for i, am := range ams {
+				if !test.failing[i] {
+					assert.Equal(1, am.requests())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+)
+
+const (
+	defSaveInterval = 10 * time.Second
+	saveTimeout     = 5 * time.Second
+)
+
+// Backfiller knows how to evaluate the SLO intervals that have been missed
+// since the last evaluation of an SLO.
+type Backfiller interface {
+	// Backfill creates the outputs of the SLO intervals missed between the last
+	// evaluation and the evaluation time, at their historical timestamps. Returns
+	// the number of backfilled intervals.
+	Backfill(ctx context.Context, cluster string, sl *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO, t time.Time, out output.Output) (int, error)
+	// Evaluated marks the SLO of a cluster as evaluated at the evaluation time.
+	Evaluated(cluster string, sl *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO, t time.Time)
+}
+
+// Cfg is the configuration of the RangeBackfiller.
+type Cfg struct {
+	// Interval is the SLO evaluation interval, the resync period of the operator.
+	Interval time.Duration
+	// MaxWindow is the maximum time that will be backfilled, the intervals
+	// missed before the window are lost.
+	MaxWindow time.Duration
+	// Store is where the last evaluation times are persisted, if not set they
+	// are only in memory and the operator downtime will not be backfilled.
+	Store CheckpointStore
+	// SaveInterval is the interval the last evaluation times are persisted.
+	SaveInterval time.Duration
+}
+
+// Validate will validate the cfg setting safe defaults.
+func (c *Cfg) Validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	if c.MaxWindow <= 0 {
+		return fmt.Errorf("max window must be positive")
+	}
+	if c.SaveInterval <= 0 {
+		c.SaveInterval = defSaveInterval
+	}
+
+	return nil
+}
+
+// RangeBackfiller is a Backfiller that gets the SLI results of the missed
+// intervals with range queries. It tracks the last evaluation time of every
+// SLO and persists it, so the operator downtime can be backfilled on startup.
+type RangeBackfiller struct {
+	cfg       Cfg
+	retriever sli.RangeRetriever
+	logger    log.Logger
+
+	mu    sync.Mutex
+	last  map[string]time.Time
+	dirty bool
+}
+
+// NewRangeBackfiller returns a new RangeBackfiller.
+func NewRangeBackfiller(cfg Cfg, retriever sli.RangeRetriever, logger log.Logger) (*RangeBackfiller, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &RangeBackfiller{
+		cfg:       cfg,
+		retriever: retriever,
+		logger:    logger,
+		last:      map[string]time.Time{},
+	}, nil
+}
+
+// Backfill satisfies Backfiller interface.
+func (r *RangeBackfiller) Backfill(ctx context.Context, cluster string, sl *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO, t time.Time, out output.Output) (int, error) {
+	id := status.SLOID(cluster, sl, slo)
+	r.mu.Lock()
+	last, ok := r.last[id]
+	r.mu.Unlock()
+
+	// Without a previous evaluation there is nothing missed.
+	if !ok {
+		return 0, nil
+	}
+
+	start, end, ok := r.missedRange(last, t)
+	if !ok {
+		return 0, nil
+	}
+
+	results, err := r.retriever.RetrieveRange(ctx, &slo.ServiceLevelIndicator, start, end, r.cfg.Interval)
+	if err != nil {
+		return 0, err
+	}
+
+	// The invalid results are discarded like on the live evaluations.
+	n := 0
+	for _, tr := range results {
+		tr := tr
+		err := out.Create(ctx, cluster, sl, slo, &tr.Result)
+		if err != nil {
+			r.logger.With("slo", id).Warnf("discarded backfilled SLI result at %s: %s", tr.Time.Format(time.RFC3339), err)
+			continue
+		}
+		n++
+	}
+
+	// The missed intervals are evaluated, if the current evaluation fails they
+	// don't need to be backfilled again.
+	r.Evaluated(cluster, sl, slo, end)
+	r.logger.With("slo", id).Infof("backfilled %d missed evaluations from %s to %s", n, start.Format(time.RFC3339), end.Format(time.RFC3339))
+
+	return n, nil
+}
+
+// missedRange returns the times of the first and the last missed evaluation
+// between the last evaluation and the evaluation time, false if there are no
+// missed evaluations. The range is limited to the max window.
+func (r *RangeBackfiller) missedRange(last, t time.Time) (start, end time.Time, ok bool) {
+	interval := r.cfg.Interval
+
+	start = last.Add(interval)
+	if minStart := t.Add(-r.cfg.MaxWindow); start.Before(minStart) {
+		// Keep the evaluations aligned with the last evaluation.
+		skipped := (minStart.Sub(start) + interval - 1) / interval
+		start = start.Add(skipped * interval)
+	}
+
+	// The evaluations with less than half an interval to the evaluation time
+	// are not missed, they are the current one with some delay.
+	limit := t.Add(-interval / 2)
+	if start.After(limit) {
+		return time.Time{}, time.Time{}, false
+	}
+	end = start.Add(limit.Sub(start) / interval * interval)
+
+	return start, end, true
+}
+
+// Evaluated satisfies Backfiller interface.
+func (r *RangeBackfiller) Evaluated(cluster string, sl *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO, t time.Time) {
+	id := status.SLOID(cluster, sl, slo)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t.After(r.last[id]) {
+		r.last[id] = t
+		r.dirty = true
+	}
+}
+
+// Load loads the persisted last evaluation times, it should be called before
+// any evaluation.
+func (r *RangeBackfiller) Load(ctx context.Context) error {
+	if r.cfg.Store == nil {
+		return nil
+	}
+
+	last, err := r.cfg.Store.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("could not load the backfill checkpoints from %s: %s", r.cfg.Store, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, t := range last {
+		if t.After(r.last[id]) {
+			r.last[id] = t
+		}
+	}
+	r.logger.Infof("loaded %d backfill checkpoints from %s", len(last), r.cfg.Store)
+
+	return nil
+}
+
+// Save persists the last evaluation times if they have changed. The SLOs that
+// haven't been evaluated for longer than twice the max window are forgotten,
+// they have been deleted or are disabled.
+func (r *RangeBackfiller) Save(ctx context.Context) error {
+	if r.cfg.Store == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	if !r.dirty {
+		r.mu.Unlock()
+		return nil
+	}
+	oldest := time.Now().Add(-2 * r.cfg.MaxWindow)
+	last := make(map[string]time.Time, len(r.last))
+	for id, t := range r.last {
+		if t.Before(oldest) {
+			delete(r.last, id)
+			continue
+		}
+		last[id] = t
+	}
+	r.dirty = false
+	r.mu.Unlock()
+
+	err := r.cfg.Store.Save(ctx, last)
+	if err != nil {
+		r.mu.Lock()
+		r.dirty = true
+		r.mu.Unlock()
+		return fmt.Errorf("could not save the backfill checkpoints on %s: %s", r.cfg.Store, err)
+	}
+
+	return nil
+}
+
+// Run persists the last evaluation times periodically until the stop channel
+// is closed, when stopped they are persisted for the last time.
+func (r *RangeBackfiller) Run(stopC <-chan struct{}) error {
+	save := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), saveTimeout)
+		defer cancel()
+		if err := r.Save(ctx); err != nil {
+			r.logger.Errorf("%s", err)
+		}
+	}
+
+	t := time.NewTicker(r.cfg.SaveInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			save()
+		case <-stopC:
+			save()
+			return nil
+		}
+	}
+}
+
+// Dummy is a Dummy implementation of the backfiller, it doesn't backfill.
+var Dummy = &dummy{}
+
+type dummy struct{}
+
+func (dummy) Backfill(_ context.Context, _ string, _ *monitoringv1alpha1.ServiceLevel, _ *monitoringv1alpha1.SLO, _ time.Time, _ output.Output) (int, error) {
+	return 0, nil
+}
+func (dummy) Evaluated(_ string, _ *monitoringv1alpha1.ServiceLevel, _ *monitoringv1alpha1.SLO, _ time.Time) {
+}
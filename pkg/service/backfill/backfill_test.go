@@ -0,0 +1,217 @@
+package backfill_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+
+	moutput "github.com/spotahome/service-level-operator/mocks/service/output"
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/backfill"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
+)
+
+var (
+	sl0 = &monitoringv1alpha1.ServiceLevel{
+		ObjectMeta: metav1.ObjectMeta{Name: "sl0", Namespace: "ns0"},
+	}
+	slo0 = &monitoringv1alpha1.SLO{
+		Name: "slo0",
+		ServiceLevelIndicator: monitoringv1alpha1.SLI{
+			SLISource: monitoringv1alpha1.SLISource{
+				Prometheus: &monitoringv1alpha1.PrometheusSLISource{},
+			},
+		},
+	}
+)
+
+// fakeRangeRetriever returns a result at every step of the range and records
+// the requested ranges.
+type fakeRangeRetriever struct {
+	err    error
+	ranges [][2]time.Time
+}
+
+func (f *fakeRangeRetriever) RetrieveRange(_ context.Context, _ *monitoringv1alpha1.SLI, start, end time.Time, step time.Duration) ([]sli.TimedResult, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.ranges = append(f.ranges, [2]time.Time{start, end})
+	res := []sli.TimedResult{}
+	for t := start; !t.After(end); t = t.Add(step) {
+		res = append(res, sli.TimedResult{Time: t, Result: sli.Result{TotalQ: 100, ErrorQ: 1}})
+	}
+	return res, nil
+}
+
+func TestRangeBackfillerBackfill(t *testing.T) {
+	now := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		last      *time.Time
+		retErr    error
+		expRanges [][2]time.Time
+		expN      int
+		expErr    bool
+	}{
+		"Without previous evaluation there should not be backfill.": {
+			expN: 0,
+		},
+		"With a recent evaluation there should not be backfill.": {
+			last: timePtr(now.Add(-12 * time.Second)),
+			expN: 0,
+		},
+		"The missed evaluations should be backfilled at their timestamps.": {
+			last:      timePtr(now.Add(-time.Minute)),
+			expRanges: [][2]time.Time{{now.Add(-50 * time.Second), now.Add(-10 * time.Second)}},
+			expN:      5,
+		},
+		"The missed evaluations should be limited to the max window.": {
+			last:      timePtr(now.Add(-24*time.Hour - 5*time.Second)),
+			expRanges: [][2]time.Time{{now.Add(-time.Hour + 5*time.Second), now.Add(-5 * time.Second)}},
+			expN:      360,
+		},
+		"An error retrieving the missed evaluations should fail.": {
+			last:   timePtr(now.Add(-time.Minute)),
+			retErr: errors.New("wanted error"),
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			ret := &fakeRangeRetriever{err: test.retErr}
+			b, err := backfill.NewRangeBackfiller(backfill.Cfg{Interval: 10 * time.Second, MaxWindow: time.Hour}, ret, log.Dummy)
+			require.NoError(err)
+			if test.last != nil {
+				b.Evaluated("", sl0, slo0, *test.last)
+			}
+
+			mout := &moutput.Output{}
+			mout.On("Create", mock.Anything, "", sl0, slo0, mock.Anything).Return(nil)
+
+			n, err := b.Backfill(context.Background(), "", sl0, slo0, now, mout)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expN, n)
+				assert.Equal(test.expRanges, ret.ranges)
+				mout.AssertNumberOfCalls(t, "Create", test.expN)
+			}
+		})
+	}
+}
+
+func TestRangeBackfillerBackfillFailedEvaluation(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	now := time.Now()
+	ret := &fakeRangeRetriever{}
+	b, err := backfill.NewRangeBackfiller(backfill.Cfg{Interval: 10 * time.Second, MaxWindow: time.Hour}, ret, log.Dummy)
+	require.NoError(err)
+	b.Evaluated("", sl0, slo0, now.Add(-time.Minute))
+
+	mout := &moutput.Output{}
+	mout.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	// Once backfilled, if the current evaluation fails, only the failed
+	// evaluation should be backfilled on the next evaluation.
+	n, err := b.Backfill(context.Background(), "", sl0, slo0, now, mout)
+	require.NoError(err)
+	assert.Equal(5, n)
+	n, err = b.Backfill(context.Background(), "", sl0, slo0, now.Add(10*time.Second), mout)
+	require.NoError(err)
+	assert.Equal(1, n)
+	assert.Equal([2]time.Time{now, now}, ret.ranges[1])
+}
+
+func TestCheckpointStores(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backfill")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	stores := map[string]backfill.CheckpointStore{
+		"file": backfill.FileStore{Path: filepath.Join(dir, "checkpoints.json")},
+		"configmap": backfill.ConfigMapStore{
+			Client:    kubernetesfake.NewSimpleClientset(),
+			Namespace: "ns0",
+			Name:      "checkpoints",
+		},
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Not persisted checkpoints should be empty.
+			last, err := store.Load(context.Background())
+			require.NoError(err)
+			assert.Empty(last)
+
+			// The saved checkpoints should be loaded, also when they are replaced.
+			exp := map[string]time.Time{"ns0:sl0:slo0": time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)}
+			require.NoError(store.Save(context.Background(), map[string]time.Time{"ns0:sl0:slo1": time.Now()}))
+			require.NoError(store.Save(context.Background(), exp))
+			last, err = store.Load(context.Background())
+			require.NoError(err)
+			assert.Equal(len(exp), len(last))
+			for id, t := range exp {
+				assert.True(t.Equal(last[id]))
+			}
+		})
+	}
+}
+
+func TestRangeBackfillerPersistence(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "backfill")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	cfg := backfill.Cfg{
+		Interval:  10 * time.Second,
+		MaxWindow: time.Hour,
+		Store:     backfill.FileStore{Path: filepath.Join(dir, "checkpoints.json")},
+	}
+	now := time.Now()
+
+	// The operator evaluates and stops.
+	b, err := backfill.NewRangeBackfiller(cfg, &fakeRangeRetriever{}, log.Dummy)
+	require.NoError(err)
+	require.NoError(b.Load(context.Background()))
+	b.Evaluated("", sl0, slo0, now.Add(-time.Minute))
+	require.NoError(b.Save(context.Background()))
+
+	// The restarted operator should backfill the downtime.
+	ret := &fakeRangeRetriever{}
+	b, err = backfill.NewRangeBackfiller(cfg, ret, log.Dummy)
+	require.NoError(err)
+	require.NoError(b.Load(context.Background()))
+
+	mout := &moutput.Output{}
+	mout.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	n, err := b.Backfill(context.Background(), "", sl0, slo0, now, mout)
+	require.NoError(err)
+	assert.Equal(5, n)
+}
+
+func timePtr(t time.Time) *time.Time { return &t }
@@ -0,0 +1,146 @@
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const defConfigMapKey = "checkpoints.json"
+
+// CheckpointStore knows how to persist the last evaluation time of the SLOs.
+type CheckpointStore interface {
+	// Load returns the persisted last evaluation times by SLO ID, empty if
+	// they have not been persisted yet.
+	Load(ctx context.Context) (map[string]time.Time, error)
+	// Save persists the last evaluation times by SLO ID.
+	Save(ctx context.Context, last map[string]time.Time) error
+	// String returns the description of the store.
+	String() string
+}
+
+// FileStore persists the checkpoints on a JSON file, the file is replaced
+// atomically on every save.
+type FileStore struct {
+	Path string
+}
+
+// Load satisfies CheckpointStore interface.
+func (f FileStore) Load(_ context.Context) (map[string]time.Time, error) {
+	bs, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]time.Time{}, nil
+		}
+		return nil, err
+	}
+
+	return decodeCheckpoints(bs)
+}
+
+// Save satisfies CheckpointStore interface.
+func (f FileStore) Save(_ context.Context, last map[string]time.Time) error {
+	bs, err := json.Marshal(last)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.Path), filepath.Base(f.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(bs)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+func (f FileStore) String() string { return "file:" + f.Path }
+
+// ConfigMapStore persists the checkpoints on a key of a Kubernetes ConfigMap,
+// the ConfigMap is created if it's missing.
+type ConfigMapStore struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Name      string
+}
+
+// Load satisfies CheckpointStore interface.
+func (c ConfigMapStore) Load(_ context.Context) (map[string]time.Time, error) {
+	cm, err := c.Client.CoreV1().ConfigMaps(c.Namespace).Get(c.Name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return map[string]time.Time{}, nil
+		}
+		return nil, err
+	}
+
+	data, ok := cm.Data[defConfigMapKey]
+	if !ok {
+		return map[string]time.Time{}, nil
+	}
+
+	return decodeCheckpoints([]byte(data))
+}
+
+// Save satisfies CheckpointStore interface.
+func (c ConfigMapStore) Save(_ context.Context, last map[string]time.Time) error {
+	bs, err := json.Marshal(last)
+	if err != nil {
+		return err
+	}
+
+	cli := c.Client.CoreV1().ConfigMaps(c.Namespace)
+	cm, err := cli.Get(c.Name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		_, err := cli.Create(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      c.Name,
+				Namespace: c.Namespace,
+			},
+			Data: map[string]string{defConfigMapKey: string(bs)},
+		})
+		return err
+	}
+
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[defConfigMapKey] = string(bs)
+	_, err = cli.Update(cm)
+	return err
+}
+
+func (c ConfigMapStore) String() string {
+	return fmt.Sprintf("configmap:%s/%s", c.Namespace, c.Name)
+}
+
+func decodeCheckpoints(bs []byte) (map[string]time.Time, error) {
+	last := map[string]time.Time{}
+	err := json.Unmarshal(bs, &last)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoints: %s", err)
+	}
+	return last, nil
+}
@@ -0,0 +1,143 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
+)
+
+// Config is the configuration of a backtest.
+type Config struct {
+	// Start is the start of the backtested time range.
+	Start time.Time
+	// End is the end of the backtested time range.
+	End time.Time
+	// Interval is the SLO evaluation interval, the resync period of the operator.
+	Interval time.Duration
+	// SLO is the SLO to backtest, by default all the SLOs of the service level.
+	SLO string
+}
+
+// Validate validates the configuration.
+func (c Config) Validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	if !c.End.After(c.Start) {
+		return fmt.Errorf("end must be after start")
+	}
+	return nil
+}
+
+// SLOResult is the result of backtesting an SLO, what the operator would have
+// reported at the end of the time range if it had been evaluating the SLO
+// since the start of the time range.
+type SLOResult struct {
+	Namespace        string  `json:"namespace"`
+	ServiceLevel     string  `json:"serviceLevel"`
+	SLO              string  `json:"slo"`
+	ObjectivePercent float64 `json:"objectivePercent"`
+	Disabled         bool    `json:"disabled,omitempty"`
+	// Evaluations is the number of evaluations that have been counted.
+	Evaluations int `json:"evaluations"`
+	// FailedEvaluations is the number of evaluations with invalid SLI results
+	// that the operator would have discarded.
+	FailedEvaluations int `json:"failedEvaluations"`
+	// Counters are the counters the Prometheus output would have.
+	Counters output.SLOCounters `json:"counters"`
+	// AvailabilityRatio is the availability of the whole time range.
+	AvailabilityRatio float64 `json:"availabilityRatio"`
+	// RemainingErrorBudgetRatio is the error budget that would remain at the end
+	// of the time range, negative if the budget has been exhausted.
+	RemainingErrorBudgetRatio float64 `json:"remainingErrorBudgetRatio"`
+	// ObjectiveMet is true when the availability is equal or greater than the objective.
+	ObjectiveMet bool `json:"objectiveMet"`
+	// Error is the error retrieving the SLIs of the SLO.
+	Error string `json:"error,omitempty"`
+}
+
+// Run backtests the SLOs of a service level, the SLIs are retrieved at every
+// interval of the time range and accumulated like the Prometheus output does.
+// The errors retrieving an SLO are set on its result so the rest of the SLOs
+// are backtested independently.
+func Run(ctx context.Context, retriever sli.RangeRetriever, sl *monitoringv1alpha1.ServiceLevel, cfg Config) ([]SLOResult, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	err = sl.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s/%s service level: %s", sl.Namespace, sl.Name, err)
+	}
+
+	// The counters are accumulated with the same output the operator uses.
+	promOutput := output.NewPrometheus(output.PrometheusCfg{}, prometheus.NewRegistry(), log.Dummy)
+	counters, ok := promOutput.(output.CounterGetter)
+	if !ok {
+		return nil, fmt.Errorf("the prometheus output doesn't have counters")
+	}
+
+	var res []SLOResult
+	found := false
+	for i := range sl.Spec.ServiceLevelObjectives {
+		slo := &sl.Spec.ServiceLevelObjectives[i]
+		if cfg.SLO != "" && slo.Name != cfg.SLO {
+			continue
+		}
+		found = true
+
+		r := SLOResult{
+			Namespace:        sl.Namespace,
+			ServiceLevel:     sl.Name,
+			SLO:              slo.Name,
+			ObjectivePercent: slo.AvailabilityObjectivePercent,
+			Disabled:         slo.Disable,
+			Counters:         output.SLOCounters{Objective: slo.AvailabilityObjectivePercent / 100},
+		}
+		if slo.Disable {
+			res = append(res, r)
+			continue
+		}
+
+		results, err := retriever.RetrieveRange(ctx, &slo.ServiceLevelIndicator, cfg.Start, cfg.End, cfg.Interval)
+		if err != nil {
+			r.Error = err.Error()
+			res = append(res, r)
+			continue
+		}
+
+		for _, tr := range results {
+			tr := tr
+			err := promOutput.Create(ctx, "", sl, slo, &tr.Result)
+			if err != nil {
+				r.FailedEvaluations++
+				continue
+			}
+			r.Evaluations++
+		}
+
+		if c, ok := counters.GetSLOCounters("", sl, slo); ok {
+			r.Counters = c
+		}
+		if availability, remaining, ok := r.Counters.ErrorBudget(); ok {
+			r.AvailabilityRatio = availability
+			r.RemainingErrorBudgetRatio = remaining
+			r.ObjectiveMet = availability >= r.Counters.Objective
+		}
+
+		res = append(res, r)
+	}
+
+	if cfg.SLO != "" && !found {
+		return nil, fmt.Errorf("%s SLO not found on %s/%s service level", cfg.SLO, sl.Namespace, sl.Name)
+	}
+
+	return res, nil
+}
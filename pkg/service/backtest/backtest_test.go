@@ -0,0 +1,138 @@
+package backtest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/service/backtest"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
+)
+
+// fakeRangeRetriever returns the results of the SLIs by the total query.
+type fakeRangeRetriever map[string][]sli.Result
+
+func (f fakeRangeRetriever) RetrieveRange(_ context.Context, s *monitoringv1alpha1.SLI, start, end time.Time, step time.Duration) ([]sli.TimedResult, error) {
+	results, ok := f[s.Prometheus.TotalQuery]
+	if !ok {
+		return nil, errors.New("wanted error")
+	}
+	res := []sli.TimedResult{}
+	for i, r := range results {
+		res = append(res, sli.TimedResult{Time: start.Add(time.Duration(i) * step), Result: r})
+	}
+	return res, nil
+}
+
+func newSLO(name string, objective float64, query string) monitoringv1alpha1.SLO {
+	return monitoringv1alpha1.SLO{
+		Name:                         name,
+		AvailabilityObjectivePercent: objective,
+		ServiceLevelIndicator: monitoringv1alpha1.SLI{
+			SLISource: monitoringv1alpha1.SLISource{
+				Prometheus: &monitoringv1alpha1.PrometheusSLISource{TotalQuery: query, ErrorQuery: query + "_errors"},
+			},
+		},
+		Output: monitoringv1alpha1.Output{
+			Prometheus: &monitoringv1alpha1.PrometheusOutputSource{},
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	start := time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		slos      []monitoringv1alpha1.SLO
+		retriever fakeRangeRetriever
+		cfg       backtest.Config
+		expResult []backtest.SLOResult
+		expErr    bool
+	}{
+		"An invalid config should fail.": {
+			slos:   []monitoringv1alpha1.SLO{newSLO("slo0", 99, "q0")},
+			cfg:    backtest.Config{Start: start, End: start, Interval: time.Minute},
+			expErr: true,
+		},
+		"A missing SLO should fail.": {
+			slos:   []monitoringv1alpha1.SLO{newSLO("slo0", 99, "q0")},
+			cfg:    backtest.Config{Start: start, End: start.Add(time.Hour), Interval: time.Minute, SLO: "slo1"},
+			expErr: true,
+		},
+		"The results should be accumulated like the operator does.": {
+			slos: []monitoringv1alpha1.SLO{newSLO("slo0", 90, "q0")},
+			retriever: fakeRangeRetriever{
+				"q0": {
+					{TotalQ: 100, ErrorQ: 0},
+					{TotalQ: 100, ErrorQ: 10},
+					{TotalQ: 100, ErrorQ: 20},
+					{TotalQ: 10, ErrorQ: 20}, // Invalid, discarded.
+					{TotalQ: 0, ErrorQ: 0},
+				},
+			},
+			cfg: backtest.Config{Start: start, End: start.Add(time.Hour), Interval: time.Minute},
+			expResult: []backtest.SLOResult{
+				{
+					ServiceLevel:              "sl0",
+					Namespace:                 "ns0",
+					SLO:                       "slo0",
+					ObjectivePercent:          90,
+					Evaluations:               4,
+					FailedEvaluations:         1,
+					Counters:                  output.SLOCounters{ErrorRatioSum: 0.3, Count: 4, Objective: 0.9},
+					AvailabilityRatio:         0.925,
+					RemainingErrorBudgetRatio: 0.25,
+					ObjectiveMet:              true,
+				},
+			},
+		},
+		"Disabled SLOs should not be backtested and the SLO errors should be reported on the SLO.": {
+			slos: func() []monitoringv1alpha1.SLO {
+				disabled := newSLO("slo0", 99, "q0")
+				disabled.Disable = true
+				return []monitoringv1alpha1.SLO{disabled, newSLO("slo1", 99, "missing")}
+			}(),
+			retriever: fakeRangeRetriever{},
+			cfg:       backtest.Config{Start: start, End: start.Add(time.Hour), Interval: time.Minute},
+			expResult: []backtest.SLOResult{
+				{ServiceLevel: "sl0", Namespace: "ns0", SLO: "slo0", ObjectivePercent: 99, Disabled: true, Counters: output.SLOCounters{Objective: 0.99}},
+				{ServiceLevel: "sl0", Namespace: "ns0", SLO: "slo1", ObjectivePercent: 99, Counters: output.SLOCounters{Objective: 0.99}, Error: "wanted error"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sl := &monitoringv1alpha1.ServiceLevel{
+				ObjectMeta: metav1.ObjectMeta{Name: "sl0", Namespace: "ns0"},
+				Spec:       monitoringv1alpha1.ServiceLevelSpec{ServiceLevelObjectives: test.slos},
+			}
+			res, err := backtest.Run(context.Background(), test.retriever, sl, test.cfg)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				if assert.Len(res, len(test.expResult)) {
+					for i, exp := range test.expResult {
+						got := res[i]
+						assert.InDelta(exp.Counters.ErrorRatioSum, got.Counters.ErrorRatioSum, 1e-9)
+						assert.InDelta(exp.AvailabilityRatio, got.AvailabilityRatio, 1e-9)
+						assert.InDelta(exp.RemainingErrorBudgetRatio, got.RemainingErrorBudgetRatio, 1e-9)
+						exp.Counters.ErrorRatioSum, got.Counters.ErrorRatioSum = 0, 0
+						exp.AvailabilityRatio, got.AvailabilityRatio = 0, 0
+						exp.RemainingErrorBudgetRatio, got.RemainingErrorBudgetRatio = 0, 0
+						assert.Equal(exp, got)
+					}
+				}
+			}
+		})
+	}
+}
@@ -16,18 +16,30 @@ import (
 )
 
 // fakeFactory is a fake factory that has already loaded faked objects on the Kubernetes clients.
-type fakeFactory struct{}
+type fakeFactory struct {
+	crdObjs []runtime.Object
+}
 
 // NewFake returns the faked Kubernetes clients factory.
 func NewFake() ClientFactory {
-	return &fakeFactory{}
+	return &fakeFactory{crdObjs: crdObjs}
+}
+
+// NewFakeWithServiceLevels returns a faked Kubernetes clients factory that has
+// the service levels instead of the default faked ones.
+func NewFakeWithServiceLevels(sls []*monitoringv1alpha1.ServiceLevel) ClientFactory {
+	objs := make([]runtime.Object, 0, len(sls))
+	for _, sl := range sls {
+		objs = append(objs, sl.DeepCopy())
+	}
+	return &fakeFactory{crdObjs: objs}
 }
 
 func (f *fakeFactory) GetSTDClient() (kubernetes.Interface, error) {
 	return kubernetesfake.NewSimpleClientset(stdObjs...), nil
 }
 func (f *fakeFactory) GetCRDClient() (crdcli.Interface, error) {
-	return crdclifake.NewSimpleClientset(crdObjs...), nil
+	return crdclifake.NewSimpleClientset(f.crdObjs...), nil
 }
 func (f *fakeFactory) GetAPIExtensionClient() (apiextensionscli.Interface, error) {
 	cli := apiextensionsclifake.NewSimpleClientset(aexObjs...)
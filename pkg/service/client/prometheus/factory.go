@@ -1,7 +1,10 @@
 package prometheus
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"sync"
 
 	"github.com/prometheus/client_golang/api"
@@ -47,9 +50,10 @@ func (f *BaseFactory) GetV1APIClient(address string) (promv1.API, error) {
 	return promv1.NewAPI(cli), nil
 }
 
+const defAddressKey = ""
+
 // WithDefaultV1APIClient sets a default client for V1 api client.
 func (f *BaseFactory) WithDefaultV1APIClient(address string) error {
-	const defAddressKey = ""
 	f.climu.Lock()
 	defer f.climu.Unlock()
 
@@ -62,12 +66,46 @@ func (f *BaseFactory) WithDefaultV1APIClient(address string) error {
 	return nil
 }
 
+// WithoutDefaultV1APIClient removes the default client for V1 api client,
+// getting a client without address will error.
+func (f *BaseFactory) WithoutDefaultV1APIClient() {
+	f.climu.Lock()
+	defer f.climu.Unlock()
+
+	delete(f.v1Clis, defAddressKey)
+}
+
 func newClient(address string) (api.Client, error) {
 	if address == "" {
 		return nil, fmt.Errorf("address can't be empty")
 	}
 
-	return api.NewClient(api.Config{Address: address})
+	cli, err := api.NewClient(api.Config{Address: address})
+	if err != nil {
+		return nil, err
+	}
+	return warningsClient{Client: cli}, nil
+}
+
+// warningsClient returns the warnings of the Prometheus responses, the v1 API
+// client doesn't get them from the response body.
+type warningsClient struct {
+	api.Client
+}
+
+func (w warningsClient) Do(ctx context.Context, req *http.Request) (*http.Response, []byte, api.Warnings, error) {
+	resp, body, warnings, err := w.Client.Do(ctx, req)
+	if err != nil || len(warnings) > 0 {
+		return resp, body, warnings, err
+	}
+
+	var r struct {
+		Warnings []string `json:"warnings"`
+	}
+	if json.Unmarshal(body, &r) == nil && len(r.Warnings) > 0 {
+		warnings = r.Warnings
+	}
+	return resp, body, warnings, nil
 }
 
 // MockFactory returns a predefined prometheus v1 API client.
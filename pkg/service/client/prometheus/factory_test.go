@@ -1,9 +1,16 @@
 package prometheus_test
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/api"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/spotahome/service-level-operator/pkg/service/client/prometheus"
 )
@@ -38,6 +45,17 @@ func TestBaseFactoryV1Client(t *testing.T) {
 			address: "",
 			expErr:  false,
 		},
+
+		"Getting a missing address client after removing the default client should error.": {
+			cli: func() *prometheus.BaseFactory {
+				f := prometheus.NewBaseFactory()
+				f.WithDefaultV1APIClient("http://127.0.0.1:9090")
+				f.WithoutDefaultV1APIClient()
+				return f
+			},
+			address: "",
+			expErr:  true,
+		},
 	}
 
 	for name, test := range tests {
@@ -56,3 +74,22 @@ func TestBaseFactoryV1Client(t *testing.T) {
 	}
 
 }
+
+func TestBaseFactoryV1ClientWarnings(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"status":"success","warnings":["partial response"],"data":{"resultType":"vector","result":[]}}`)
+	}))
+	defer srv.Close()
+
+	f := prometheus.NewBaseFactory()
+	cli, err := f.GetV1APIClient(srv.URL)
+	require.NoError(err)
+
+	_, warnings, err := cli.Query(context.Background(), "up", time.Now())
+	require.NoError(err)
+	assert.Equal(api.Warnings{"partial response"}, warnings)
+}
@@ -3,6 +3,8 @@ package prometheus
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/api"
@@ -14,54 +16,75 @@ var (
 	slo3CallCount int
 )
 
+// FakeQueryFunc returns the faked value of a query at a point in time.
+type FakeQueryFunc func(ts time.Time) float64
+
 type fakeFactory struct {
+	queryFuncs map[string]FakeQueryFunc
 }
 
 // NewFakeFactory returns a new fake factory.
 func NewFakeFactory() ClientFactory {
-	return &fakeFactory{}
+	return NewFakeFactoryWithQueries(map[string]FakeQueryFunc{
+		"slo0_total": func(_ time.Time) float64 { return 100 },
+		"slo0_error": func(_ time.Time) float64 { return 1 },
+		"slo1_total": func(_ time.Time) float64 { return 1000 },
+		"slo1_error": func(_ time.Time) float64 { return 1 },
+		"slo2_total": func(_ time.Time) float64 { return 100000 },
+		"slo2_error": func(_ time.Time) float64 { return 12 },
+		"slo3_total": func(_ time.Time) float64 { return 10000 },
+		"slo3_error": func(_ time.Time) float64 {
+			// Every 2 calls return error.
+			slo3CallCount++
+			if slo3CallCount%2 == 0 {
+				return 1
+			}
+			return 0
+		},
+	})
+}
+
+// NewFakeFactoryWithQueries returns a new fake factory whose clients answer the
+// queries with the values of the query funcs, the rest of the queries fail.
+func NewFakeFactoryWithQueries(queryFuncs map[string]FakeQueryFunc) ClientFactory {
+	return &fakeFactory{queryFuncs: queryFuncs}
 }
 
 // GetV1APIClient satisfies ClientFactory interface.
 func (f *fakeFactory) GetV1APIClient(_ string) (promv1.API, error) {
-	return &fakeAPICli{
-		queryFuncs: map[string]func() float64{
-			"slo0_total": func() float64 { return 100 },
-			"slo0_error": func() float64 { return 1 },
-			"slo1_total": func() float64 { return 1000 },
-			"slo1_error": func() float64 { return 1 },
-			"slo2_total": func() float64 { return 100000 },
-			"slo2_error": func() float64 { return 12 },
-			"slo3_total": func() float64 { return 10000 },
-			"slo3_error": func() float64 {
-				// Every 2 calls return error.
-				slo3CallCount++
-				if slo3CallCount%2 == 0 {
-					return 1
-				}
-				return 0
-			},
-		},
-	}, nil
+	return &fakeAPICli{queryFuncs: f.queryFuncs}, nil
 }
 
 // fakeAPICli is a faked http client.
 type fakeAPICli struct {
-	queryFuncs map[string]func() float64
+	queryFuncs map[string]FakeQueryFunc
 }
 
-func (f *fakeAPICli) Query(_ context.Context, query string, ts time.Time) (model.Value, api.Warnings, error) {
-
-	fn, ok := f.queryFuncs[query]
+func (f *fakeAPICli) queryFunc(query string) (FakeQueryFunc, error) {
+	// Multiline queries on YAML manifests usually end with a new line.
+	fn, ok := f.queryFuncs[strings.TrimSpace(query)]
 	if !ok {
-		return nil, nil, fmt.Errorf("not faked result")
+		known := make([]string, 0, len(f.queryFuncs))
+		for q := range f.queryFuncs {
+			known = append(known, q)
+		}
+		sort.Strings(known)
+		return nil, fmt.Errorf("not faked %q query, the faked queries are: %s", query, strings.Join(known, ", "))
+	}
+	return fn, nil
+}
+
+func (f *fakeAPICli) Query(_ context.Context, query string, ts time.Time) (model.Value, api.Warnings, error) {
+	fn, err := f.queryFunc(query)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	return model.Vector{
 		&model.Sample{
 			Metric:    model.Metric{},
-			Timestamp: model.Time(time.Now().UTC().Nanosecond()),
-			Value:     model.SampleValue(fn()),
+			Timestamp: model.TimeFromUnixNano(ts.UnixNano()),
+			Value:     model.SampleValue(fn(ts)),
 		},
 	}, nil, nil
 }
@@ -91,7 +114,23 @@ func (f *fakeAPICli) LabelValues(_ context.Context, label string) (model.LabelVa
 	return model.LabelValues{}, nil, nil
 }
 func (f *fakeAPICli) QueryRange(_ context.Context, query string, r promv1.Range) (model.Value, api.Warnings, error) {
-	return nil, nil, nil
+	fn, err := f.queryFunc(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	if r.Step <= 0 {
+		return nil, nil, fmt.Errorf("zero or negative query resolution step widths are not accepted")
+	}
+
+	ss := &model.SampleStream{Metric: model.Metric{}}
+	for ts := r.Start; !ts.After(r.End); ts = ts.Add(r.Step) {
+		ss.Values = append(ss.Values, model.SamplePair{
+			Timestamp: model.TimeFromUnixNano(ts.UnixNano()),
+			Value:     model.SampleValue(fn(ts)),
+		})
+	}
+
+	return model.Matrix{ss}, nil, nil
 }
 func (f *fakeAPICli) Series(_ context.Context, matches []string, startTime time.Time, endTime time.Time) ([]model.LabelSet, api.Warnings, error) {
 	return []model.LabelSet{}, nil, nil
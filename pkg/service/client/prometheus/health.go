@@ -0,0 +1,26 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// pingQuery is a query that every Prometheus can answer without data.
+const pingQuery = "vector(1)"
+
+// CheckV1APIClient checks that the Prometheus of the address (empty for the
+// default one) is reachable running a trivial query.
+func CheckV1APIClient(ctx context.Context, f ClientFactory, address string) error {
+	cli, err := f.GetV1APIClient(address)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = cli.Query(ctx, pingQuery, time.Now())
+	if err != nil {
+		return fmt.Errorf("prometheus not reachable: %s", err)
+	}
+
+	return nil
+}
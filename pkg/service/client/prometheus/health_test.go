@@ -0,0 +1,44 @@
+package prometheus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mpromv1 "github.com/spotahome/service-level-operator/mocks/github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/spotahome/service-level-operator/pkg/service/client/prometheus"
+)
+
+func TestCheckV1APIClient(t *testing.T) {
+	tests := map[string]struct {
+		queryErr error
+		expErr   bool
+	}{
+		"A reachable Prometheus should be healthy.": {},
+		"A not reachable Prometheus should not be healthy.": {
+			queryErr: errors.New("wanted error"),
+			expErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			mapi := &mpromv1.API{}
+			mapi.On("Query", mock.Anything, "vector(1)", mock.Anything).Once().Return(model.Vector{}, nil, test.queryErr)
+
+			err := prometheus.CheckV1APIClient(context.Background(), &prometheus.MockFactory{Cli: mapi}, "")
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			mapi.AssertExpectations(t)
+		})
+	}
+}
@@ -3,8 +3,10 @@ package configuration
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"net/url"
 )
 
 // DefaultSLISource is a configuration object with the default
@@ -18,19 +20,47 @@ type PrometheusSLISource struct {
 	Address string `json:"address,omitempty"`
 }
 
+// Validate validates the default SLI source configuration.
+func (d *DefaultSLISource) Validate() error {
+	// Not having a default source is valid.
+	if d.Prometheus.Address == "" {
+		return nil
+	}
+
+	u, err := url.Parse(d.Prometheus.Address)
+	if err != nil {
+		return fmt.Errorf("invalid prometheus address: %s", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid prometheus address %q, it should be an absolute URL", d.Prometheus.Address)
+	}
+
+	return nil
+}
 
 // Loader knows how to load configuration based on different formats.
-// At this moment configuration is not versioned, the configuration
-// is so simple that if it grows we could refactor and add version,
-// in this case not versioned configuration could be loaded as v1.
+// The not versioned configuration (only the default SLI sources) is loaded
+// as v1.
 type Loader interface {
+	// LoadConfiguration will load the operator configuration.
+	LoadConfiguration(ctx context.Context, r io.Reader) (*Configuration, error)
 	// LoadDefaultSLISource will load the default sli source configuration .
 	LoadDefaultSLISource(ctx context.Context, r io.Reader) (*DefaultSLISource, error)
 }
 
-// JSONLoader knows how to load application configuration.
+// JSONLoader knows how to load not versioned (v1) application configuration.
 type JSONLoader struct{}
 
+// LoadConfiguration satisfies Loader interface by loading in JSON format.
+func (j JSONLoader) LoadConfiguration(ctx context.Context, r io.Reader) (*Configuration, error) {
+	dss, err := j.LoadDefaultSLISource(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Configuration{DefaultSLISource: *dss}, nil
+}
+
 // LoadDefaultSLISource satisfies Loader interface by loading in JSON format.
 func (j JSONLoader) LoadDefaultSLISource(_ context.Context, r io.Reader) (*DefaultSLISource, error) {
 	bs, err := ioutil.ReadAll(r)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/spotahome/service-level-operator/pkg/service/configuration"
 	"github.com/stretchr/testify/assert"
@@ -44,3 +45,281 @@ func TestJSONLoaderLoadDefaultSLISource(t *testing.T) {
 		})
 	}
 }
+
+func TestYAMLLoaderLoadConfiguration(t *testing.T) {
+	tests := map[string]struct {
+		config    string
+		expConfig *configuration.Configuration
+		expErr    bool
+	}{
+		"Not versioned JSON configuration should be loaded as v1.": {
+			config: `{"prometheus": {"address": "http://test:9090"}}`,
+			expConfig: &configuration.Configuration{
+				DefaultSLISource: configuration.DefaultSLISource{
+					Prometheus: configuration.PrometheusSLISource{
+						Address: "http://test:9090",
+					},
+				},
+			},
+		},
+
+		"Not versioned YAML configuration should be loaded as v1.": {
+			config: `
+prometheus:
+  address: http://test:9090
+`,
+			expConfig: &configuration.Configuration{
+				DefaultSLISource: configuration.DefaultSLISource{
+					Prometheus: configuration.PrometheusSLISource{
+						Address: "http://test:9090",
+					},
+				},
+			},
+		},
+
+		"Not versioned configuration with unknown fields should error.": {
+			config: `
+prometheus:
+  adress: http://test:9090
+`,
+			expErr: true,
+		},
+
+		"A v2 configuration without version should error.": {
+			config: `
+kind: Configuration
+defaultSLISources:
+  prometheus:
+    address: http://test:9090
+`,
+			expErr: true,
+		},
+
+		"Correct v2 configuration should be loaded without error.": {
+			config: `
+apiVersion: service-level-operator.spotahome.com/v2
+kind: Configuration
+resync: 30s
+workers: 4
+namespaces: [ns0, ns1]
+labelSelector: team=a-team
+checkCRDOnly: true
+disableEvents: true
+defaultSLISources:
+  prometheus:
+    address: http://test:9090
+output:
+  prometheus:
+    expireDuration: 5m
+sliCircuitBreaker:
+  failureThreshold: 3
+  openDuration: 1m
+server:
+  listenAddress: ":9000"
+  metricsPath: /metricz
+clusters:
+  name: cluster0
+  kubeContexts: [cluster1]
+  kubeconfigSecrets: [ns0/cluster2]
+tracing:
+  otlpEndpoint: otel-collector:4318
+  insecure: true
+operatorMetrics:
+  sloMetrics: true
+  maxSLOs: 100
+health:
+  livenessResyncPeriods: 20
+backfill:
+  maxWindow: 1h
+  checkpointConfigMap: ns0/slo-checkpoints
+dashboards:
+  enabled: true
+  namespace: monitoring
+  labels:
+    grafana_dashboard: "1"
+  window: 28d
+reports:
+  dir: /var/lib/slo-reports
+  formats: [markdown, csv]
+  prometheusAddress: http://prometheus:9090
+alertmanager:
+  urls: [http://alertmanager-0:9093, http://alertmanager-1:9093]
+  budgetThresholds: [50, 90]
+  burnRate: 6
+  burnRateWindow: 6h
+gate:
+  minErrorBudgetPercent: 20
+  allowUnknown: true
+`,
+			expConfig: &configuration.Configuration{
+				ResyncPeriod:  30 * time.Second,
+				Workers:       4,
+				Namespaces:    []string{"ns0", "ns1"},
+				LabelSelector: "team=a-team",
+				CheckCRDOnly:  true,
+				DisableEvents: true,
+				DefaultSLISource: configuration.DefaultSLISource{
+					Prometheus: configuration.PrometheusSLISource{
+						Address: "http://test:9090",
+					},
+				},
+				PrometheusOutput: configuration.PrometheusOutput{
+					ExpireDuration: 5 * time.Minute,
+				},
+				SLICircuitBreaker: configuration.SLICircuitBreaker{
+					FailureThreshold: 3,
+					OpenDuration:     time.Minute,
+				},
+				Server: configuration.Server{
+					ListenAddress: ":9000",
+					MetricsPath:   "/metricz",
+				},
+				Clusters: configuration.Clusters{
+					Name:              "cluster0",
+					KubeContexts:      []string{"cluster1"},
+					KubeconfigSecrets: []string{"ns0/cluster2"},
+				},
+				Tracing: configuration.Tracing{
+					OTLPEndpoint: "otel-collector:4318",
+					Insecure:     true,
+				},
+				OperatorMetrics: configuration.OperatorMetrics{
+					SLOMetrics: true,
+					MaxSLOs:    100,
+				},
+				Health: configuration.Health{
+					LivenessResyncPeriods: 20,
+				},
+				Backfill: configuration.Backfill{
+					MaxWindow:           time.Hour,
+					CheckpointConfigMap: "ns0/slo-checkpoints",
+				},
+				Dashboards: configuration.Dashboards{
+					Enabled:   true,
+					Namespace: "monitoring",
+					Labels:    map[string]string{"grafana_dashboard": "1"},
+					Window:    "28d",
+				},
+				Reports: configuration.Reports{
+					Dir:               "/var/lib/slo-reports",
+					Formats:           []string{"markdown", "csv"},
+					PrometheusAddress: "http://prometheus:9090",
+				},
+				Alertmanager: configuration.Alertmanager{
+					URLs:             []string{"http://alertmanager-0:9093", "http://alertmanager-1:9093"},
+					BudgetThresholds: []float64{50, 90},
+					BurnRate:         6,
+					BurnRateWindow:   6 * time.Hour,
+				},
+				Gate: configuration.Gate{
+					MinErrorBudgetPercent: 20,
+					AllowUnknown:          true,
+				},
+			},
+		},
+
+		"Unknown fields on v2 configuration should error.": {
+			config: `
+apiVersion: service-level-operator.spotahome.com/v2
+kind: Configuration
+workerz: 4
+`,
+			expErr: true,
+		},
+
+		"Wrong kind on v2 configuration should error.": {
+			config: `
+apiVersion: service-level-operator.spotahome.com/v2
+kind: Config
+`,
+			expErr: true,
+		},
+
+		"Unknown configuration version should error.": {
+			config: `
+apiVersion: service-level-operator.spotahome.com/v99
+kind: Configuration
+`,
+			expErr: true,
+		},
+
+		"Using namespaces and namespace selector at the same time should error.": {
+			config: `
+apiVersion: service-level-operator.spotahome.com/v2
+kind: Configuration
+namespaces: [ns0]
+namespaceSelector: slo.enabled=true
+`,
+			expErr: true,
+		},
+
+		"Persisting the backfill checkpoints on a file and a configmap at the same time should error.": {
+			config: `
+apiVersion: service-level-operator.spotahome.com/v2
+kind: Configuration
+backfill:
+  maxWindow: 1h
+  checkpointPath: /tmp/checkpoints.json
+  checkpointConfigMap: ns0/slo-checkpoints
+`,
+			expErr: true,
+		},
+
+		"Invalid dashboards window should error.": {
+			config: `
+apiVersion: service-level-operator.spotahome.com/v2
+kind: Configuration
+dashboards:
+  enabled: true
+  window: 4 weeks
+`,
+			expErr: true,
+		},
+
+		"Invalid alertmanager budget thresholds should error.": {
+			config: `
+apiVersion: service-level-operator.spotahome.com/v2
+kind: Configuration
+alertmanager:
+  urls: [http://alertmanager:9093]
+  budgetThresholds: [50, 0]
+`,
+			expErr: true,
+		},
+
+		"Invalid gate min error budget percent should error.": {
+			config: `
+apiVersion: service-level-operator.spotahome.com/v2
+kind: Configuration
+gate:
+  minErrorBudgetPercent: 120
+`,
+			expErr: true,
+		},
+
+		"Invalid configuration values should error.": {
+			config: `
+apiVersion: service-level-operator.spotahome.com/v2
+kind: Configuration
+defaultSLISources:
+  prometheus:
+    address: test
+`,
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			r := strings.NewReader(test.config)
+			gotConfig, err := configuration.YAMLLoader{}.LoadConfiguration(context.TODO(), r)
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expConfig, gotConfig)
+			}
+		})
+	}
+}
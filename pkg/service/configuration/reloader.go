@@ -0,0 +1,208 @@
+package configuration
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/metrics"
+)
+
+const defReloadInterval = 10 * time.Second
+
+// Source knows how to get the raw configuration data from a place.
+type Source interface {
+	// Get returns the raw configuration data.
+	Get(ctx context.Context) ([]byte, error)
+	// String returns the description of the source.
+	String() string
+}
+
+// FileSource gets the configuration data from a file. The file is
+// opened on every get, so the symlink swaps that Kubernetes makes when
+// updating mounted ConfigMaps are handled transparently.
+type FileSource struct {
+	Path string
+}
+
+// Get satisfies Source interface.
+func (f FileSource) Get(_ context.Context) ([]byte, error) {
+	return ioutil.ReadFile(f.Path)
+}
+
+func (f FileSource) String() string { return "file:" + f.Path }
+
+// ConfigMapSource gets the configuration data from a key of a Kubernetes ConfigMap.
+type ConfigMapSource struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Name      string
+	Key       string
+}
+
+// Get satisfies Source interface.
+func (c ConfigMapSource) Get(_ context.Context) ([]byte, error) {
+	cm, err := c.Client.CoreV1().ConfigMaps(c.Namespace).Get(c.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := cm.Data[c.Key]
+	if !ok {
+		return nil, fmt.Errorf("missing %q key on %s/%s configmap", c.Key, c.Namespace, c.Name)
+	}
+
+	return []byte(data), nil
+}
+
+func (c ConfigMapSource) String() string {
+	return fmt.Sprintf("configmap:%s/%s:%s", c.Namespace, c.Name, c.Key)
+}
+
+// DefaultSLISourceReloaderCfg is the configuration of the DefaultSLISourceReloader.
+type DefaultSLISourceReloaderCfg struct {
+	// Interval is the interval the source will be checked for changes.
+	Interval time.Duration
+	// Source is where the configuration will be get from.
+	Source Source
+	// Loader is the loader used to load the configuration data.
+	Loader Loader
+	// OnReload will be called with the new configuration, if it returns an error
+	// the new configuration will be treated as invalid.
+	OnReload func(*DefaultSLISource) error
+}
+
+// Validate will validate the cfg setting safe defaults.
+func (c *DefaultSLISourceReloaderCfg) Validate() error {
+	if c.Source == nil {
+		return fmt.Errorf("source is required")
+	}
+	if c.OnReload == nil {
+		return fmt.Errorf("on reload callback is required")
+	}
+	if c.Interval <= 0 {
+		c.Interval = defReloadInterval
+	}
+	if c.Loader == nil {
+		c.Loader = YAMLLoader{}
+	}
+
+	return nil
+}
+
+// DefaultSLISourceReloader watches the default SLI source configuration and
+// applies it when it changes. A new configuration is only applied if it's valid,
+// if not, the previous one will be kept.
+type DefaultSLISourceReloader struct {
+	cfg        DefaultSLISourceReloaderCfg
+	metricssvc metrics.Service
+	logger     log.Logger
+
+	mu         sync.Mutex
+	lastHash   [sha256.Size]byte
+	failedHash [sha256.Size]byte
+	loaded     bool
+	current    *DefaultSLISource
+}
+
+// NewDefaultSLISourceReloader returns a new DefaultSLISourceReloader.
+func NewDefaultSLISourceReloader(cfg DefaultSLISourceReloaderCfg, metricssvc metrics.Service, logger log.Logger) (*DefaultSLISourceReloader, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &DefaultSLISourceReloader{
+		cfg:        cfg,
+		metricssvc: metricssvc,
+		logger:     logger.With("source", cfg.Source.String()),
+	}, nil
+}
+
+// Current returns the current applied configuration.
+func (d *DefaultSLISourceReloader) Current() *DefaultSLISource {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.current
+}
+
+// Reload will get the configuration from the source and apply it if it has changed.
+// Returns true if a new configuration has been applied.
+func (d *DefaultSLISourceReloader) Reload(ctx context.Context) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	data, err := d.cfg.Source.Get(ctx)
+	if err != nil {
+		return false, fmt.Errorf("could not get configuration: %s", err)
+	}
+
+	// Don't apply again the current or an already failed configuration.
+	hash := sha256.Sum256(data)
+	if (d.loaded && hash == d.lastHash) || hash == d.failedHash {
+		return false, nil
+	}
+
+	err = d.apply(ctx, data)
+	d.metricssvc.IncDefaultSLISourceReload(err == nil)
+	if err != nil {
+		d.failedHash = hash
+		return false, err
+	}
+
+	d.lastHash = hash
+	d.failedHash = [sha256.Size]byte{}
+	d.loaded = true
+
+	return true, nil
+}
+
+func (d *DefaultSLISourceReloader) apply(ctx context.Context, data []byte) error {
+	cfg, err := d.cfg.Loader.LoadDefaultSLISource(ctx, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("could not load configuration: %s", err)
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %s", err)
+	}
+
+	err = d.cfg.OnReload(cfg)
+	if err != nil {
+		return fmt.Errorf("could not apply configuration: %s", err)
+	}
+	d.current = cfg
+
+	return nil
+}
+
+// Run will check the configuration source at regular intervals reloading the configuration
+// when it changes, it will run until the stop channel is closed.
+func (d *DefaultSLISourceReloader) Run(stopC <-chan struct{}) error {
+	t := time.NewTicker(d.cfg.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stopC:
+			return nil
+		case <-t.C:
+			reloaded, err := d.Reload(context.Background())
+			if err != nil {
+				d.logger.Errorf("default SLI source configuration not reloaded, keeping previous one: %s", err)
+				continue
+			}
+			if reloaded {
+				d.logger.Infof("default SLI source configuration reloaded")
+			}
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package configuration_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/configuration"
+	"github.com/spotahome/service-level-operator/pkg/service/metrics"
+)
+
+func TestDefaultSLISourceReloader(t *testing.T) {
+	tests := map[string]struct {
+		configs      []string
+		onReloadErr  error
+		expReloaded  []bool
+		expErr       []bool
+		expAddresses []string
+	}{
+		"Loading a valid configuration should apply it.": {
+			configs:      []string{`{"prometheus": {"address": "http://test:9090"}}`},
+			expReloaded:  []bool{true},
+			expErr:       []bool{false},
+			expAddresses: []string{"http://test:9090"},
+		},
+
+		"Loading the same configuration should not apply it again.": {
+			configs: []string{
+				`{"prometheus": {"address": "http://test:9090"}}`,
+				`{"prometheus": {"address": "http://test:9090"}}`,
+			},
+			expReloaded:  []bool{true, false},
+			expErr:       []bool{false, false},
+			expAddresses: []string{"http://test:9090"},
+		},
+
+		"Changing the configuration should apply the new one.": {
+			configs: []string{
+				`{"prometheus": {"address": "http://test:9090"}}`,
+				`{"prometheus": {"address": "http://test2:9090"}}`,
+			},
+			expReloaded:  []bool{true, true},
+			expErr:       []bool{false, false},
+			expAddresses: []string{"http://test:9090", "http://test2:9090"},
+		},
+
+		"An invalid configuration should not be applied and the previous kept.": {
+			configs: []string{
+				`{"prometheus": {"address": "http://test:9090"}}`,
+				`{"prometheus":`,
+				`{"prometheus": {"address": "test2"}}`,
+			},
+			expReloaded:  []bool{true, false, false},
+			expErr:       []bool{false, true, true},
+			expAddresses: []string{"http://test:9090"},
+		},
+
+		"An already failed configuration should not be applied again.": {
+			configs: []string{
+				`{"prometheus":`,
+				`{"prometheus":`,
+			},
+			expReloaded: []bool{false, false},
+			expErr:      []bool{true, false},
+		},
+
+		"An error applying the configuration should not mark it as loaded.": {
+			configs:     []string{`{"prometheus": {"address": "http://test:9090"}}`},
+			onReloadErr: errors.New("wanted error"),
+			expReloaded: []bool{false},
+			expErr:      []bool{true},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			dir, err := ioutil.TempDir("", "sli-source-reload")
+			require.NoError(err)
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "config.json")
+
+			var gotAddresses []string
+			r, err := configuration.NewDefaultSLISourceReloader(configuration.DefaultSLISourceReloaderCfg{
+				Source: configuration.FileSource{Path: path},
+				OnReload: func(cfg *configuration.DefaultSLISource) error {
+					if test.onReloadErr != nil {
+						return test.onReloadErr
+					}
+					gotAddresses = append(gotAddresses, cfg.Prometheus.Address)
+					return nil
+				},
+			}, metrics.Dummy, log.Dummy)
+			require.NoError(err)
+
+			for i, cfg := range test.configs {
+				require.NoError(ioutil.WriteFile(path, []byte(cfg), 0644))
+
+				reloaded, err := r.Reload(context.TODO())
+				assert.Equal(test.expReloaded[i], reloaded)
+				assert.Equal(test.expErr[i], err != nil)
+			}
+
+			assert.Equal(test.expAddresses, gotAddresses)
+		})
+	}
+}
+
+func TestFileSourceSymlinkSwap(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Simulate how Kubernetes updates the mounted ConfigMaps.
+	dir, err := ioutil.TempDir("", "sli-source-symlink")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	writeVersion := func(version, data string) {
+		vdir := filepath.Join(dir, version)
+		require.NoError(os.Mkdir(vdir, 0755))
+		require.NoError(ioutil.WriteFile(filepath.Join(vdir, "config.json"), []byte(data), 0644))
+		tmpLink := filepath.Join(dir, "..data_tmp")
+		require.NoError(os.Symlink(vdir, tmpLink))
+		require.NoError(os.Rename(tmpLink, filepath.Join(dir, "..data")))
+	}
+	writeVersion("v1", "v1-data")
+	require.NoError(os.Symlink(filepath.Join(dir, "..data", "config.json"), filepath.Join(dir, "config.json")))
+
+	src := configuration.FileSource{Path: filepath.Join(dir, "config.json")}
+	data, err := src.Get(context.TODO())
+	require.NoError(err)
+	assert.Equal("v1-data", string(data))
+
+	writeVersion("v2", "v2-data")
+	data, err = src.Get(context.TODO())
+	require.NoError(err)
+	assert.Equal("v2-data", string(data))
+}
+
+func TestConfigMapSource(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sli-source",
+			Namespace: "ns0",
+		},
+		Data: map[string]string{
+			"config.json": `{"prometheus": {"address": "http://test:9090"}}`,
+		},
+	}
+
+	tests := map[string]struct {
+		src     configuration.ConfigMapSource
+		expData string
+		expErr  bool
+	}{
+		"Getting the data from a configmap key should return the data.": {
+			src:     configuration.ConfigMapSource{Namespace: "ns0", Name: "sli-source", Key: "config.json"},
+			expData: `{"prometheus": {"address": "http://test:9090"}}`,
+		},
+
+		"Getting the data from a missing configmap key should error.": {
+			src:    configuration.ConfigMapSource{Namespace: "ns0", Name: "sli-source", Key: "missing.json"},
+			expErr: true,
+		},
+
+		"Getting the data from a missing configmap should error.": {
+			src:    configuration.ConfigMapSource{Namespace: "ns1", Name: "sli-source", Key: "config.json"},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			test.src.Client = kubernetesfake.NewSimpleClientset(cm)
+			data, err := test.src.Get(context.TODO())
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expData, string(data))
+			}
+		})
+	}
+}
@@ -0,0 +1,466 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/prometheus/common/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// APIVersionV2 is the API version of the v2 configuration. The not versioned
+	// configuration (only with the default SLI sources) is the v1.
+	APIVersionV2 = "service-level-operator.spotahome.com/v2"
+	// ConfigurationKind is the kind of the operator configuration.
+	ConfigurationKind = "Configuration"
+)
+
+// Configuration is the operator configuration. This is the internal representation
+// of the configuration, all the configuration file versions are loaded into
+// this one. Not set (zero) values mean that the operator defaults will be used.
+type Configuration struct {
+	// ResyncPeriod is the SLO calculation interval.
+	ResyncPeriod time.Duration
+	// Workers is the number of concurrent workers handling the service levels.
+	Workers int
+	// Namespaces are the namespaces to filter the service levels.
+	Namespaces []string
+	// NamespaceSelector is the label selector of the namespaces to filter the service levels.
+	NamespaceSelector string
+	// LabelSelector is the label selector to filter the service levels.
+	LabelSelector string
+	// CheckCRDOnly will check the CRD is present instead of managing it.
+	CheckCRDOnly bool
+	// DisableEvents disables the Kubernetes events of the service levels.
+	DisableEvents bool
+	// DefaultSLISource is the default SLI source configuration.
+	DefaultSLISource DefaultSLISource
+	// PrometheusOutput is the Prometheus output configuration.
+	PrometheusOutput PrometheusOutput
+	// SLICircuitBreaker is the SLI source circuit breaker configuration.
+	SLICircuitBreaker SLICircuitBreaker
+	// Server is the HTTP server configuration.
+	Server Server
+	// Clusters is the configuration of the watched clusters.
+	Clusters Clusters
+	// Tracing is the tracing configuration.
+	Tracing Tracing
+	// OperatorMetrics is the configuration of the operator own metrics.
+	OperatorMetrics OperatorMetrics
+	// Health is the configuration of the health checks.
+	Health Health
+	// Backfill is the configuration of the missed evaluations backfill.
+	Backfill Backfill
+	// Dashboards is the configuration of the Grafana dashboards.
+	Dashboards Dashboards
+	// Reports is the configuration of the monthly SLO compliance reports.
+	Reports Reports
+	// Alertmanager is the configuration of the alerts sent to Alertmanager.
+	Alertmanager Alertmanager
+	// Gate is the default policy of the error budget gate.
+	Gate Gate
+}
+
+// Gate is the default error budget policy of the gate API.
+type Gate struct {
+	// MinErrorBudgetPercent is the minimum remaining error budget percent of the SLOs to allow.
+	MinErrorBudgetPercent float64
+	// AllowUnknown allows the SLOs whose error budget is not known yet.
+	AllowUnknown bool
+}
+
+// Alertmanager is the configuration of the SLO alerts sent to Alertmanager.
+type Alertmanager struct {
+	// URLs are the URLs of the Alertmanagers, empty disables the alerts.
+	URLs []string
+	// BudgetThresholds are the consumed error budget percents that fire an alert.
+	BudgetThresholds []float64
+	// DisableBurnRate disables the burn rate alerts.
+	DisableBurnRate bool
+	// BurnRate is the error budget burn rate that fires an alert.
+	BurnRate float64
+	// BurnRateWindow is the window of the burn rate.
+	BurnRateWindow time.Duration
+}
+
+// Reports is the configuration of the monthly SLO compliance reports.
+type Reports struct {
+	// Dir is the directory where the reports are written, empty disables them.
+	Dir string
+	// Formats are the formats of the reports.
+	Formats []string
+	// PrometheusAddress is the address of the Prometheus with the operator metrics.
+	PrometheusAddress string
+}
+
+// Dashboards is the configuration of the Grafana dashboards published as configmaps.
+type Dashboards struct {
+	// Enabled enables publishing the dashboards.
+	Enabled bool
+	// Namespace is the namespace of the dashboard configmaps, by default the
+	// namespace of the service levels.
+	Namespace string
+	// Labels are the labels of the dashboard configmaps.
+	Labels map[string]string
+	// Window is the time window (Prometheus duration) of the dashboards.
+	Window string
+}
+
+// Backfill is the configuration of the missed evaluations backfill.
+type Backfill struct {
+	// MaxWindow is the maximum time that will be backfilled, 0 disables the backfill.
+	MaxWindow time.Duration
+	// CheckpointPath is the file where the last evaluation times are persisted.
+	CheckpointPath string
+	// CheckpointConfigMap is the ConfigMap (in namespace/name format) where the
+	// last evaluation times are persisted.
+	CheckpointConfigMap string
+}
+
+// Health is the configuration of the health checks.
+type Health struct {
+	// LivenessResyncPeriods is the number of resync periods without handling any
+	// service level that will make the operator not alive.
+	LivenessResyncPeriods int
+}
+
+// OperatorMetrics is the configuration of the operator own metrics.
+type OperatorMetrics struct {
+	// SLOMetrics enables the per SLO metrics.
+	SLOMetrics bool
+	// MaxSLOs is the maximum number of SLOs with per SLO metrics.
+	MaxSLOs int
+}
+
+// Tracing is the tracing configuration.
+type Tracing struct {
+	// OTLPEndpoint is the OTLP HTTP endpoint where the traces will be exported.
+	OTLPEndpoint string
+	// Insecure exports the traces without TLS.
+	Insecure bool
+}
+
+// Clusters is the configuration of the watched clusters.
+type Clusters struct {
+	// Name is the name of the cluster where the operator runs.
+	Name string
+	// KubeContexts are the kubeconfig contexts of the additional clusters.
+	KubeContexts []string
+	// KubeconfigSecrets are the secrets (in namespace/name format) with the
+	// kubeconfigs of the additional clusters.
+	KubeconfigSecrets []string
+}
+
+// PrometheusOutput is the Prometheus output configuration.
+type PrometheusOutput struct {
+	// ExpireDuration is the time a SLO metric will expire if is not refreshed.
+	ExpireDuration time.Duration
+}
+
+// SLICircuitBreaker is the SLI source circuit breaker configuration.
+type SLICircuitBreaker struct {
+	// Disable disables the circuit breaker.
+	Disable bool
+	// FailureThreshold is the number of consecutive failures that will open the circuit.
+	FailureThreshold int
+	// OpenDuration is the time the circuit will be open.
+	OpenDuration time.Duration
+}
+
+// Server is the HTTP server configuration.
+type Server struct {
+	// ListenAddress is the address where the HTTP server will listen.
+	ListenAddress string
+	// MetricsPath is the path where the metrics will be served.
+	MetricsPath string
+}
+
+// typeMeta is used to know the version of a configuration.
+type typeMeta struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+}
+
+// configurationV2 is the v2 configuration file format.
+type configurationV2 struct {
+	typeMeta          `json:",inline"`
+	Resync            metav1.Duration     `json:"resync,omitempty"`
+	Workers           int                 `json:"workers,omitempty"`
+	Namespaces        []string            `json:"namespaces,omitempty"`
+	NamespaceSelector string              `json:"namespaceSelector,omitempty"`
+	LabelSelector     string              `json:"labelSelector,omitempty"`
+	CheckCRDOnly      bool                `json:"checkCRDOnly,omitempty"`
+	DisableEvents     bool                `json:"disableEvents,omitempty"`
+	DefaultSLISources DefaultSLISource    `json:"defaultSLISources,omitempty"`
+	Output            outputV2            `json:"output,omitempty"`
+	SLICircuitBreaker sliCircuitBreakerV2 `json:"sliCircuitBreaker,omitempty"`
+	Server            serverV2            `json:"server,omitempty"`
+	Clusters          clustersV2          `json:"clusters,omitempty"`
+	Tracing           tracingV2           `json:"tracing,omitempty"`
+	OperatorMetrics   operatorMetricsV2   `json:"operatorMetrics,omitempty"`
+	Health            healthV2            `json:"health,omitempty"`
+	Backfill          backfillV2          `json:"backfill,omitempty"`
+	Dashboards        dashboardsV2        `json:"dashboards,omitempty"`
+	Reports           reportsV2           `json:"reports,omitempty"`
+	Alertmanager      alertmanagerV2      `json:"alertmanager,omitempty"`
+	Gate              gateV2              `json:"gate,omitempty"`
+}
+
+type outputV2 struct {
+	Prometheus prometheusOutputV2 `json:"prometheus,omitempty"`
+}
+
+type prometheusOutputV2 struct {
+	ExpireDuration metav1.Duration `json:"expireDuration,omitempty"`
+}
+
+type sliCircuitBreakerV2 struct {
+	Disable          bool            `json:"disable,omitempty"`
+	FailureThreshold int             `json:"failureThreshold,omitempty"`
+	OpenDuration     metav1.Duration `json:"openDuration,omitempty"`
+}
+
+type clustersV2 struct {
+	Name              string   `json:"name,omitempty"`
+	KubeContexts      []string `json:"kubeContexts,omitempty"`
+	KubeconfigSecrets []string `json:"kubeconfigSecrets,omitempty"`
+}
+
+type tracingV2 struct {
+	OTLPEndpoint string `json:"otlpEndpoint,omitempty"`
+	Insecure     bool   `json:"insecure,omitempty"`
+}
+
+type operatorMetricsV2 struct {
+	SLOMetrics bool `json:"sloMetrics,omitempty"`
+	MaxSLOs    int  `json:"maxSLOs,omitempty"`
+}
+
+type healthV2 struct {
+	LivenessResyncPeriods int `json:"livenessResyncPeriods,omitempty"`
+}
+
+type backfillV2 struct {
+	MaxWindow           metav1.Duration `json:"maxWindow,omitempty"`
+	CheckpointPath      string          `json:"checkpointPath,omitempty"`
+	CheckpointConfigMap string          `json:"checkpointConfigMap,omitempty"`
+}
+
+type dashboardsV2 struct {
+	Enabled   bool              `json:"enabled,omitempty"`
+	Namespace string            `json:"namespace,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Window    string            `json:"window,omitempty"`
+}
+
+type reportsV2 struct {
+	Dir               string   `json:"dir,omitempty"`
+	Formats           []string `json:"formats,omitempty"`
+	PrometheusAddress string   `json:"prometheusAddress,omitempty"`
+}
+
+type alertmanagerV2 struct {
+	URLs             []string        `json:"urls,omitempty"`
+	BudgetThresholds []float64       `json:"budgetThresholds,omitempty"`
+	DisableBurnRate  bool            `json:"disableBurnRate,omitempty"`
+	BurnRate         float64         `json:"burnRate,omitempty"`
+	BurnRateWindow   metav1.Duration `json:"burnRateWindow,omitempty"`
+}
+
+type gateV2 struct {
+	MinErrorBudgetPercent float64 `json:"minErrorBudgetPercent,omitempty"`
+	AllowUnknown          bool    `json:"allowUnknown,omitempty"`
+}
+
+type serverV2 struct {
+	ListenAddress string `json:"listenAddress,omitempty"`
+	MetricsPath   string `json:"metricsPath,omitempty"`
+}
+
+func (c configurationV2) toConfiguration() *Configuration {
+	return &Configuration{
+		ResyncPeriod:      c.Resync.Duration,
+		Workers:           c.Workers,
+		Namespaces:        c.Namespaces,
+		NamespaceSelector: c.NamespaceSelector,
+		LabelSelector:     c.LabelSelector,
+		CheckCRDOnly:      c.CheckCRDOnly,
+		DisableEvents:     c.DisableEvents,
+		DefaultSLISource:  c.DefaultSLISources,
+		PrometheusOutput: PrometheusOutput{
+			ExpireDuration: c.Output.Prometheus.ExpireDuration.Duration,
+		},
+		SLICircuitBreaker: SLICircuitBreaker{
+			Disable:          c.SLICircuitBreaker.Disable,
+			FailureThreshold: c.SLICircuitBreaker.FailureThreshold,
+			OpenDuration:     c.SLICircuitBreaker.OpenDuration.Duration,
+		},
+		Server: Server{
+			ListenAddress: c.Server.ListenAddress,
+			MetricsPath:   c.Server.MetricsPath,
+		},
+		Clusters: Clusters{
+			Name:              c.Clusters.Name,
+			KubeContexts:      c.Clusters.KubeContexts,
+			KubeconfigSecrets: c.Clusters.KubeconfigSecrets,
+		},
+		Tracing: Tracing{
+			OTLPEndpoint: c.Tracing.OTLPEndpoint,
+			Insecure:     c.Tracing.Insecure,
+		},
+		OperatorMetrics: OperatorMetrics{
+			SLOMetrics: c.OperatorMetrics.SLOMetrics,
+			MaxSLOs:    c.OperatorMetrics.MaxSLOs,
+		},
+		Health: Health{
+			LivenessResyncPeriods: c.Health.LivenessResyncPeriods,
+		},
+		Backfill: Backfill{
+			MaxWindow:           c.Backfill.MaxWindow.Duration,
+			CheckpointPath:      c.Backfill.CheckpointPath,
+			CheckpointConfigMap: c.Backfill.CheckpointConfigMap,
+		},
+		Dashboards: Dashboards{
+			Enabled:   c.Dashboards.Enabled,
+			Namespace: c.Dashboards.Namespace,
+			Labels:    c.Dashboards.Labels,
+			Window:    c.Dashboards.Window,
+		},
+		Reports: Reports{
+			Dir:               c.Reports.Dir,
+			Formats:           c.Reports.Formats,
+			PrometheusAddress: c.Reports.PrometheusAddress,
+		},
+		Alertmanager: Alertmanager{
+			URLs:             c.Alertmanager.URLs,
+			BudgetThresholds: c.Alertmanager.BudgetThresholds,
+			DisableBurnRate:  c.Alertmanager.DisableBurnRate,
+			BurnRate:         c.Alertmanager.BurnRate,
+			BurnRateWindow:   c.Alertmanager.BurnRateWindow.Duration,
+		},
+		Gate: Gate{
+			MinErrorBudgetPercent: c.Gate.MinErrorBudgetPercent,
+			AllowUnknown:          c.Gate.AllowUnknown,
+		},
+	}
+}
+
+// Validate validates the configuration.
+func (c *Configuration) Validate() error {
+	if c.ResyncPeriod < 0 {
+		return fmt.Errorf("resync can't be negative")
+	}
+	if len(c.Namespaces) > 0 && c.NamespaceSelector != "" {
+		return fmt.Errorf("namespaces and namespace selector can't be used at the same time")
+	}
+	if c.Workers < 0 {
+		return fmt.Errorf("workers can't be negative")
+	}
+	if c.PrometheusOutput.ExpireDuration < 0 {
+		return fmt.Errorf("prometheus output expire duration can't be negative")
+	}
+	if c.SLICircuitBreaker.FailureThreshold < 0 {
+		return fmt.Errorf("sli circuit breaker failure threshold can't be negative")
+	}
+	if c.OperatorMetrics.MaxSLOs < 0 {
+		return fmt.Errorf("operator metrics max SLOs can't be negative")
+	}
+	if c.Health.LivenessResyncPeriods < 0 {
+		return fmt.Errorf("health liveness resync periods can't be negative")
+	}
+	if c.SLICircuitBreaker.OpenDuration < 0 {
+		return fmt.Errorf("sli circuit breaker open duration can't be negative")
+	}
+	if c.Backfill.MaxWindow < 0 {
+		return fmt.Errorf("backfill max window can't be negative")
+	}
+	if c.Backfill.CheckpointPath != "" && c.Backfill.CheckpointConfigMap != "" {
+		return fmt.Errorf("backfill checkpoints can't be persisted on a file and a configmap at the same time")
+	}
+	if c.Dashboards.Window != "" {
+		if _, err := model.ParseDuration(c.Dashboards.Window); err != nil {
+			return fmt.Errorf("invalid dashboards window: %s", err)
+		}
+	}
+	for _, t := range c.Alertmanager.BudgetThresholds {
+		if t <= 0 {
+			return fmt.Errorf("alertmanager budget thresholds must be positive")
+		}
+	}
+	if c.Alertmanager.BurnRate < 0 {
+		return fmt.Errorf("alertmanager burn rate can't be negative")
+	}
+	if c.Alertmanager.BurnRateWindow < 0 {
+		return fmt.Errorf("alertmanager burn rate window can't be negative")
+	}
+	if c.Gate.MinErrorBudgetPercent < 0 || c.Gate.MinErrorBudgetPercent > 100 {
+		return fmt.Errorf("gate min error budget percent must be between 0 and 100")
+	}
+
+	return c.DefaultSLISource.Validate()
+}
+
+// YAMLLoader knows how to load versioned application configuration in YAML
+// (or JSON) format. The not versioned configuration will be loaded as the
+// v1 version.
+type YAMLLoader struct{}
+
+// LoadConfiguration satisfies Loader interface.
+func (y YAMLLoader) LoadConfiguration(_ context.Context, r io.Reader) (*Configuration, error) {
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	tm := typeMeta{}
+	err = yaml.Unmarshal(bs, &tm)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg *Configuration
+	switch tm.APIVersion {
+	// Not versioned configuration is v1.
+	case "":
+		dss := DefaultSLISource{}
+		err := yaml.UnmarshalStrict(bs, &dss)
+		if err != nil {
+			return nil, err
+		}
+		cfg = &Configuration{DefaultSLISource: dss}
+	case APIVersionV2:
+		if tm.Kind != ConfigurationKind {
+			return nil, fmt.Errorf("unsupported %q kind, should be %q", tm.Kind, ConfigurationKind)
+		}
+		cv2 := configurationV2{}
+		err := yaml.UnmarshalStrict(bs, &cv2)
+		if err != nil {
+			return nil, err
+		}
+		cfg = cv2.toConfiguration()
+	default:
+		return nil, fmt.Errorf("unsupported %q configuration version", tm.APIVersion)
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %s", err)
+	}
+
+	return cfg, nil
+}
+
+// LoadDefaultSLISource satisfies Loader interface.
+func (y YAMLLoader) LoadDefaultSLISource(ctx context.Context, r io.Reader) (*DefaultSLISource, error) {
+	cfg, err := y.LoadConfiguration(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cfg.DefaultSLISource, nil
+}
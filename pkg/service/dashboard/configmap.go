@@ -0,0 +1,247 @@
+package dashboard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/kubernetes"
+)
+
+const (
+	configMapPrefix = "slo-dashboard-"
+	maxNameLength   = 253
+	// managedByLabel identifies the configmaps managed by the operator, the rest
+	// are never updated or deleted.
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedBy      = "service-level-operator"
+	// AnnotationServiceLevel is the service level (namespace/name) of a dashboard configmap.
+	AnnotationServiceLevel = "service-level-operator.spotahome.com/service-level"
+)
+
+// DefaultConfigMapLabels are the labels of the dashboard configmaps discovered
+// by the Grafana sidecar with its default configuration.
+var DefaultConfigMapLabels = map[string]string{"grafana_dashboard": "1"}
+
+// ConfigMapCfg is the configuration of the dashboard configmaps.
+type ConfigMapCfg struct {
+	// Dashboard is the configuration of the dashboards.
+	Dashboard Cfg
+	// Namespace is the namespace of the configmaps, by default the namespace
+	// of the service level.
+	Namespace string
+	// Labels are the labels of the configmaps, by default the Grafana sidecar one.
+	Labels map[string]string
+}
+
+func (c *ConfigMapCfg) defaults() error {
+	if len(c.Labels) == 0 {
+		c.Labels = DefaultConfigMapLabels
+	}
+	return c.Dashboard.defaults()
+}
+
+// ConfigMapName returns the name of the dashboard configmap of a service level.
+func ConfigMapName(namespace, name string) string {
+	n := configMapPrefix + namespace + "-" + name
+	if len(n) > maxNameLength {
+		n = n[:maxNameLength]
+	}
+	return n
+}
+
+// ConfigMap returns the configmap with the dashboard of a service level of a
+// cluster. The configmaps on the service level namespace are owned by it, so
+// they are garbage collected with the service level.
+func ConfigMap(cluster string, sl *monitoringv1alpha1.ServiceLevel, cfg ConfigMapCfg) (*corev1.ConfigMap, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, err
+	}
+
+	js, err := JSON(cluster, sl, cfg.Dashboard)
+	if err != nil {
+		return nil, err
+	}
+
+	ns := cfg.Namespace
+	if ns == "" {
+		ns = sl.Namespace
+	}
+	labels := map[string]string{managedByLabel: managedBy}
+	for k, v := range cfg.Labels {
+		labels[k] = v
+	}
+
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        ConfigMapName(sl.Namespace, sl.Name),
+			Namespace:   ns,
+			Labels:      labels,
+			Annotations: map[string]string{AnnotationServiceLevel: sl.Namespace + "/" + sl.Name},
+		},
+		Data: map[string]string{
+			fmt.Sprintf("%s-%s.json", sl.Namespace, sl.Name): string(js),
+		},
+	}
+	if ns == sl.Namespace && sl.UID != "" {
+		controller := true
+		cm.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: monitoringv1alpha1.SchemeGroupVersion.String(),
+			Kind:       monitoringv1alpha1.ServiceLevelKind,
+			Name:       sl.Name,
+			UID:        sl.UID,
+			Controller: &controller,
+		}}
+	}
+	return cm, nil
+}
+
+// Publisher knows how to publish the dashboards of the service levels.
+type Publisher interface {
+	// Publish publishes the dashboard of the service level of a cluster, if it's
+	// already published it's updated only when it changed.
+	Publish(ctx context.Context, cluster string, sl *monitoringv1alpha1.ServiceLevel) error
+	// Delete deletes the published dashboard of the service level.
+	Delete(ctx context.Context, namespace, name string) error
+}
+
+// Dummy is a publisher that doesn't publish the dashboards.
+var Dummy Publisher = dummy(0)
+
+type dummy int
+
+func (dummy) Publish(_ context.Context, _ string, _ *monitoringv1alpha1.ServiceLevel) error {
+	return nil
+}
+func (dummy) Delete(_ context.Context, _, _ string) error { return nil }
+
+// ConfigMapPublisher publishes the dashboards as configmaps, to be loaded by the
+// Grafana sidecar. The service levels are published on every resync, so the
+// published dashboards are cached and only the changed ones are updated.
+type ConfigMapPublisher struct {
+	cfg    ConfigMapCfg
+	svc    kubernetes.ConfigMap
+	logger log.Logger
+
+	mu sync.Mutex
+	// published are the hashes of the published configmaps by service level.
+	published map[string]string
+}
+
+// NewConfigMapPublisher returns a new configmap publisher.
+func NewConfigMapPublisher(cfg ConfigMapCfg, svc kubernetes.ConfigMap, logger log.Logger) (*ConfigMapPublisher, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, err
+	}
+	return &ConfigMapPublisher{
+		cfg:       cfg,
+		svc:       svc,
+		logger:    logger,
+		published: map[string]string{},
+	}, nil
+}
+
+// Publish satisfies Publisher interface.
+func (c *ConfigMapPublisher) Publish(_ context.Context, cluster string, sl *monitoringv1alpha1.ServiceLevel) error {
+	cm, err := ConfigMap(cluster, sl, c.cfg)
+	if err != nil {
+		return err
+	}
+	key := sl.Namespace + "/" + sl.Name
+	hash := configMapHash(cm)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.published[key] == hash {
+		return nil
+	}
+
+	current, err := c.svc.GetConfigMap(cm.Namespace, cm.Name)
+	switch {
+	case errors.IsNotFound(err):
+		if _, err := c.svc.CreateConfigMap(cm.Namespace, cm); err != nil {
+			return fmt.Errorf("could not create dashboard configmap: %s", err)
+		}
+		c.logger.Infof("dashboard configmap %s/%s created", cm.Namespace, cm.Name)
+	case err != nil:
+		return err
+	case current.Labels[managedByLabel] != managedBy:
+		return fmt.Errorf("the %s/%s configmap already exists and is not managed by the operator", cm.Namespace, cm.Name)
+	case configMapHash(current) != hash:
+		current = current.DeepCopy()
+		current.Labels = cm.Labels
+		current.Annotations = cm.Annotations
+		current.OwnerReferences = cm.OwnerReferences
+		current.Data = cm.Data
+		if _, err := c.svc.UpdateConfigMap(cm.Namespace, current); err != nil {
+			return fmt.Errorf("could not update dashboard configmap: %s", err)
+		}
+		c.logger.Infof("dashboard configmap %s/%s updated", cm.Namespace, cm.Name)
+	}
+
+	c.published[key] = hash
+	return nil
+}
+
+// Delete satisfies Publisher interface.
+func (c *ConfigMapPublisher) Delete(_ context.Context, namespace, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.published, namespace+"/"+name)
+
+	ns := c.cfg.Namespace
+	if ns == "" {
+		ns = namespace
+	}
+	cmName := ConfigMapName(namespace, name)
+	current, err := c.svc.GetConfigMap(ns, cmName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if current.Labels[managedByLabel] != managedBy {
+		return nil
+	}
+
+	err = c.svc.DeleteConfigMap(ns, cmName)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("could not delete dashboard configmap: %s", err)
+	}
+	c.logger.Infof("dashboard configmap %s/%s deleted", ns, cmName)
+	return nil
+}
+
+// configMapHash returns the hash of the managed fields of a configmap.
+func configMapHash(cm *corev1.ConfigMap) string {
+	h := sha256.New()
+	write := func(m map[string]string) {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(h, "%q=%q;", k, m[k])
+		}
+		fmt.Fprint(h, "|")
+	}
+	write(cm.Labels)
+	write(cm.Annotations)
+	write(cm.Data)
+	for _, ref := range cm.OwnerReferences {
+		fmt.Fprintf(h, "%s/%s/%s/%s;", ref.APIVersion, ref.Kind, ref.Name, ref.UID)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
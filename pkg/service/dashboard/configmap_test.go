@@ -0,0 +1,113 @@
+package dashboard_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/dashboard"
+	"github.com/spotahome/service-level-operator/pkg/service/kubernetes"
+)
+
+func TestConfigMap(t *testing.T) {
+	tests := map[string]struct {
+		cfg          dashboard.ConfigMapCfg
+		expNamespace string
+		expLabels    map[string]string
+		expOwned     bool
+	}{
+		"By default the configmap should be on the service level namespace and owned by it.": {
+			expNamespace: "shop",
+			expLabels:    map[string]string{"grafana_dashboard": "1", "app.kubernetes.io/managed-by": "service-level-operator"},
+			expOwned:     true,
+		},
+		"The configmap namespace and labels should be configurable.": {
+			cfg:          dashboard.ConfigMapCfg{Namespace: "monitoring", Labels: map[string]string{"dashboards": "slo"}},
+			expNamespace: "monitoring",
+			expLabels:    map[string]string{"dashboards": "slo", "app.kubernetes.io/managed-by": "service-level-operator"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			sl := newServiceLevel()
+			cm, err := dashboard.ConfigMap("", sl, test.cfg)
+			require.NoError(err)
+
+			js, err := dashboard.JSON("", sl, test.cfg.Dashboard)
+			require.NoError(err)
+			assert.Equal("slo-dashboard-shop-checkout", cm.Name)
+			assert.Equal(test.expNamespace, cm.Namespace)
+			assert.Equal(test.expLabels, cm.Labels)
+			assert.Equal(map[string]string{"shop-checkout.json": string(js)}, cm.Data)
+			assert.Equal(test.expOwned, len(cm.OwnerReferences) == 1)
+		})
+	}
+}
+
+func TestConfigMapPublisher(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cli := fake.NewSimpleClientset()
+	p, err := dashboard.NewConfigMapPublisher(dashboard.ConfigMapCfg{}, kubernetes.NewConfigMap(cli, log.Dummy), log.Dummy)
+	require.NoError(err)
+	ctx := context.Background()
+	get := func() *corev1.ConfigMap {
+		cm, err := cli.CoreV1().ConfigMaps("shop").Get("slo-dashboard-shop-checkout", metav1.GetOptions{})
+		require.NoError(err)
+		return cm
+	}
+
+	// The dashboard should be created.
+	sl := newServiceLevel()
+	require.NoError(p.Publish(ctx, "", sl))
+	assert.Contains(get().Data["shop-checkout.json"], `"title": "SLO availability"`)
+
+	// Unchanged dashboards should not call the API again.
+	cli.ClearActions()
+	require.NoError(p.Publish(ctx, "", sl))
+	assert.Empty(cli.Actions())
+
+	// Changed dashboards should be updated.
+	sl.Spec.ServiceLevelObjectives[0].Name = "errors"
+	require.NoError(p.Publish(ctx, "", sl))
+	assert.Contains(get().Data["shop-checkout.json"], `"title": "SLO errors"`)
+
+	// The dashboards should be deleted and missing dashboards ignored.
+	require.NoError(p.Delete(ctx, "shop", "checkout"))
+	_, err = cli.CoreV1().ConfigMaps("shop").Get("slo-dashboard-shop-checkout", metav1.GetOptions{})
+	assert.Error(err)
+	assert.NoError(p.Delete(ctx, "shop", "checkout"))
+}
+
+func TestConfigMapPublisherUnmanaged(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// A configmap with the same name not created by the operator.
+	unmanaged := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "slo-dashboard-shop-checkout", Namespace: "shop"},
+		Data:       map[string]string{"custom.json": "{}"},
+	}
+	cli := fake.NewSimpleClientset(unmanaged)
+	p, err := dashboard.NewConfigMapPublisher(dashboard.ConfigMapCfg{}, kubernetes.NewConfigMap(cli, log.Dummy), log.Dummy)
+	require.NoError(err)
+	ctx := context.Background()
+
+	// The unmanaged configmaps should not be updated nor deleted.
+	assert.Error(p.Publish(ctx, "", newServiceLevel()))
+	assert.NoError(p.Delete(ctx, "shop", "checkout"))
+	cm, err := cli.CoreV1().ConfigMaps("shop").Get("slo-dashboard-shop-checkout", metav1.GetOptions{})
+	require.NoError(err)
+	assert.Equal(unmanaged.Data, cm.Data)
+}
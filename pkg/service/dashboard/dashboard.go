@@ -0,0 +1,322 @@
+package dashboard
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/common/model"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+const (
+	defWindow     = "30d"
+	schemaVersion = 39
+	datasourceVar = "datasource"
+)
+
+// burnRateWindows are the windows of the burn rate panels, the multiwindow
+// alerts use the same ones.
+var burnRateWindows = []string{"5m", "1h", "6h", "1d"}
+
+// Cfg is the configuration of the dashboards.
+type Cfg struct {
+	// Window is the time window of the availability and the error budget.
+	Window string
+}
+
+func (c *Cfg) defaults() error {
+	if c.Window == "" {
+		c.Window = defWindow
+	}
+	if _, err := model.ParseDuration(c.Window); err != nil {
+		return fmt.Errorf("invalid window: %s", err)
+	}
+	return nil
+}
+
+// Dashboard is a Grafana dashboard.
+type Dashboard struct {
+	UID           string     `json:"uid"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description,omitempty"`
+	Tags          []string   `json:"tags"`
+	Editable      bool       `json:"editable"`
+	SchemaVersion int        `json:"schemaVersion"`
+	Refresh       string     `json:"refresh"`
+	Time          TimeRange  `json:"time"`
+	Templating    Templating `json:"templating"`
+	Panels        []Panel    `json:"panels"`
+}
+
+// TimeRange is the time range of a dashboard.
+type TimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Templating are the variables of a dashboard.
+type Templating struct {
+	List []Variable `json:"list"`
+}
+
+// Variable is a dashboard variable.
+type Variable struct {
+	Name  string `json:"name"`
+	Label string `json:"label,omitempty"`
+	Type  string `json:"type"`
+	Query string `json:"query"`
+}
+
+// Panel is a dashboard panel.
+type Panel struct {
+	ID          int          `json:"id"`
+	Type        string       `json:"type"`
+	Title       string       `json:"title"`
+	Description string       `json:"description,omitempty"`
+	GridPos     GridPos      `json:"gridPos"`
+	Datasource  *Datasource  `json:"datasource,omitempty"`
+	Targets     []Target     `json:"targets,omitempty"`
+	FieldConfig *FieldConfig `json:"fieldConfig,omitempty"`
+	Collapsed   bool         `json:"collapsed,omitempty"`
+}
+
+// GridPos is the position of a panel.
+type GridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Datasource is the datasource of a panel.
+type Datasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+// Target is a query of a panel.
+type Target struct {
+	RefID        string `json:"refId"`
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat,omitempty"`
+	Instant      bool   `json:"instant,omitempty"`
+}
+
+// FieldConfig is the field configuration of a panel.
+type FieldConfig struct {
+	Defaults FieldDefaults `json:"defaults"`
+}
+
+// FieldDefaults are the default field options of a panel.
+type FieldDefaults struct {
+	Unit       string      `json:"unit,omitempty"`
+	Decimals   *int        `json:"decimals,omitempty"`
+	Min        *float64    `json:"min,omitempty"`
+	Max        *float64    `json:"max,omitempty"`
+	Thresholds *Thresholds `json:"thresholds,omitempty"`
+}
+
+// Thresholds are the thresholds of a panel.
+type Thresholds struct {
+	Mode  string      `json:"mode"`
+	Steps []Threshold `json:"steps"`
+}
+
+// Threshold is a threshold step, the first one doesn't have value.
+type Threshold struct {
+	Color string   `json:"color"`
+	Value *float64 `json:"value"`
+}
+
+// UID returns the dashboard UID of a service level of a cluster, it's stable
+// so the dashboard is updated instead of created again.
+func UID(cluster string, sl *monitoringv1alpha1.ServiceLevel) string {
+	h := sha1.Sum([]byte(fmt.Sprintf("%s/%s/%s", cluster, sl.Namespace, sl.Name)))
+	return "slo-" + hex.EncodeToString(h[:])[:16]
+}
+
+// New returns the dashboard of a service level of a cluster (empty on the
+// default cluster). Every SLO has a row with its availability, remaining error
+// budget and burn rates on the window, based on the operator metrics, and the
+// raw results of the SLI queries.
+func New(cluster string, sl *monitoringv1alpha1.ServiceLevel, cfg Cfg) (*Dashboard, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, err
+	}
+
+	title := fmt.Sprintf("SLO / %s / %s", sl.Namespace, sl.Name)
+	if cluster != "" {
+		title = fmt.Sprintf("SLO / %s / %s / %s", cluster, sl.Namespace, sl.Name)
+	}
+
+	b := &builder{cfg: cfg, cluster: cluster}
+	for _, slo := range sl.Spec.ServiceLevelObjectives {
+		b.addSLO(sl, slo)
+	}
+
+	return &Dashboard{
+		UID:           UID(cluster, sl),
+		Title:         title,
+		Description:   fmt.Sprintf("Service level generated by service-level-operator from the %s/%s ServiceLevel.", sl.Namespace, sl.Name),
+		Tags:          []string{"service-level-operator", "slo"},
+		Editable:      false,
+		SchemaVersion: schemaVersion,
+		Refresh:       "1m",
+		Time:          TimeRange{From: "now-" + cfg.Window, To: "now"},
+		Templating: Templating{List: []Variable{
+			{Name: datasourceVar, Label: "Datasource", Type: "datasource", Query: "prometheus"},
+		}},
+		Panels: b.panels,
+	}, nil
+}
+
+// JSON returns the dashboard JSON of a service level of a cluster.
+func JSON(cluster string, sl *monitoringv1alpha1.ServiceLevel, cfg Cfg) ([]byte, error) {
+	d, err := New(cluster, sl, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(d, "", "  ")
+}
+
+type builder struct {
+	cfg     Cfg
+	cluster string
+	panels  []Panel
+	y       int
+}
+
+func (b *builder) add(p Panel) {
+	p.ID = len(b.panels) + 1
+	if p.Type != "row" {
+		p.Datasource = &Datasource{Type: "prometheus", UID: "${" + datasourceVar + "}"}
+	}
+	b.panels = append(b.panels, p)
+}
+
+func (b *builder) addSLO(sl *monitoringv1alpha1.ServiceLevel, slo monitoringv1alpha1.SLO) {
+	sel := selector(b.cluster, sl, slo)
+	w := b.cfg.Window
+	errorRatio := func(window string) string {
+		return fmt.Sprintf("sum(increase(service_level_sli_result_error_ratio_total{%s}[%s]))\n/\nsum(increase(service_level_sli_result_count_total{%s}[%s]))", sel, window, sel, window)
+	}
+	objective := fmt.Sprintf("max(service_level_slo_objective_ratio{%s})", sel)
+
+	title := "SLO " + slo.Name
+	if slo.Disable {
+		title += " (disabled)"
+	}
+	b.add(Panel{Type: "row", Title: title, Description: slo.Description, GridPos: GridPos{H: 1, W: 24, X: 0, Y: b.y}})
+	b.y++
+
+	b.add(Panel{
+		Type:        "stat",
+		Title:       fmt.Sprintf("Availability (%s)", w),
+		Description: slo.Description,
+		GridPos:     GridPos{H: 6, W: 6, X: 0, Y: b.y},
+		Targets:     []Target{{RefID: "A", Expr: fmt.Sprintf("1 - (\n%s\n)", errorRatio(w)), Instant: true}},
+		FieldConfig: &FieldConfig{Defaults: FieldDefaults{
+			Unit:     "percentunit",
+			Decimals: intPtr(3),
+			Thresholds: &Thresholds{Mode: "absolute", Steps: []Threshold{
+				{Color: "red"},
+				{Color: "green", Value: floatPtr(slo.AvailabilityObjectivePercent / 100)},
+			}},
+		}},
+	})
+	b.add(Panel{
+		Type:    "stat",
+		Title:   "Objective",
+		GridPos: GridPos{H: 6, W: 4, X: 6, Y: b.y},
+		Targets: []Target{{RefID: "A", Expr: objective, Instant: true}},
+		FieldConfig: &FieldConfig{Defaults: FieldDefaults{
+			Unit:     "percentunit",
+			Decimals: intPtr(3),
+		}},
+	})
+	b.add(Panel{
+		Type:        "gauge",
+		Title:       fmt.Sprintf("Remaining error budget (%s)", w),
+		Description: "The ratio of the error budget of the window that has not been consumed.",
+		GridPos:     GridPos{H: 6, W: 6, X: 10, Y: b.y},
+		Targets:     []Target{{RefID: "A", Expr: fmt.Sprintf("1 - (\n(%s)\n/\n(1 - %s)\n)", errorRatio(w), objective), Instant: true}},
+		FieldConfig: &FieldConfig{Defaults: FieldDefaults{
+			Unit:     "percentunit",
+			Decimals: intPtr(2),
+			Min:      floatPtr(0),
+			Max:      floatPtr(1),
+			Thresholds: &Thresholds{Mode: "absolute", Steps: []Threshold{
+				{Color: "red"},
+				{Color: "orange", Value: floatPtr(0.25)},
+				{Color: "green", Value: floatPtr(0.5)},
+			}},
+		}},
+	})
+
+	burnRates := make([]Target, 0, len(burnRateWindows))
+	for i, bw := range burnRateWindows {
+		burnRates = append(burnRates, Target{
+			RefID:        string(rune('A' + i)),
+			Expr:         fmt.Sprintf("(\n%s\n)\n/\n(1 - %s)", errorRatio(bw), objective),
+			LegendFormat: bw,
+		})
+	}
+	b.add(Panel{
+		Type:        "timeseries",
+		Title:       "Burn rate",
+		Description: fmt.Sprintf("The speed the error budget is consumed, 1 consumes all the error budget in %s.", w),
+		GridPos:     GridPos{H: 6, W: 8, X: 16, Y: b.y},
+		Targets:     burnRates,
+		FieldConfig: &FieldConfig{Defaults: FieldDefaults{
+			Decimals: intPtr(2),
+			Min:      floatPtr(0),
+			Thresholds: &Thresholds{Mode: "absolute", Steps: []Threshold{
+				{Color: "green"},
+				{Color: "orange", Value: floatPtr(1)},
+				{Color: "red", Value: floatPtr(14.4)},
+			}},
+		}},
+	})
+	b.y += 6
+
+	// The raw SLI queries run on the dashboard datasource, it may not be the SLI
+	// source Prometheus.
+	if prom := slo.ServiceLevelIndicator.Prometheus; prom != nil {
+		desc := "The raw results of the SLI queries."
+		if prom.Address != "" {
+			desc = fmt.Sprintf("The raw results of the SLI queries, the SLI source is %s.", prom.Address)
+		}
+		b.add(Panel{
+			Type:        "timeseries",
+			Title:       "SLI queries",
+			Description: desc,
+			GridPos:     GridPos{H: 8, W: 24, X: 0, Y: b.y},
+			Targets: []Target{
+				{RefID: "A", Expr: prom.TotalQuery, LegendFormat: "total"},
+				{RefID: "B", Expr: prom.ErrorQuery, LegendFormat: "errors"},
+			},
+		})
+		b.y += 8
+	}
+}
+
+// selector returns the label matchers of the operator metrics of an SLO of a cluster.
+func selector(cluster string, sl *monitoringv1alpha1.ServiceLevel, slo monitoringv1alpha1.SLO) string {
+	matchers := []string{
+		fmt.Sprintf("namespace=%q", sl.Namespace),
+		fmt.Sprintf("service_level=%q", sl.Name),
+		fmt.Sprintf("slo=%q", slo.Name),
+	}
+	if cluster != "" {
+		matchers = append(matchers, fmt.Sprintf("cluster=%q", cluster))
+	}
+	return strings.Join(matchers, ", ")
+}
+
+func intPtr(i int) *int { return &i }
+
+func floatPtr(f float64) *float64 { return &f }
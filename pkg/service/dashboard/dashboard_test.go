@@ -0,0 +1,99 @@
+package dashboard_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/service/dashboard"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func newServiceLevel() *monitoringv1alpha1.ServiceLevel {
+	return &monitoringv1alpha1.ServiceLevel{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "checkout",
+			Namespace: "shop",
+			UID:       "2c4cfbb4-7d0a-4a5a-9a8f-1b1fc5e2e7d3",
+		},
+		Spec: monitoringv1alpha1.ServiceLevelSpec{
+			ServiceLevelObjectives: []monitoringv1alpha1.SLO{
+				{
+					Name:                         "availability",
+					Description:                  "Requests without server errors.",
+					AvailabilityObjectivePercent: 99.9,
+					ServiceLevelIndicator: monitoringv1alpha1.SLI{
+						SLISource: monitoringv1alpha1.SLISource{
+							Prometheus: &monitoringv1alpha1.PrometheusSLISource{
+								Address:    "http://prometheus:9090",
+								TotalQuery: `sum(increase(http_requests_total{service="checkout"}[2m]))`,
+								ErrorQuery: `sum(increase(http_requests_total{service="checkout", code=~"5.."}[2m]))`,
+							},
+						},
+					},
+					Output: monitoringv1alpha1.Output{
+						Prometheus: &monitoringv1alpha1.PrometheusOutputSource{},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := map[string]struct {
+		cluster string
+		cfg     dashboard.Cfg
+		golden  string
+	}{
+		"A service level should have a row per SLO with the availability, budget and burn rates.": {
+			golden: "checkout.golden.json",
+		},
+		"The cluster service levels should select the cluster metrics on a custom window.": {
+			cluster: "eu-west-1",
+			cfg:     dashboard.Cfg{Window: "7d"},
+			golden:  "checkout-cluster.golden.json",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			got, err := dashboard.JSON(test.cluster, newServiceLevel(), test.cfg)
+			require.NoError(err)
+
+			golden := filepath.Join("testdata", test.golden)
+			if *update {
+				require.NoError(ioutil.WriteFile(golden, got, 0644))
+			}
+			exp, err := ioutil.ReadFile(golden)
+			require.NoError(err)
+			assert.Equal(string(exp), string(got))
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sl := newServiceLevel()
+	d, err := dashboard.New("", sl, dashboard.Cfg{})
+	require.NoError(err)
+	assert.Equal(dashboard.UID("", sl), d.UID, "the UID should be stable to update the dashboard")
+	assert.Equal("now-30d", d.Time.From)
+
+	assert.NotEqual(d.UID, dashboard.UID("eu-west-1", sl), "the service levels of every cluster should have their own dashboard")
+
+	_, err = dashboard.New("", sl, dashboard.Cfg{Window: "30 days"})
+	assert.Error(err)
+}
@@ -0,0 +1,115 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
+)
+
+// SLOResult is the result of evaluating an SLO once, what the operator would get
+// from the SLI source on a resync.
+type SLOResult struct {
+	Namespace        string  `json:"namespace"`
+	ServiceLevel     string  `json:"serviceLevel"`
+	SLO              string  `json:"slo"`
+	ObjectivePercent float64 `json:"objectivePercent"`
+	Disabled         bool    `json:"disabled,omitempty"`
+	TotalQuery       string  `json:"totalQuery,omitempty"`
+	ErrorQuery       string  `json:"errorQuery,omitempty"`
+	// TotalQ and ErrorQ are the raw results of the queries.
+	TotalQ        float64 `json:"totalQ"`
+	ErrorQ        float64 `json:"errorQ"`
+	TotalQSamples int     `json:"totalQSamples"`
+	ErrorQSamples int     `json:"errorQSamples"`
+	// ErrorRatio and AvailabilityRatio are only valid when the SLI result is valid.
+	ErrorRatio        float64 `json:"errorRatio"`
+	AvailabilityRatio float64 `json:"availabilityRatio"`
+	// ObjectiveMet is true when the availability is equal or greater than the objective.
+	ObjectiveMet bool `json:"objectiveMet"`
+	// LatencySeconds is the time spent retrieving the SLI.
+	LatencySeconds float64 `json:"latencySeconds"`
+	// Warnings are the warnings returned by the SLI source.
+	Warnings []string `json:"warnings,omitempty"`
+	// Error is the error retrieving the SLI or the reason the result is invalid.
+	Error string `json:"error,omitempty"`
+}
+
+// Run evaluates the SLOs of a service level once, using the same retrievers the
+// operator uses. If slo is set only that SLO is evaluated. The errors retrieving an
+// SLO are set on its result so the rest of the SLOs are evaluated independently.
+func Run(ctx context.Context, retrieverFact sli.RetrieverFactory, sl *monitoringv1alpha1.ServiceLevel, slo string) ([]SLOResult, error) {
+	err := sl.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s/%s service level: %s", sl.Namespace, sl.Name, err)
+	}
+
+	var res []SLOResult
+	found := false
+	for i := range sl.Spec.ServiceLevelObjectives {
+		s := &sl.Spec.ServiceLevelObjectives[i]
+		if slo != "" && s.Name != slo {
+			continue
+		}
+		found = true
+
+		r := SLOResult{
+			Namespace:        sl.Namespace,
+			ServiceLevel:     sl.Name,
+			SLO:              s.Name,
+			ObjectivePercent: s.AvailabilityObjectivePercent,
+			Disabled:         s.Disable,
+		}
+		if prom := s.ServiceLevelIndicator.Prometheus; prom != nil {
+			r.TotalQuery = prom.TotalQuery
+			r.ErrorQuery = prom.ErrorQuery
+		}
+		if s.Disable {
+			res = append(res, r)
+			continue
+		}
+
+		retriever, err := retrieverFact.GetStrategy(&s.ServiceLevelIndicator)
+		if err != nil {
+			r.Error = err.Error()
+			res = append(res, r)
+			continue
+		}
+
+		start := time.Now()
+		result, err := retriever.Retrieve(ctx, &s.ServiceLevelIndicator)
+		r.LatencySeconds = time.Since(start).Seconds()
+		if err != nil {
+			r.Error = err.Error()
+			res = append(res, r)
+			continue
+		}
+
+		r.TotalQ = result.TotalQ
+		r.ErrorQ = result.ErrorQ
+		r.TotalQSamples = result.TotalQSamples
+		r.ErrorQSamples = result.ErrorQSamples
+		r.Warnings = result.Warnings
+
+		// Same checks the operator does before using the result.
+		errRatio, err := result.ErrorRatio()
+		if err != nil {
+			r.Error = fmt.Sprintf("invalid SLI result: %s", err)
+			res = append(res, r)
+			continue
+		}
+		r.ErrorRatio = errRatio
+		r.AvailabilityRatio = 1 - errRatio
+		r.ObjectiveMet = r.AvailabilityRatio*100 >= s.AvailabilityObjectivePercent
+
+		res = append(res, r)
+	}
+
+	if slo != "" && !found {
+		return nil, fmt.Errorf("%s SLO not found on %s/%s service level", slo, sl.Namespace, sl.Name)
+	}
+
+	return res, nil
+}
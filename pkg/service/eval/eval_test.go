@@ -0,0 +1,154 @@
+package eval_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	msli "github.com/spotahome/service-level-operator/mocks/service/sli"
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/service/eval"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
+)
+
+var (
+	slo0 = monitoringv1alpha1.SLO{
+		Name:                         "slo0",
+		AvailabilityObjectivePercent: 99,
+		ServiceLevelIndicator: monitoringv1alpha1.SLI{
+			SLISource: monitoringv1alpha1.SLISource{
+				Prometheus: &monitoringv1alpha1.PrometheusSLISource{TotalQuery: "q0", ErrorQuery: "q0_errors"},
+			},
+		},
+		Output: monitoringv1alpha1.Output{
+			Prometheus: &monitoringv1alpha1.PrometheusOutputSource{},
+		},
+	}
+
+	slo1 = monitoringv1alpha1.SLO{
+		Name:                         "slo1",
+		AvailabilityObjectivePercent: 99.9,
+		ServiceLevelIndicator: monitoringv1alpha1.SLI{
+			SLISource: monitoringv1alpha1.SLISource{
+				Prometheus: &monitoringv1alpha1.PrometheusSLISource{TotalQuery: "q1", ErrorQuery: "q1_errors"},
+			},
+		},
+		Output: monitoringv1alpha1.Output{
+			Prometheus: &monitoringv1alpha1.PrometheusOutputSource{},
+		},
+	}
+
+	slo2 = monitoringv1alpha1.SLO{
+		Name:                         "slo2",
+		AvailabilityObjectivePercent: 99,
+		ServiceLevelIndicator: monitoringv1alpha1.SLI{
+			SLISource: monitoringv1alpha1.SLISource{
+				Prometheus: &monitoringv1alpha1.PrometheusSLISource{TotalQuery: "q2", ErrorQuery: "q2_errors"},
+			},
+		},
+		Output: monitoringv1alpha1.Output{
+			Prometheus: &monitoringv1alpha1.PrometheusOutputSource{},
+		},
+	}
+
+	slo3 = monitoringv1alpha1.SLO{
+		Name:                         "slo3",
+		AvailabilityObjectivePercent: 99,
+		ServiceLevelIndicator: monitoringv1alpha1.SLI{
+			SLISource: monitoringv1alpha1.SLISource{
+				Prometheus: &monitoringv1alpha1.PrometheusSLISource{TotalQuery: "q3", ErrorQuery: "q3_errors"},
+			},
+		},
+		Output: monitoringv1alpha1.Output{
+			Prometheus: &monitoringv1alpha1.PrometheusOutputSource{},
+		},
+	}
+)
+
+func TestRun(t *testing.T) {
+	tests := map[string]struct {
+		slos      []monitoringv1alpha1.SLO
+		slo       string
+		results   map[string]sli.Result
+		expResult []eval.SLOResult
+		expErr    bool
+	}{
+		"An invalid service level should fail.": {
+			slos:   []monitoringv1alpha1.SLO{},
+			expErr: true,
+		},
+		"A missing SLO should fail.": {
+			slos:   []monitoringv1alpha1.SLO{slo0},
+			slo:    "slo1",
+			expErr: true,
+		},
+		"The SLOs should be evaluated independently.": {
+			slos: []monitoringv1alpha1.SLO{
+				slo0,
+				slo1,
+				slo2,
+				slo3,
+			},
+			results: map[string]sli.Result{
+				"q0": {TotalQ: 100, ErrorQ: 1, TotalQSamples: 1, ErrorQSamples: 1, Warnings: []string{"w0"}},
+				"q1": {TotalQ: 100, ErrorQ: 1, TotalQSamples: 1, ErrorQSamples: 1},
+				"q2": {TotalQ: 1, ErrorQ: 2, TotalQSamples: 1, ErrorQSamples: 1},
+			},
+			expResult: []eval.SLOResult{
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo0", ObjectivePercent: 99, TotalQuery: "q0", ErrorQuery: "q0_errors", TotalQ: 100, ErrorQ: 1, TotalQSamples: 1, ErrorQSamples: 1, ErrorRatio: 0.01, AvailabilityRatio: 0.99, ObjectiveMet: true, Warnings: []string{"w0"}},
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo1", ObjectivePercent: 99.9, TotalQuery: "q1", ErrorQuery: "q1_errors", TotalQ: 100, ErrorQ: 1, TotalQSamples: 1, ErrorQSamples: 1, ErrorRatio: 0.01, AvailabilityRatio: 0.99},
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo2", ObjectivePercent: 99, TotalQuery: "q2", ErrorQuery: "q2_errors", TotalQ: 1, ErrorQ: 2, TotalQSamples: 1, ErrorQSamples: 1, Error: "invalid SLI result: 2.000000 can't be higher than 1.000000"},
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo3", ObjectivePercent: 99, TotalQuery: "q3", ErrorQuery: "q3_errors", Error: "wanted error"},
+			},
+		},
+		"Only the selected SLO should be evaluated.": {
+			slos: []monitoringv1alpha1.SLO{
+				slo0,
+				slo1,
+			},
+			slo: "slo1",
+			results: map[string]sli.Result{
+				"q1": {TotalQ: 10, TotalQSamples: 1},
+			},
+			expResult: []eval.SLOResult{
+				{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo1", ObjectivePercent: 99.9, TotalQuery: "q1", ErrorQuery: "q1_errors", TotalQ: 10, TotalQSamples: 1, AvailabilityRatio: 1, ObjectiveMet: true},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			mret := &msli.Retriever{}
+			for _, slo := range test.slos {
+				q := slo.ServiceLevelIndicator.Prometheus.TotalQuery
+				if r, ok := test.results[q]; ok {
+					mret.On("Retrieve", mock.Anything, mock.MatchedBy(func(s *monitoringv1alpha1.SLI) bool { return s.Prometheus.TotalQuery == q })).Return(r, nil)
+					continue
+				}
+				mret.On("Retrieve", mock.Anything, mock.MatchedBy(func(s *monitoringv1alpha1.SLI) bool { return s.Prometheus.TotalQuery == q })).Return(sli.Result{}, errors.New("wanted error"))
+			}
+
+			sl := &monitoringv1alpha1.ServiceLevel{
+				ObjectMeta: metav1.ObjectMeta{Name: "sl0", Namespace: "ns0"},
+				Spec:       monitoringv1alpha1.ServiceLevelSpec{ServiceLevelObjectives: test.slos},
+			}
+			res, err := eval.Run(context.Background(), sli.MockRetrieverFactory{Mock: mret}, sl, test.slo)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				for i := range res {
+					assert.True(res[i].LatencySeconds >= 0)
+					res[i].LatencySeconds = 0
+				}
+				assert.Equal(test.expResult, res)
+			}
+		})
+	}
+}
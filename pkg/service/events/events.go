@@ -0,0 +1,310 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/kubernetes"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+)
+
+// Reasons of the recorded service level events.
+const (
+	ReasonInvalid             = "InvalidServiceLevel"
+	ReasonValid               = "ServiceLevelValid"
+	ReasonEvaluationFailed    = "SLOEvaluationFailed"
+	ReasonEvaluationSkipped   = "SLOEvaluationSkipped"
+	ReasonEvaluationRecovered = "SLOEvaluationRecovered"
+	ReasonObjectiveBreached   = "SLOObjectiveBreached"
+	ReasonObjectiveMet        = "SLOObjectiveMet"
+	ReasonBudgetExhausted     = "SLOErrorBudgetExhausted"
+	ReasonBudgetRecovered     = "SLOErrorBudgetRecovered"
+)
+
+const (
+	defMinInterval    = time.Minute
+	defRepeatInterval = 15 * time.Minute
+)
+
+// Cfg is the configuration of the events recorder.
+type Cfg struct {
+	// MinInterval is the minimum interval between the events of the same condition
+	// of an SLO (evaluation, objective or error budget), by default 1m.
+	MinInterval time.Duration
+	// RepeatInterval is the interval a warning is recorded again while its
+	// condition persists, by default 15m.
+	RepeatInterval time.Duration
+}
+
+func (c *Cfg) defaults() {
+	if c.MinInterval <= 0 {
+		c.MinInterval = defMinInterval
+	}
+	if c.RepeatInterval <= 0 {
+		c.RepeatInterval = defRepeatInterval
+	}
+}
+
+// event is a Kubernetes event of a service level.
+type event struct {
+	eventType string
+	reason    string
+	message   string
+	// detail is the part of the message that changes the condition (e.g. the
+	// error), the rest of the message can change without recording it again.
+	detail string
+}
+
+// condition is the last recorded event of a condition.
+type condition struct {
+	event
+	recordedAt time.Time
+}
+
+// next returns true if the event should be recorded. The conditions start as
+// healthy so the normal events are only recorded after a warning.
+func (c *condition) next(e event, now time.Time, minInterval, repeatInterval time.Duration) bool {
+	elapsed := now.Sub(c.recordedAt)
+	switch {
+	case c.reason == "":
+		if e.eventType == corev1.EventTypeNormal {
+			return false
+		}
+	case c.reason != e.reason:
+		// The condition changed, the changes are rate limited so a flapping
+		// condition only records its state when it differs from the recorded one.
+		if elapsed < minInterval {
+			return false
+		}
+	case e.eventType == corev1.EventTypeNormal:
+		return false
+	case c.detail != e.detail:
+		if elapsed < minInterval {
+			return false
+		}
+	default:
+		if elapsed < repeatInterval {
+			return false
+		}
+	}
+
+	c.event = e
+	c.recordedAt = now
+	return true
+}
+
+// sloConditions are the conditions of an SLO.
+type sloConditions struct {
+	serviceLevel string
+	evaluation   condition
+	objective    condition
+	budget       condition
+}
+
+// Recorder is a status recorder middleware that records the lifecycle and the
+// failures of the SLOs as Kubernetes events on their service levels, so they
+// can be checked with `kubectl describe`. It records the validation errors, the
+// evaluation failures and recoveries, the objective breaches and the error
+// budget exhaustion.
+//
+// The events are recorded when the conditions change, the warnings of the
+// conditions that persist are recorded again every repeat interval to avoid
+// event storms. A recorder is used for the service levels of a single cluster.
+type Recorder struct {
+	status.Recorder
+	cfg    Cfg
+	events kubernetes.Event
+	logger log.Logger
+
+	mu      sync.Mutex
+	invalid map[string]*condition
+	slos    map[string]*sloConditions
+}
+
+// NewRecorder returns a new events recorder that wraps a status recorder.
+func NewRecorder(cfg Cfg, events kubernetes.Event, next status.Recorder, logger log.Logger) *Recorder {
+	cfg.defaults()
+	return &Recorder{
+		Recorder: next,
+		cfg:      cfg,
+		events:   events,
+		logger:   logger,
+		invalid:  map[string]*condition{},
+		slos:     map[string]*sloConditions{},
+	}
+}
+
+// RecordValidationError satisfies status.ValidationRecorder interface.
+func (r *Recorder) RecordValidationError(cluster string, sl *monitoringv1alpha1.ServiceLevel, err error) {
+	if vr, ok := r.Recorder.(status.ValidationRecorder); ok {
+		vr.RecordValidationError(cluster, sl, err)
+	}
+
+	slKey := status.ServiceLevelKey(cluster, sl.Namespace, sl.Name)
+	r.mu.Lock()
+	c, ok := r.invalid[slKey]
+	if !ok {
+		c = &condition{}
+		r.invalid[slKey] = c
+	}
+	e := event{
+		eventType: corev1.EventTypeWarning,
+		reason:    ReasonInvalid,
+		message:   fmt.Sprintf("The service level is not valid: %s", err),
+		detail:    err.Error(),
+	}
+	// The validation errors only change when the service level changes.
+	record := c.next(e, time.Now(), 0, r.cfg.RepeatInterval)
+	r.mu.Unlock()
+
+	if record {
+		r.record(sl, e)
+	}
+}
+
+// SetServiceLevel satisfies status.Recorder interface.
+func (r *Recorder) SetServiceLevel(cluster string, sl *monitoringv1alpha1.ServiceLevel) {
+	r.Recorder.SetServiceLevel(cluster, sl)
+
+	slKey := status.ServiceLevelKey(cluster, sl.Namespace, sl.Name)
+	enabled := map[string]bool{}
+	for i := range sl.Spec.ServiceLevelObjectives {
+		slo := &sl.Spec.ServiceLevelObjectives[i]
+		if !slo.Disable {
+			enabled[status.SLOID(cluster, sl, slo)] = true
+		}
+	}
+
+	r.mu.Lock()
+	// The conditions of the removed and disabled SLOs are forgotten.
+	for id, c := range r.slos {
+		if c.serviceLevel == slKey && !enabled[id] {
+			delete(r.slos, id)
+		}
+	}
+	_, wasInvalid := r.invalid[slKey]
+	delete(r.invalid, slKey)
+	r.mu.Unlock()
+
+	if wasInvalid {
+		r.record(sl, event{eventType: corev1.EventTypeNormal, reason: ReasonValid, message: "The service level is valid"})
+	}
+}
+
+// DeleteServiceLevel satisfies status.Recorder interface.
+func (r *Recorder) DeleteServiceLevel(cluster, namespace, name string) {
+	r.Recorder.DeleteServiceLevel(cluster, namespace, name)
+
+	slKey := status.ServiceLevelKey(cluster, namespace, name)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.invalid, slKey)
+	for id, c := range r.slos {
+		if c.serviceLevel == slKey {
+			delete(r.slos, id)
+		}
+	}
+}
+
+// RecordSLOEvaluation satisfies status.Recorder interface.
+func (r *Recorder) RecordSLOEvaluation(e status.SLOEvaluation) {
+	r.Recorder.RecordSLOEvaluation(e)
+
+	if e.SLO.Disable {
+		return
+	}
+	sl, slo := e.ServiceLevel, e.SLO
+
+	var evs []event
+	r.mu.Lock()
+	id := status.SLOID(e.Cluster, sl, slo)
+	c, ok := r.slos[id]
+	if !ok {
+		c = &sloConditions{}
+		r.slos[id] = c
+	}
+	c.serviceLevel = status.ServiceLevelKey(e.Cluster, sl.Namespace, sl.Name)
+	next := func(cond *condition, ev event) {
+		if cond.next(ev, e.Time, r.cfg.MinInterval, r.cfg.RepeatInterval) {
+			evs = append(evs, ev)
+		}
+	}
+
+	switch {
+	case e.Skipped:
+		next(&c.evaluation, event{
+			eventType: corev1.EventTypeWarning,
+			reason:    ReasonEvaluationSkipped,
+			message:   fmt.Sprintf("The %s SLO is not evaluated, its SLI source is unavailable: %s", slo.Name, e.Err),
+			detail:    e.Err.Error(),
+		})
+	case e.Err != nil:
+		next(&c.evaluation, event{
+			eventType: corev1.EventTypeWarning,
+			reason:    ReasonEvaluationFailed,
+			message:   fmt.Sprintf("The %s SLO evaluation failed: %s", slo.Name, e.Err),
+			detail:    e.Err.Error(),
+		})
+	default:
+		next(&c.evaluation, event{
+			eventType: corev1.EventTypeNormal,
+			reason:    ReasonEvaluationRecovered,
+			message:   fmt.Sprintf("The %s SLO is evaluated again", slo.Name),
+		})
+	}
+
+	// The objective is checked with the SLI result of the evaluation.
+	objective := slo.AvailabilityObjectivePercent / 100
+	if e.Err == nil && e.Result != nil {
+		if availability, err := e.Result.AvailabilityRatio(); err == nil {
+			ev := event{
+				eventType: corev1.EventTypeNormal,
+				reason:    ReasonObjectiveMet,
+				message:   fmt.Sprintf("The %s SLO availability is %s, it meets the %s objective", slo.Name, status.FormatPercent(availability), status.FormatPercent(objective)),
+			}
+			if availability < objective {
+				ev = event{
+					eventType: corev1.EventTypeWarning,
+					reason:    ReasonObjectiveBreached,
+					message:   fmt.Sprintf("The %s SLO availability is %s, below the %s objective", slo.Name, status.FormatPercent(availability), status.FormatPercent(objective)),
+				}
+			}
+			next(&c.objective, ev)
+		}
+	}
+
+	// The error budget is checked with the output counters.
+	if e.Err == nil && e.Counters != nil {
+		if availability, remaining, ok := e.Counters.ErrorBudget(); ok {
+			ev := event{
+				eventType: corev1.EventTypeNormal,
+				reason:    ReasonBudgetRecovered,
+				message:   fmt.Sprintf("The %s SLO has %s of its error budget remaining", slo.Name, status.FormatPercent(remaining)),
+			}
+			if output.ErrorBudgetExhausted(remaining) {
+				ev = event{
+					eventType: corev1.EventTypeWarning,
+					reason:    ReasonBudgetExhausted,
+					message:   fmt.Sprintf("The %s SLO error budget is exhausted, its availability is %s (objective %s)", slo.Name, status.FormatPercent(availability), status.FormatPercent(e.Counters.Objective)),
+				}
+			}
+			next(&c.budget, ev)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, ev := range evs {
+		r.record(sl, ev)
+	}
+}
+
+func (r *Recorder) record(sl *monitoringv1alpha1.ServiceLevel, e event) {
+	r.logger.With("sl", sl.Name).Debugf("recording %s event: %s", e.reason, e.message)
+	r.events.RecordEvent(sl, e.eventType, e.reason, e.message)
+}
@@ -0,0 +1,197 @@
+package events_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/events"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+)
+
+// fakeEvents stores the recorded events as "type:reason".
+type fakeEvents struct {
+	mu       sync.Mutex
+	recorded []string
+}
+
+func (f *fakeEvents) RecordEvent(_ *monitoringv1alpha1.ServiceLevel, eventType, reason, _ string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.recorded = append(f.recorded, eventType+":"+reason)
+}
+
+func (f *fakeEvents) flush() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	recorded := f.recorded
+	f.recorded = nil
+	return recorded
+}
+
+var sl0 = &monitoringv1alpha1.ServiceLevel{
+	ObjectMeta: metav1.ObjectMeta{Namespace: "ns0", Name: "sl0"},
+	Spec: monitoringv1alpha1.ServiceLevelSpec{
+		ServiceLevelObjectives: []monitoringv1alpha1.SLO{
+			{Name: "slo0", AvailabilityObjectivePercent: 99},
+		},
+	},
+}
+
+// step is an evaluation of the SLO at a time.
+type step struct {
+	at        time.Duration
+	err       error
+	skipped   bool
+	result    *sli.Result
+	counters  *output.SLOCounters
+	expEvents []string
+}
+
+var (
+	okResult     = &sli.Result{TotalQ: 100, ErrorQ: 0}
+	badResult    = &sli.Result{TotalQ: 100, ErrorQ: 5}
+	okCounters   = &output.SLOCounters{ErrorRatioSum: 0, Count: 100, Objective: 0.99}
+	badCounters  = &output.SLOCounters{ErrorRatioSum: 2, Count: 100, Objective: 0.99}
+	queryErr     = errors.New("query error")
+	otherErr     = errors.New("other error")
+	circuitError = &sli.CircuitOpenError{Address: "http://prometheus:9090"}
+)
+
+func TestRecorder(t *testing.T) {
+	tests := map[string]struct {
+		steps []step
+	}{
+		"Healthy evaluations shouldn't record events.": {
+			steps: []step{
+				{at: 0, result: okResult, counters: okCounters},
+				{at: time.Hour, result: okResult, counters: okCounters},
+			},
+		},
+		"Failing and recovering an evaluation should record both.": {
+			steps: []step{
+				{at: 0, err: queryErr, expEvents: []string{"Warning:SLOEvaluationFailed"}},
+				{at: 10 * time.Second, err: queryErr},
+				{at: 2 * time.Minute, result: okResult, counters: okCounters, expEvents: []string{"Normal:SLOEvaluationRecovered"}},
+			},
+		},
+		"Skipped evaluations should be recorded.": {
+			steps: []step{
+				{at: 0, err: circuitError, skipped: true, expEvents: []string{"Warning:SLOEvaluationSkipped"}},
+				{at: 2 * time.Minute, result: okResult, counters: okCounters, expEvents: []string{"Normal:SLOEvaluationRecovered"}},
+			},
+		},
+		"Persisting failures should be recorded again after the repeat interval.": {
+			steps: []step{
+				{at: 0, err: queryErr, expEvents: []string{"Warning:SLOEvaluationFailed"}},
+				{at: 14 * time.Minute, err: queryErr},
+				{at: 15 * time.Minute, err: queryErr, expEvents: []string{"Warning:SLOEvaluationFailed"}},
+			},
+		},
+		"A different failure should be recorded after the min interval.": {
+			steps: []step{
+				{at: 0, err: queryErr, expEvents: []string{"Warning:SLOEvaluationFailed"}},
+				{at: 10 * time.Second, err: otherErr},
+				{at: time.Minute, err: otherErr, expEvents: []string{"Warning:SLOEvaluationFailed"}},
+			},
+		},
+		"A flapping evaluation should only record the changes once per min interval.": {
+			steps: []step{
+				{at: 0, err: queryErr, expEvents: []string{"Warning:SLOEvaluationFailed"}},
+				{at: 10 * time.Second, result: okResult, counters: okCounters},
+				{at: 20 * time.Second, err: queryErr},
+				{at: 30 * time.Second, result: okResult, counters: okCounters},
+				{at: 40 * time.Second, err: queryErr},
+				// Failing again, already recorded.
+				{at: 2 * time.Minute, err: queryErr},
+				{at: 3 * time.Minute, result: okResult, counters: okCounters, expEvents: []string{"Normal:SLOEvaluationRecovered"}},
+			},
+		},
+		"Breaching and meeting the objective should record both.": {
+			steps: []step{
+				{at: 0, result: badResult, counters: okCounters, expEvents: []string{"Warning:SLOObjectiveBreached"}},
+				{at: 10 * time.Second, result: badResult, counters: okCounters},
+				{at: 2 * time.Minute, result: okResult, counters: okCounters, expEvents: []string{"Normal:SLOObjectiveMet"}},
+			},
+		},
+		"Exhausting and recovering the error budget should record both.": {
+			steps: []step{
+				{at: 0, result: okResult, counters: badCounters, expEvents: []string{"Warning:SLOErrorBudgetExhausted"}},
+				// The availability changes shouldn't record the exhausted budget again.
+				{at: 2 * time.Minute, result: okResult, counters: &output.SLOCounters{ErrorRatioSum: 2, Count: 150, Objective: 0.99}},
+				{at: 4 * time.Minute, result: okResult, counters: &output.SLOCounters{ErrorRatioSum: 2, Count: 300, Objective: 0.99}, expEvents: []string{"Normal:SLOErrorBudgetRecovered"}},
+			},
+		},
+		"A 100% objective without errors shouldn't exhaust the error budget.": {
+			steps: []step{
+				{at: 0, result: okResult, counters: &output.SLOCounters{ErrorRatioSum: 0, Count: 100, Objective: 1}},
+				{at: 2 * time.Minute, result: okResult, counters: &output.SLOCounters{ErrorRatioSum: 0.1, Count: 200, Objective: 1}, expEvents: []string{"Warning:SLOErrorBudgetExhausted"}},
+			},
+		},
+		"Failed evaluations shouldn't change the objective and the error budget.": {
+			steps: []step{
+				{at: 0, result: badResult, counters: badCounters, expEvents: []string{"Warning:SLOObjectiveBreached", "Warning:SLOErrorBudgetExhausted"}},
+				{at: 2 * time.Minute, err: queryErr, counters: okCounters, expEvents: []string{"Warning:SLOEvaluationFailed"}},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			evs := &fakeEvents{}
+			r := events.NewRecorder(events.Cfg{}, evs, status.Dummy, log.Dummy)
+			r.SetServiceLevel("", sl0)
+
+			start := time.Now()
+			for i, s := range test.steps {
+				r.RecordSLOEvaluation(status.SLOEvaluation{
+					ServiceLevel: sl0,
+					SLO:          &sl0.Spec.ServiceLevelObjectives[0],
+					Time:         start.Add(s.at),
+					Result:       s.result,
+					Err:          s.err,
+					Skipped:      s.skipped,
+					Counters:     s.counters,
+				})
+				assert.Equal(s.expEvents, evs.flush(), "step %d", i)
+			}
+		})
+	}
+}
+
+func TestRecorderValidationErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	evs := &fakeEvents{}
+	r := events.NewRecorder(events.Cfg{}, evs, status.Dummy, log.Dummy)
+
+	// The same validation error should be recorded once.
+	r.RecordValidationError("", sl0, errors.New("invalid objective"))
+	r.RecordValidationError("", sl0, errors.New("invalid objective"))
+	assert.Equal([]string{"Warning:InvalidServiceLevel"}, evs.flush())
+
+	// A different validation error should be recorded.
+	r.RecordValidationError("", sl0, errors.New("invalid name"))
+	assert.Equal([]string{"Warning:InvalidServiceLevel"}, evs.flush())
+
+	// Fixing the service level should be recorded once.
+	r.SetServiceLevel("", sl0)
+	r.SetServiceLevel("", sl0)
+	assert.Equal([]string{"Normal:ServiceLevelValid"}, evs.flush())
+
+	// Deleted service levels should forget their state.
+	r.RecordValidationError("", sl0, errors.New("invalid objective"))
+	r.DeleteServiceLevel("", sl0.Namespace, sl0.Name)
+	r.RecordValidationError("", sl0, errors.New("invalid objective"))
+	assert.Equal([]string{"Warning:InvalidServiceLevel", "Warning:InvalidServiceLevel"}, evs.flush())
+}
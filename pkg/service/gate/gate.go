@@ -0,0 +1,236 @@
+package gate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+)
+
+// AnnotationPolicy is the service level annotation with the JSON budget policy
+// that overrides the default policy of the gate for that service level.
+const AnnotationPolicy = "service-level-operator.spotahome.com/gate-policy"
+
+// ErrNotFound is returned when the service level of a decision is not handled.
+var ErrNotFound = errors.New("service level not found")
+
+// Policy is an error budget policy of the gate.
+type Policy struct {
+	// MinErrorBudgetPercent is the minimum remaining error budget percent of the
+	// SLOs to allow, 0 by default (the SLOs with their error budget exhausted deny).
+	MinErrorBudgetPercent float64 `json:"minErrorBudgetPercent"`
+	// AllowUnknown allows the SLOs whose error budget is not known yet (e.g. not
+	// evaluated since the operator started).
+	AllowUnknown bool `json:"allowUnknown"`
+	// SLOs are the policies of the SLOs by SLO name.
+	SLOs map[string]SLOPolicy `json:"slos,omitempty"`
+}
+
+// SLOPolicy is the error budget policy of an SLO.
+type SLOPolicy struct {
+	// MinErrorBudgetPercent overrides the minimum remaining error budget percent of the policy.
+	MinErrorBudgetPercent *float64 `json:"minErrorBudgetPercent,omitempty"`
+	// Ignore ignores the SLO on the decisions.
+	Ignore bool `json:"ignore,omitempty"`
+}
+
+// Validate validates the policy.
+func (p Policy) Validate() error {
+	if p.MinErrorBudgetPercent < 0 || p.MinErrorBudgetPercent > 100 {
+		return fmt.Errorf("the min error budget percent must be between 0 and 100")
+	}
+	for name, slo := range p.SLOs {
+		if slo.MinErrorBudgetPercent != nil && (*slo.MinErrorBudgetPercent < 0 || *slo.MinErrorBudgetPercent > 100) {
+			return fmt.Errorf("the %s SLO min error budget percent must be between 0 and 100", name)
+		}
+	}
+	return nil
+}
+
+// Override returns the policy overridden by a JSON policy (e.g. the policy
+// annotation), only the fields set on the JSON policy are overridden.
+func (p Policy) Override(js string) (Policy, error) {
+	o := p
+	o.SLOs = map[string]SLOPolicy{}
+	for name, slo := range p.SLOs {
+		o.SLOs[name] = slo
+	}
+
+	override := struct {
+		MinErrorBudgetPercent *float64             `json:"minErrorBudgetPercent"`
+		AllowUnknown          *bool                `json:"allowUnknown"`
+		SLOs                  map[string]SLOPolicy `json:"slos"`
+	}{}
+	dec := json.NewDecoder(strings.NewReader(js))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&override); err != nil {
+		return Policy{}, fmt.Errorf("invalid policy: %s", err)
+	}
+	if override.MinErrorBudgetPercent != nil {
+		o.MinErrorBudgetPercent = *override.MinErrorBudgetPercent
+	}
+	if override.AllowUnknown != nil {
+		o.AllowUnknown = *override.AllowUnknown
+	}
+	for name, slo := range override.SLOs {
+		o.SLOs[name] = slo
+	}
+
+	if err := o.Validate(); err != nil {
+		return Policy{}, err
+	}
+	return o, nil
+}
+
+// PolicyOf returns the policy of a service level, the policy overridden by the
+// policy annotation of the service level.
+func (p Policy) PolicyOf(annotations map[string]string) (Policy, error) {
+	js, ok := annotations[AnnotationPolicy]
+	if !ok {
+		return p, nil
+	}
+	o, err := p.Override(js)
+	if err != nil {
+		return Policy{}, fmt.Errorf("invalid %s annotation: %s", AnnotationPolicy, err)
+	}
+	return o, nil
+}
+
+// Decision is a decision of the gate for a service level.
+type Decision struct {
+	// Allowed is true if all the SLOs of the service level allow.
+	Allowed      bool   `json:"allowed"`
+	Cluster      string `json:"cluster,omitempty"`
+	Namespace    string `json:"namespace"`
+	ServiceLevel string `json:"serviceLevel"`
+	// Reasons are the reasons of the SLOs that deny.
+	Reasons []string      `json:"reasons,omitempty"`
+	Policy  Policy        `json:"policy"`
+	SLOs    []SLODecision `json:"slos"`
+}
+
+// SLODecision is a decision of the gate for an SLO.
+type SLODecision struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Allowed bool   `json:"allowed"`
+	// Ignored is true when the SLO is disabled or ignored by the policy.
+	Ignored                     bool     `json:"ignored,omitempty"`
+	ErrorBudgetRemainingPercent *float64 `json:"errorBudgetRemainingPercent,omitempty"`
+	MinErrorBudgetPercent       float64  `json:"minErrorBudgetPercent"`
+	Reason                      string   `json:"reason,omitempty"`
+}
+
+// Decide decides with a policy if a service level allows, a service level
+// allows when all its SLOs have more remaining error budget than the minimum
+// of the policy. The error budget is the one of the operator output counters.
+func Decide(p Policy, sl status.ServiceLevelStatus) Decision {
+	d := Decision{
+		Allowed:      true,
+		Cluster:      sl.Cluster,
+		Namespace:    sl.Namespace,
+		ServiceLevel: sl.Name,
+		Policy:       p,
+		SLOs:         []SLODecision{},
+	}
+
+	for _, slo := range sl.SLOs {
+		sp := p.SLOs[slo.Name]
+		sd := SLODecision{
+			ID:                    slo.ID,
+			Name:                  slo.Name,
+			Allowed:               true,
+			MinErrorBudgetPercent: p.MinErrorBudgetPercent,
+		}
+		if sp.MinErrorBudgetPercent != nil {
+			sd.MinErrorBudgetPercent = *sp.MinErrorBudgetPercent
+		}
+
+		_, remaining, ok := slo.ErrorBudget()
+		if ok {
+			pct := remaining * 100
+			sd.ErrorBudgetRemainingPercent = &pct
+		}
+
+		switch {
+		case slo.Disabled:
+			sd.Ignored = true
+			sd.Reason = "the SLO is disabled"
+		case sp.Ignore:
+			sd.Ignored = true
+			sd.Reason = "the SLO is ignored by the policy"
+		case !ok && p.AllowUnknown:
+			sd.Reason = "the error budget is unknown"
+		case !ok:
+			sd.Allowed = false
+			sd.Reason = "the error budget is unknown, the SLO has not been evaluated yet"
+		case output.ErrorBudgetExhausted(remaining):
+			sd.Allowed = false
+			sd.Reason = "the error budget is exhausted"
+		case *sd.ErrorBudgetRemainingPercent < sd.MinErrorBudgetPercent:
+			sd.Allowed = false
+			sd.Reason = fmt.Sprintf("the error budget remaining is %s, below the %s minimum", formatPercent(*sd.ErrorBudgetRemainingPercent), formatPercent(sd.MinErrorBudgetPercent))
+		}
+
+		if !sd.Allowed {
+			d.Allowed = false
+			d.Reasons = append(d.Reasons, fmt.Sprintf("%s SLO: %s", slo.Name, sd.Reason))
+		}
+		d.SLOs = append(d.SLOs, sd)
+	}
+
+	return d
+}
+
+// Gate decides if the changes of the handled service levels are allowed based
+// on their error budget.
+type Gate struct {
+	policy   Policy
+	reader   status.Reader
+	policies *Policies
+}
+
+// NewGate returns a new gate with a default policy, the policies of the service
+// levels are overridden with the policy annotation tracked by the policies (if
+// not nil).
+func NewGate(policy Policy, reader status.Reader, policies *Policies) (*Gate, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &Gate{
+		policy:   policy,
+		reader:   reader,
+		policies: policies,
+	}, nil
+}
+
+// Decide decides if a handled service level allows, ErrNotFound is returned if
+// the service level is not handled.
+func (g *Gate) Decide(cluster, namespace, name string) (Decision, error) {
+	sl, ok := g.reader.GetServiceLevel(cluster, namespace, name)
+	if !ok {
+		return Decision{}, ErrNotFound
+	}
+
+	p := g.policy
+	if g.policies != nil {
+		var err error
+		p, err = g.policy.PolicyOf(g.policies.Annotations(cluster, namespace, name))
+		if err != nil {
+			return Decision{}, err
+		}
+	}
+
+	return Decide(p, sl), nil
+}
+
+// formatPercent formats a percent with 3 decimals at most.
+func formatPercent(pct float64) string {
+	s := strconv.FormatFloat(pct, 'f', 3, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	return s + "%"
+}
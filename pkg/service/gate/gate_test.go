@@ -0,0 +1,283 @@
+package gate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/service/gate"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+)
+
+func float64P(f float64) *float64 {
+	return &f
+}
+
+// counters returns output counters of a 99% objective with a remaining error
+// budget ratio.
+func counters(remaining float64) *output.SLOCounters {
+	return &output.SLOCounters{ErrorRatioSum: (1 - remaining) * 0.01 * 100, Count: 100, Objective: 0.99}
+}
+
+func TestDecide(t *testing.T) {
+	tests := map[string]struct {
+		policy     gate.Policy
+		slos       []status.SLOStatus
+		expAllowed bool
+		expReasons []string
+		expIgnored []bool
+	}{
+		"Without SLOs it should allow.": {
+			expAllowed: true,
+		},
+		"SLOs with error budget remaining should allow.": {
+			slos: []status.SLOStatus{
+				{Name: "slo0", OutputCounters: counters(0.5)},
+				{Name: "slo1", OutputCounters: counters(0.1)},
+			},
+			expAllowed: true,
+			expIgnored: []bool{false, false},
+		},
+		"An SLO with the error budget exhausted should deny.": {
+			slos: []status.SLOStatus{
+				{Name: "slo0", OutputCounters: counters(0.5)},
+				{Name: "slo1", OutputCounters: counters(-0.5)},
+			},
+			expAllowed: false,
+			expReasons: []string{"slo1 SLO: the error budget is exhausted"},
+			expIgnored: []bool{false, false},
+		},
+		"An SLO without error budget that isn't exhausted should allow.": {
+			slos: []status.SLOStatus{
+				{Name: "slo0", OutputCounters: &output.SLOCounters{ErrorRatioSum: 0, Count: 100, Objective: 1}},
+			},
+			expAllowed: true,
+			expIgnored: []bool{false},
+		},
+		"An SLO without error budget that is exhausted should deny.": {
+			slos: []status.SLOStatus{
+				{Name: "slo0", OutputCounters: &output.SLOCounters{ErrorRatioSum: 0.1, Count: 100, Objective: 1}},
+			},
+			expAllowed: false,
+			expReasons: []string{"slo0 SLO: the error budget is exhausted"},
+			expIgnored: []bool{false},
+		},
+		"An SLO with the error budget exhausted should deny as exhausted with a minimum.": {
+			policy: gate.Policy{MinErrorBudgetPercent: 20},
+			slos: []status.SLOStatus{
+				{Name: "slo0", OutputCounters: counters(-0.5)},
+			},
+			expAllowed: false,
+			expReasons: []string{"slo0 SLO: the error budget is exhausted"},
+			expIgnored: []bool{false},
+		},
+		"An SLO with the minimum error budget remaining should allow.": {
+			policy: gate.Policy{MinErrorBudgetPercent: 50},
+			slos: []status.SLOStatus{
+				{Name: "slo0", OutputCounters: &output.SLOCounters{ErrorRatioSum: 0.5, Count: 100, Objective: 0.99}},
+			},
+			expAllowed: true,
+			expIgnored: []bool{false},
+		},
+		"An SLO with less error budget remaining than the minimum should deny.": {
+			policy: gate.Policy{MinErrorBudgetPercent: 20},
+			slos: []status.SLOStatus{
+				{Name: "slo0", OutputCounters: counters(0.1)},
+			},
+			expAllowed: false,
+			expReasons: []string{"slo0 SLO: the error budget remaining is 10%, below the 20% minimum"},
+			expIgnored: []bool{false},
+		},
+		"The SLO policy minimum should override the policy minimum.": {
+			policy: gate.Policy{
+				MinErrorBudgetPercent: 20,
+				SLOs: map[string]gate.SLOPolicy{
+					"slo0": {MinErrorBudgetPercent: float64P(5)},
+					"slo1": {MinErrorBudgetPercent: float64P(50)},
+				},
+			},
+			slos: []status.SLOStatus{
+				{Name: "slo0", OutputCounters: counters(0.1)},
+				{Name: "slo1", OutputCounters: counters(0.4)},
+			},
+			expAllowed: false,
+			expReasons: []string{"slo1 SLO: the error budget remaining is 40%, below the 50% minimum"},
+			expIgnored: []bool{false, false},
+		},
+		"Ignored and disabled SLOs should allow.": {
+			policy: gate.Policy{
+				SLOs: map[string]gate.SLOPolicy{"slo0": {Ignore: true}},
+			},
+			slos: []status.SLOStatus{
+				{Name: "slo0", OutputCounters: counters(-1)},
+				{Name: "slo1", Disabled: true},
+			},
+			expAllowed: true,
+			expIgnored: []bool{true, true},
+		},
+		"An SLO with an unknown error budget should deny.": {
+			slos: []status.SLOStatus{
+				{Name: "slo0"},
+			},
+			expAllowed: false,
+			expReasons: []string{"slo0 SLO: the error budget is unknown, the SLO has not been evaluated yet"},
+			expIgnored: []bool{false},
+		},
+		"An SLO with an unknown error budget should allow if the policy allows unknown.": {
+			policy: gate.Policy{AllowUnknown: true},
+			slos: []status.SLOStatus{
+				{Name: "slo0"},
+			},
+			expAllowed: true,
+			expIgnored: []bool{false},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			d := gate.Decide(test.policy, status.ServiceLevelStatus{Namespace: "ns0", Name: "sl0", SLOs: test.slos})
+
+			assert.Equal(test.expAllowed, d.Allowed)
+			assert.Equal(test.expReasons, d.Reasons)
+			assert.Equal("ns0", d.Namespace)
+			assert.Equal("sl0", d.ServiceLevel)
+			ignored := []bool{}
+			for _, slo := range d.SLOs {
+				ignored = append(ignored, slo.Ignored)
+			}
+			if test.expIgnored == nil {
+				test.expIgnored = []bool{}
+			}
+			assert.Equal(test.expIgnored, ignored)
+		})
+	}
+}
+
+func TestPolicyOverride(t *testing.T) {
+	base := gate.Policy{
+		MinErrorBudgetPercent: 10,
+		SLOs: map[string]gate.SLOPolicy{
+			"slo0": {Ignore: true},
+		},
+	}
+
+	tests := map[string]struct {
+		annotations map[string]string
+		expPolicy   gate.Policy
+		expErr      bool
+	}{
+		"Without annotation the policy should not change.": {
+			expPolicy: base,
+		},
+		"The annotation should only override its fields.": {
+			annotations: map[string]string{
+				gate.AnnotationPolicy: `{"allowUnknown": true, "slos": {"slo1": {"minErrorBudgetPercent": 30}}}`,
+			},
+			expPolicy: gate.Policy{
+				MinErrorBudgetPercent: 10,
+				AllowUnknown:          true,
+				SLOs: map[string]gate.SLOPolicy{
+					"slo0": {Ignore: true},
+					"slo1": {MinErrorBudgetPercent: float64P(30)},
+				},
+			},
+		},
+		"The annotation should override the SLO policies.": {
+			annotations: map[string]string{
+				gate.AnnotationPolicy: `{"minErrorBudgetPercent": 0, "slos": {"slo0": {}}}`,
+			},
+			expPolicy: gate.Policy{
+				SLOs: map[string]gate.SLOPolicy{
+					"slo0": {},
+				},
+			},
+		},
+		"An annotation with unknown fields should fail.": {
+			annotations: map[string]string{
+				gate.AnnotationPolicy: `{"minBudget": 20}`,
+			},
+			expErr: true,
+		},
+		"An annotation with an invalid minimum should fail.": {
+			annotations: map[string]string{
+				gate.AnnotationPolicy: `{"slos": {"slo1": {"minErrorBudgetPercent": 120}}}`,
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			p, err := base.PolicyOf(test.annotations)
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expPolicy, p)
+			}
+		})
+	}
+
+	// The base policy should not be modified by the overrides.
+	assert.Equal(t, map[string]gate.SLOPolicy{"slo0": {Ignore: true}}, base.SLOs)
+}
+
+func TestGate(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sl := &monitoringv1alpha1.ServiceLevel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns0", Name: "sl0"},
+		Spec: monitoringv1alpha1.ServiceLevelSpec{
+			ServiceLevelObjectives: []monitoringv1alpha1.SLO{
+				{Name: "slo0", AvailabilityObjectivePercent: 99},
+			},
+		},
+	}
+	store := status.NewMemory(time.Minute)
+	policies := gate.NewPolicies(store)
+	g, err := gate.NewGate(gate.Policy{MinErrorBudgetPercent: 20}, store, policies)
+	require.NoError(err)
+
+	_, err = g.Decide("", "ns0", "sl0")
+	assert.Equal(gate.ErrNotFound, err)
+
+	policies.SetServiceLevel("", sl)
+	policies.RecordSLOEvaluation(status.SLOEvaluation{
+		ServiceLevel: sl,
+		SLO:          &sl.Spec.ServiceLevelObjectives[0],
+		Time:         time.Now(),
+		Counters:     counters(0.1),
+	})
+	d, err := g.Decide("", "ns0", "sl0")
+	require.NoError(err)
+	assert.False(d.Allowed)
+
+	// The annotation policy should override the default policy.
+	sl = sl.DeepCopy()
+	sl.Annotations = map[string]string{gate.AnnotationPolicy: `{"minErrorBudgetPercent": 5}`}
+	policies.SetServiceLevel("", sl)
+	d, err = g.Decide("", "ns0", "sl0")
+	require.NoError(err)
+	assert.True(d.Allowed)
+
+	sl.Annotations[gate.AnnotationPolicy] = `{"minErrorBudgetPercent": "5"}`
+	policies.SetServiceLevel("", sl)
+	_, err = g.Decide("", "ns0", "sl0")
+	assert.Error(err)
+
+	policies.DeleteServiceLevel("", "ns0", "sl0")
+	_, err = g.Decide("", "ns0", "sl0")
+	assert.Equal(gate.ErrNotFound, err)
+
+	_, err = gate.NewGate(gate.Policy{MinErrorBudgetPercent: -1}, store, nil)
+	assert.Error(err)
+}
@@ -0,0 +1,59 @@
+package gate
+
+import (
+	"sync"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+)
+
+// Policies is a status recorder middleware that tracks the policy annotations of
+// the handled service levels.
+type Policies struct {
+	status.Recorder
+
+	mu          sync.RWMutex
+	annotations map[string]string
+}
+
+// NewPolicies returns a new policies recorder middleware that wraps a status recorder.
+func NewPolicies(next status.Recorder) *Policies {
+	return &Policies{
+		Recorder:    next,
+		annotations: map[string]string{},
+	}
+}
+
+// SetServiceLevel satisfies status.Recorder interface.
+func (p *Policies) SetServiceLevel(cluster string, sl *monitoringv1alpha1.ServiceLevel) {
+	p.Recorder.SetServiceLevel(cluster, sl)
+
+	key := status.ServiceLevelKey(cluster, sl.Namespace, sl.Name)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if js, ok := sl.Annotations[AnnotationPolicy]; ok {
+		p.annotations[key] = js
+	} else {
+		delete(p.annotations, key)
+	}
+}
+
+// DeleteServiceLevel satisfies status.Recorder interface.
+func (p *Policies) DeleteServiceLevel(cluster, namespace, name string) {
+	p.Recorder.DeleteServiceLevel(cluster, namespace, name)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.annotations, status.ServiceLevelKey(cluster, namespace, name))
+}
+
+// Annotations returns the policy annotation of a service level, as annotations.
+func (p *Policies) Annotations(cluster, namespace, name string) map[string]string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	js, ok := p.annotations[status.ServiceLevelKey(cluster, namespace, name)]
+	if !ok {
+		return nil
+	}
+	return map[string]string{AnnotationPolicy: js}
+}
@@ -0,0 +1,139 @@
+package health
+
+import (
+	"context"
+	"sync"
+)
+
+// Checker knows how to check the health of a component.
+type Checker interface {
+	// Check returns an error if the component is not healthy.
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc is a helper to use functions as Checker.
+type CheckerFunc func(ctx context.Context) error
+
+// Check satisfies Checker interface.
+func (c CheckerFunc) Check(ctx context.Context) error {
+	return c(ctx)
+}
+
+// Registerer knows how to register health checks.
+type Registerer interface {
+	// RegisterReadiness registers a check that needs to be healthy to consider
+	// the operator ready.
+	RegisterReadiness(name string, c Checker)
+	// RegisterLiveness registers a check that when not healthy means that the
+	// operator is stuck and needs to be restarted.
+	RegisterLiveness(name string, c Checker)
+}
+
+// Reporter knows how to report the health.
+type Reporter interface {
+	// Readiness returns the result of the readiness checks.
+	Readiness(ctx context.Context) Result
+	// Liveness returns the result of the liveness checks.
+	Liveness(ctx context.Context) Result
+}
+
+// Result is the result of a set of health checks.
+type Result struct {
+	Healthy bool          `json:"healthy"`
+	Checks  []CheckResult `json:"checks"`
+}
+
+// CheckResult is the result of a health check.
+type CheckResult struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+type namedChecker struct {
+	name    string
+	checker Checker
+}
+
+// Checks are the registered health checks.
+type Checks struct {
+	mu        sync.Mutex
+	readiness []namedChecker
+	liveness  []namedChecker
+}
+
+// NewChecks returns a new health checks registry without checks, without checks
+// the operator is healthy.
+func NewChecks() *Checks {
+	return &Checks{}
+}
+
+// RegisterReadiness satisfies Registerer interface.
+func (c *Checks) RegisterReadiness(name string, checker Checker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readiness = append(c.readiness, namedChecker{name: name, checker: checker})
+}
+
+// RegisterLiveness satisfies Registerer interface.
+func (c *Checks) RegisterLiveness(name string, checker Checker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.liveness = append(c.liveness, namedChecker{name: name, checker: checker})
+}
+
+// Readiness satisfies Reporter interface.
+func (c *Checks) Readiness(ctx context.Context) Result {
+	c.mu.Lock()
+	checkers := c.readiness
+	c.mu.Unlock()
+	return run(ctx, checkers)
+}
+
+// Liveness satisfies Reporter interface.
+func (c *Checks) Liveness(ctx context.Context) Result {
+	c.mu.Lock()
+	checkers := c.liveness
+	c.mu.Unlock()
+	return run(ctx, checkers)
+}
+
+// run runs all the checks concurrently and returns the results in the same
+// order the checks were registered.
+func run(ctx context.Context, checkers []namedChecker) Result {
+	res := Result{
+		Healthy: true,
+		Checks:  make([]CheckResult, len(checkers)),
+	}
+
+	var wg sync.WaitGroup
+	for i, nc := range checkers {
+		wg.Add(1)
+		go func(i int, nc namedChecker) {
+			defer wg.Done()
+			cr := CheckResult{Name: nc.name, Healthy: true}
+			if err := nc.checker.Check(ctx); err != nil {
+				cr.Healthy = false
+				cr.Error = err.Error()
+			}
+			res.Checks[i] = cr
+		}(i, nc)
+	}
+	wg.Wait()
+
+	for _, cr := range res.Checks {
+		if !cr.Healthy {
+			res.Healthy = false
+		}
+	}
+
+	return res
+}
+
+// Dummy is a Dummy implementation of the health checks registerer.
+var Dummy = &dummy{}
+
+type dummy struct{}
+
+func (dummy) RegisterReadiness(_ string, _ Checker) {}
+func (dummy) RegisterLiveness(_ string, _ Checker)  {}
@@ -0,0 +1,54 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spotahome/service-level-operator/pkg/service/health"
+)
+
+func TestChecks(t *testing.T) {
+	ok := health.CheckerFunc(func(_ context.Context) error { return nil })
+	failing := health.CheckerFunc(func(_ context.Context) error { return errors.New("wanted error") })
+
+	tests := map[string]struct {
+		register func(r health.Registerer)
+		expReady health.Result
+		expLive  health.Result
+	}{
+		"Without checks it should be healthy.": {
+			register: func(r health.Registerer) {},
+			expReady: health.Result{Healthy: true, Checks: []health.CheckResult{}},
+			expLive:  health.Result{Healthy: true, Checks: []health.CheckResult{}},
+		},
+		"A failing check should make the result not healthy and report every check.": {
+			register: func(r health.Registerer) {
+				r.RegisterReadiness("check0", ok)
+				r.RegisterReadiness("check1", failing)
+				r.RegisterLiveness("check2", ok)
+			},
+			expReady: health.Result{Healthy: false, Checks: []health.CheckResult{
+				{Name: "check0", Healthy: true},
+				{Name: "check1", Healthy: false, Error: "wanted error"},
+			}},
+			expLive: health.Result{Healthy: true, Checks: []health.CheckResult{
+				{Name: "check2", Healthy: true},
+			}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			checks := health.NewChecks()
+			test.register(checks)
+
+			assert.Equal(test.expReady, checks.Readiness(context.Background()))
+			assert.Equal(test.expLive, checks.Liveness(context.Background()))
+		})
+	}
+}
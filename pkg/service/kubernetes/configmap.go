@@ -0,0 +1,48 @@
+package kubernetes
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/spotahome/service-level-operator/pkg/log"
+)
+
+// ConfigMap knows how to interact with Kubernetes on the
+// configmaps.
+type ConfigMap interface {
+	// GetConfigMap will get a configmap.
+	GetConfigMap(namespace, name string) (*corev1.ConfigMap, error)
+	// CreateConfigMap will create a configmap.
+	CreateConfigMap(namespace string, cm *corev1.ConfigMap) (*corev1.ConfigMap, error)
+	// UpdateConfigMap will update a configmap.
+	UpdateConfigMap(namespace string, cm *corev1.ConfigMap) (*corev1.ConfigMap, error)
+	// DeleteConfigMap will delete a configmap.
+	DeleteConfigMap(namespace, name string) error
+}
+
+type configMap struct {
+	cli    kubernetes.Interface
+	logger log.Logger
+}
+
+// NewConfigMap returns a new configmap service.
+func NewConfigMap(stdcli kubernetes.Interface, logger log.Logger) ConfigMap {
+	return &configMap{
+		cli:    stdcli,
+		logger: logger,
+	}
+}
+
+func (c *configMap) GetConfigMap(namespace, name string) (*corev1.ConfigMap, error) {
+	return c.cli.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+}
+func (c *configMap) CreateConfigMap(namespace string, cm *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	return c.cli.CoreV1().ConfigMaps(namespace).Create(cm)
+}
+func (c *configMap) UpdateConfigMap(namespace string, cm *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	return c.cli.CoreV1().ConfigMaps(namespace).Update(cm)
+}
+func (c *configMap) DeleteConfigMap(namespace, name string) error {
+	return c.cli.CoreV1().ConfigMaps(namespace).Delete(name, &metav1.DeleteOptions{})
+}
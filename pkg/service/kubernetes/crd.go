@@ -1,8 +1,12 @@
 package kubernetes
 
 import (
+	"fmt"
+
 	koopercrd "github.com/spotahome/kooper/client/crd"
 	apiextensionscli "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
 
 	"github.com/spotahome/service-level-operator/pkg/log"
 )
@@ -15,12 +19,17 @@ type CRD interface {
 	// EnsurePresentCRD will create the custom resource and wait to be ready
 	// if there is not already present.
 	EnsurePresentCRD(conf CRDConf) error
+	// CheckPresentCRD will check the custom resource is present and its version
+	// served without creating or updating it. It uses the discovery API so it
+	// doesn't require permissions on the CRDs.
+	CheckPresentCRD(conf CRDConf) error
 }
 
 // crdService is the CRD service implementation using API calls to kubernetes.
 type crd struct {
-	crdCli koopercrd.Interface
-	logger log.Logger
+	crdCli    koopercrd.Interface
+	discovery discovery.DiscoveryInterface
+	logger    log.Logger
 }
 
 // NewCRD returns a new CRD KubeService.
@@ -29,8 +38,9 @@ func NewCRD(aeClient apiextensionscli.Interface, logger log.Logger) CRD {
 	crdCli := koopercrd.NewClient(aeClient, logger)
 
 	return &crd{
-		crdCli: crdCli,
-		logger: logger,
+		crdCli:    crdCli,
+		discovery: aeClient.Discovery(),
+		logger:    logger,
 	}
 }
 
@@ -38,3 +48,69 @@ func NewCRD(aeClient apiextensionscli.Interface, logger log.Logger) CRD {
 func (c *crd) EnsurePresentCRD(conf CRDConf) error {
 	return c.crdCli.EnsurePresent(conf)
 }
+
+// CheckPresentCRD satisfies workspace.Service interface.
+func (c *crd) CheckPresentCRD(conf CRDConf) error {
+	name := fmt.Sprintf("%s.%s", conf.NamePlural, conf.Group)
+
+	groups, err := c.discovery.ServerGroups()
+	if err != nil {
+		return fmt.Errorf("could not discover the cluster API groups: %s", err)
+	}
+
+	var served []string
+	for _, g := range groups.Groups {
+		if g.Name != conf.Group {
+			continue
+		}
+		for _, v := range g.Versions {
+			served = append(served, v.Version)
+		}
+	}
+	if len(served) == 0 {
+		return fmt.Errorf("crd %s is not present on the cluster, it needs to be registered before running the operator", name)
+	}
+
+	gv := schema.GroupVersion{Group: conf.Group, Version: conf.Version}.String()
+	found := false
+	for _, v := range served {
+		if v == conf.Version {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("crd %s version %s is not served, the served versions are: %v", name, conf.Version, served)
+	}
+
+	resources, err := c.discovery.ServerResourcesForGroupVersion(gv)
+	if err != nil {
+		return fmt.Errorf("could not discover %s resources: %s", gv, err)
+	}
+
+	var present, statusPresent bool
+	for _, r := range resources.APIResources {
+		switch r.Name {
+		case conf.NamePlural:
+			if r.Kind != conf.Kind {
+				return fmt.Errorf("crd %s has %s kind, expected %s", name, r.Kind, conf.Kind)
+			}
+			if r.Namespaced != (conf.Scope == koopercrd.NamespaceScoped) {
+				return fmt.Errorf("crd %s has the wrong scope, expected %s", name, conf.Scope)
+			}
+			present = true
+		case conf.NamePlural + "/status":
+			statusPresent = true
+		}
+	}
+	if !present {
+		return fmt.Errorf("crd %s is not present on %s, it needs to be registered before running the operator", name, gv)
+	}
+	if conf.EnableStatusSubresource && !statusPresent {
+		return fmt.Errorf("crd %s doesn't have the status subresource enabled", name)
+	}
+
+	c.logger.Infof("crd %s present with served versions: %v", name, served)
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package kubernetes_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiextensionsclifake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/kubernetes"
+)
+
+func TestCRDCheckPresentCRD(t *testing.T) {
+	conf := kubernetes.CRDConf{
+		Kind:                    monitoringv1alpha1.ServiceLevelKind,
+		NamePlural:              monitoringv1alpha1.ServiceLevelNamePlural,
+		Group:                   "monitoring.spotahome.com",
+		Version:                 "v1alpha1",
+		Scope:                   monitoringv1alpha1.ServiceLevelScope,
+		EnableStatusSubresource: true,
+	}
+
+	tests := map[string]struct {
+		resources []*metav1.APIResourceList
+		expErr    bool
+	}{
+		"A present and served CRD should not fail.": {
+			resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "monitoring.spotahome.com/v1alpha1",
+					APIResources: []metav1.APIResource{
+						{Name: "servicelevels", Kind: "ServiceLevel", Namespaced: true},
+						{Name: "servicelevels/status", Kind: "ServiceLevel", Namespaced: true},
+					},
+				},
+			},
+		},
+
+		"A missing CRD should fail.": {
+			resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "apps/v1",
+					APIResources: []metav1.APIResource{
+						{Name: "deployments", Kind: "Deployment", Namespaced: true},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"A CRD without the required version served should fail.": {
+			resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "monitoring.spotahome.com/v1",
+					APIResources: []metav1.APIResource{
+						{Name: "servicelevels", Kind: "ServiceLevel", Namespaced: true},
+						{Name: "servicelevels/status", Kind: "ServiceLevel", Namespaced: true},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"A CRD without the required resource on the group should fail.": {
+			resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "monitoring.spotahome.com/v1alpha1",
+					APIResources: []metav1.APIResource{
+						{Name: "otherlevels", Kind: "OtherLevel", Namespaced: true},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"A CRD with the wrong scope should fail.": {
+			resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "monitoring.spotahome.com/v1alpha1",
+					APIResources: []metav1.APIResource{
+						{Name: "servicelevels", Kind: "ServiceLevel", Namespaced: false},
+						{Name: "servicelevels/status", Kind: "ServiceLevel", Namespaced: false},
+					},
+				},
+			},
+			expErr: true,
+		},
+
+		"A CRD without the status subresource should fail.": {
+			resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "monitoring.spotahome.com/v1alpha1",
+					APIResources: []metav1.APIResource{
+						{Name: "servicelevels", Kind: "ServiceLevel", Namespaced: true},
+					},
+				},
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			cli := apiextensionsclifake.NewSimpleClientset()
+			cli.Discovery().(*fakediscovery.FakeDiscovery).Resources = test.resources
+
+			svc := kubernetes.NewCRD(cli, log.Dummy)
+			err := svc.CheckPresentCRD(conf)
+
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+
+			// The CRDs should never be created or updated.
+			for _, action := range cli.Actions() {
+				assert.Equal("get", action.GetVerb())
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package kubernetes
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+)
+
+const eventComponent = "service-level-operator"
+
+// Event knows how to interact with Kubernetes on the
+// events of the service levels.
+type Event interface {
+	// RecordEvent will record an event on a service level, the event type is
+	// Normal or Warning.
+	RecordEvent(sl *monitoringv1alpha1.ServiceLevel, eventType, reason, message string)
+}
+
+type event struct {
+	recorder record.EventRecorder
+	logger   log.Logger
+}
+
+// NewEvent returns a new event service. The events are recorded asynchronously,
+// the repeated events of a service level are aggregated by the client before
+// being sent to Kubernetes.
+func NewEvent(stdcli kubernetes.Interface, logger log.Logger) Event {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(eventSink{cli: stdcli})
+
+	return &event{
+		recorder: broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: eventComponent}),
+		logger:   logger,
+	}
+}
+
+func (e *event) RecordEvent(sl *monitoringv1alpha1.ServiceLevel, eventType, reason, message string) {
+	// The service levels from the informers don't have the type meta, and the
+	// self link may be empty, so the reference is not inferred from the object.
+	ref := &corev1.ObjectReference{
+		Kind:            monitoringv1alpha1.ServiceLevelKind,
+		APIVersion:      monitoringv1alpha1.SchemeGroupVersion.String(),
+		Namespace:       sl.Namespace,
+		Name:            sl.Name,
+		UID:             sl.UID,
+		ResourceVersion: sl.ResourceVersion,
+	}
+	e.recorder.Event(ref, eventType, reason, message)
+}
+
+// eventSink records the events with the client of their namespace.
+type eventSink struct {
+	cli kubernetes.Interface
+}
+
+func (e eventSink) sink(ev *corev1.Event) record.EventSink {
+	return &typedcorev1.EventSinkImpl{Interface: e.cli.CoreV1().Events(ev.Namespace)}
+}
+
+func (e eventSink) Create(ev *corev1.Event) (*corev1.Event, error) {
+	return e.sink(ev).Create(ev)
+}
+
+func (e eventSink) Update(ev *corev1.Event) (*corev1.Event, error) {
+	return e.sink(ev).Update(ev)
+}
+
+func (e eventSink) Patch(ev *corev1.Event, data []byte) (*corev1.Event, error) {
+	return e.sink(ev).Patch(ev, data)
+}
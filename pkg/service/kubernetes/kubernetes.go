@@ -12,11 +12,19 @@ import (
 // objects.
 type Service interface {
 	ServiceLevel
+	Namespace
+	ConfigMap
+	Secret
+	Event
 	CRD
 }
 
 type service struct {
 	ServiceLevel
+	Namespace
+	ConfigMap
+	Secret
+	Event
 	CRD
 }
 
@@ -24,6 +32,10 @@ type service struct {
 func New(stdcli kubernetes.Interface, crdcli crdcli.Interface, apiextcli apiextensionscli.Interface, logger log.Logger) Service {
 	return &service{
 		ServiceLevel: NewServiceLevel(crdcli, logger),
+		Namespace:    NewNamespace(stdcli, logger),
+		ConfigMap:    NewConfigMap(stdcli, logger),
+		Secret:       NewSecret(stdcli, logger),
+		Event:        NewEvent(stdcli, logger),
 		CRD:          NewCRD(apiextcli, logger),
 	}
 }
@@ -0,0 +1,39 @@
+package kubernetes
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/spotahome/service-level-operator/pkg/log"
+)
+
+// Namespace knows how to interact with Kubernetes on the
+// namespaces.
+type Namespace interface {
+	// ListNamespaces will list the namespaces.
+	ListNamespaces(opts metav1.ListOptions) (*corev1.NamespaceList, error)
+	// WatchNamespaces will watch the namespaces.
+	WatchNamespaces(opts metav1.ListOptions) (watch.Interface, error)
+}
+
+type namespace struct {
+	cli    kubernetes.Interface
+	logger log.Logger
+}
+
+// NewNamespace returns a new namespace service.
+func NewNamespace(stdcli kubernetes.Interface, logger log.Logger) Namespace {
+	return &namespace{
+		cli:    stdcli,
+		logger: logger,
+	}
+}
+
+func (n *namespace) ListNamespaces(opts metav1.ListOptions) (*corev1.NamespaceList, error) {
+	return n.cli.CoreV1().Namespaces().List(opts)
+}
+func (n *namespace) WatchNamespaces(opts metav1.ListOptions) (watch.Interface, error) {
+	return n.cli.CoreV1().Namespaces().Watch(opts)
+}
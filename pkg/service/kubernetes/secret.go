@@ -0,0 +1,33 @@
+package kubernetes
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/spotahome/service-level-operator/pkg/log"
+)
+
+// Secret knows how to interact with Kubernetes on the
+// secrets.
+type Secret interface {
+	// GetSecret will get a secret.
+	GetSecret(namespace, name string) (*corev1.Secret, error)
+}
+
+type secret struct {
+	cli    kubernetes.Interface
+	logger log.Logger
+}
+
+// NewSecret returns a new secret service.
+func NewSecret(stdcli kubernetes.Interface, logger log.Logger) Secret {
+	return &secret{
+		cli:    stdcli,
+		logger: logger,
+	}
+}
+
+func (s *secret) GetSecret(namespace, name string) (*corev1.Secret, error) {
+	return s.cli.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+}
@@ -0,0 +1,282 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql"
+	"sigs.k8s.io/yaml"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/service/gate"
+	"github.com/spotahome/service-level-operator/pkg/service/manifest"
+	"github.com/spotahome/service-level-operator/pkg/service/notify"
+)
+
+// Severity is the severity of a diagnostic.
+type Severity string
+
+const (
+	// SeverityError is a problem that makes the service level invalid or wrong.
+	SeverityError Severity = "error"
+	// SeverityWarning is a suspicious setting that may not be what is wanted.
+	SeverityWarning Severity = "warning"
+)
+
+// Rules of the diagnostics.
+const (
+	RuleRead          = "read"
+	RuleSchema        = "schema"
+	RuleValidation    = "validation"
+	RuleMetadata      = "metadata"
+	RuleSLOName       = "slo-name"
+	RuleDuplicatedSLO = "duplicated-slo"
+	RuleDuplicatedSL  = "duplicated-service-level"
+	RuleObjective     = "objective"
+	RuleQuery         = "query"
+	RuleOutputLabel   = "output-label"
+	RuleNotification  = "notification"
+	RuleGatePolicy    = "gate-policy"
+)
+
+const (
+	stdinPath           = "<stdin>"
+	maxObjectivePercent = 100
+)
+
+var sloNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// queryTypes are the names of the PromQL value types on the Prometheus docs.
+var queryTypes = map[promql.ValueType]string{
+	promql.ValueTypeScalar: "scalar",
+	promql.ValueTypeVector: "instant vector",
+	promql.ValueTypeMatrix: "range vector",
+	promql.ValueTypeString: "string",
+}
+
+// Diagnostic is a problem found on a service level manifest.
+type Diagnostic struct {
+	Path         string   `json:"path"`
+	Line         int      `json:"line"`
+	Severity     Severity `json:"severity"`
+	Rule         string   `json:"rule"`
+	ServiceLevel string   `json:"serviceLevel,omitempty"`
+	Message      string   `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s (%s)", d.Path, d.Line, d.Severity, d.Message, d.Rule)
+}
+
+// HasErrors returns true if any of the diagnostics is an error.
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadError returns the diagnostic of a file that can't be read.
+func ReadError(path string, err error) Diagnostic {
+	if path == "-" || path == "" {
+		path = stdinPath
+	}
+	line := 1
+	if derr, ok := err.(*manifest.DocumentError); ok {
+		line = derr.Document.Line
+		err = derr.Err
+	}
+	return Diagnostic{Path: path, Line: line, Severity: SeverityError, Rule: RuleRead, Message: err.Error()}
+}
+
+// Lint checks the service level documents and returns the problems found, sorted
+// by file and line.
+func Lint(docs []manifest.Document) []Diagnostic {
+	diags := []Diagnostic{}
+	seen := map[string]manifest.Document{}
+	for _, doc := range docs {
+		diags = append(diags, lintDocument(doc)...)
+
+		sl := doc.ServiceLevel
+		if sl.Name == "" {
+			continue
+		}
+		id := fmt.Sprintf("%s/%s", sl.Namespace, sl.Name)
+		if prev, ok := seen[id]; ok {
+			l := newLinter(doc)
+			l.errorf(RuleDuplicatedSL, []interface{}{"metadata", "name"}, "service level is duplicated, first defined at %s", prev.Position())
+			diags = append(diags, l.diags...)
+			continue
+		}
+		seen[id] = doc
+	}
+
+	Sort(diags)
+	return diags
+}
+
+// Sort sorts the diagnostics by file and line.
+func Sort(diags []Diagnostic) {
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].Path != diags[j].Path {
+			return diags[i].Path < diags[j].Path
+		}
+		return diags[i].Line < diags[j].Line
+	})
+}
+
+type linter struct {
+	doc   manifest.Document
+	path  string
+	sl    string
+	diags []Diagnostic
+}
+
+func newLinter(doc manifest.Document) *linter {
+	path := doc.Path
+	if path == "" {
+		path = stdinPath
+	}
+	sl := doc.ServiceLevel.Name
+	if ns := doc.ServiceLevel.Namespace; ns != "" && sl != "" {
+		sl = ns + "/" + sl
+	}
+	return &linter{doc: doc, path: path, sl: sl}
+}
+
+func (l *linter) add(sev Severity, rule string, line int, format string, args ...interface{}) {
+	l.diags = append(l.diags, Diagnostic{
+		Path:         l.path,
+		Line:         line,
+		Severity:     sev,
+		Rule:         rule,
+		ServiceLevel: l.sl,
+		Message:      fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) errorf(rule string, field []interface{}, format string, args ...interface{}) {
+	l.add(SeverityError, rule, l.doc.ValueLine(field...), format, args...)
+}
+
+func (l *linter) warnf(rule string, field []interface{}, format string, args ...interface{}) {
+	l.add(SeverityWarning, rule, l.doc.ValueLine(field...), format, args...)
+}
+
+func lintDocument(doc manifest.Document) []Diagnostic {
+	l := newLinter(doc)
+	sl := doc.ServiceLevel
+
+	// Unknown fields are ignored by the API, most of the times they are typos.
+	if err := yaml.UnmarshalStrict(doc.Raw, &monitoringv1alpha1.ServiceLevel{}); err != nil {
+		msg := err.Error()
+		for _, prefix := range []string{"error unmarshaling JSON: ", "while decoding JSON: ", "json: "} {
+			msg = strings.TrimPrefix(msg, prefix)
+		}
+		l.errorf(RuleSchema, nil, "%s", msg)
+	}
+
+	if sl.Name == "" {
+		l.errorf(RuleMetadata, []interface{}{"metadata"}, "service level must have a name")
+	}
+
+	// The same validation the operator does.
+	if err := sl.Validate(); err != nil {
+		l.errorf(RuleValidation, nil, "%s", err)
+	}
+
+	names := map[string]bool{}
+	for i, slo := range sl.Spec.ServiceLevelObjectives {
+		sloPath := func(p ...interface{}) []interface{} {
+			return append([]interface{}{"spec", "serviceLevelObjectives", i}, p...)
+		}
+
+		if slo.Name != "" && !sloNameRegexp.MatchString(slo.Name) {
+			l.errorf(RuleSLOName, sloPath("name"), "%q SLO name must be made of [a-zA-Z0-9] and '_' (underscore) characters", slo.Name)
+		}
+		if slo.Name != "" && names[slo.Name] {
+			l.errorf(RuleDuplicatedSLO, sloPath("name"), "%q SLO is duplicated", slo.Name)
+		}
+		names[slo.Name] = true
+
+		obj := slo.AvailabilityObjectivePercent
+		switch {
+		case obj < 0 || obj > maxObjectivePercent:
+			l.errorf(RuleObjective, sloPath("availabilityObjectivePercent"), "%q SLO availability objective must be between 0 and 100, got %g", slo.Name, obj)
+		case obj == maxObjectivePercent:
+			l.warnf(RuleObjective, sloPath("availabilityObjectivePercent"), "%q SLO availability objective of 100%% doesn't have error budget", slo.Name)
+		}
+
+		if prom := slo.ServiceLevelIndicator.Prometheus; prom != nil {
+			l.lintQuery(slo.Name, "total query", prom.TotalQuery, sloPath("serviceLevelIndicator", "prometheus", "totalQuery"))
+			l.lintQuery(slo.Name, "error query", prom.ErrorQuery, sloPath("serviceLevelIndicator", "prometheus", "errorQuery"))
+		}
+
+		if out := slo.Output.Prometheus; out != nil {
+			for name := range out.Labels {
+				labelPath := sloPath("output", "prometheus", "labels", name)
+				switch {
+				case !model.LabelName(name).IsValid():
+					l.errorf(RuleOutputLabel, labelPath, "%q SLO output label name %q is not a valid Prometheus label name", slo.Name, name)
+				case strings.HasPrefix(name, model.ReservedLabelPrefix):
+					l.errorf(RuleOutputLabel, labelPath, "%q SLO output label name %q is reserved for internal use", slo.Name, name)
+				case monitoringv1alpha1.ReservedOutputLabels[name]:
+					l.errorf(RuleOutputLabel, labelPath, "%q SLO output label name %q is already set by the operator", slo.Name, name)
+				}
+			}
+		}
+	}
+
+	for i, n := range sl.Spec.Notifications {
+		if n.Webhook == nil || n.Webhook.Template == "" {
+			continue
+		}
+		if _, err := notify.ParseTemplate(n.Webhook.Template); err != nil {
+			l.errorf(RuleNotification, []interface{}{"spec", "notifications", i, "webhook", "template"}, "%q notification template is not valid: %s", n.Name, err)
+		}
+	}
+
+	if _, ok := sl.Annotations[gate.AnnotationPolicy]; ok {
+		policyPath := []interface{}{"metadata", "annotations", gate.AnnotationPolicy}
+		p, err := gate.Policy{}.PolicyOf(sl.Annotations)
+		if err != nil {
+			l.errorf(RuleGatePolicy, policyPath, "%s", err)
+		}
+		for name := range p.SLOs {
+			if !names[name] {
+				l.warnf(RuleGatePolicy, policyPath, "gate policy %q SLO is not an SLO of the service level", name)
+			}
+		}
+	}
+
+	return l.diags
+}
+
+// lintQuery checks the query is valid PromQL and returns a single value.
+func (l *linter) lintQuery(slo, desc, query string, field []interface{}) {
+	if strings.TrimSpace(query) == "" {
+		l.errorf(RuleQuery, field, "%q SLO %s is missing", slo, desc)
+		return
+	}
+
+	expr, err := promql.ParseExpr(query)
+	if err != nil {
+		line := l.doc.ValueLine(field...)
+		// The queries with new lines are literal block scalars, these keep the
+		// query lines on the file. The Prometheus parser sets the line (starting
+		// at 1) only on the multiline queries.
+		if perr, ok := err.(*promql.ParseErr); ok && perr.Line > 0 {
+			line += perr.Line - 1
+		}
+		l.add(SeverityError, RuleQuery, line, "%q SLO %s is not valid PromQL: %s", slo, desc, err)
+		return
+	}
+	if typ := expr.Type(); typ != promql.ValueTypeVector && typ != promql.ValueTypeScalar {
+		l.errorf(RuleQuery, field, "%q SLO %s must return an instant vector or a scalar, got %s", slo, desc, queryTypes[typ])
+	}
+}
@@ -0,0 +1,152 @@
+package lint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spotahome/service-level-operator/pkg/service/lint"
+	"github.com/spotahome/service-level-operator/pkg/service/manifest"
+)
+
+const validSL = `apiVersion: monitoring.spotahome.com/v1alpha1
+kind: ServiceLevel
+metadata:
+  name: sl0
+  namespace: ns0
+spec:
+  serviceLevelObjectives:
+    - name: "9999_http_request_lt_500"
+      availabilityObjectivePercent: 99.99
+      serviceLevelIndicator:
+        prometheus:
+          address: http://127.0.0.1:9090
+          totalQuery: sum(increase(http_request_total[2m]))
+          errorQuery: |
+            sum(
+              increase(http_request_total{code=~"5.."}[2m])
+            )
+      output:
+        prometheus:
+          labels:
+            team: a-team
+`
+
+func TestLint(t *testing.T) {
+	tests := map[string]struct {
+		manifest string
+		expDiags []string
+		expErr   bool
+	}{
+		"A valid service level shouldn't have diagnostics.": {
+			manifest: validSL,
+			expDiags: []string{},
+		},
+		"Invalid PromQL should be reported on the line of the query.": {
+			manifest: strings.Replace(validSL, "increase(http_request_total{code", "increase(http_request_total{code=", 1),
+			expDiags: []string{
+				`test.yaml:16: error: "9999_http_request_lt_500" SLO error query is not valid PromQL: 3:37: parse error: unexpected <op:=~> in label matching, expected string (query)`,
+			},
+			expErr: true,
+		},
+		"Queries that don't return a single value per series should be reported.": {
+			manifest: strings.Replace(validSL, "totalQuery: sum(increase(http_request_total[2m]))", "totalQuery: http_request_total[2m]", 1),
+			expDiags: []string{
+				`test.yaml:13: error: "9999_http_request_lt_500" SLO total query must return an instant vector or a scalar, got range vector (query)`,
+			},
+			expErr: true,
+		},
+		"Invalid SLO names, objectives and labels should be reported.": {
+			manifest: strings.NewReplacer(
+				`"9999_http_request_lt_500"`, `"99.99-http"`,
+				"99.99", "100",
+				"team: a-team", "slo: test",
+			).Replace(validSL),
+			expDiags: []string{
+				`test.yaml:1: error: the 99.99-http SLO output label "slo" is already set by the operator (validation)`,
+				`test.yaml:8: error: "99.99-http" SLO name must be made of [a-zA-Z0-9] and '_' (underscore) characters (slo-name)`,
+				`test.yaml:9: warning: "99.99-http" SLO availability objective of 100% doesn't have error budget (objective)`,
+				`test.yaml:21: error: "99.99-http" SLO output label name "slo" is already set by the operator (output-label)`,
+			},
+			expErr: true,
+		},
+		"Unknown fields and API validation errors should be reported.": {
+			manifest: strings.Replace(validSL, "      output:\n        prometheus:\n          labels:\n            team: a-team\n", "      outputs: {}\n", 1),
+			expDiags: []string{
+				`test.yaml:1: error: unknown field "outputs" (schema)`,
+				`test.yaml:1: error: the 9999_http_request_lt_500 SLO must have at least one output source (validation)`,
+			},
+			expErr: true,
+		},
+		"Duplicated SLOs and service levels should be reported.": {
+			manifest: strings.Replace(validSL, "            team: a-team\n", "            team: a-team\n"+`    - name: "9999_http_request_lt_500"
+      availabilityObjectivePercent: 99
+      serviceLevelIndicator:
+        prometheus:
+          totalQuery: vector(1)
+          errorQuery: vector(0)
+      output:
+        prometheus: {}
+`, 1) + "---\n" + validSL,
+			expDiags: []string{
+				`test.yaml:22: error: "9999_http_request_lt_500" SLO is duplicated (duplicated-slo)`,
+				`test.yaml:34: error: service level is duplicated, first defined at test.yaml:1 (duplicated-service-level)`,
+			},
+			expErr: true,
+		},
+		"Invalid notification templates should be reported.": {
+			manifest: validSL + `  notifications:
+    - name: chat
+      webhook:
+        urlSecretRef:
+          name: chat-webhook
+          key: url
+        template: '{"text": {{ .Summary }'
+`,
+			expDiags: []string{
+				`test.yaml:1: error: the chat notification webhook template is not valid: template: message:1: unexpected "}" in operand (validation)`,
+				`test.yaml:28: error: "chat" notification template is not valid: template: message:1: unexpected "}" in operand (notification)`,
+			},
+			expErr: true,
+		},
+		"Invalid gate policy annotations should be reported.": {
+			manifest: strings.Replace(validSL, "  namespace: ns0\n", `  namespace: ns0
+  annotations:
+    service-level-operator.spotahome.com/gate-policy: '{"minErrorBudgetPercent": 120}'
+`, 1),
+			expDiags: []string{
+				`test.yaml:7: error: invalid service-level-operator.spotahome.com/gate-policy annotation: the min error budget percent must be between 0 and 100 (gate-policy)`,
+			},
+			expErr: true,
+		},
+		"Gate policies of unknown SLOs should be warned.": {
+			manifest: strings.Replace(validSL, "  namespace: ns0\n", `  namespace: ns0
+  annotations:
+    service-level-operator.spotahome.com/gate-policy: '{"slos": {"unknown": {"ignore": true}}}'
+`, 1),
+			expDiags: []string{
+				`test.yaml:7: warning: gate policy "unknown" SLO is not an SLO of the service level (gate-policy)`,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			docs, err := manifest.Read(strings.NewReader(test.manifest), "test.yaml")
+			require.NoError(err)
+
+			diags := lint.Lint(docs)
+			got := []string{}
+			for _, d := range diags {
+				got = append(got, d.String())
+			}
+			assert.Equal(test.expDiags, got)
+			assert.Equal(test.expErr, lint.HasErrors(diags))
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+	"sigs.k8s.io/yaml"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+// Document is a service level manifest document.
+type Document struct {
+	// Path is the path of the file that has the document, empty if read from stdin.
+	Path string
+	// Line is the line of the file where the document starts.
+	Line int
+	// Raw is the raw YAML of the document.
+	Raw []byte
+	// ServiceLevel is the service level of the document.
+	ServiceLevel *monitoringv1alpha1.ServiceLevel
+}
+
+// Position returns the file and line of the document.
+func (d Document) Position() string {
+	path := d.Path
+	if path == "" {
+		path = "<stdin>"
+	}
+	return fmt.Sprintf("%s:%d", path, d.Line)
+}
+
+// DocumentError is an error of a document that can't be read.
+type DocumentError struct {
+	Document Document
+	Err      error
+}
+
+func (d *DocumentError) Error() string {
+	return fmt.Sprintf("%s: %s", d.Document.Position(), d.Err)
+}
+
+// ValueLine returns the line of the file where the value of a field starts, the
+// field is identified by its path of map keys (string) and list indexes (int).
+// For the block scalars (e.g. multiline queries) it's the line where the content
+// starts. If the field is not found it returns the line of the document.
+func (d Document) ValueLine(path ...interface{}) int {
+	root := &yamlv3.Node{}
+	if err := yamlv3.Unmarshal(d.Raw, root); err != nil || len(root.Content) == 0 {
+		return d.Line
+	}
+
+	node := root.Content[0]
+	for _, p := range path {
+		var next *yamlv3.Node
+		switch p := p.(type) {
+		case string:
+			if node.Kind != yamlv3.MappingNode {
+				return d.Line
+			}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == p {
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case int:
+			if node.Kind == yamlv3.SequenceNode && p >= 0 && p < len(node.Content) {
+				next = node.Content[p]
+			}
+		}
+		if next == nil {
+			return d.Line
+		}
+		node = next
+	}
+
+	line := d.Line + node.Line - 1
+	if node.Style&(yamlv3.LiteralStyle|yamlv3.FoldedStyle) != 0 {
+		line++
+	}
+	return line
+}
+
+// Read reads the service levels of a (multi document) YAML or JSON stream, the
+// path is used to identify the documents. The empty documents are ignored and the
+// documents that are not service levels are an error.
+func Read(r io.Reader, path string) ([]Document, error) {
+	raws, err := splitDocuments(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []Document
+	for _, raw := range raws {
+		if isEmptyDocument(raw.data) {
+			continue
+		}
+
+		doc := Document{Path: path, Line: raw.line, Raw: raw.data}
+		sl := &monitoringv1alpha1.ServiceLevel{}
+		err := yaml.Unmarshal(raw.data, sl)
+		if err != nil {
+			return nil, &DocumentError{Document: doc, Err: err}
+		}
+		if sl.Kind != "" && sl.Kind != monitoringv1alpha1.ServiceLevelKind {
+			return nil, &DocumentError{Document: doc, Err: fmt.Errorf("unsupported %q kind, should be %q", sl.Kind, monitoringv1alpha1.ServiceLevelKind)}
+		}
+		doc.ServiceLevel = sl
+		docs = append(docs, doc)
+	}
+
+	return docs, nil
+}
+
+// ReadFiles reads the service levels of the files, the directories are read
+// recursively (only the YAML and JSON files). "-" reads from stdin.
+func ReadFiles(paths ...string) ([]Document, error) {
+	files, err := Files(paths...)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []Document
+	for _, file := range files {
+		d, err := ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, d...)
+	}
+
+	return docs, nil
+}
+
+// Files returns the manifest files of the paths, the directories are walked
+// recursively (only the YAML and JSON files). "-" is stdin.
+func Files(paths ...string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		if path == "-" {
+			files = append(files, path)
+			continue
+		}
+
+		err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// The explicit files are read whatever the extension is.
+			if info.IsDir() || (p != path && !isManifestFile(p)) {
+				return nil
+			}
+			files = append(files, p)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// ReadFile reads the service levels of a file, "-" reads from stdin.
+func ReadFile(path string) ([]Document, error) {
+	if path == "-" {
+		return Read(os.Stdin, "")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(f, path)
+}
+
+// ServiceLevels returns the service levels of the documents.
+func ServiceLevels(docs []Document) []*monitoringv1alpha1.ServiceLevel {
+	sls := make([]*monitoringv1alpha1.ServiceLevel, 0, len(docs))
+	for _, d := range docs {
+		sls = append(sls, d.ServiceLevel)
+	}
+	return sls
+}
+
+// Write writes the service levels as a multi document YAML stream.
+func Write(w io.Writer, sls []*monitoringv1alpha1.ServiceLevel) error {
+	objs := make([]interface{}, 0, len(sls))
+	for _, sl := range sls {
+		sl = sl.DeepCopy()
+		sl.APIVersion = monitoringv1alpha1.SchemeGroupVersion.String()
+		sl.Kind = monitoringv1alpha1.ServiceLevelKind
+		objs = append(objs, sl)
+	}
+	return WriteObjects(w, objs)
+}
+
+// WriteObjects writes Kubernetes objects as a multi document YAML stream, the
+// objects should have their type metadata set.
+func WriteObjects(w io.Writer, objs []interface{}) error {
+	for i, o := range objs {
+		// The metadata is marshaled without the server side fields (e.g. the
+		// null creation timestamp).
+		obj := map[string]interface{}{}
+		data, err := json.Marshal(o)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if md, ok := obj["metadata"].(map[string]interface{}); ok {
+			delete(md, "creationTimestamp")
+		}
+		data, err = yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isManifestFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+type rawDocument struct {
+	line int
+	data []byte
+}
+
+// splitDocuments splits a YAML stream in its documents.
+func splitDocuments(r io.Reader) ([]rawDocument, error) {
+	var docs []rawDocument
+	current := rawDocument{line: 1}
+	var b bytes.Buffer
+
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	line := 0
+	for s.Scan() {
+		line++
+		text := s.Text()
+		if isDocumentSeparator(text) {
+			current.data = append([]byte(nil), b.Bytes()...)
+			docs = append(docs, current)
+			current = rawDocument{line: line + 1}
+			b.Reset()
+			continue
+		}
+		b.WriteString(text)
+		b.WriteByte('\n')
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	current.data = b.Bytes()
+	docs = append(docs, current)
+
+	return docs, nil
+}
+
+func isDocumentSeparator(line string) bool {
+	if !strings.HasPrefix(line, "---") {
+		return false
+	}
+	rest := strings.TrimSpace(strings.TrimPrefix(line, "---"))
+	return rest == "" || strings.HasPrefix(rest, "#")
+}
+
+// isEmptyDocument returns true if the document only has comments and blank lines.
+func isEmptyDocument(data []byte) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+package manifest_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spotahome/service-level-operator/pkg/service/manifest"
+)
+
+func TestRead(t *testing.T) {
+	tests := map[string]struct {
+		manifest string
+		expNames []string
+		expLines []int
+		expErr   bool
+	}{
+		"Multiple documents should be read with their position.": {
+			manifest: `# Comment.
+---
+apiVersion: monitoring.spotahome.com/v1alpha1
+kind: ServiceLevel
+metadata:
+  name: sl0
+---
+# Only a comment.
+--- # Separator with comment.
+kind: ServiceLevel
+metadata:
+  name: sl1
+`,
+			expNames: []string{"sl0", "sl1"},
+			expLines: []int{3, 10},
+		},
+		"JSON documents should be read.": {
+			manifest: `{"kind": "ServiceLevel", "metadata": {"name": "sl0"}}`,
+			expNames: []string{"sl0"},
+			expLines: []int{1},
+		},
+		"Other kinds should fail.": {
+			manifest: `kind: Deployment`,
+			expErr:   true,
+		},
+		"Invalid documents should fail.": {
+			manifest: `kind: [ServiceLevel`,
+			expErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			docs, err := manifest.Read(strings.NewReader(test.manifest), "test.yaml")
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			if assert.NoError(err) {
+				var names []string
+				var lines []int
+				for _, d := range docs {
+					names = append(names, d.ServiceLevel.Name)
+					lines = append(lines, d.Line)
+				}
+				assert.Equal(test.expNames, names)
+				assert.Equal(test.expLines, lines)
+			}
+		})
+	}
+}
+
+func TestDocumentValueLine(t *testing.T) {
+	raw := `---
+kind: ServiceLevel
+metadata:
+  name: sl0
+spec:
+  serviceLevelObjectives:
+    - name: slo0
+    - name: slo1
+      serviceLevelIndicator:
+        prometheus:
+          totalQuery: |
+            sum(
+              rate(http_requests_total[5m])
+            )
+`
+	docs, err := manifest.Read(strings.NewReader(raw), "test.yaml")
+	if !assert.NoError(t, err) || !assert.Len(t, docs, 1) {
+		return
+	}
+	doc := docs[0]
+
+	tests := map[string]struct {
+		path    []interface{}
+		expLine int
+	}{
+		"A map field should return its line.":              {path: []interface{}{"metadata", "name"}, expLine: 4},
+		"A list item field should return its line.":        {path: []interface{}{"spec", "serviceLevelObjectives", 1, "name"}, expLine: 8},
+		"A block scalar should return its content line.":   {path: []interface{}{"spec", "serviceLevelObjectives", 1, "serviceLevelIndicator", "prometheus", "totalQuery"}, expLine: 12},
+		"A missing field should return the document line.": {path: []interface{}{"spec", "serviceLevelObjectives", 5}, expLine: 2},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expLine, doc.ValueLine(test.path...))
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	raw := `apiVersion: monitoring.spotahome.com/v1alpha1
+kind: ServiceLevel
+metadata:
+  name: sl0
+  namespace: ns0
+spec:
+  serviceLevelObjectives:
+  - availabilityObjectivePercent: 99.9
+    name: slo0
+    output:
+      prometheus: {}
+    serviceLevelIndicator:
+      prometheus:
+        address: http://127.0.0.1:9090
+        errorQuery: sum(errors)
+        totalQuery: sum(total)
+`
+	docs, err := manifest.Read(strings.NewReader(raw+"---\n"+raw), "")
+	require.NoError(err)
+	sls := manifest.ServiceLevels(docs)
+	// The written service levels always have the type.
+	sls[1].Kind = ""
+
+	var b bytes.Buffer
+	require.NoError(manifest.Write(&b, sls))
+	assert.Equal(raw+"---\n"+raw, b.String())
+}
@@ -15,3 +15,11 @@ func (dummy) ObserveSLIRetrieveDuration(_ *monitoringv1alpha1.SLI, _ string, sta
 func (dummy) IncSLIRetrieveError(_ *monitoringv1alpha1.SLI, _ string)                             {}
 func (dummy) ObserveOuputCreateDuration(_ *monitoringv1alpha1.SLO, _ string, startTime time.Time) {}
 func (dummy) IncOuputCreateError(_ *monitoringv1alpha1.SLO, _ string)                             {}
+func (dummy) IncSLIRetrieveCircuitOpen(_ *monitoringv1alpha1.SLI, _ string)                       {}
+func (dummy) SetSLISourceCircuitState(_, _, _ string)                                             {}
+func (dummy) IncDefaultSLISourceReload(_ bool)                                                    {}
+func (dummy) ObserveSLOEvaluation(_ string, _ *monitoringv1alpha1.ServiceLevel, _ *monitoringv1alpha1.SLO, _ time.Time, _ error) {
+}
+func (dummy) SetSLOQueryResultSamples(_ string, _ *monitoringv1alpha1.ServiceLevel, _ *monitoringv1alpha1.SLO, _ string, _ int) {
+}
+func (dummy) IncNotificationDelivery(_, _ string, _ bool) {}
@@ -19,4 +19,17 @@ type Service interface {
 	ObserveOuputCreateDuration(slo *monitoringv1alpha1.SLO, kind string, startTime time.Time)
 	// IncOuputCreateError will increment the number of errors on the SLO output creation.
 	IncOuputCreateError(slo *monitoringv1alpha1.SLO, kind string)
+	// IncSLIRetrieveCircuitOpen will increment the number of SLI retrievals skipped because
+	// the SLI source circuit was open.
+	IncSLIRetrieveCircuitOpen(sli *monitoringv1alpha1.SLI, kind string)
+	// SetSLISourceCircuitState will set the current circuit breaker state of an SLI source.
+	SetSLISourceCircuitState(address, kind, state string)
+	// IncDefaultSLISourceReload will increment the number of default SLI source configuration reloads.
+	IncDefaultSLISourceReload(success bool)
+	// ObserveSLOEvaluation will monitor the evaluation of an SLO of a cluster, its duration and if it was successful.
+	ObserveSLOEvaluation(cluster string, sl *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO, startTime time.Time, err error)
+	// SetSLOQueryResultSamples will set the number of samples returned by an SLI query of an SLO of a cluster.
+	SetSLOQueryResultSamples(cluster string, sl *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO, query string, samples int)
+	// IncNotificationDelivery will increment the number of notification deliveries of an event.
+	IncNotificationDelivery(kind, event string, success bool)
 }
@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -8,27 +10,113 @@ import (
 )
 
 const (
-	promNamespace = "service_level"
-	promSubsystem = "processing"
+	promNamespace    = "service_level"
+	promSubsystem    = "processing"
+	promCfgSubsystem = "configuration"
+	promNotSubsystem = "notification"
+
+	defMaxSLOs           = 1000
+	defSLOExpireDuration = 10 * time.Minute
 )
 
 var (
 	buckets = prometheus.DefBuckets
 )
 
+// PrometheusCfg is the configuration of the Prometheus metrics service.
+type PrometheusCfg struct {
+	// SLOMetrics enables the per SLO metrics, these metrics have the namespace,
+	// service level and SLO labels.
+	SLOMetrics bool
+	// MaxSLOs is the maximum number of SLOs that will have per SLO metrics, the
+	// SLOs after this limit will not be measured, this caps the cardinality.
+	MaxSLOs int
+	// SLOExpireDuration is the time the metrics of an SLO will be removed if the
+	// SLO is not evaluated, this way deleted SLOs don't count on the limit.
+	SLOExpireDuration time.Duration
+}
+
+// Validate will validate the cfg setting safe defaults.
+func (p *PrometheusCfg) Validate() {
+	if p.MaxSLOs == 0 {
+		p.MaxSLOs = defMaxSLOs
+	}
+	if p.SLOExpireDuration == 0 {
+		p.SLOExpireDuration = defSLOExpireDuration
+	}
+}
+
+// sloKey identifies an SLO on the per SLO metrics.
+type sloKey struct {
+	cluster      string
+	namespace    string
+	serviceLevel string
+	slo          string
+}
+
+func newSLOKey(cluster string, sl *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO) sloKey {
+	return sloKey{
+		cluster:      cluster,
+		namespace:    sl.Namespace,
+		serviceLevel: sl.Name,
+		slo:          slo.Name,
+	}
+}
+
+func (s sloKey) labels() prometheus.Labels {
+	return prometheus.Labels{
+		"cluster":       s.cluster,
+		"namespace":     s.namespace,
+		"service_level": s.serviceLevel,
+		"slo":           s.slo,
+	}
+}
+
 type prometheusService struct {
+	cfg PrometheusCfg
+
 	sliRetrieveHistogram   *prometheus.HistogramVec
 	sliRetrieveErrCounter  *prometheus.CounterVec
 	outputCreateHistogram  *prometheus.HistogramVec
 	outputCreateErrCounter *prometheus.CounterVec
+	sliCircuitOpenCounter  *prometheus.CounterVec
+	sliCircuitStateGauge   *prometheus.GaugeVec
+	defSLISrcReloadCounter *prometheus.CounterVec
+	notDeliveryCounter     *prometheus.CounterVec
+
+	sloLastSuccessGauge    *prometheus.GaugeVec
+	sloConsecutiveErrGauge *prometheus.GaugeVec
+	sloEvaluationHistogram *prometheus.HistogramVec
+	sloQuerySamplesGauge   *prometheus.GaugeVec
+	sloDroppedCounter      prometheus.Counter
+
+	// slos has the last time each SLO was measured and its consecutive failures.
+	slosMu sync.Mutex
+	slos   map[sloKey]*sloState
+
+	// circuitStates has the last state set for each SLI source circuit,
+	// so it can be unset when the state changes.
+	circuitStatesMu sync.Mutex
+	circuitStates   map[[2]string]string
 
 	reg prometheus.Registerer
 }
 
+type sloState struct {
+	lastSeen            time.Time
+	consecutiveFailures float64
+	queries             map[string]struct{}
+}
+
 // NewPrometheus returns a new metrics.Service implementation that
 // knows how to monitor gusing Prometheus as backend.
-func NewPrometheus(reg prometheus.Registerer) Service {
+func NewPrometheus(cfg PrometheusCfg, reg prometheus.Registerer) Service {
+	cfg.Validate()
+
+	sloLabels := []string{"cluster", "namespace", "service_level", "slo"}
 	p := &prometheusService{
+		cfg: cfg,
+
 		sliRetrieveHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
 			Namespace: promNamespace,
 			Subsystem: promSubsystem,
@@ -59,6 +147,73 @@ func NewPrometheus(reg prometheus.Registerer) Service {
 			Help:      "Total number SLI and SLO output creation failures.",
 		}, []string{"kind"}),
 
+		sliCircuitOpenCounter: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: promNamespace,
+			Subsystem: promSubsystem,
+			Name:      "sli_retrieve_circuit_open_total",
+			Help:      "Total number of SLI retrievals skipped due to an open SLI source circuit.",
+		}, []string{"kind"}),
+
+		sliCircuitStateGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: promNamespace,
+			Subsystem: promSubsystem,
+			Name:      "sli_source_circuit_state",
+			Help:      "The circuit breaker state of the SLI sources, the current state has 1 as value.",
+		}, []string{"kind", "address", "state"}),
+
+		defSLISrcReloadCounter: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: promNamespace,
+			Subsystem: promCfgSubsystem,
+			Name:      "default_sli_source_reloads_total",
+			Help:      "Total number of default SLI source configuration reloads.",
+		}, []string{"success"}),
+
+		notDeliveryCounter: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: promNamespace,
+			Subsystem: promNotSubsystem,
+			Name:      "deliveries_total",
+			Help:      "Total number of notification deliveries of the SLO events.",
+		}, []string{"kind", "event", "success"}),
+
+		sloLastSuccessGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: promNamespace,
+			Subsystem: promSubsystem,
+			Name:      "slo_last_success_timestamp_seconds",
+			Help:      "The timestamp of the last successful evaluation of the SLO.",
+		}, sloLabels),
+
+		sloConsecutiveErrGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: promNamespace,
+			Subsystem: promSubsystem,
+			Name:      "slo_consecutive_failures",
+			Help:      "The number of consecutive failed evaluations of the SLO.",
+		}, sloLabels),
+
+		sloEvaluationHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: promNamespace,
+			Subsystem: promSubsystem,
+			Name:      "slo_evaluation_duration_seconds",
+			Help:      "The duration seconds to evaluate the SLO.",
+			Buckets:   buckets,
+		}, sloLabels),
+
+		sloQuerySamplesGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: promNamespace,
+			Subsystem: promSubsystem,
+			Name:      "slo_query_result_samples",
+			Help:      "The number of samples returned by the last SLI query of the SLO.",
+		}, append(sloLabels, "query")),
+
+		sloDroppedCounter: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: promNamespace,
+			Subsystem: promSubsystem,
+			Name:      "slo_metrics_dropped_total",
+			Help:      "Total number of SLO measurements not exposed because the maximum number of SLOs with metrics was reached.",
+		}),
+
+		circuitStates: map[[2]string]string{},
+		slos:          map[sloKey]*sloState{},
+
 		reg: reg,
 	}
 
@@ -67,31 +222,161 @@ func NewPrometheus(reg prometheus.Registerer) Service {
 	return p
 }
 
-func (p prometheusService) registerMetrics() {
+func (p *prometheusService) registerMetrics() {
 	p.reg.MustRegister(
 		p.sliRetrieveHistogram,
 		p.sliRetrieveErrCounter,
 		p.outputCreateHistogram,
 		p.outputCreateErrCounter,
+		p.sliCircuitOpenCounter,
+		p.sliCircuitStateGauge,
+		p.defSLISrcReloadCounter,
+		p.notDeliveryCounter,
 	)
+
+	if p.cfg.SLOMetrics {
+		p.reg.MustRegister(
+			p.sloLastSuccessGauge,
+			p.sloConsecutiveErrGauge,
+			p.sloEvaluationHistogram,
+			p.sloQuerySamplesGauge,
+			p.sloDroppedCounter,
+		)
+	}
 }
 
 // ObserveSLIRetrieveDuration satisfies metrics.Service interface.
-func (p prometheusService) ObserveSLIRetrieveDuration(_ *monitoringv1alpha1.SLI, kind string, startTime time.Time) {
+func (p *prometheusService) ObserveSLIRetrieveDuration(_ *monitoringv1alpha1.SLI, kind string, startTime time.Time) {
 	p.sliRetrieveHistogram.WithLabelValues(kind).Observe(time.Since(startTime).Seconds())
 }
 
 // IncSLIRetrieveError satisfies metrics.Service interface.
-func (p prometheusService) IncSLIRetrieveError(_ *monitoringv1alpha1.SLI, kind string) {
+func (p *prometheusService) IncSLIRetrieveError(_ *monitoringv1alpha1.SLI, kind string) {
 	p.sliRetrieveErrCounter.WithLabelValues(kind).Inc()
 }
 
 // ObserveOuputCreateDuration satisfies metrics.Service interface.
-func (p prometheusService) ObserveOuputCreateDuration(_ *monitoringv1alpha1.SLO, kind string, startTime time.Time) {
+func (p *prometheusService) ObserveOuputCreateDuration(_ *monitoringv1alpha1.SLO, kind string, startTime time.Time) {
 	p.outputCreateHistogram.WithLabelValues(kind).Observe(time.Since(startTime).Seconds())
 }
 
 // IncOuputCreateError satisfies metrics.Service interface.
-func (p prometheusService) IncOuputCreateError(_ *monitoringv1alpha1.SLO, kind string) {
+func (p *prometheusService) IncOuputCreateError(_ *monitoringv1alpha1.SLO, kind string) {
 	p.outputCreateErrCounter.WithLabelValues(kind).Inc()
 }
+
+// IncSLIRetrieveCircuitOpen satisfies metrics.Service interface.
+func (p *prometheusService) IncSLIRetrieveCircuitOpen(_ *monitoringv1alpha1.SLI, kind string) {
+	p.sliCircuitOpenCounter.WithLabelValues(kind).Inc()
+}
+
+// SetSLISourceCircuitState satisfies metrics.Service interface.
+func (p *prometheusService) SetSLISourceCircuitState(address, kind, state string) {
+	p.circuitStatesMu.Lock()
+	defer p.circuitStatesMu.Unlock()
+
+	key := [2]string{kind, address}
+	if prev, ok := p.circuitStates[key]; ok && prev != state {
+		p.sliCircuitStateGauge.WithLabelValues(kind, address, prev).Set(0)
+	}
+	p.circuitStates[key] = state
+	p.sliCircuitStateGauge.WithLabelValues(kind, address, state).Set(1)
+}
+
+// IncDefaultSLISourceReload satisfies metrics.Service interface.
+func (p *prometheusService) IncDefaultSLISourceReload(success bool) {
+	p.defSLISrcReloadCounter.WithLabelValues(strconv.FormatBool(success)).Inc()
+}
+
+// IncNotificationDelivery satisfies metrics.Service interface.
+func (p *prometheusService) IncNotificationDelivery(kind, event string, success bool) {
+	p.notDeliveryCounter.WithLabelValues(kind, event, strconv.FormatBool(success)).Inc()
+}
+
+// ObserveSLOEvaluation satisfies metrics.Service interface.
+func (p *prometheusService) ObserveSLOEvaluation(cluster string, sl *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO, startTime time.Time, err error) {
+	if !p.cfg.SLOMetrics {
+		return
+	}
+
+	p.slosMu.Lock()
+	defer p.slosMu.Unlock()
+
+	key := newSLOKey(cluster, sl, slo)
+	state, ok := p.trackSLO(key)
+	if !ok {
+		return
+	}
+
+	labels := key.labels()
+	p.sloEvaluationHistogram.With(labels).Observe(time.Since(startTime).Seconds())
+	if err != nil {
+		state.consecutiveFailures++
+	} else {
+		state.consecutiveFailures = 0
+		p.sloLastSuccessGauge.With(labels).Set(float64(time.Now().UnixNano()) / 1e9)
+	}
+	p.sloConsecutiveErrGauge.With(labels).Set(state.consecutiveFailures)
+}
+
+// SetSLOQueryResultSamples satisfies metrics.Service interface.
+func (p *prometheusService) SetSLOQueryResultSamples(cluster string, sl *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO, query string, samples int) {
+	if !p.cfg.SLOMetrics {
+		return
+	}
+
+	p.slosMu.Lock()
+	defer p.slosMu.Unlock()
+
+	key := newSLOKey(cluster, sl, slo)
+	state, ok := p.trackSLO(key)
+	if !ok {
+		return
+	}
+	state.queries[query] = struct{}{}
+
+	labels := key.labels()
+	labels["query"] = query
+	p.sloQuerySamplesGauge.With(labels).Set(float64(samples))
+}
+
+// trackSLO will track the SLO and return its state, it will return false if the
+// SLO can't be tracked because the maximum number of SLOs has been reached. The
+// SLOs that have not been measured for a while are removed first. Needs to be
+// called with the SLOs lock held.
+func (p *prometheusService) trackSLO(key sloKey) (*sloState, bool) {
+	now := time.Now()
+	if state, ok := p.slos[key]; ok {
+		state.lastSeen = now
+		return state, true
+	}
+
+	// Remove the metrics of the SLOs that are not being measured.
+	for k, state := range p.slos {
+		if now.Sub(state.lastSeen) > p.cfg.SLOExpireDuration {
+			p.deleteSLO(k)
+		}
+	}
+
+	if len(p.slos) >= p.cfg.MaxSLOs {
+		p.sloDroppedCounter.Inc()
+		return nil, false
+	}
+
+	state := &sloState{lastSeen: now, queries: map[string]struct{}{}}
+	p.slos[key] = state
+	return state, true
+}
+
+func (p *prometheusService) deleteSLO(key sloKey) {
+	state := p.slos[key]
+	delete(p.slos, key)
+	labels := key.labels()
+	p.sloLastSuccessGauge.Delete(labels)
+	p.sloConsecutiveErrGauge.Delete(labels)
+	p.sloEvaluationHistogram.Delete(labels)
+	for query := range state.queries {
+		labels["query"] = query
+		p.sloQuerySamplesGauge.Delete(labels)
+	}
+}
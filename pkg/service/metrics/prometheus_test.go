@@ -1,6 +1,7 @@
 package metrics_test
 
 import (
+	"errors"
 	"io/ioutil"
 	"net/http/httptest"
 	"testing"
@@ -9,18 +10,26 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
 	"github.com/spotahome/service-level-operator/pkg/service/metrics"
 )
 
 func TestPrometheusMetrics(t *testing.T) {
 	kind := "test"
+	sl0 := &monitoringv1alpha1.ServiceLevel{ObjectMeta: metav1.ObjectMeta{Namespace: "ns0", Name: "sl0"}}
+	sl1 := &monitoringv1alpha1.ServiceLevel{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "sl1"}}
+	slo0 := &monitoringv1alpha1.SLO{Name: "slo0"}
+	slo1 := &monitoringv1alpha1.SLO{Name: "slo1"}
 
 	tests := []struct {
-		name       string
-		addMetrics func(metrics.Service)
-		expMetrics []string
-		expCode    int
+		name          string
+		cfg           metrics.PrometheusCfg
+		addMetrics    func(metrics.Service)
+		expMetrics    []string
+		expNotMetrics []string
+		expCode       int
 	}{
 		{
 			name: "Measuring SLO realted metrics should expose SLO processing metrics on the prometheus endpoint.",
@@ -80,6 +89,122 @@ func TestPrometheusMetrics(t *testing.T) {
 			},
 			expCode: 200,
 		},
+		{
+			name: "Measuring SLI source circuit breaker related metrics should expose the circuit metrics on the prometheus endpoint.",
+			addMetrics: func(s metrics.Service) {
+				s.SetSLISourceCircuitState("http://prom0:9090", kind, "closed")
+				s.SetSLISourceCircuitState("http://prom0:9090", kind, "open")
+				s.SetSLISourceCircuitState("http://prom1:9090", kind, "closed")
+				s.IncSLIRetrieveCircuitOpen(nil, kind)
+				s.IncSLIRetrieveCircuitOpen(nil, kind)
+			},
+			expMetrics: []string{
+				`service_level_processing_sli_retrieve_circuit_open_total{kind="test"} 2`,
+				`service_level_processing_sli_source_circuit_state{address="http://prom0:9090",kind="test",state="closed"} 0`,
+				`service_level_processing_sli_source_circuit_state{address="http://prom0:9090",kind="test",state="open"} 1`,
+				`service_level_processing_sli_source_circuit_state{address="http://prom1:9090",kind="test",state="closed"} 1`,
+			},
+			expCode: 200,
+		},
+		{
+			name: "Measuring configuration related metrics should expose configuration metrics on the prometheus endpoint.",
+			addMetrics: func(s metrics.Service) {
+				s.IncDefaultSLISourceReload(true)
+				s.IncDefaultSLISourceReload(false)
+				s.IncDefaultSLISourceReload(true)
+			},
+			expMetrics: []string{
+				`service_level_configuration_default_sli_source_reloads_total{success="false"} 1`,
+				`service_level_configuration_default_sli_source_reloads_total{success="true"} 2`,
+			},
+			expCode: 200,
+		},
+		{
+			name: "Measuring notification deliveries should expose notification metrics on the prometheus endpoint.",
+			addMetrics: func(s metrics.Service) {
+				s.IncNotificationDelivery("webhook", "breached", true)
+				s.IncNotificationDelivery("webhook", "breached", false)
+				s.IncNotificationDelivery("webhook", "recovered", true)
+				s.IncNotificationDelivery("webhook", "breached", true)
+			},
+			expMetrics: []string{
+				`service_level_notification_deliveries_total{event="breached",kind="webhook",success="false"} 1`,
+				`service_level_notification_deliveries_total{event="breached",kind="webhook",success="true"} 2`,
+				`service_level_notification_deliveries_total{event="recovered",kind="webhook",success="true"} 1`,
+			},
+			expCode: 200,
+		},
+		{
+			name: "Measuring SLO evaluations without the SLO metrics enabled shouldn't expose the per SLO metrics.",
+			addMetrics: func(s metrics.Service) {
+				s.ObserveSLOEvaluation("", sl0, slo0, time.Now(), nil)
+				s.SetSLOQueryResultSamples("", sl0, slo0, "total", 1)
+			},
+			expNotMetrics: []string{
+				`service_level_processing_slo_`,
+			},
+			expCode: 200,
+		},
+		{
+			name: "Measuring SLO evaluations with the SLO metrics enabled should expose the per SLO metrics.",
+			cfg:  metrics.PrometheusCfg{SLOMetrics: true},
+			addMetrics: func(s metrics.Service) {
+				now := time.Now()
+				s.ObserveSLOEvaluation("", sl0, slo0, now.Add(-15*time.Millisecond), nil)
+				s.ObserveSLOEvaluation("", sl0, slo0, now.Add(-3*time.Second), errors.New("wanted error"))
+				s.ObserveSLOEvaluation("", sl0, slo0, now.Add(-3*time.Second), errors.New("wanted error"))
+				s.ObserveSLOEvaluation("cluster1", sl1, slo1, now, errors.New("wanted error"))
+				s.ObserveSLOEvaluation("cluster1", sl1, slo1, now, nil)
+				s.SetSLOQueryResultSamples("", sl0, slo0, "total", 1)
+				s.SetSLOQueryResultSamples("", sl0, slo0, "error", 0)
+			},
+			expMetrics: []string{
+				`service_level_processing_slo_consecutive_failures{cluster="",namespace="ns0",service_level="sl0",slo="slo0"} 2`,
+				`service_level_processing_slo_consecutive_failures{cluster="cluster1",namespace="ns1",service_level="sl1",slo="slo1"} 0`,
+				`service_level_processing_slo_evaluation_duration_seconds_bucket{cluster="",namespace="ns0",service_level="sl0",slo="slo0",le="0.025"} 1`,
+				`service_level_processing_slo_evaluation_duration_seconds_count{cluster="",namespace="ns0",service_level="sl0",slo="slo0"} 3`,
+				`service_level_processing_slo_last_success_timestamp_seconds{cluster="",namespace="ns0",service_level="sl0",slo="slo0"}`,
+				`service_level_processing_slo_last_success_timestamp_seconds{cluster="cluster1",namespace="ns1",service_level="sl1",slo="slo1"}`,
+				`service_level_processing_slo_query_result_samples{cluster="",namespace="ns0",query="error",service_level="sl0",slo="slo0"} 0`,
+				`service_level_processing_slo_query_result_samples{cluster="",namespace="ns0",query="total",service_level="sl0",slo="slo0"} 1`,
+			},
+			expCode: 200,
+		},
+		{
+			name: "Measuring more SLOs than the maximum should drop the measurements of the SLOs over the limit.",
+			cfg:  metrics.PrometheusCfg{SLOMetrics: true, MaxSLOs: 1},
+			addMetrics: func(s metrics.Service) {
+				s.ObserveSLOEvaluation("", sl0, slo0, time.Now(), nil)
+				s.ObserveSLOEvaluation("cluster1", sl1, slo1, time.Now(), nil)
+				s.SetSLOQueryResultSamples("cluster1", sl1, slo1, "total", 1)
+				s.ObserveSLOEvaluation("", sl0, slo0, time.Now(), nil)
+			},
+			expMetrics: []string{
+				`service_level_processing_slo_evaluation_duration_seconds_count{cluster="",namespace="ns0",service_level="sl0",slo="slo0"} 2`,
+				`service_level_processing_slo_metrics_dropped_total 2`,
+			},
+			expNotMetrics: []string{
+				`slo="slo1"`,
+			},
+			expCode: 200,
+		},
+		{
+			name: "SLOs not measured for a while should be removed and not count on the limit.",
+			cfg:  metrics.PrometheusCfg{SLOMetrics: true, MaxSLOs: 1, SLOExpireDuration: time.Millisecond},
+			addMetrics: func(s metrics.Service) {
+				s.ObserveSLOEvaluation("", sl0, slo0, time.Now(), nil)
+				time.Sleep(5 * time.Millisecond)
+				s.ObserveSLOEvaluation("cluster1", sl1, slo1, time.Now(), nil)
+			},
+			expMetrics: []string{
+				`service_level_processing_slo_evaluation_duration_seconds_count{cluster="cluster1",namespace="ns1",service_level="sl1",slo="slo1"} 1`,
+				`service_level_processing_slo_metrics_dropped_total 0`,
+			},
+			expNotMetrics: []string{
+				`slo="slo0"`,
+			},
+			expCode: 200,
+		},
 	}
 
 	for _, test := range tests {
@@ -87,7 +212,7 @@ func TestPrometheusMetrics(t *testing.T) {
 			assert := assert.New(t)
 
 			reg := prometheus.NewRegistry()
-			m := metrics.NewPrometheus(reg)
+			m := metrics.NewPrometheus(test.cfg, reg)
 
 			// Add desired metrics
 			test.addMetrics(m)
@@ -105,6 +230,9 @@ func TestPrometheusMetrics(t *testing.T) {
 				for _, expMetric := range test.expMetrics {
 					assert.Contains(string(body), expMetric, "metric not present on the result of metrics service")
 				}
+				for _, expNotMetric := range test.expNotMetrics {
+					assert.NotContains(string(body), expNotMetric, "metric present on the result of metrics service")
+				}
 			}
 		})
 	}
@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+)
+
+// Event is a state change of an SLO.
+type Event struct {
+	// Type is the type of the event (breached, recovered or budgetReset).
+	Type         string
+	Time         time.Time
+	Cluster      string
+	Namespace    string
+	ServiceLevel string
+	SLO          string
+	Description  string
+	// Labels are the output labels of the SLO.
+	Labels map[string]string
+	// ObjectiveRatio is the availability objective of the SLO.
+	ObjectiveRatio float64
+	// AvailabilityRatio is the availability of the SLO when the event happened.
+	AvailabilityRatio float64
+	// ErrorBudgetRemainingRatio is the remaining error budget of the SLO when
+	// the event happened, negative once it has been exhausted.
+	ErrorBudgetRemainingRatio float64
+}
+
+// ID returns the identifier of the SLO of the event.
+func (e Event) ID() string {
+	id := fmt.Sprintf("%s/%s/%s", e.Namespace, e.ServiceLevel, e.SLO)
+	if e.Cluster != "" {
+		id = e.Cluster + "/" + id
+	}
+	return id
+}
+
+// Summary returns a human readable summary of the event, used by the default
+// message templates.
+func (e Event) Summary() string {
+	switch e.Type {
+	case monitoringv1alpha1.NotificationEventBreached:
+		return fmt.Sprintf("SLO breached: %s availability is %s (objective %s), the error budget is exhausted.",
+			e.ID(), status.FormatPercent(e.AvailabilityRatio), status.FormatPercent(e.ObjectiveRatio))
+	case monitoringv1alpha1.NotificationEventRecovered:
+		return fmt.Sprintf("SLO recovered: %s availability is %s (objective %s), %s of the error budget remaining.",
+			e.ID(), status.FormatPercent(e.AvailabilityRatio), status.FormatPercent(e.ObjectiveRatio), status.FormatPercent(e.ErrorBudgetRemainingRatio))
+	case monitoringv1alpha1.NotificationEventBudgetReset:
+		return fmt.Sprintf("SLO error budget reset: %s has its whole error budget again.", e.ID())
+	}
+	return fmt.Sprintf("SLO %s: %s.", e.Type, e.ID())
+}
@@ -0,0 +1,381 @@
+package notify
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/metrics"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+)
+
+const (
+	defMinInterval   = 5 * time.Minute
+	defFlushInterval = 10 * time.Second
+	sendTimeout      = 10 * time.Second
+)
+
+// Sender knows how to send the SLO events of a notification.
+type Sender interface {
+	// Send sends an event to the notification destination.
+	Send(ctx context.Context, n *monitoringv1alpha1.Notification, e Event) error
+}
+
+// Cfg is the configuration of the notifier.
+type Cfg struct {
+	// FlushInterval is the interval the pending notifications are sent, by default 10s.
+	FlushInterval time.Duration
+}
+
+func (c *Cfg) defaults() {
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defFlushInterval
+	}
+}
+
+// sloState is the last known state of an SLO.
+type sloState struct {
+	serviceLevel string
+	breached     bool
+	count        float64
+}
+
+// channel is the state of a notification of an SLO.
+type channel struct {
+	serviceLevel string
+	slo          string
+	notification monitoringv1alpha1.Notification
+	// notified is the last notified state event, empty when the SLO has not been breached.
+	notified string
+	// state is the last state event not notified yet and reset the last budget
+	// reset not notified yet.
+	state       *Event
+	reset       *Event
+	lastAttempt time.Time
+	sending     bool
+}
+
+func (c *channel) minInterval() time.Duration {
+	if c.notification.MinInterval != nil {
+		return c.notification.MinInterval.Duration
+	}
+	return defMinInterval
+}
+
+func (c *channel) notifies(event string) bool {
+	if len(c.notification.Events) == 0 {
+		return true
+	}
+	for _, e := range c.notification.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Notifier is a status recorder middleware that notifies the state changes of
+// the SLOs (breached, recovered and error budget reset) to the notifications
+// of their service levels. The states are tracked from the output counters of
+// the recorded evaluations, an SLO is breached while its error budget is
+// exhausted.
+//
+// A notification of an SLO sends a message at most every min interval, the
+// state changes in between are coalesced so a flapping SLO only notifies its
+// state when it differs from the last notified one. The failed messages are
+// retried after the min interval.
+type Notifier struct {
+	status.Recorder
+	cfg        Cfg
+	sender     Sender
+	metricssvc metrics.Service
+	logger     log.Logger
+
+	mu       sync.Mutex
+	slos     map[string]*sloState
+	channels map[string]*channel
+}
+
+// NewNotifier returns a new notifier that wraps a status recorder.
+func NewNotifier(cfg Cfg, sender Sender, next status.Recorder, metricssvc metrics.Service, logger log.Logger) *Notifier {
+	cfg.defaults()
+	return &Notifier{
+		Recorder:   next,
+		cfg:        cfg,
+		sender:     sender,
+		metricssvc: metricssvc,
+		logger:     logger,
+		slos:       map[string]*sloState{},
+		channels:   map[string]*channel{},
+	}
+}
+
+// SetServiceLevel satisfies status.Recorder interface.
+func (n *Notifier) SetServiceLevel(cluster string, sl *monitoringv1alpha1.ServiceLevel) {
+	n.Recorder.SetServiceLevel(cluster, sl)
+
+	slKey := status.ServiceLevelKey(cluster, sl.Namespace, sl.Name)
+	enabled := map[string]bool{}
+	for i := range sl.Spec.ServiceLevelObjectives {
+		slo := &sl.Spec.ServiceLevelObjectives[i]
+		if !slo.Disable {
+			enabled[status.SLOID(cluster, sl, slo)] = true
+		}
+	}
+	notifications := map[string]monitoringv1alpha1.Notification{}
+	for _, nt := range sl.Spec.Notifications {
+		notifications[nt.Name] = nt
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	// The state of the removed and disabled SLOs, and the removed notifications is forgotten.
+	for id, st := range n.slos {
+		if st.serviceLevel == slKey && !enabled[id] {
+			delete(n.slos, id)
+		}
+	}
+	for key, ch := range n.channels {
+		if ch.serviceLevel != slKey {
+			continue
+		}
+		nt, ok := notifications[ch.notification.Name]
+		if !ok || !enabled[ch.slo] || !notifiesSLO(&nt, ch.slo, cluster, sl) {
+			delete(n.channels, key)
+			continue
+		}
+		ch.notification = *nt.DeepCopy()
+	}
+}
+
+// DeleteServiceLevel satisfies status.Recorder interface.
+func (n *Notifier) DeleteServiceLevel(cluster, namespace, name string) {
+	n.Recorder.DeleteServiceLevel(cluster, namespace, name)
+
+	slKey := status.ServiceLevelKey(cluster, namespace, name)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for id, st := range n.slos {
+		if st.serviceLevel == slKey {
+			delete(n.slos, id)
+		}
+	}
+	for key, ch := range n.channels {
+		if ch.serviceLevel == slKey {
+			delete(n.channels, key)
+		}
+	}
+}
+
+// RecordSLOEvaluation satisfies status.Recorder interface.
+func (n *Notifier) RecordSLOEvaluation(e status.SLOEvaluation) {
+	n.Recorder.RecordSLOEvaluation(e)
+
+	if e.Err != nil || e.Skipped || e.Counters == nil || e.SLO.Disable {
+		return
+	}
+	availability, remaining, ok := e.Counters.ErrorBudget()
+	if !ok {
+		return
+	}
+	sl, slo := e.ServiceLevel, e.SLO
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	// The SLOs start as not breached.
+	id := status.SLOID(e.Cluster, sl, slo)
+	st, ok := n.slos[id]
+	if !ok {
+		st = &sloState{}
+		n.slos[id] = st
+	}
+	st.serviceLevel = status.ServiceLevelKey(e.Cluster, sl.Namespace, sl.Name)
+
+	newEvent := func(typ string) *Event {
+		labels := map[string]string{}
+		if slo.Output.Prometheus != nil {
+			for k, v := range slo.Output.Prometheus.Labels {
+				labels[k] = v
+			}
+		}
+		return &Event{
+			Type:                      typ,
+			Time:                      e.Time,
+			Cluster:                   e.Cluster,
+			Namespace:                 sl.Namespace,
+			ServiceLevel:              sl.Name,
+			SLO:                       slo.Name,
+			Description:               slo.Description,
+			Labels:                    labels,
+			ObjectiveRatio:            e.Counters.Objective,
+			AvailabilityRatio:         availability,
+			ErrorBudgetRemainingRatio: remaining,
+		}
+	}
+
+	var reset, state *Event
+	// The output counters are reset when they expire (e.g. the SLO was not
+	// evaluated for a while), this resets the error budget.
+	if e.Counters.Count < st.count {
+		reset = newEvent(monitoringv1alpha1.NotificationEventBudgetReset)
+		st.breached = false
+	}
+	st.count = e.Counters.Count
+	breached := output.ErrorBudgetExhausted(remaining)
+	if breached != st.breached {
+		typ := monitoringv1alpha1.NotificationEventRecovered
+		if breached {
+			typ = monitoringv1alpha1.NotificationEventBreached
+		}
+		state = newEvent(typ)
+		st.breached = breached
+	}
+	if reset == nil && state == nil {
+		return
+	}
+
+	for i := range sl.Spec.Notifications {
+		nt := &sl.Spec.Notifications[i]
+		if !notifiesSLO(nt, id, e.Cluster, sl) {
+			continue
+		}
+		key := id + "/" + nt.Name
+		ch, ok := n.channels[key]
+		if !ok {
+			ch = &channel{serviceLevel: st.serviceLevel, slo: id}
+			n.channels[key] = ch
+		}
+		ch.notification = *nt.DeepCopy()
+		if reset != nil {
+			ch.reset = reset
+			// The reset recovers the SLO without a recovered event.
+			ch.state = nil
+			if ch.notified == monitoringv1alpha1.NotificationEventBreached {
+				ch.notified = monitoringv1alpha1.NotificationEventRecovered
+			}
+		}
+		if state != nil {
+			ch.state = state
+		}
+	}
+}
+
+// Run sends the pending notifications periodically until stopped.
+func (n *Notifier) Run(stopC <-chan struct{}) error {
+	t := time.NewTicker(n.cfg.FlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			n.Flush(ctx, time.Now())
+			cancel()
+		case <-stopC:
+			return nil
+		}
+	}
+}
+
+// Flush sends the pending notifications whose min interval has passed. The
+// budget resets are sent before the state changes, one message per notification
+// of an SLO on every flush.
+func (n *Notifier) Flush(ctx context.Context, now time.Time) {
+	type message struct {
+		ch           *channel
+		notification monitoringv1alpha1.Notification
+		event        *Event
+	}
+
+	n.mu.Lock()
+	var msgs []message
+	for _, ch := range n.channels {
+		if ch.sending || now.Sub(ch.lastAttempt) < ch.minInterval() {
+			continue
+		}
+
+		// The state changes that end on the notified state are not notified (e.g.
+		// breached and recovered again), neither the not notified events.
+		if ch.state != nil && ch.reset == nil {
+			if ch.state.Type == ch.notified || (ch.notified == "" && ch.state.Type == monitoringv1alpha1.NotificationEventRecovered) {
+				ch.state = nil
+			} else if !ch.notifies(ch.state.Type) {
+				ch.notified = ch.state.Type
+				ch.state = nil
+			}
+		}
+		if ch.reset != nil && !ch.notifies(ch.reset.Type) {
+			ch.reset = nil
+		}
+
+		ev := ch.reset
+		if ev == nil {
+			ev = ch.state
+		}
+		if ev == nil {
+			continue
+		}
+		ch.sending = true
+		msgs = append(msgs, message{ch: ch, notification: ch.notification, event: ev})
+	}
+	n.mu.Unlock()
+
+	// The messages are sent in order so the channels receive them ordered.
+	sort.Slice(msgs, func(i, j int) bool {
+		if !msgs[i].event.Time.Equal(msgs[j].event.Time) {
+			return msgs[i].event.Time.Before(msgs[j].event.Time)
+		}
+		return msgs[i].event.ID()+msgs[i].notification.Name < msgs[j].event.ID()+msgs[j].notification.Name
+	})
+	for _, m := range msgs {
+		err := n.sender.Send(ctx, &m.notification, *m.event)
+		n.metricssvc.IncNotificationDelivery(KindWebhook, m.event.Type, err == nil)
+		if err != nil {
+			n.logger.Errorf("could not notify %s %s event: %s", m.event.ID(), m.event.Type, err)
+		}
+
+		n.mu.Lock()
+		m.ch.sending = false
+		m.ch.lastAttempt = now
+		if err == nil {
+			switch m.event {
+			case m.ch.reset:
+				m.ch.reset = nil
+			case m.ch.state:
+				m.ch.state = nil
+				m.ch.notified = m.event.Type
+			default:
+				// A newer event arrived while sending.
+				if m.event.Type != monitoringv1alpha1.NotificationEventBudgetReset {
+					m.ch.notified = m.event.Type
+				}
+			}
+		}
+		n.mu.Unlock()
+	}
+}
+
+// notifiesSLO returns true if the notification notifies an SLO (by its ID) of a
+// service level of a cluster.
+func notifiesSLO(nt *monitoringv1alpha1.Notification, id, cluster string, sl *monitoringv1alpha1.ServiceLevel) bool {
+	if len(nt.SLOs) == 0 {
+		return true
+	}
+	for i := range sl.Spec.ServiceLevelObjectives {
+		slo := &sl.Spec.ServiceLevelObjectives[i]
+		if status.SLOID(cluster, sl, slo) != id {
+			continue
+		}
+		for _, name := range nt.SLOs {
+			if name == slo.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
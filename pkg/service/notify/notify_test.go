@@ -0,0 +1,265 @@
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/metrics"
+	"github.com/spotahome/service-level-operator/pkg/service/notify"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+)
+
+// fakeSender stores the sent messages as "notification:slo:event".
+type fakeSender struct {
+	mu   sync.Mutex
+	sent []string
+	fail bool
+}
+
+func (f *fakeSender) Send(_ context.Context, n *monitoringv1alpha1.Notification, e notify.Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return errors.New("wanted error")
+	}
+	f.sent = append(f.sent, n.Name+":"+e.SLO+":"+e.Type)
+	return nil
+}
+
+func (f *fakeSender) flush() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sent := f.sent
+	f.sent = nil
+	return sent
+}
+
+var (
+	minInterval = &metav1.Duration{Duration: time.Minute}
+	webhook     = &monitoringv1alpha1.WebhookNotification{
+		URLSecretRef: monitoringv1alpha1.SecretKeyRef{Name: "webhook", Key: "url"},
+	}
+	chat = monitoringv1alpha1.Notification{Name: "chat", MinInterval: minInterval, Webhook: webhook}
+	sl0  = &monitoringv1alpha1.ServiceLevel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns0", Name: "sl0"},
+		Spec: monitoringv1alpha1.ServiceLevelSpec{
+			ServiceLevelObjectives: []monitoringv1alpha1.SLO{
+				{Name: "slo0", AvailabilityObjectivePercent: 99},
+				{Name: "slo1", AvailabilityObjectivePercent: 99},
+			},
+			Notifications: []monitoringv1alpha1.Notification{chat},
+		},
+	}
+)
+
+// step is an evaluation of an SLO with its counters followed by a flush.
+type step struct {
+	slo       int
+	errSum    float64
+	count     float64
+	objective float64 // 0.99 by default.
+	flushAt   time.Duration
+	expSent   []string
+	senderKO  bool
+}
+
+func TestNotifier(t *testing.T) {
+	tests := map[string]struct {
+		notifications []monitoringv1alpha1.Notification
+		steps         []step
+	}{
+		"Breaching and recovering an SLO should notify both state changes.": {
+			notifications: []monitoringv1alpha1.Notification{chat},
+			steps: []step{
+				{errSum: 0, count: 100, flushAt: 0},
+				{errSum: 2, count: 100, flushAt: time.Minute, expSent: []string{"chat:slo0:breached"}},
+				{errSum: 2, count: 300, flushAt: 2 * time.Minute, expSent: []string{"chat:slo0:recovered"}},
+			},
+		},
+		"Recovering without being breached shouldn't notify.": {
+			notifications: []monitoringv1alpha1.Notification{chat},
+			steps: []step{
+				{errSum: 0.5, count: 100, flushAt: 0},
+				{errSum: 0.5, count: 200, flushAt: time.Minute},
+			},
+		},
+		"A flapping SLO should only notify once per min interval and coalesce the state changes.": {
+			notifications: []monitoringv1alpha1.Notification{chat},
+			steps: []step{
+				{errSum: 2, count: 100, flushAt: 0, expSent: []string{"chat:slo0:breached"}},
+				{errSum: 2, count: 300, flushAt: 10 * time.Second},
+				{errSum: 6, count: 300, flushAt: 20 * time.Second},
+				{errSum: 6, count: 700, flushAt: 30 * time.Second},
+				{errSum: 12, count: 700, flushAt: 40 * time.Second},
+				// Breached again, already notified.
+				{errSum: 12, count: 700, flushAt: 2 * time.Minute},
+				{errSum: 12, count: 2000, flushAt: 2*time.Minute + 10*time.Second, expSent: []string{"chat:slo0:recovered"}},
+			},
+		},
+		"Resetting the output counters should notify the budget reset.": {
+			notifications: []monitoringv1alpha1.Notification{chat},
+			steps: []step{
+				{errSum: 2, count: 100, flushAt: 0, expSent: []string{"chat:slo0:breached"}},
+				{errSum: 0, count: 1, flushAt: time.Minute, expSent: []string{"chat:slo0:budgetReset"}},
+				// The reset recovered the SLO.
+				{errSum: 0, count: 100, flushAt: 2 * time.Minute},
+			},
+		},
+		"The notification events should be filtered.": {
+			notifications: []monitoringv1alpha1.Notification{
+				{Name: "chat", Events: []string{"recovered"}, MinInterval: minInterval, Webhook: webhook},
+			},
+			steps: []step{
+				{errSum: 2, count: 100, flushAt: 0},
+				{errSum: 2, count: 300, flushAt: time.Minute, expSent: []string{"chat:slo0:recovered"}},
+				{errSum: 0, count: 1, flushAt: 2 * time.Minute},
+			},
+		},
+		"The notification SLOs should be filtered.": {
+			notifications: []monitoringv1alpha1.Notification{
+				{Name: "chat", SLOs: []string{"slo1"}, MinInterval: minInterval, Webhook: webhook},
+			},
+			steps: []step{
+				{slo: 0, errSum: 2, count: 100, flushAt: 0},
+				{slo: 1, errSum: 2, count: 100, flushAt: time.Minute, expSent: []string{"chat:slo1:breached"}},
+			},
+		},
+		"Every notification should be notified independently.": {
+			notifications: []monitoringv1alpha1.Notification{
+				{Name: "chat0", MinInterval: minInterval, Webhook: webhook},
+				{Name: "chat1", MinInterval: minInterval, Webhook: webhook},
+			},
+			steps: []step{
+				{errSum: 2, count: 100, flushAt: 0, expSent: []string{"chat0:slo0:breached", "chat1:slo0:breached"}},
+			},
+		},
+		"The failed messages should be retried after the min interval.": {
+			notifications: []monitoringv1alpha1.Notification{chat},
+			steps: []step{
+				{errSum: 2, count: 100, flushAt: 0, senderKO: true},
+				{errSum: 2, count: 100, flushAt: 30 * time.Second},
+				{errSum: 2, count: 100, flushAt: time.Minute, expSent: []string{"chat:slo0:breached"}},
+			},
+		},
+		"A 100% objective without errors shouldn't be breached.": {
+			notifications: []monitoringv1alpha1.Notification{chat},
+			steps: []step{
+				{errSum: 0, count: 100, objective: 1, flushAt: 0},
+				{errSum: 0.1, count: 200, objective: 1, flushAt: time.Minute, expSent: []string{"chat:slo0:breached"}},
+			},
+		},
+		"Service levels without notifications shouldn't notify.": {
+			steps: []step{
+				{errSum: 2, count: 100, flushAt: 0},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sender := &fakeSender{}
+			n := notify.NewNotifier(notify.Cfg{}, sender, status.Dummy, metrics.Dummy, log.Dummy)
+			sl := sl0.DeepCopy()
+			sl.Spec.Notifications = test.notifications
+			n.SetServiceLevel("", sl)
+
+			start := time.Now()
+			for i, s := range test.steps {
+				slo := &sl.Spec.ServiceLevelObjectives[s.slo]
+				objective := s.objective
+				if objective == 0 {
+					objective = 0.99
+				}
+				n.RecordSLOEvaluation(status.SLOEvaluation{
+					ServiceLevel: sl,
+					SLO:          slo,
+					Time:         start.Add(s.flushAt),
+					Counters:     &output.SLOCounters{ErrorRatioSum: s.errSum, Count: s.count, Objective: objective},
+				})
+				sender.fail = s.senderKO
+				n.Flush(context.Background(), start.Add(s.flushAt))
+				assert.Equal(s.expSent, sender.flush(), "step %d", i)
+			}
+		})
+	}
+}
+
+func TestNotifierServiceLevelChanges(t *testing.T) {
+	tests := map[string]struct {
+		change  func(n *notify.Notifier, sl *monitoringv1alpha1.ServiceLevel)
+		expSent []string
+	}{
+		"Without changes the pending notifications should be sent.": {
+			change:  func(n *notify.Notifier, sl *monitoringv1alpha1.ServiceLevel) {},
+			expSent: []string{"chat:slo0:recovered"},
+		},
+		"Deleting the service level should discard the pending notifications.": {
+			change: func(n *notify.Notifier, sl *monitoringv1alpha1.ServiceLevel) {
+				n.DeleteServiceLevel("", sl.Namespace, sl.Name)
+			},
+		},
+		"Removing the notification should discard the pending notifications.": {
+			change: func(n *notify.Notifier, sl *monitoringv1alpha1.ServiceLevel) {
+				sl = sl.DeepCopy()
+				sl.Spec.Notifications = nil
+				n.SetServiceLevel("", sl)
+			},
+		},
+		"Disabling the SLO should discard the pending notifications.": {
+			change: func(n *notify.Notifier, sl *monitoringv1alpha1.ServiceLevel) {
+				sl = sl.DeepCopy()
+				sl.Spec.ServiceLevelObjectives[0].Disable = true
+				n.SetServiceLevel("", sl)
+			},
+		},
+		"Changing the notification events should apply to the pending notifications.": {
+			change: func(n *notify.Notifier, sl *monitoringv1alpha1.ServiceLevel) {
+				sl = sl.DeepCopy()
+				sl.Spec.Notifications[0].Events = []string{"breached"}
+				n.SetServiceLevel("", sl)
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			sender := &fakeSender{}
+			n := notify.NewNotifier(notify.Cfg{}, sender, status.Dummy, metrics.Dummy, log.Dummy)
+			n.SetServiceLevel("", sl0)
+
+			// Breached and notified, then recovered and pending.
+			start := time.Now()
+			record := func(errSum, count float64) {
+				n.RecordSLOEvaluation(status.SLOEvaluation{
+					ServiceLevel: sl0,
+					SLO:          &sl0.Spec.ServiceLevelObjectives[0],
+					Time:         start,
+					Counters:     &output.SLOCounters{ErrorRatioSum: errSum, Count: count, Objective: 0.99},
+				})
+			}
+			record(2, 100)
+			n.Flush(context.Background(), start)
+			require.Equal([]string{"chat:slo0:breached"}, sender.flush())
+			record(2, 300)
+
+			test.change(n, sl0)
+			n.Flush(context.Background(), start.Add(time.Hour))
+			assert.Equal(test.expSent, sender.flush())
+		})
+	}
+}
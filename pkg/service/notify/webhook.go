@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/service/kubernetes"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+)
+
+// KindWebhook is the kind of the chat webhook notifications.
+const KindWebhook = "webhook"
+
+// DefaultWebhookTemplate is the default message body of the webhook
+// notifications, it's compatible with Slack and Mattermost incoming webhooks.
+const DefaultWebhookTemplate = `{"text": {{ json .Summary }}}`
+
+// templateFuncs are the monitoringv1alpha1.WebhookTemplateFuncs.
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"percent": status.FormatPercent,
+}
+
+// ParseTemplate parses a message template, the templates have the json (JSON
+// encodes a value) and percent (formats a ratio as a percent) functions.
+func ParseTemplate(tpl string) (*template.Template, error) {
+	return template.New("message").Funcs(templateFuncs).Option("missingkey=error").Parse(tpl)
+}
+
+// SecretGetter knows how to get the secrets of the clusters.
+type SecretGetter interface {
+	// GetSecret gets a secret of a cluster.
+	GetSecret(cluster, namespace, name string) (*corev1.Secret, error)
+}
+
+// ClusterSecrets gets the secrets with the Kubernetes service of every cluster,
+// by cluster name.
+type ClusterSecrets map[string]kubernetes.Secret
+
+// GetSecret satisfies SecretGetter interface.
+func (c ClusterSecrets) GetSecret(cluster, namespace, name string) (*corev1.Secret, error) {
+	svc, ok := c[cluster]
+	if !ok {
+		return nil, fmt.Errorf("unknown %q cluster", cluster)
+	}
+	return svc.GetSecret(namespace, name)
+}
+
+type webhookSender struct {
+	secrets SecretGetter
+	cli     *http.Client
+
+	mu        sync.Mutex
+	templates map[string]parsedTemplate
+}
+
+// parsedTemplate is the parsed template of a notification.
+type parsedTemplate struct {
+	text string
+	tpl  *template.Template
+}
+
+// NewWebhookSender returns a new sender of the chat webhook notifications. The
+// webhook URL is read from the notification secret on every message, so the
+// secret changes are applied without restarts.
+func NewWebhookSender(secrets SecretGetter, cli *http.Client) Sender {
+	if cli == nil {
+		cli = http.DefaultClient
+	}
+	return &webhookSender{
+		secrets:   secrets,
+		cli:       cli,
+		templates: map[string]parsedTemplate{},
+	}
+}
+
+// Send satisfies Sender interface.
+func (w *webhookSender) Send(ctx context.Context, n *monitoringv1alpha1.Notification, e Event) error {
+	if n.Webhook == nil {
+		return fmt.Errorf("the %s notification is not a webhook notification", n.Name)
+	}
+
+	url, err := w.url(e, n.Webhook.URLSecretRef)
+	if err != nil {
+		return err
+	}
+	body, err := w.render(n, e)
+	if err != nil {
+		return fmt.Errorf("could not render the %s notification template: %s", n.Name, err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		// The URL is a secret, it's not on the errors.
+		return fmt.Errorf("the %s notification webhook URL is not valid", n.Name)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.cli.Do(req)
+	if err != nil {
+		// The URL is a secret, it's not on the errors.
+		if uerr, ok := err.(interface{ Unwrap() error }); ok {
+			err = uerr.Unwrap()
+		}
+		return fmt.Errorf("could not send the %s notification: %s", n.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("the %s notification webhook returned %s: %s", n.Name, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (w *webhookSender) url(e Event, ref monitoringv1alpha1.SecretKeyRef) (string, error) {
+	s, err := w.secrets.GetSecret(e.Cluster, e.Namespace, ref.Name)
+	if err != nil {
+		return "", fmt.Errorf("could not get the %s webhook secret: %s", ref.Name, err)
+	}
+	url := strings.TrimSpace(string(s.Data[ref.Key]))
+	if url == "" {
+		return "", fmt.Errorf("the %s webhook secret doesn't have the %s key", ref.Name, ref.Key)
+	}
+	return url, nil
+}
+
+// render renders the event with the notification template, the parsed
+// templates are cached by notification.
+func (w *webhookSender) render(n *monitoringv1alpha1.Notification, e Event) ([]byte, error) {
+	text := n.Webhook.Template
+	if text == "" {
+		text = DefaultWebhookTemplate
+	}
+
+	key := status.ServiceLevelKey(e.Cluster, e.Namespace, e.ServiceLevel) + "/" + n.Name
+	w.mu.Lock()
+	pt, ok := w.templates[key]
+	if !ok || pt.text != text {
+		t, err := ParseTemplate(text)
+		if err != nil {
+			w.mu.Unlock()
+			return nil, err
+		}
+		pt = parsedTemplate{text: text, tpl: t}
+		w.templates[key] = pt
+	}
+	w.mu.Unlock()
+
+	var b bytes.Buffer
+	if err := pt.tpl.Execute(&b, e); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
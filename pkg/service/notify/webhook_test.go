@@ -0,0 +1,162 @@
+package notify_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/kubernetes"
+	"github.com/spotahome/service-level-operator/pkg/service/notify"
+)
+
+func TestWebhookSender(t *testing.T) {
+	event := notify.Event{
+		Type:                      monitoringv1alpha1.NotificationEventBreached,
+		Time:                      time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+		Namespace:                 "ns0",
+		ServiceLevel:              "sl0",
+		SLO:                       "slo0",
+		Labels:                    map[string]string{"team": "checkout"},
+		ObjectiveRatio:            0.999,
+		AvailabilityRatio:         0.99812,
+		ErrorBudgetRemainingRatio: -0.88,
+	}
+
+	tests := map[string]struct {
+		template  string
+		secretKey string
+		status    int
+		expBody   string
+		expErr    bool
+	}{
+		"The default template should send a Slack compatible message.": {
+			secretKey: "url",
+			status:    http.StatusOK,
+			expBody:   `{"text": "SLO breached: ns0/sl0/slo0 availability is 99.812% (objective 99.9%), the error budget is exhausted."}`,
+		},
+		"A custom template should be rendered with the event.": {
+			template:  `{"text": {{ printf "%s %s/%s (%s) %s" .Type .ServiceLevel .SLO .Labels.team (percent .AvailabilityRatio) | json }}, "channel": "#slo"}`,
+			secretKey: "url",
+			status:    http.StatusOK,
+			expBody:   `{"text": "breached sl0/slo0 (checkout) 99.812%", "channel": "#slo"}`,
+		},
+		"A template with missing fields should fail.": {
+			template:  `{{ .Labels.missing }}`,
+			secretKey: "url",
+			status:    http.StatusOK,
+			expErr:    true,
+		},
+		"A missing secret key should fail.": {
+			secretKey: "missing",
+			status:    http.StatusOK,
+			expErr:    true,
+		},
+		"An invalid webhook URL should fail without the URL on the error.": {
+			secretKey: "invalid",
+			status:    http.StatusOK,
+			expErr:    true,
+		},
+		"A webhook error should fail.": {
+			secretKey: "url",
+			status:    http.StatusBadRequest,
+			expErr:    true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var gotBody string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				gotBody = string(b)
+				assert.Equal("/hooks/secret-token", r.URL.Path)
+				assert.Equal("application/json", r.Header.Get("Content-Type"))
+				w.WriteHeader(test.status)
+			}))
+			defer srv.Close()
+
+			cli := kubernetesfake.NewSimpleClientset(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns0", Name: "webhook"},
+				Data: map[string][]byte{
+					"url":     []byte(srv.URL + "/hooks/secret-token\n"),
+					"invalid": []byte(srv.URL + "/hooks/%zz-secret-token"),
+				},
+			})
+			secrets := notify.ClusterSecrets{"": kubernetes.NewSecret(cli, log.Dummy)}
+			sender := notify.NewWebhookSender(secrets, nil)
+
+			n := &monitoringv1alpha1.Notification{
+				Name: "chat",
+				Webhook: &monitoringv1alpha1.WebhookNotification{
+					URLSecretRef: monitoringv1alpha1.SecretKeyRef{Name: "webhook", Key: test.secretKey},
+					Template:     test.template,
+				},
+			}
+			err := sender.Send(context.Background(), n, event)
+
+			if test.expErr {
+				assert.Error(err)
+				assert.NotContains(err.Error(), "secret-token")
+				return
+			}
+			require.NoError(err)
+			assert.Equal(test.expBody, gotBody)
+		})
+	}
+}
+
+func TestWebhookSenderTemplateChanges(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		gotBody = string(b)
+	}))
+	defer srv.Close()
+
+	cli := kubernetesfake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns0", Name: "webhook"},
+		Data:       map[string][]byte{"url": []byte(srv.URL)},
+	})
+	sender := notify.NewWebhookSender(notify.ClusterSecrets{"": kubernetes.NewSecret(cli, log.Dummy)}, nil)
+	n := &monitoringv1alpha1.Notification{
+		Name: "chat",
+		Webhook: &monitoringv1alpha1.WebhookNotification{
+			URLSecretRef: monitoringv1alpha1.SecretKeyRef{Name: "webhook", Key: "url"},
+			Template:     `{{ .SLO }}`,
+		},
+	}
+	event := notify.Event{Namespace: "ns0", ServiceLevel: "sl0", SLO: "slo0"}
+
+	require.NoError(sender.Send(context.Background(), n, event))
+	assert.Equal("slo0", gotBody)
+
+	// The edited templates should be applied.
+	n.Webhook.Template = `{{ .ServiceLevel }}`
+	require.NoError(sender.Send(context.Background(), n, event))
+	assert.Equal("sl0", gotBody)
+}
+
+func TestParseTemplateFuncs(t *testing.T) {
+	// The validation of the service levels checks the templates with these functions.
+	for _, name := range monitoringv1alpha1.WebhookTemplateFuncs {
+		_, err := notify.ParseTemplate("{{ " + name + " 1 }}")
+		assert.NoError(t, err, name)
+	}
+}
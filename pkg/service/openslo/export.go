@@ -0,0 +1,249 @@
+package openslo
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/common/model"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+// Annotations set on the exported objects to keep the original service level data.
+const (
+	AnnotationNamespace    = "service-level-operator.spotahome.com/namespace"
+	AnnotationServiceLevel = "service-level-operator.spotahome.com/service-level"
+	AnnotationSLO          = "service-level-operator.spotahome.com/slo"
+	AnnotationDisabled     = "service-level-operator.spotahome.com/disabled"
+)
+
+const (
+	defTimeWindow = "30d"
+	maxNameLength = 63
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// ExportConfig is the configuration of the export.
+type ExportConfig struct {
+	// TimeWindow is the rolling time window of the SLOs, the service levels
+	// don't have one (the operator counters don't have a window).
+	TimeWindow string
+}
+
+func (c *ExportConfig) defaults() error {
+	if c.TimeWindow == "" {
+		c.TimeWindow = defTimeWindow
+	}
+	if _, err := model.ParseDuration(c.TimeWindow); err != nil {
+		return fmt.Errorf("invalid time window: %s", err)
+	}
+	return nil
+}
+
+// Issue is something of a service level that can't be represented exactly on
+// OpenSLO.
+type Issue struct {
+	// Object is the service level or SLO with the issue.
+	Object string
+	// Message is the description of the issue.
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s", i.Object, i.Message)
+}
+
+// Export converts the service levels to OpenSLO objects. Every service level is a
+// Service, and every SLO an SLO with its ratio metric SLI, the Prometheus addresses
+// are DataSources. The objects are returned in dependency order with the issues
+// of what can't be represented.
+func Export(sls []*monitoringv1alpha1.ServiceLevel, cfg ExportConfig) ([]interface{}, []Issue, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, nil, err
+	}
+
+	e := &exporter{cfg: cfg, names: map[string]string{}}
+	for _, sl := range sls {
+		if err := sl.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s/%s service level: %s", sl.Namespace, sl.Name, err)
+		}
+		e.exportServiceLevel(sl)
+	}
+
+	return e.objs, e.issues, nil
+}
+
+type exporter struct {
+	cfg    ExportConfig
+	objs   []interface{}
+	issues []Issue
+	// names are the used object names (kind/name) and their origin.
+	names map[string]string
+}
+
+func (e *exporter) issuef(object, format string, args ...interface{}) {
+	e.issues = append(e.issues, Issue{Object: object, Message: fmt.Sprintf(format, args...)})
+}
+
+// name returns a valid OpenSLO name for an object, the invalid names are
+// sanitized and the clashing ones reported.
+func (e *exporter) name(kind, name, origin string) string {
+	valid := sanitizeName(name)
+	if valid != name {
+		e.issuef(origin, "%q is not a valid OpenSLO %s name, renamed to %q", name, kind, valid)
+	}
+	key := kind + "/" + valid
+	if prev, ok := e.names[key]; ok && prev != origin {
+		e.issuef(origin, "%s name %q is already used by %s", kind, valid, prev)
+	}
+	e.names[key] = origin
+	return valid
+}
+
+func (e *exporter) exportServiceLevel(sl *monitoringv1alpha1.ServiceLevel) {
+	origin := sl.Namespace + "/" + sl.Name
+
+	svc := Service{
+		APIVersion: APIVersion,
+		Kind:       KindService,
+		Metadata: Metadata{
+			Name:        e.name(KindService, sl.Name, origin),
+			Labels:      copyLabels(sl.Labels),
+			Annotations: map[string]string{AnnotationNamespace: sl.Namespace, AnnotationServiceLevel: sl.Name},
+		},
+	}
+	e.objs = append(e.objs, svc)
+
+	// A data source per Prometheus address.
+	dataSources := map[string]string{}
+	var addresses []string
+	for _, slo := range sl.Spec.ServiceLevelObjectives {
+		addr := slo.ServiceLevelIndicator.Prometheus.Address
+		if _, ok := dataSources[addr]; ok || addr == "" {
+			continue
+		}
+		dataSources[addr] = ""
+		addresses = append(addresses, addr)
+	}
+	for i, addr := range addresses {
+		name := svc.Metadata.Name + "-prometheus"
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d", name, i)
+		}
+		name = e.name(KindDataSource, name, origin)
+		dataSources[addr] = name
+		e.objs = append(e.objs, DataSource{
+			APIVersion: APIVersion,
+			Kind:       KindDataSource,
+			Metadata:   Metadata{Name: name},
+			Spec: DataSourceSpec{
+				Type:              MetricSourceTypePrometheus,
+				ConnectionDetails: map[string]string{"url": addr},
+			},
+		})
+	}
+
+	for _, slo := range sl.Spec.ServiceLevelObjectives {
+		e.exportSLO(sl, slo, svc.Metadata.Name, dataSources)
+	}
+}
+
+func (e *exporter) exportSLO(sl *monitoringv1alpha1.ServiceLevel, slo monitoringv1alpha1.SLO, service string, dataSources map[string]string) {
+	origin := fmt.Sprintf("%s/%s/%s", sl.Namespace, sl.Name, slo.Name)
+	prom := slo.ServiceLevelIndicator.Prometheus
+	annotations := map[string]string{
+		AnnotationNamespace:    sl.Namespace,
+		AnnotationServiceLevel: sl.Name,
+		AnnotationSLO:          slo.Name,
+	}
+
+	if prom.Address == "" {
+		e.issuef(origin, "the SLI doesn't have a Prometheus address, the metric source doesn't reference a data source")
+	}
+	if slo.Disable {
+		e.issuef(origin, "OpenSLO SLOs can't be disabled, exported with the %q annotation", AnnotationDisabled)
+		annotations[AnnotationDisabled] = "true"
+	}
+
+	metricSource := func(query string) *MetricSourceRef {
+		return &MetricSourceRef{MetricSource: MetricSource{
+			MetricSourceRef: dataSources[prom.Address],
+			Type:            MetricSourceTypePrometheus,
+			Spec:            map[string]string{"query": query},
+		}}
+	}
+
+	name := e.name(KindSLO, service+"-"+slo.Name, origin)
+	sli := SLI{
+		APIVersion: APIVersion,
+		Kind:       KindSLI,
+		Metadata:   Metadata{Name: e.name(KindSLI, name, origin)},
+		Spec: SLISpec{
+			// The queries return the events of the evaluation time, not counters.
+			RatioMetric: &RatioMetric{
+				Counter: false,
+				Bad:     metricSource(prom.ErrorQuery),
+				Total:   metricSource(prom.TotalQuery),
+			},
+		},
+	}
+
+	var labels map[string]string
+	if slo.Output.Prometheus != nil {
+		labels = copyLabels(slo.Output.Prometheus.Labels)
+	}
+
+	e.objs = append(e.objs, sli, SLO{
+		APIVersion: APIVersion,
+		Kind:       KindSLO,
+		Metadata: Metadata{
+			Name:        name,
+			DisplayName: slo.Name,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: SLOSpec{
+			Description:     slo.Description,
+			Service:         service,
+			IndicatorRef:    sli.Metadata.Name,
+			TimeWindow:      []TimeWindow{{Duration: e.cfg.TimeWindow, IsRolling: true}},
+			BudgetingMethod: BudgetingMethodOccurrences,
+			Objectives:      []Objective{{Target: percentToRatio(slo.AvailabilityObjectivePercent)}},
+		},
+	})
+}
+
+// percentToRatio converts a percent to a ratio without the float error of the
+// division (e.g. 99.99 is 0.9999 and not 0.9998999999999999).
+func percentToRatio(p float64) float64 {
+	return math.Round(p*1e8) / 1e10
+}
+
+// sanitizeName returns a valid RFC 1123 label.
+func sanitizeName(name string) string {
+	n := invalidNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	if len(n) > maxNameLength {
+		n = n[:maxNameLength]
+	}
+	return strings.Trim(n, "-")
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	res := make(map[string]string, len(labels))
+	for _, k := range keys {
+		res[k] = labels[k]
+	}
+	return res
+}
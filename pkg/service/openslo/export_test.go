@@ -0,0 +1,113 @@
+package openslo_test
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/service/lint"
+	"github.com/spotahome/service-level-operator/pkg/service/manifest"
+	"github.com/spotahome/service-level-operator/pkg/service/openslo"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestExport(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		golden string
+		cfg    openslo.ExportConfig
+		// invalid is set when the input has service levels the operator
+		// would reject on purpose.
+		invalid bool
+	}{
+		"Service levels should be exported with their labels, descriptions and objectives.": {
+			input:  "basic.yaml",
+			golden: "basic.golden",
+		},
+		"The time window of the SLOs should be configurable.": {
+			input:  "basic.yaml",
+			golden: "time-window.golden",
+			cfg:    openslo.ExportConfig{TimeWindow: "7d"},
+		},
+		"What can't be represented on OpenSLO should be reported.": {
+			input:   "issues.yaml",
+			golden:  "issues.golden",
+			invalid: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			f, err := os.Open(filepath.Join("testdata", test.input))
+			require.NoError(err)
+			defer f.Close()
+			docs, err := manifest.Read(f, test.input)
+			require.NoError(err)
+			require.Equal(test.invalid, lint.HasErrors(lint.Lint(docs)), "the input should only have lint errors on purpose")
+
+			objs, issues, err := openslo.Export(manifest.ServiceLevels(docs), test.cfg)
+			require.NoError(err)
+
+			var b bytes.Buffer
+			for _, i := range issues {
+				fmt.Fprintf(&b, "# %s\n", i)
+			}
+			require.NoError(openslo.Encode(&b, objs))
+
+			golden := filepath.Join("testdata", test.golden)
+			if *update {
+				require.NoError(ioutil.WriteFile(golden, b.Bytes(), 0644))
+			}
+			exp, err := ioutil.ReadFile(golden)
+			require.NoError(err)
+			assert.Equal(string(exp), b.String())
+		})
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	tests := map[string]struct {
+		sl  *monitoringv1alpha1.ServiceLevel
+		cfg openslo.ExportConfig
+	}{
+		"An invalid service level should fail.": {
+			sl: &monitoringv1alpha1.ServiceLevel{},
+		},
+		"An invalid time window should fail.": {
+			sl: &monitoringv1alpha1.ServiceLevel{
+				Spec: monitoringv1alpha1.ServiceLevelSpec{
+					ServiceLevelObjectives: []monitoringv1alpha1.SLO{
+						{
+							Name:                         "slo",
+							AvailabilityObjectivePercent: 99,
+							ServiceLevelIndicator: monitoringv1alpha1.SLI{
+								SLISource: monitoringv1alpha1.SLISource{Prometheus: &monitoringv1alpha1.PrometheusSLISource{}},
+							},
+							Output: monitoringv1alpha1.Output{Prometheus: &monitoringv1alpha1.PrometheusOutputSource{}},
+						},
+					},
+				},
+			},
+			cfg: openslo.ExportConfig{TimeWindow: "1 month"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := openslo.Export([]*monitoringv1alpha1.ServiceLevel{test.sl}, test.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
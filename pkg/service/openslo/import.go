@@ -0,0 +1,363 @@
+package openslo
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+var (
+	invalidSLONameChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+	vectorSelector      = regexp.MustCompile(`^\s*[a-zA-Z_:][a-zA-Z0-9_:]*\s*(\{[^}]*\})?\s*$`)
+)
+
+// badOps are the PromQL comparison operators of the bad events of a threshold
+// metric by the objective operator of the good events.
+var badOps = map[string]string{
+	OpLT:  ">=",
+	OpLTE: ">",
+	OpGT:  "<=",
+	OpGTE: "<",
+}
+
+// ImportConfig is the configuration of the import.
+type ImportConfig struct {
+	// Namespace is the namespace of the service levels, the exported ones keep
+	// their original namespace.
+	Namespace string
+	// Window is the range used to get the increase of the ratio metrics that
+	// are counters, the operator needs the events of every evaluation.
+	Window string
+}
+
+func (c *ImportConfig) defaults() error {
+	if c.Window == "" {
+		return nil
+	}
+	if _, err := model.ParseDuration(c.Window); err != nil {
+		return fmt.Errorf("invalid window: %s", err)
+	}
+	return nil
+}
+
+// Import converts the OpenSLO objects to service levels. Every Service with
+// SLOs is a service level, and every SLO objective a service level SLO with the
+// error and total queries of its SLI. The objects can be the ones returned by
+// Read or Export, what is not imported is returned as issues.
+func Import(objs []interface{}, cfg ImportConfig) ([]*monitoringv1alpha1.ServiceLevel, []Issue, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, nil, err
+	}
+
+	im := &importer{
+		cfg:         cfg,
+		services:    map[string]*Service{},
+		slis:        map[string]*SLI{},
+		dataSources: map[string]*DataSource{},
+		sls:         map[string]*monitoringv1alpha1.ServiceLevel{},
+	}
+	var slos []*SLO
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *Service:
+			im.services[o.Metadata.Name] = o
+		case *SLI:
+			im.slis[o.Metadata.Name] = o
+		case *DataSource:
+			im.dataSources[o.Metadata.Name] = o
+		case *SLO:
+			slos = append(slos, o)
+		case *Unsupported:
+			im.issuef(fmt.Sprintf("%s/%s", o.Kind, o.Metadata.Name), "%s objects are not supported, ignored", o.Kind)
+		// The exported objects are values.
+		case Service:
+			im.services[o.Metadata.Name] = &o
+		case SLI:
+			im.slis[o.Metadata.Name] = &o
+		case DataSource:
+			im.dataSources[o.Metadata.Name] = &o
+		case SLO:
+			slos = append(slos, &o)
+		default:
+			return nil, nil, fmt.Errorf("unknown %T OpenSLO object", obj)
+		}
+	}
+
+	for _, slo := range slos {
+		if err := im.importSLO(slo); err != nil {
+			return nil, nil, fmt.Errorf("%s/%s: %s", KindSLO, slo.Metadata.Name, err)
+		}
+	}
+
+	for _, sl := range im.order {
+		if err := sl.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s service level: %s", sl.Name, err)
+		}
+	}
+	return im.order, im.issues, nil
+}
+
+type importer struct {
+	cfg         ImportConfig
+	services    map[string]*Service
+	slis        map[string]*SLI
+	dataSources map[string]*DataSource
+	issues      []Issue
+	// sls are the imported service levels by namespace and name, order keeps
+	// the order of the SLOs.
+	sls   map[string]*monitoringv1alpha1.ServiceLevel
+	order []*monitoringv1alpha1.ServiceLevel
+}
+
+func (im *importer) issuef(object, format string, args ...interface{}) {
+	im.issues = append(im.issues, Issue{Object: object, Message: fmt.Sprintf(format, args...)})
+}
+
+// serviceLevel returns the service level of an OpenSLO SLO service. The
+// exported objects keep the namespace and name of their service level.
+func (im *importer) serviceLevel(slo *SLO) *monitoringv1alpha1.ServiceLevel {
+	service := slo.Spec.Service
+	svc, ok := im.services[service]
+	if !ok {
+		svc = &Service{}
+	}
+
+	name := firstNonEmpty(slo.Metadata.Annotations[AnnotationServiceLevel], svc.Metadata.Annotations[AnnotationServiceLevel], service)
+	ns := firstNonEmpty(slo.Metadata.Annotations[AnnotationNamespace], svc.Metadata.Annotations[AnnotationNamespace], im.cfg.Namespace)
+	key := ns + "/" + name
+	if sl, ok := im.sls[key]; ok {
+		return sl
+	}
+
+	sl := &monitoringv1alpha1.ServiceLevel{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: monitoringv1alpha1.SchemeGroupVersion.String(),
+			Kind:       monitoringv1alpha1.ServiceLevelKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+			Labels:    copyLabels(svc.Metadata.Labels),
+		},
+	}
+	if svc.Spec.Description != "" {
+		im.issuef(KindService+"/"+service, "service levels don't have a description, ignored")
+	}
+
+	im.sls[key] = sl
+	im.order = append(im.order, sl)
+	return sl
+}
+
+func (im *importer) importSLO(slo *SLO) error {
+	object := KindSLO + "/" + slo.Metadata.Name
+
+	switch slo.Spec.BudgetingMethod {
+	case BudgetingMethodOccurrences, "":
+	default:
+		return fmt.Errorf("%q budgeting method is not supported, only %s", slo.Spec.BudgetingMethod, BudgetingMethodOccurrences)
+	}
+	if slo.Spec.Service == "" {
+		return fmt.Errorf("the service is required")
+	}
+	if len(slo.Spec.Objectives) == 0 {
+		return fmt.Errorf("at least one objective is required")
+	}
+	if len(slo.Spec.AlertPolicies) > 0 {
+		im.issuef(object, "alert policies are not supported, ignored")
+	}
+
+	sli := slo.Spec.Indicator
+	if sli == nil {
+		s, ok := im.slis[slo.Spec.IndicatorRef]
+		if !ok {
+			return fmt.Errorf("the %q SLI is missing", slo.Spec.IndicatorRef)
+		}
+		sli = s
+	}
+
+	// The exported SLOs keep the original name.
+	origName := slo.Metadata.Annotations[AnnotationSLO]
+	if origName == "" {
+		origName = slo.Metadata.Name
+	}
+	name := sloName(origName)
+	if name != origName {
+		im.issuef(object, "%q is not a valid SLO name, renamed to %q", origName, name)
+	}
+
+	sl := im.serviceLevel(slo)
+	for i, obj := range slo.Spec.Objectives {
+		objName := name
+		if len(slo.Spec.Objectives) > 1 {
+			suffix := strconv.Itoa(i)
+			if obj.DisplayName != "" {
+				suffix = sloName(obj.DisplayName)
+			}
+			objName = name + "_" + suffix
+		}
+
+		objective, err := objectivePercent(obj)
+		if err != nil {
+			return fmt.Errorf("objective %d: %s", i, err)
+		}
+		src, err := im.sliSource(sli, obj)
+		if err != nil {
+			return fmt.Errorf("%s/%s: %s", KindSLI, sli.Metadata.Name, err)
+		}
+
+		sl.Spec.ServiceLevelObjectives = append(sl.Spec.ServiceLevelObjectives, monitoringv1alpha1.SLO{
+			Name:                         objName,
+			Description:                  slo.Spec.Description,
+			Disable:                      slo.Metadata.Annotations[AnnotationDisabled] == "true",
+			AvailabilityObjectivePercent: objective,
+			ServiceLevelIndicator:        monitoringv1alpha1.SLI{SLISource: monitoringv1alpha1.SLISource{Prometheus: src}},
+			Output: monitoringv1alpha1.Output{
+				Prometheus: &monitoringv1alpha1.PrometheusOutputSource{Labels: copyLabels(slo.Metadata.Labels)},
+			},
+		})
+	}
+	return nil
+}
+
+// sliSource returns the Prometheus SLI source of an OpenSLO SLI. The ratio
+// metrics error query is the bad metric or the total minus the good one, the
+// threshold metrics are the number of series that don't meet the objective.
+func (im *importer) sliSource(sli *SLI, obj Objective) (*monitoringv1alpha1.PrometheusSLISource, error) {
+	switch {
+	case sli.Spec.RatioMetric != nil:
+		rm := sli.Spec.RatioMetric
+		if rm.Total == nil {
+			return nil, fmt.Errorf("the ratio metric total is required")
+		}
+		if (rm.Good == nil) == (rm.Bad == nil) {
+			return nil, fmt.Errorf("the ratio metric requires one of good or bad")
+		}
+
+		address, total, err := im.metricQuery(rm.Total, rm.Counter)
+		if err != nil {
+			return nil, fmt.Errorf("total: %s", err)
+		}
+		var (
+			eventsAddress, errorQuery string
+		)
+		if rm.Bad != nil {
+			eventsAddress, errorQuery, err = im.metricQuery(rm.Bad, rm.Counter)
+			if err != nil {
+				return nil, fmt.Errorf("bad: %s", err)
+			}
+		} else {
+			var good string
+			eventsAddress, good, err = im.metricQuery(rm.Good, rm.Counter)
+			if err != nil {
+				return nil, fmt.Errorf("good: %s", err)
+			}
+			errorQuery = fmt.Sprintf("(%s) - (%s)", total, good)
+		}
+		if eventsAddress != address {
+			return nil, fmt.Errorf("the ratio metric queries must use the same data source")
+		}
+
+		return &monitoringv1alpha1.PrometheusSLISource{Address: address, TotalQuery: total, ErrorQuery: errorQuery}, nil
+
+	case sli.Spec.ThresholdMetric != nil:
+		op, ok := badOps[obj.Op]
+		if !ok {
+			return nil, fmt.Errorf("the threshold metric objectives require an op (lt, lte, gt or gte), got %q", obj.Op)
+		}
+		if obj.Value == nil {
+			return nil, fmt.Errorf("the threshold metric objectives require a value")
+		}
+		address, query, err := im.metricQuery(sli.Spec.ThresholdMetric, false)
+		if err != nil {
+			return nil, err
+		}
+
+		return &monitoringv1alpha1.PrometheusSLISource{
+			Address:    address,
+			TotalQuery: fmt.Sprintf("count(%s)", query),
+			ErrorQuery: fmt.Sprintf("sum((%s) %s bool %s)", query, op, strconv.FormatFloat(*obj.Value, 'g', -1, 64)),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("only ratio and threshold metrics are supported")
+}
+
+// metricQuery returns the Prometheus address and query of a metric source, the
+// counters are converted to their increase on the import window.
+func (im *importer) metricQuery(ref *MetricSourceRef, counter bool) (address, query string, err error) {
+	ms := ref.MetricSource
+	typ := ms.Type
+	if ms.MetricSourceRef != "" {
+		ds, ok := im.dataSources[ms.MetricSourceRef]
+		if !ok {
+			return "", "", fmt.Errorf("the %q data source is missing", ms.MetricSourceRef)
+		}
+		if typ == "" {
+			typ = ds.Spec.Type
+		}
+		address = ds.Spec.ConnectionDetails["url"]
+		if address == "" {
+			address = ds.Spec.ConnectionDetails["address"]
+		}
+	}
+	if !strings.EqualFold(typ, MetricSourceTypePrometheus) {
+		return "", "", fmt.Errorf("%q metric sources are not supported, only %s", typ, MetricSourceTypePrometheus)
+	}
+
+	query = ms.Spec["query"]
+	if query == "" {
+		query = ms.Spec["promql"]
+	}
+	if query == "" {
+		return "", "", fmt.Errorf("the metric source query is required")
+	}
+	if strings.Contains(query, "{{") {
+		return "", "", fmt.Errorf("templated queries are not supported: %s", query)
+	}
+
+	if counter {
+		if im.cfg.Window == "" {
+			return "", "", fmt.Errorf("counter metrics require a window to get their increase")
+		}
+		if vectorSelector.MatchString(query) {
+			query = fmt.Sprintf("sum(increase(%s[%s]))", strings.TrimSpace(query), im.cfg.Window)
+		} else {
+			query = fmt.Sprintf("sum(increase((%s)[%s:]))", query, im.cfg.Window)
+		}
+	}
+	return address, query, nil
+}
+
+// objectivePercent returns the availability objective percent of an objective.
+func objectivePercent(obj Objective) (float64, error) {
+	p := obj.TargetPercent
+	if p == 0 {
+		p = math.Round(obj.Target*1e10) / 1e8
+	}
+	if p <= 0 || p > 100 {
+		return 0, fmt.Errorf("the target must be between 0 and 1, or the target percent between 0 and 100")
+	}
+	return p, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// sloName returns a valid service level SLO name.
+func sloName(name string) string {
+	return strings.Trim(invalidSLONameChars.ReplaceAllString(name, "_"), "_")
+}
@@ -0,0 +1,214 @@
+package openslo_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spotahome/service-level-operator/pkg/service/manifest"
+	"github.com/spotahome/service-level-operator/pkg/service/openslo"
+)
+
+func TestImport(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		golden string
+		cfg    openslo.ImportConfig
+	}{
+		"Ratio and threshold SLIs should be imported as total and error queries.": {
+			input:  "import.yaml",
+			golden: "import.golden",
+			cfg:    openslo.ImportConfig{Namespace: "shop", Window: "2m"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			f, err := os.Open(filepath.Join("testdata", test.input))
+			require.NoError(err)
+			defer f.Close()
+			objs, err := openslo.Read(f)
+			require.NoError(err)
+
+			sls, issues, err := openslo.Import(objs, test.cfg)
+			require.NoError(err)
+
+			var b bytes.Buffer
+			for _, i := range issues {
+				fmt.Fprintf(&b, "# %s\n", i)
+			}
+			require.NoError(manifest.Write(&b, sls))
+
+			golden := filepath.Join("testdata", test.golden)
+			if *update {
+				require.NoError(ioutil.WriteFile(golden, b.Bytes(), 0644))
+			}
+			exp, err := ioutil.ReadFile(golden)
+			require.NoError(err)
+			assert.Equal(string(exp), b.String())
+		})
+	}
+}
+
+func TestImportExported(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	f, err := os.Open(filepath.Join("testdata", "issues.yaml"))
+	require.NoError(err)
+	defer f.Close()
+	docs, err := manifest.Read(f, "issues.yaml")
+	require.NoError(err)
+	exp := manifest.ServiceLevels(docs)
+
+	// Export and read again the OpenSLO objects like the CLI does.
+	objs, _, err := openslo.Export(exp, openslo.ExportConfig{})
+	require.NoError(err)
+	var b bytes.Buffer
+	require.NoError(openslo.Encode(&b, objs))
+	objs, err = openslo.Read(&b)
+	require.NoError(err)
+
+	// The exported objects keep the service level namespaces and names, the
+	// invalid SLO names are fixed.
+	exp[0].Spec.ServiceLevelObjectives[0].Name = "Availability_99_9"
+	exp[0].Spec.ServiceLevelObjectives[1].Name = "old_availability"
+	got, _, err := openslo.Import(objs, openslo.ImportConfig{})
+	require.NoError(err)
+	assert.Equal(exp, got)
+}
+
+func TestImportErrors(t *testing.T) {
+	const sli = `
+apiVersion: openslo/v1
+kind: SLI
+metadata:
+  name: sli
+spec:
+  ratioMetric:
+    counter: false
+    bad:
+      metricSource:
+        type: Prometheus
+        spec:
+          query: sum(errors)
+    total:
+      metricSource:
+        type: Prometheus
+        spec:
+          query: sum(total)
+---
+`
+	const slo = `
+apiVersion: openslo/v1
+kind: SLO
+metadata:
+  name: slo
+spec:
+  service: svc
+  indicatorRef: sli
+  budgetingMethod: Occurrences
+  objectives:
+    - target: 0.99
+`
+
+	tests := map[string]struct {
+		specs  string
+		cfg    openslo.ImportConfig
+		expErr string
+	}{
+		"Time slices budgeting method should fail.": {
+			specs:  sli + strings.Replace(slo, "Occurrences", "Timeslices", 1),
+			expErr: `"Timeslices" budgeting method is not supported`,
+		},
+		"A missing SLI should fail.": {
+			specs:  slo,
+			expErr: `the "sli" SLI is missing`,
+		},
+		"Counters without window should fail.": {
+			specs:  strings.Replace(sli, "counter: false", "counter: true", 1) + slo,
+			expErr: "counter metrics require a window",
+		},
+		"Templated queries should fail.": {
+			specs:  strings.Replace(sli, "sum(errors)", "sum(rate(errors[{{.window}}]))", 1) + slo,
+			cfg:    openslo.ImportConfig{Window: "5m"},
+			expErr: "templated queries are not supported",
+		},
+		"Non Prometheus metric sources should fail.": {
+			specs:  strings.Replace(sli, "type: Prometheus", "type: Datadog", 1) + slo,
+			expErr: `"Datadog" metric sources are not supported`,
+		},
+		"Threshold metrics without objective operator should fail.": {
+			specs: `
+apiVersion: openslo/v1
+kind: SLI
+metadata:
+  name: sli
+spec:
+  thresholdMetric:
+    metricSource:
+      type: Prometheus
+      spec:
+        query: latency
+---
+` + slo,
+			expErr: "the threshold metric objectives require an op",
+		},
+		"Objectives out of range should fail.": {
+			specs:  sli + strings.Replace(slo, "target: 0.99", "targetPercent: 120", 1),
+			expErr: "the target must be between 0 and 1",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			objs, err := openslo.Read(strings.NewReader(test.specs))
+			require.NoError(err)
+			_, _, err = openslo.Import(objs, test.cfg)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), test.expErr)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := map[string]struct {
+		specs  string
+		expErr string
+	}{
+		"Unknown versions should fail.": {
+			specs:  "apiVersion: openslo/v1alpha\nkind: SLO\n",
+			expErr: `line 1: unsupported "openslo/v1alpha" OpenSLO version`,
+		},
+		"Unknown kinds should fail.": {
+			specs:  "---\napiVersion: openslo/v1\nkind: Project\n",
+			expErr: `line 2: unknown "Project" OpenSLO kind`,
+		},
+		"Labels with multiple values should fail.": {
+			specs:  "apiVersion: openslo/v1\nkind: Service\nmetadata:\n  name: svc\n  labels:\n    team: [a, b]\n",
+			expErr: `the "team" label has 2 values`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := openslo.Read(strings.NewReader(test.specs))
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), test.expErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package openslo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// APIVersion is the supported OpenSLO specification version.
+const APIVersion = "openslo/v1"
+
+// OpenSLO kinds.
+const (
+	KindService    = "Service"
+	KindSLI        = "SLI"
+	KindSLO        = "SLO"
+	KindDataSource = "DataSource"
+
+	KindAlertPolicy             = "AlertPolicy"
+	KindAlertCondition          = "AlertCondition"
+	KindAlertNotificationTarget = "AlertNotificationTarget"
+)
+
+// Budgeting methods.
+const (
+	BudgetingMethodOccurrences     = "Occurrences"
+	BudgetingMethodTimeslices      = "Timeslices"
+	BudgetingMethodRatioTimeslices = "RatioTimeslices"
+)
+
+// MetricSourceTypePrometheus is the type of the Prometheus metric sources.
+const MetricSourceTypePrometheus = "Prometheus"
+
+// Metadata is the metadata of the OpenSLO objects.
+type Metadata struct {
+	Name        string            `yaml:"name"`
+	DisplayName string            `yaml:"displayName,omitempty"`
+	Labels      Labels            `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// Labels are the labels of the OpenSLO objects, the specification allows a
+// list of values for a label but only single values are supported.
+type Labels map[string]string
+
+// UnmarshalYAML satisfies yaml.Unmarshaler interface.
+func (l *Labels) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: labels must be a map", node.Line)
+	}
+
+	res := Labels{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		k, v := node.Content[i].Value, node.Content[i+1]
+		switch {
+		case v.Kind == yaml.ScalarNode:
+			res[k] = v.Value
+		case v.Kind == yaml.SequenceNode && len(v.Content) == 1 && v.Content[0].Kind == yaml.ScalarNode:
+			res[k] = v.Content[0].Value
+		case v.Kind == yaml.SequenceNode:
+			return fmt.Errorf("line %d: the %q label has %d values, only labels with a single value are supported", v.Line, k, len(v.Content))
+		default:
+			return fmt.Errorf("line %d: the %q label value must be a string", v.Line, k)
+		}
+	}
+	*l = res
+	return nil
+}
+
+// Service is an OpenSLO service, a group of SLOs.
+type Service struct {
+	APIVersion string      `yaml:"apiVersion"`
+	Kind       string      `yaml:"kind"`
+	Metadata   Metadata    `yaml:"metadata"`
+	Spec       ServiceSpec `yaml:"spec"`
+}
+
+// ServiceSpec is the spec of an OpenSLO service.
+type ServiceSpec struct {
+	Description string `yaml:"description,omitempty"`
+}
+
+// DataSource is an OpenSLO data source, the connection to a metrics backend.
+type DataSource struct {
+	APIVersion string         `yaml:"apiVersion"`
+	Kind       string         `yaml:"kind"`
+	Metadata   Metadata       `yaml:"metadata"`
+	Spec       DataSourceSpec `yaml:"spec"`
+}
+
+// DataSourceSpec is the spec of an OpenSLO data source.
+type DataSourceSpec struct {
+	Description       string            `yaml:"description,omitempty"`
+	Type              string            `yaml:"type"`
+	ConnectionDetails map[string]string `yaml:"connectionDetails"`
+}
+
+// SLI is an OpenSLO service level indicator.
+type SLI struct {
+	APIVersion string   `yaml:"apiVersion,omitempty"`
+	Kind       string   `yaml:"kind,omitempty"`
+	Metadata   Metadata `yaml:"metadata"`
+	Spec       SLISpec  `yaml:"spec"`
+}
+
+// SLISpec is the spec of an OpenSLO SLI.
+type SLISpec struct {
+	Description     string           `yaml:"description,omitempty"`
+	ThresholdMetric *MetricSourceRef `yaml:"thresholdMetric,omitempty"`
+	RatioMetric     *RatioMetric     `yaml:"ratioMetric,omitempty"`
+}
+
+// RatioMetric is an SLI based on the ratio of good or bad events against the total.
+type RatioMetric struct {
+	// Counter is true when the metrics are monotonically increasing counters.
+	Counter bool             `yaml:"counter"`
+	Good    *MetricSourceRef `yaml:"good,omitempty"`
+	Bad     *MetricSourceRef `yaml:"bad,omitempty"`
+	Total   *MetricSourceRef `yaml:"total,omitempty"`
+}
+
+// MetricSourceRef wraps a metric source.
+type MetricSourceRef struct {
+	MetricSource MetricSource `yaml:"metricSource"`
+}
+
+// MetricSource is the query of a metric on a data source.
+type MetricSource struct {
+	MetricSourceRef string            `yaml:"metricSourceRef,omitempty"`
+	Type            string            `yaml:"type,omitempty"`
+	Spec            map[string]string `yaml:"spec"`
+}
+
+// SLO is an OpenSLO service level objective.
+type SLO struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Metadata   Metadata `yaml:"metadata"`
+	Spec       SLOSpec  `yaml:"spec"`
+}
+
+// SLOSpec is the spec of an OpenSLO SLO.
+type SLOSpec struct {
+	Description     string       `yaml:"description,omitempty"`
+	Service         string       `yaml:"service"`
+	IndicatorRef    string       `yaml:"indicatorRef,omitempty"`
+	Indicator       *SLI         `yaml:"indicator,omitempty"`
+	TimeWindow      []TimeWindow `yaml:"timeWindow,omitempty"`
+	BudgetingMethod string       `yaml:"budgetingMethod"`
+	Objectives      []Objective  `yaml:"objectives"`
+	// AlertPolicies are not supported, only kept to report them.
+	AlertPolicies []yaml.Node `yaml:"alertPolicies,omitempty"`
+}
+
+// TimeWindow is the time window of an SLO.
+type TimeWindow struct {
+	Duration  string `yaml:"duration"`
+	IsRolling bool   `yaml:"isRolling"`
+}
+
+// Objective is a target of an SLO, the threshold metric SLIs also have the
+// operator and value that make an event good.
+type Objective struct {
+	DisplayName   string   `yaml:"displayName,omitempty"`
+	Op            string   `yaml:"op,omitempty"`
+	Value         *float64 `yaml:"value,omitempty"`
+	Target        float64  `yaml:"target,omitempty"`
+	TargetPercent float64  `yaml:"targetPercent,omitempty"`
+}
+
+// Objective operators.
+const (
+	OpLT  = "lt"
+	OpLTE = "lte"
+	OpGT  = "gt"
+	OpGTE = "gte"
+)
+
+// Encode writes the OpenSLO objects as a multi document YAML stream.
+func Encode(w io.Writer, objs []interface{}) error {
+	for i, obj := range objs {
+		var b bytes.Buffer
+		enc := yaml.NewEncoder(&b)
+		enc.SetIndent(2)
+		if err := enc.Encode(obj); err != nil {
+			return err
+		}
+		if err := enc.Close(); err != nil {
+			return err
+		}
+
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(b.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unsupported is an OpenSLO object of a kind that doesn't have an equivalent on
+// the service levels (e.g. alert policies).
+type Unsupported struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	// Line is the line of the object on the read stream.
+	Line int `yaml:"-"`
+}
+
+// Read reads the OpenSLO objects of a multi document YAML stream, the objects
+// are returned as pointers of their kind type.
+func Read(r io.Reader) ([]interface{}, error) {
+	var objs []interface{}
+	dec := yaml.NewDecoder(r)
+	for {
+		node := &yaml.Node{}
+		err := dec.Decode(node)
+		if err == io.EOF {
+			return objs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Empty documents.
+		if len(node.Content) == 0 || node.Content[0].Kind == yaml.ScalarNode && node.Content[0].Tag == "!!null" {
+			continue
+		}
+		line := node.Content[0].Line
+
+		var header struct {
+			APIVersion string `yaml:"apiVersion"`
+			Kind       string `yaml:"kind"`
+		}
+		if err := node.Decode(&header); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		if header.APIVersion != APIVersion {
+			return nil, fmt.Errorf("line %d: unsupported %q OpenSLO version, should be %s", line, header.APIVersion, APIVersion)
+		}
+
+		var obj interface{}
+		switch header.Kind {
+		case KindService:
+			obj = &Service{}
+		case KindSLI:
+			obj = &SLI{}
+		case KindSLO:
+			obj = &SLO{}
+		case KindDataSource:
+			obj = &DataSource{}
+		case KindAlertPolicy, KindAlertCondition, KindAlertNotificationTarget:
+			obj = &Unsupported{Line: line}
+		default:
+			return nil, fmt.Errorf("line %d: unknown %q OpenSLO kind", line, header.Kind)
+		}
+		if err := node.Decode(obj); err != nil {
+			return nil, fmt.Errorf("line %d: invalid %s: %s", line, header.Kind, err)
+		}
+		objs = append(objs, obj)
+	}
+}
@@ -1,8 +1,13 @@
 package output
 
 import (
+	"context"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
 	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
 	"github.com/spotahome/service-level-operator/pkg/service/metrics"
 	"github.com/spotahome/service-level-operator/pkg/service/sli"
@@ -26,12 +31,46 @@ func NewMetricsMiddleware(metricssvc metrics.Service, kind string, next Output)
 }
 
 // Create satisfies slo.Output interface.
-func (m metricsMiddleware) Create(serviceLevel *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO, result *sli.Result) (err error) {
+func (m metricsMiddleware) Create(ctx context.Context, cluster string, serviceLevel *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO, result *sli.Result) (err error) {
 	defer func(t time.Time) {
 		m.metricssvc.ObserveOuputCreateDuration(slo, m.kind, t)
 		if err != nil {
 			m.metricssvc.IncOuputCreateError(slo, m.kind)
 		}
 	}(time.Now())
-	return m.next.Create(serviceLevel, slo, result)
+	return m.next.Create(ctx, cluster, serviceLevel, slo, result)
+}
+
+// tracingMiddleware will trace the calls to the SLO output.
+type tracingMiddleware struct {
+	kind   string
+	tracer trace.Tracer
+	next   Output
+}
+
+// NewTracingMiddleware returns a new tracing middleware that wraps a Output SLO
+// service and traces every output creation.
+func NewTracingMiddleware(tracer trace.Tracer, kind string, next Output) Output {
+	return tracingMiddleware{
+		kind:   kind,
+		tracer: tracer,
+		next:   next,
+	}
+}
+
+// Create satisfies slo.Output interface.
+func (t tracingMiddleware) Create(ctx context.Context, cluster string, serviceLevel *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO, result *sli.Result) error {
+	ctx, span := t.tracer.Start(ctx, "Output.Create", trace.WithAttributes(
+		attribute.String("output.kind", t.kind),
+		attribute.String("slo", slo.Name),
+	))
+	defer span.End()
+
+	err := t.next.Create(ctx, cluster, serviceLevel, slo, result)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return err
 }
@@ -1,6 +1,8 @@
 package output
 
 import (
+	"context"
+
 	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
 	"github.com/spotahome/service-level-operator/pkg/log"
 	"github.com/spotahome/service-level-operator/pkg/service/sli"
@@ -9,8 +11,54 @@ import (
 // Output knows how expose/send/create the output of a SLO and SLI result.
 type Output interface {
 	// Create will create the SLI result and the SLO on the specific format.
-	// It receives the SLI's SLO and it's result.
-	Create(serviceLevel *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO, result *sli.Result) error
+	// It receives the cluster of the service level (empty on the default
+	// cluster), the SLI's SLO and it's result.
+	Create(ctx context.Context, cluster string, serviceLevel *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO, result *sli.Result) error
+}
+
+// SLOCounters are the counters of an SLO held by an output.
+type SLOCounters struct {
+	// ErrorRatioSum is the sum of the error ratios of all the SLI results.
+	ErrorRatioSum float64 `json:"errorRatioSum"`
+	// Count is the number of SLI results.
+	Count float64 `json:"count"`
+	// Objective is the objective of the SLO in ratio unit.
+	Objective float64 `json:"objective"`
+}
+
+// ErrorBudget returns the availability and the remaining error budget (both in
+// ratio unit) of the counters, false if there aren't SLI results. The remaining
+// error budget is negative when the budget has been exhausted.
+func (c SLOCounters) ErrorBudget() (availability, remaining float64, ok bool) {
+	if c.Count <= 0 {
+		return 0, 0, false
+	}
+
+	errRat := c.ErrorRatioSum / c.Count
+	availability = 1 - errRat
+	budget := 1 - c.Objective
+	if budget <= 0 {
+		// With a 100% objective any error exhausts the budget.
+		if errRat > 0 {
+			return availability, -1, true
+		}
+		return availability, 0, true
+	}
+
+	return availability, 1 - errRat/budget, true
+}
+
+// ErrorBudgetExhausted returns true if a remaining error budget returned by
+// ErrorBudget has been exhausted. A 100% objective doesn't have error budget
+// but it's only exhausted when there are errors.
+func ErrorBudgetExhausted(remaining float64) bool {
+	return remaining < 0
+}
+
+// CounterGetter knows how to get the SLO counters held by an output.
+type CounterGetter interface {
+	// GetSLOCounters returns the counters of the SLO of a cluster, false if the output doesn't have them.
+	GetSLOCounters(cluster string, serviceLevel *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO) (SLOCounters, bool)
 }
 
 type logger struct {
@@ -26,7 +74,7 @@ func NewLogger(l log.Logger) Output {
 }
 
 // Create will log the result on the console.
-func (l *logger) Create(serviceLevel *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO, result *sli.Result) error {
+func (l *logger) Create(_ context.Context, _ string, serviceLevel *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO, result *sli.Result) error {
 	errorRat, err := result.ErrorRatio()
 	if err != nil {
 		return err
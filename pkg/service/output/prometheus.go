@@ -1,6 +1,7 @@
 package output
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -23,6 +24,7 @@ const (
 // of the metrics so when the collector is called it creates
 // the metrics based on this values.
 type metricValue struct {
+	cluster      string
 	serviceLevel *monitoringv1alpha1.ServiceLevel
 	slo          *monitoringv1alpha1.SLO
 	errorSum     float64
@@ -85,12 +87,12 @@ func NewPrometheus(cfg PrometheusCfg, reg prometheus.Registerer, logger log.Logg
 
 // Create satisfies output interface. By setting the correct values on the different
 // metrics of the SLO.
-func (p *prometheusOutput) Create(serviceLevel *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO, result *sli.Result) error {
+func (p *prometheusOutput) Create(_ context.Context, cluster string, serviceLevel *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO, result *sli.Result) error {
 	p.metricValuesMu.Lock()
 	defer p.metricValuesMu.Unlock()
 
 	// Get the current metrics for the SLO.
-	sloID := fmt.Sprintf("%s-%s-%s", serviceLevel.Namespace, serviceLevel.Name, slo.Name)
+	sloID := getSLOID(cluster, serviceLevel, slo)
 	if _, ok := p.metricValues[sloID]; !ok {
 		p.metricValues[sloID] = &metricValue{}
 	}
@@ -108,6 +110,7 @@ func (p *prometheusOutput) Create(serviceLevel *monitoringv1alpha1.ServiceLevel,
 	}
 
 	metric := p.metricValues[sloID]
+	metric.cluster = cluster
 	metric.serviceLevel = serviceLevel
 	metric.slo = slo
 	metric.errorSum += errRat
@@ -120,6 +123,29 @@ func (p *prometheusOutput) Create(serviceLevel *monitoringv1alpha1.ServiceLevel,
 	return nil
 }
 
+// GetSLOCounters satisfies output.CounterGetter interface.
+func (p *prometheusOutput) GetSLOCounters(cluster string, serviceLevel *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO) (SLOCounters, bool) {
+	p.metricValuesMu.Lock()
+	defer p.metricValuesMu.Unlock()
+
+	metric, ok := p.metricValues[getSLOID(cluster, serviceLevel, slo)]
+	if !ok {
+		return SLOCounters{}, false
+	}
+
+	return SLOCounters{
+		ErrorRatioSum: metric.errorSum,
+		Count:         metric.countSum,
+		Objective:     metric.objective,
+	}, true
+}
+
+// getSLOID returns the ID of the SLO metrics. The cluster is part of the ID so the
+// same SLO on different clusters doesn't share the counters.
+func getSLOID(cluster string, serviceLevel *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO) string {
+	return fmt.Sprintf("%s-%s-%s-%s", cluster, serviceLevel.Namespace, serviceLevel.Name, slo.Name)
+}
+
 // Describe satisfies prometheus.Collector interface.
 func (p *prometheusOutput) Describe(chan<- *prometheus.Desc) {}
 
@@ -139,62 +165,88 @@ func (p *prometheusOutput) Collect(ch chan<- prometheus.Metric) {
 			continue
 		}
 
-		ns := metric.serviceLevel.Namespace
-		slName := metric.serviceLevel.Name
-		sloName := metric.slo.Name
+		labelNames, labelValues := p.getVariableLabels(metric)
 		var labels map[string]string
 		// Check just in case.
 		if metric.slo.Output.Prometheus != nil && metric.slo.Output.Prometheus.Labels != nil {
-			labels = metric.slo.Output.Prometheus.Labels
+			labels = p.getConstLabels(metric)
 		}
 
-		ch <- p.getSLIErrorMetric(ns, slName, sloName, labels, metric.errorSum)
-		ch <- p.getSLICountMetric(ns, slName, sloName, labels, metric.countSum)
-		ch <- p.getSLOObjectiveMetric(ns, slName, sloName, labels, metric.objective)
+		ch <- p.getSLIErrorMetric(labelNames, labelValues, labels, metric.errorSum)
+		ch <- p.getSLICountMetric(labelNames, labelValues, labels, metric.countSum)
+		ch <- p.getSLOObjectiveMetric(labelNames, labelValues, labels, metric.objective)
 	}
 
 	// Collect all SLOs metric.
 	p.logger.Debugf("finished collecting all the service level metrics")
 }
 
-func (p *prometheusOutput) getSLIErrorMetric(ns, serviceLevel, slo string, constLabels prometheus.Labels, value float64) prometheus.Metric {
+// getVariableLabels returns the label names and values of the SLO metrics. The
+// cluster label is only set when the service level belongs to a named cluster.
+func (p *prometheusOutput) getVariableLabels(metric *metricValue) (names []string, values []string) {
+	names = []string{"namespace", "service_level", "slo"}
+	values = []string{metric.serviceLevel.Namespace, metric.serviceLevel.Name, metric.slo.Name}
+	if cluster := metric.cluster; cluster != "" {
+		names = append(names, "cluster")
+		values = append(values, cluster)
+	}
+
+	return names, values
+}
+
+// getConstLabels returns the SLO output labels. The labels already set by the
+// operator are dropped, a repeated label name would make the metrics invalid.
+func (p *prometheusOutput) getConstLabels(metric *metricValue) map[string]string {
+	labels := map[string]string{}
+	for k, v := range metric.slo.Output.Prometheus.Labels {
+		if monitoringv1alpha1.ReservedOutputLabels[k] {
+			p.logger.With("slo", metric.slo.Name).With("service-level", metric.serviceLevel.Name).Warningf("%q output label is already set by the operator, ignoring", k)
+			continue
+		}
+		labels[k] = v
+	}
+
+	return labels
+}
+
+func (p *prometheusOutput) getSLIErrorMetric(labelNames, labelValues []string, constLabels prometheus.Labels, value float64) prometheus.Metric {
 	return prometheus.MustNewConstMetric(
 		prometheus.NewDesc(
 			prometheus.BuildFQName(promNS, promSLISubsystem, "result_error_ratio_total"),
 			"Is the error or failure ratio of an SLI result.",
-			[]string{"namespace", "service_level", "slo"},
+			labelNames,
 			constLabels,
 		),
 		prometheus.CounterValue,
 		value,
-		ns, serviceLevel, slo,
+		labelValues...,
 	)
 }
 
-func (p *prometheusOutput) getSLICountMetric(ns, serviceLevel, slo string, constLabels prometheus.Labels, value float64) prometheus.Metric {
+func (p *prometheusOutput) getSLICountMetric(labelNames, labelValues []string, constLabels prometheus.Labels, value float64) prometheus.Metric {
 	return prometheus.MustNewConstMetric(
 		prometheus.NewDesc(
 			prometheus.BuildFQName(promNS, promSLISubsystem, "result_count_total"),
 			"Is the number of times an SLI result has been processed.",
-			[]string{"namespace", "service_level", "slo"},
+			labelNames,
 			constLabels,
 		),
 		prometheus.CounterValue,
 		value,
-		ns, serviceLevel, slo,
+		labelValues...,
 	)
 }
 
-func (p *prometheusOutput) getSLOObjectiveMetric(ns, serviceLevel, slo string, constLabels prometheus.Labels, value float64) prometheus.Metric {
+func (p *prometheusOutput) getSLOObjectiveMetric(labelNames, labelValues []string, constLabels prometheus.Labels, value float64) prometheus.Metric {
 	return prometheus.MustNewConstMetric(
 		prometheus.NewDesc(
 			prometheus.BuildFQName(promNS, promSLOSubsystem, "objective_ratio"),
 			"Is the objective of the SLO in ratio unit.",
-			[]string{"namespace", "service_level", "slo"},
+			labelNames,
 			constLabels,
 		),
 		prometheus.GaugeValue,
 		value,
-		ns, serviceLevel, slo,
+		labelValues...,
 	)
 }
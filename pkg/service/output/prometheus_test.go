@@ -1,6 +1,7 @@
 package output_test
 
 import (
+	"context"
 	"io/ioutil"
 	"net/http/httptest"
 	"testing"
@@ -63,6 +64,18 @@ var (
 			},
 		},
 	}
+	slo12 = &monitoringv1alpha1.SLO{
+		Name:                         "slo12-test",
+		AvailabilityObjectivePercent: 99,
+		Output: monitoringv1alpha1.Output{
+			Prometheus: &monitoringv1alpha1.PrometheusOutputSource{
+				Labels: map[string]string{
+					"cluster": "cluster1",
+					"team":    "team1",
+				},
+			},
+		},
+	}
 )
 
 func TestPrometheusOutput(t *testing.T) {
@@ -76,7 +89,7 @@ func TestPrometheusOutput(t *testing.T) {
 		{
 			name: "Creating a output result should expose all the required metrics",
 			createResults: func(output output.Output) {
-				output.Create(sl0, slo00, &sli.Result{
+				output.Create(context.Background(), "", sl0, slo00, &sli.Result{
 					TotalQ: 1000000,
 					ErrorQ: 122,
 				})
@@ -93,7 +106,7 @@ func TestPrometheusOutput(t *testing.T) {
 				ExpireDuration: 500 * time.Microsecond,
 			},
 			createResults: func(output output.Output) {
-				output.Create(sl0, slo00, &sli.Result{
+				output.Create(context.Background(), "", sl0, slo00, &sli.Result{
 					TotalQ: 1000000,
 					ErrorQ: 122,
 				})
@@ -119,7 +132,7 @@ func TestPrometheusOutput(t *testing.T) {
 					&sli.Result{TotalQ: 9019, ErrorQ: 1001},
 				}
 				for _, sli := range slis {
-					output.Create(sl0, slo00, sli)
+					output.Create(context.Background(), "", sl0, slo00, sli)
 				}
 			},
 			expMetrics: []string{
@@ -131,23 +144,23 @@ func TestPrometheusOutput(t *testing.T) {
 		{
 			name: "Creating a output result should expose all the required metrics (multiple SLOs).",
 			createResults: func(output output.Output) {
-				output.Create(sl0, slo00, &sli.Result{
+				output.Create(context.Background(), "", sl0, slo00, &sli.Result{
 					TotalQ: 1000000,
 					ErrorQ: 122,
 				})
-				output.Create(sl0, slo01, &sli.Result{
+				output.Create(context.Background(), "", sl0, slo01, &sli.Result{
 					TotalQ: 1011,
 					ErrorQ: 340,
 				})
-				output.Create(sl1, slo10, &sli.Result{
+				output.Create(context.Background(), "", sl1, slo10, &sli.Result{
 					TotalQ: 9212,
 					ErrorQ: 1,
 				})
-				output.Create(sl1, slo10, &sli.Result{
+				output.Create(context.Background(), "", sl1, slo10, &sli.Result{
 					TotalQ: 3456,
 					ErrorQ: 3,
 				})
-				output.Create(sl1, slo11, &sli.Result{
+				output.Create(context.Background(), "", sl1, slo11, &sli.Result{
 					TotalQ: 998,
 					ErrorQ: 7,
 				})
@@ -170,6 +183,34 @@ func TestPrometheusOutput(t *testing.T) {
 				`service_level_slo_objective_ratio{env="test",namespace="ns1",service_level="sl1-test",slo="slo11-test",team="team1"} 0.959981`,
 			},
 		},
+		{
+			name: "Creating a output result of the same SLO on multiple clusters should expose independent metrics.",
+			createResults: func(output output.Output) {
+				output.Create(context.Background(), "cluster0", sl0, slo00, &sli.Result{TotalQ: 1000000, ErrorQ: 122})
+				output.Create(context.Background(), "cluster0", sl0, slo00, &sli.Result{TotalQ: 1000000, ErrorQ: 122})
+				output.Create(context.Background(), "cluster1", sl0, slo00, &sli.Result{TotalQ: 1000, ErrorQ: 1})
+			},
+			expMetrics: []string{
+				`service_level_sli_result_error_ratio_total{cluster="cluster0",namespace="ns0",service_level="sl0-test",slo="slo00-test"} 0.000244`,
+				`service_level_sli_result_count_total{cluster="cluster0",namespace="ns0",service_level="sl0-test",slo="slo00-test"} 2`,
+				`service_level_slo_objective_ratio{cluster="cluster0",namespace="ns0",service_level="sl0-test",slo="slo00-test"} 0.9999899999999999`,
+
+				`service_level_sli_result_error_ratio_total{cluster="cluster1",namespace="ns0",service_level="sl0-test",slo="slo00-test"} 0.001`,
+				`service_level_sli_result_count_total{cluster="cluster1",namespace="ns0",service_level="sl0-test",slo="slo00-test"} 1`,
+				`service_level_slo_objective_ratio{cluster="cluster1",namespace="ns0",service_level="sl0-test",slo="slo00-test"} 0.9999899999999999`,
+			},
+		},
+		{
+			name: "The SLO output labels already set by the operator should be ignored.",
+			createResults: func(output output.Output) {
+				output.Create(context.Background(), "cluster0", sl1, slo12, &sli.Result{TotalQ: 1000, ErrorQ: 1})
+			},
+			expMetrics: []string{
+				`service_level_sli_result_error_ratio_total{cluster="cluster0",namespace="ns1",service_level="sl1-test",slo="slo12-test",team="team1"} 0.001`,
+				`service_level_sli_result_count_total{cluster="cluster0",namespace="ns1",service_level="sl1-test",slo="slo12-test",team="team1"} 1`,
+				`service_level_slo_objective_ratio{cluster="cluster0",namespace="ns1",service_level="sl1-test",slo="slo12-test",team="team1"} 0.99`,
+			},
+		},
 	}
 
 	for _, test := range tests {
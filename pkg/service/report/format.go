@@ -0,0 +1,303 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Report formats.
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatCSV      = "csv"
+)
+
+// Formats are the supported report formats.
+var Formats = []string{FormatMarkdown, FormatHTML, FormatCSV}
+
+// Extension returns the file extension of a report format.
+func Extension(format string) string {
+	switch format {
+	case FormatMarkdown:
+		return ".md"
+	case FormatHTML:
+		return ".html"
+	}
+	return "." + format
+}
+
+// Write writes the report on a format.
+func Write(w io.Writer, r *Report, format string) error {
+	switch format {
+	case FormatMarkdown:
+		return WriteMarkdown(w, r)
+	case FormatHTML:
+		return WriteHTML(w, r)
+	case FormatCSV:
+		return WriteCSV(w, r)
+	}
+	return fmt.Errorf("unknown %q report format, should be one of: %s", format, strings.Join(Formats, ", "))
+}
+
+// WriteMarkdown writes the report as a Markdown document.
+func WriteMarkdown(w io.Writer, r *Report) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# SLO compliance report\n\n")
+	fmt.Fprintf(&b, "Period: %s\n\n", r.period())
+	fmt.Fprintf(&b, "%s\n\n", r.summary())
+
+	cluster := r.hasClusters()
+	if cluster {
+		b.WriteString("| Cluster ")
+	}
+	b.WriteString("| Namespace | Service level | SLO | Objective | Availability | Budget consumed | Status | Worst days |\n")
+	if cluster {
+		b.WriteString("|---")
+	}
+	b.WriteString("|---|---|---|---:|---:|---:|---|---|\n")
+	for _, s := range r.SLOs {
+		if cluster {
+			fmt.Fprintf(&b, "| %s ", markdownEscape(s.Cluster))
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s | %s |\n",
+			markdownEscape(s.Namespace),
+			markdownEscape(s.ServiceLevel),
+			markdownEscape(s.SLO),
+			formatPercent(s.ObjectivePercent),
+			s.availability(),
+			s.budgetConsumed(),
+			markdownStatus(s.Status()),
+			s.worstDays())
+	}
+
+	if errs := r.errors(); len(errs) > 0 {
+		b.WriteString("\n## Not reported SLOs\n\n")
+		for _, e := range errs {
+			fmt.Fprintf(&b, "- %s\n", markdownEscape(e))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCSV writes the report as CSV, a row per SLO.
+func WriteCSV(w io.Writer, r *Report) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"period_start", "period_end", "cluster", "namespace", "service_level", "slo", "objective_percent", "availability_percent", "error_budget_consumed_percent", "status", "worst_days", "error"})
+	if err != nil {
+		return err
+	}
+
+	for _, s := range r.SLOs {
+		var availability, consumed string
+		var worst []string
+		if s.reported() {
+			availability = strconv.FormatFloat(s.AvailabilityRatio*100, 'f', 4, 64)
+			consumed = strconv.FormatFloat(s.ErrorBudgetConsumedRatio*100, 'f', 2, 64)
+			for _, d := range s.WorstDays {
+				worst = append(worst, fmt.Sprintf("%s:%s", d.Day.Format(dateFormat), strconv.FormatFloat(d.AvailabilityRatio*100, 'f', 4, 64)))
+			}
+		}
+		err := cw.Write([]string{
+			r.Start.Format(dateFormat),
+			r.lastDay().Format(dateFormat),
+			s.Cluster,
+			s.Namespace,
+			s.ServiceLevel,
+			s.SLO,
+			strconv.FormatFloat(s.ObjectivePercent, 'f', -1, 64),
+			availability,
+			consumed,
+			s.Status(),
+			strings.Join(worst, ";"),
+			s.Error,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+var htmlTpl = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>SLO compliance report {{ .Period }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.4em 0.8em; text-align: left; }
+td.number { text-align: right; }
+.met { color: #1a7f37; font-weight: bold; }
+.missed { color: #cf222e; font-weight: bold; }
+.unknown, .disabled { color: #777; }
+</style>
+</head>
+<body>
+<h1>SLO compliance report</h1>
+<p>Period: {{ .Period }}</p>
+<p>{{ .Summary }}</p>
+<table>
+<thead>
+<tr>{{ if .Clusters }}<th>Cluster</th>{{ end }}<th>Namespace</th><th>Service level</th><th>SLO</th><th>Objective</th><th>Availability</th><th>Budget consumed</th><th>Status</th><th>Worst days</th></tr>
+</thead>
+<tbody>
+{{- range .SLOs }}
+<tr>{{ if $.Clusters }}<td>{{ .Cluster }}</td>{{ end }}<td>{{ .Namespace }}</td><td>{{ .ServiceLevel }}</td><td title="{{ .Description }}">{{ .SLO }}</td><td class="number">{{ .Objective }}</td><td class="number">{{ .Availability }}</td><td class="number">{{ .BudgetConsumed }}</td><td class="{{ .Status }}">{{ .Status }}</td><td>{{ .WorstDays }}</td></tr>
+{{- end }}
+</tbody>
+</table>
+{{- if .Errors }}
+<h2>Not reported SLOs</h2>
+<ul>
+{{- range .Errors }}
+<li>{{ . }}</li>
+{{- end }}
+</ul>
+{{- end }}
+</body>
+</html>
+`))
+
+// WriteHTML writes the report as a standalone HTML document.
+func WriteHTML(w io.Writer, r *Report) error {
+	type sloRow struct {
+		Cluster, Namespace, ServiceLevel, SLO, Description string
+		Objective, Availability, BudgetConsumed            string
+		Status, WorstDays                                  string
+	}
+	data := struct {
+		Period, Summary string
+		Clusters        bool
+		SLOs            []sloRow
+		Errors          []string
+	}{
+		Period:   r.period(),
+		Summary:  r.summary(),
+		Clusters: r.hasClusters(),
+		Errors:   r.errors(),
+	}
+	for _, s := range r.SLOs {
+		data.SLOs = append(data.SLOs, sloRow{
+			Cluster:        s.Cluster,
+			Namespace:      s.Namespace,
+			ServiceLevel:   s.ServiceLevel,
+			SLO:            s.SLO,
+			Description:    s.Description,
+			Objective:      formatPercent(s.ObjectivePercent),
+			Availability:   s.availability(),
+			BudgetConsumed: s.budgetConsumed(),
+			Status:         s.Status(),
+			WorstDays:      s.worstDays(),
+		})
+	}
+	return htmlTpl.Execute(w, data)
+}
+
+const dateFormat = "2006-01-02"
+
+// lastDay returns the last day of the period, the end is not included.
+func (r *Report) lastDay() time.Time {
+	return r.End.Add(-day)
+}
+
+func (r *Report) period() string {
+	return fmt.Sprintf("%s to %s (%s)", r.Start.Format(dateFormat), r.lastDay().Format(dateFormat), r.Start.Location())
+}
+
+func (r *Report) summary() string {
+	var met, total int
+	for _, s := range r.SLOs {
+		if !s.reported() {
+			continue
+		}
+		total++
+		if s.ObjectiveMet {
+			met++
+		}
+	}
+	return fmt.Sprintf("%d of %d reported SLOs met their objective.", met, total)
+}
+
+func (r *Report) hasClusters() bool {
+	for _, s := range r.SLOs {
+		if s.Cluster != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Report) errors() []string {
+	var errs []string
+	for _, s := range r.SLOs {
+		if s.Error == "" {
+			continue
+		}
+		id := fmt.Sprintf("%s/%s/%s", s.Namespace, s.ServiceLevel, s.SLO)
+		if s.Cluster != "" {
+			id = s.Cluster + "/" + id
+		}
+		errs = append(errs, fmt.Sprintf("%s: %s", id, s.Error))
+	}
+	return errs
+}
+
+func (s SLOReport) reported() bool {
+	return !s.Disabled && s.Error == ""
+}
+
+func (s SLOReport) availability() string {
+	if !s.reported() {
+		return "-"
+	}
+	return formatPercent(s.AvailabilityRatio * 100)
+}
+
+func (s SLOReport) budgetConsumed() string {
+	if !s.reported() {
+		return "-"
+	}
+	return strconv.FormatFloat(s.ErrorBudgetConsumedRatio*100, 'f', 1, 64) + "%"
+}
+
+func (s SLOReport) worstDays() string {
+	if !s.reported() || len(s.WorstDays) == 0 {
+		return "-"
+	}
+	days := make([]string, 0, len(s.WorstDays))
+	for _, d := range s.WorstDays {
+		days = append(days, fmt.Sprintf("%s (%s)", d.Day.Format(dateFormat), formatPercent(d.AvailabilityRatio*100)))
+	}
+	return strings.Join(days, ", ")
+}
+
+// formatPercent formats a percent with 3 decimals at most, so the objectives
+// like 99.95 are not rounded.
+func formatPercent(p float64) string {
+	s := strconv.FormatFloat(p, 'f', 3, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	return s + "%"
+}
+
+func markdownStatus(status string) string {
+	if status == "missed" {
+		return "**missed**"
+	}
+	return status
+}
+
+var markdownReplacer = strings.NewReplacer("|", `\|`, "\n", " ")
+
+func markdownEscape(s string) string {
+	return markdownReplacer.Replace(s)
+}
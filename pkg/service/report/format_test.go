@@ -0,0 +1,92 @@
+package report_test
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spotahome/service-level-operator/pkg/service/report"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func newReport() *report.Report {
+	return &report.Report{
+		Start: start,
+		End:   start.AddDate(0, 1, 0),
+		SLOs: []report.SLOReport{
+			{
+				Namespace: "shop", ServiceLevel: "checkout", SLO: "availability", ObjectivePercent: 99.95,
+				Description:              "Requests without <5xx> errors.",
+				AvailabilityRatio:        0.99971,
+				ErrorBudgetConsumedRatio: 0.58,
+				ObjectiveMet:             true,
+				WorstDays: []report.DayAvailability{
+					{Day: start.AddDate(0, 0, 11), AvailabilityRatio: 0.9962},
+					{Day: start.AddDate(0, 0, 3), AvailabilityRatio: 0.9991},
+				},
+			},
+			{
+				Namespace: "shop", ServiceLevel: "checkout", SLO: "latency|p99", ObjectivePercent: 99,
+				AvailabilityRatio:        0.9854,
+				ErrorBudgetConsumedRatio: 1.46,
+				WorstDays:                []report.DayAvailability{{Day: start.AddDate(0, 0, 20), AvailabilityRatio: 0.912}},
+			},
+			{Namespace: "shop", ServiceLevel: "cart", SLO: "availability", ObjectivePercent: 99.9, Disabled: true},
+			{Namespace: "shop", ServiceLevel: "cart", SLO: "latency", ObjectivePercent: 99, Error: "no SLI results on the period"},
+		},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tests := map[string]struct {
+		format string
+		golden string
+		expErr bool
+	}{
+		"Markdown reports should have a table with the SLOs.": {
+			format: report.FormatMarkdown,
+			golden: "report.golden.md",
+		},
+		"HTML reports should be standalone documents.": {
+			format: report.FormatHTML,
+			golden: "report.golden.html",
+		},
+		"CSV reports should have a row per SLO.": {
+			format: report.FormatCSV,
+			golden: "report.golden.csv",
+		},
+		"Unknown formats should fail.": {
+			format: "pdf",
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var b bytes.Buffer
+			err := report.Write(&b, newReport(), test.format)
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			golden := filepath.Join("testdata", test.golden)
+			if *update {
+				require.NoError(ioutil.WriteFile(golden, b.Bytes(), 0644))
+			}
+			exp, err := ioutil.ReadFile(golden)
+			require.NoError(err)
+			assert.Equal(string(exp), b.String())
+		})
+	}
+}
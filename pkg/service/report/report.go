@@ -0,0 +1,239 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+)
+
+const (
+	day          = 24 * time.Hour
+	defWorstDays = 3
+)
+
+// Config is the configuration of a report.
+type Config struct {
+	// Start is the start of the reported period.
+	Start time.Time
+	// End is the end of the reported period, the period should have whole days.
+	End time.Time
+	// WorstDays is the number of days with the lowest availability of every SLO
+	// on the report, by default 3.
+	WorstDays int
+}
+
+func (c *Config) defaults() error {
+	if c.WorstDays == 0 {
+		c.WorstDays = defWorstDays
+	}
+	if c.WorstDays < 0 {
+		return fmt.Errorf("worst days can't be negative")
+	}
+	if !c.End.After(c.Start) {
+		return fmt.Errorf("end must be after start")
+	}
+	if c.End.Sub(c.Start)%day != 0 {
+		return fmt.Errorf("the reported period must have whole days")
+	}
+	return nil
+}
+
+// Month returns the start and end of the month of a time, on the time location.
+func Month(t time.Time) (start, end time.Time) {
+	start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	return start, start.AddDate(0, 1, 0)
+}
+
+// ServiceLevel is a reported service level of a cluster.
+type ServiceLevel struct {
+	// Cluster is the cluster of the service level, empty on the default cluster.
+	Cluster string
+	*monitoringv1alpha1.ServiceLevel
+}
+
+// Report is the compliance report of the SLOs on a period.
+type Report struct {
+	Start time.Time   `json:"start"`
+	End   time.Time   `json:"end"`
+	SLOs  []SLOReport `json:"slos"`
+}
+
+// SLOReport is the compliance of an SLO on the reported period.
+type SLOReport struct {
+	Cluster          string  `json:"cluster,omitempty"`
+	Namespace        string  `json:"namespace"`
+	ServiceLevel     string  `json:"serviceLevel"`
+	SLO              string  `json:"slo"`
+	Description      string  `json:"description,omitempty"`
+	ObjectivePercent float64 `json:"objectivePercent"`
+	Disabled         bool    `json:"disabled,omitempty"`
+	// Counters are the increase of the operator output counters on the period.
+	Counters output.SLOCounters `json:"counters"`
+	// AvailabilityRatio is the achieved availability on the period.
+	AvailabilityRatio float64 `json:"availabilityRatio"`
+	// ErrorBudgetConsumedRatio is the ratio of the error budget of the period
+	// that has been consumed, greater than 1 if it has been exhausted.
+	ErrorBudgetConsumedRatio float64 `json:"errorBudgetConsumedRatio"`
+	// ObjectiveMet is true when the availability is equal or greater than the objective.
+	ObjectiveMet bool `json:"objectiveMet"`
+	// WorstDays are the days with the lowest availability, worst first.
+	WorstDays []DayAvailability `json:"worstDays,omitempty"`
+	// Error is the error getting the SLO metrics, or why the SLO has not been reported.
+	Error string `json:"error,omitempty"`
+}
+
+// Status returns the compliance status of the SLO.
+func (s SLOReport) Status() string {
+	switch {
+	case s.Disabled:
+		return "disabled"
+	case s.Error != "":
+		return "unknown"
+	case s.ObjectiveMet:
+		return "met"
+	}
+	return "missed"
+}
+
+// DayAvailability is the availability of an SLO on a day.
+type DayAvailability struct {
+	// Day is the start of the day.
+	Day               time.Time `json:"day"`
+	AvailabilityRatio float64   `json:"availabilityRatio"`
+}
+
+// Generate generates the compliance report of the SLOs of the service levels
+// from the operator metrics stored on Prometheus. The increase of the operator
+// counters is queried for every day of the period, so the worst days are known
+// and the counter resets of the period are handled. The errors getting the
+// metrics of an SLO are set on its report so the rest of the SLOs are reported
+// independently.
+func Generate(ctx context.Context, cli promv1.API, sls []ServiceLevel, cfg Config) (*Report, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, err
+	}
+
+	r := &Report{Start: cfg.Start, End: cfg.End, SLOs: []SLOReport{}}
+	for _, sl := range sls {
+		for _, slo := range sl.Spec.ServiceLevelObjectives {
+			sr := SLOReport{
+				Cluster:          sl.Cluster,
+				Namespace:        sl.Namespace,
+				ServiceLevel:     sl.Name,
+				SLO:              slo.Name,
+				Description:      slo.Description,
+				ObjectivePercent: slo.AvailabilityObjectivePercent,
+				Disabled:         slo.Disable,
+			}
+			if !sr.Disabled {
+				if err := reportSLO(ctx, cli, sl, slo, cfg, &sr); err != nil {
+					sr.Error = err.Error()
+				}
+			}
+			r.SLOs = append(r.SLOs, sr)
+		}
+	}
+	return r, nil
+}
+
+func reportSLO(ctx context.Context, cli promv1.API, sl ServiceLevel, slo monitoringv1alpha1.SLO, cfg Config, sr *SLOReport) error {
+	sel := selector(sl, slo)
+	errSums, err := dailyIncrease(ctx, cli, fmt.Sprintf("sum(increase(service_level_sli_result_error_ratio_total{%s}[1d]))", sel), cfg)
+	if err != nil {
+		return err
+	}
+	counts, err := dailyIncrease(ctx, cli, fmt.Sprintf("sum(increase(service_level_sli_result_count_total{%s}[1d]))", sel), cfg)
+	if err != nil {
+		return err
+	}
+
+	objective := slo.AvailabilityObjectivePercent / 100
+	sr.Counters = output.SLOCounters{Objective: objective}
+	// The days are summed in order so the report is reproducible.
+	tss := make([]int64, 0, len(counts))
+	for ts := range counts {
+		tss = append(tss, ts)
+	}
+	sort.Slice(tss, func(i, j int) bool { return tss[i] < tss[j] })
+	days := []DayAvailability{}
+	for _, ts := range tss {
+		d := time.Unix(ts, 0).In(cfg.Start.Location())
+		count, errSum := counts[ts], errSums[ts]
+		sr.Counters.ErrorRatioSum += errSum
+		sr.Counters.Count += count
+
+		dc := output.SLOCounters{ErrorRatioSum: errSum, Count: count, Objective: objective}
+		if availability, _, ok := dc.ErrorBudget(); ok {
+			days = append(days, DayAvailability{Day: d, AvailabilityRatio: availability})
+		}
+	}
+
+	availability, remaining, ok := sr.Counters.ErrorBudget()
+	if !ok {
+		return fmt.Errorf("no SLI results on the period")
+	}
+	sr.AvailabilityRatio = availability
+	sr.ErrorBudgetConsumedRatio = 1 - remaining
+	sr.ObjectiveMet = availability >= objective
+
+	// Only the days with errors are worst days.
+	sort.SliceStable(days, func(i, j int) bool {
+		if days[i].AvailabilityRatio != days[j].AvailabilityRatio {
+			return days[i].AvailabilityRatio < days[j].AvailabilityRatio
+		}
+		return days[i].Day.Before(days[j].Day)
+	})
+	for _, d := range days {
+		if len(sr.WorstDays) == cfg.WorstDays || d.AvailabilityRatio >= 1 {
+			break
+		}
+		sr.WorstDays = append(sr.WorstDays, d)
+	}
+	return nil
+}
+
+// dailyIncrease returns the value of a daily increase query by the start (unix
+// time) of every day of the period.
+func dailyIncrease(ctx context.Context, cli promv1.API, query string, cfg Config) (map[int64]float64, error) {
+	// The increase of a day is the one at the end of the day.
+	val, _, err := cli.QueryRange(ctx, query, promv1.Range{Start: cfg.Start.Add(day), End: cfg.End, Step: day})
+	if err != nil {
+		return nil, err
+	}
+	matrix, ok := val.(model.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("received metric needs to be a matrix, received: %s", val.Type())
+	}
+	if len(matrix) > 1 {
+		return nil, fmt.Errorf("the query returned %d series, it should return a single one", len(matrix))
+	}
+
+	res := map[int64]float64{}
+	for _, ss := range matrix {
+		for _, p := range ss.Values {
+			res[p.Timestamp.Time().Add(-day).Unix()] = float64(p.Value)
+		}
+	}
+	return res, nil
+}
+
+// selector returns the label matchers of the operator metrics of an SLO.
+func selector(sl ServiceLevel, slo monitoringv1alpha1.SLO) string {
+	matchers := []string{
+		fmt.Sprintf("namespace=%q", sl.Namespace),
+		fmt.Sprintf("service_level=%q", sl.Name),
+		fmt.Sprintf("slo=%q", slo.Name),
+	}
+	if sl.Cluster != "" {
+		matchers = append(matchers, fmt.Sprintf("cluster=%q", sl.Cluster))
+	}
+	return strings.Join(matchers, ", ")
+}
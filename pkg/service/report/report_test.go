@@ -0,0 +1,148 @@
+package report_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	mpromv1 "github.com/spotahome/service-level-operator/mocks/github.com/prometheus/client_golang/api/prometheus/v1"
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/report"
+)
+
+var start = time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+
+func newServiceLevel(slos ...monitoringv1alpha1.SLO) *monitoringv1alpha1.ServiceLevel {
+	return &monitoringv1alpha1.ServiceLevel{
+		ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: "shop"},
+		Spec:       monitoringv1alpha1.ServiceLevelSpec{ServiceLevelObjectives: slos},
+	}
+}
+
+// dailyMatrix returns the daily increases of a query, the first value is the
+// increase of the first day of the period.
+func dailyMatrix(values ...float64) model.Matrix {
+	ss := &model.SampleStream{Metric: model.Metric{}}
+	for i, v := range values {
+		ss.Values = append(ss.Values, model.SamplePair{
+			Timestamp: model.TimeFromUnixNano(start.Add(time.Duration(i+1) * 24 * time.Hour).UnixNano()),
+			Value:     model.SampleValue(v),
+		})
+	}
+	return model.Matrix{ss}
+}
+
+func isQuery(metric, slo string) interface{} {
+	return mock.MatchedBy(func(q string) bool {
+		return strings.Contains(q, metric) && strings.Contains(q, `slo="`+slo+`"`)
+	})
+}
+
+func TestGenerate(t *testing.T) {
+	day := func(d int) time.Time { return start.AddDate(0, 0, d) }
+	cfg := report.Config{Start: start, End: start.AddDate(0, 0, 3), WorstDays: 1}
+
+	tests := map[string]struct {
+		slos      []monitoringv1alpha1.SLO
+		mock      func(m *mpromv1.API)
+		cfg       report.Config
+		expReport []report.SLOReport
+		expErr    bool
+	}{
+		"A period without whole days should fail.": {
+			cfg:    report.Config{Start: start, End: start.Add(36 * time.Hour)},
+			expErr: true,
+		},
+		"The SLOs should be reported with the daily increase of the operator counters.": {
+			slos: []monitoringv1alpha1.SLO{
+				{Name: "met", AvailabilityObjectivePercent: 99},
+				{Name: "missed", AvailabilityObjectivePercent: 99.5, Description: "Missed SLO."},
+			},
+			mock: func(m *mpromv1.API) {
+				m.On("QueryRange", mock.Anything, isQuery("error_ratio_total", "met"), mock.Anything).Return(dailyMatrix(0, 0.5, 1), nil, nil)
+				m.On("QueryRange", mock.Anything, isQuery("count_total", "met"), mock.Anything).Return(dailyMatrix(100, 100, 100), nil, nil)
+				m.On("QueryRange", mock.Anything, isQuery("error_ratio_total", "missed"), mock.Anything).Return(dailyMatrix(1, 0, 1.5), nil, nil)
+				m.On("QueryRange", mock.Anything, isQuery("count_total", "missed"), mock.Anything).Return(dailyMatrix(100, 100, 100), nil, nil)
+			},
+			cfg: cfg,
+			expReport: []report.SLOReport{
+				{
+					Namespace: "shop", ServiceLevel: "checkout", SLO: "met", ObjectivePercent: 99,
+					Counters:                 output.SLOCounters{ErrorRatioSum: 1.5, Count: 300, Objective: 0.99},
+					AvailabilityRatio:        0.995,
+					ErrorBudgetConsumedRatio: 0.5,
+					ObjectiveMet:             true,
+					WorstDays:                []report.DayAvailability{{Day: day(2), AvailabilityRatio: 0.99}},
+				},
+				{
+					Namespace: "shop", ServiceLevel: "checkout", SLO: "missed", ObjectivePercent: 99.5, Description: "Missed SLO.",
+					Counters:                 output.SLOCounters{ErrorRatioSum: 2.5, Count: 300, Objective: 0.995},
+					AvailabilityRatio:        1 - 2.5/300,
+					ErrorBudgetConsumedRatio: 2.5 / 300 / 0.005,
+					ObjectiveMet:             false,
+					WorstDays:                []report.DayAvailability{{Day: day(2), AvailabilityRatio: 0.985}},
+				},
+			},
+		},
+		"Disabled SLOs should not be queried and the SLO errors should not fail the report.": {
+			slos: []monitoringv1alpha1.SLO{
+				{Name: "disabled", AvailabilityObjectivePercent: 99, Disable: true},
+				{Name: "failed", AvailabilityObjectivePercent: 99},
+				{Name: "nodata", AvailabilityObjectivePercent: 99},
+			},
+			mock: func(m *mpromv1.API) {
+				m.On("QueryRange", mock.Anything, isQuery("", "failed"), mock.Anything).Return(nil, nil, errors.New("wanted error"))
+				m.On("QueryRange", mock.Anything, isQuery("", "nodata"), mock.Anything).Return(model.Matrix{}, nil, nil)
+			},
+			cfg: cfg,
+			expReport: []report.SLOReport{
+				{Namespace: "shop", ServiceLevel: "checkout", SLO: "disabled", ObjectivePercent: 99, Disabled: true},
+				{Namespace: "shop", ServiceLevel: "checkout", SLO: "failed", ObjectivePercent: 99, Error: "wanted error"},
+				{Namespace: "shop", ServiceLevel: "checkout", SLO: "nodata", ObjectivePercent: 99, Counters: output.SLOCounters{Objective: 0.99}, Error: "no SLI results on the period"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mapi := &mpromv1.API{}
+			if test.mock != nil {
+				test.mock(mapi)
+			}
+
+			r, err := report.Generate(context.Background(), mapi, []report.ServiceLevel{{ServiceLevel: newServiceLevel(test.slos...)}}, test.cfg)
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			require.Len(r.SLOs, len(test.expReport))
+			for i, exp := range test.expReport {
+				got := r.SLOs[i]
+				assert.InDelta(exp.AvailabilityRatio, got.AvailabilityRatio, 1e-9)
+				assert.InDelta(exp.ErrorBudgetConsumedRatio, got.ErrorBudgetConsumedRatio, 1e-9)
+				got.AvailabilityRatio, got.ErrorBudgetConsumedRatio = exp.AvailabilityRatio, exp.ErrorBudgetConsumedRatio
+				assert.Equal(exp, got)
+			}
+			mapi.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMonth(t *testing.T) {
+	s, e := report.Month(time.Date(2026, 3, 31, 15, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), s)
+	assert.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), e)
+}
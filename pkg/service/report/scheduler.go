@@ -0,0 +1,192 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+)
+
+const (
+	defCheckInterval  = time.Hour
+	generationTimeout = 5 * time.Minute
+	filePrefix        = "slo-report-"
+)
+
+// SchedulerCfg is the configuration of the scheduled reports.
+type SchedulerCfg struct {
+	// Dir is the directory where the reports are written.
+	Dir string
+	// Formats are the formats of the reports, by default markdown.
+	Formats []string
+	// CheckInterval is the interval between the checks of missing reports, by
+	// default 1h.
+	CheckInterval time.Duration
+	// WorstDays is the number of worst days of every SLO on the reports.
+	WorstDays int
+}
+
+func (c *SchedulerCfg) defaults() error {
+	if c.Dir == "" {
+		return fmt.Errorf("the reports directory is required")
+	}
+	if len(c.Formats) == 0 {
+		c.Formats = []string{FormatMarkdown}
+	}
+	for _, f := range c.Formats {
+		if f != FormatMarkdown && f != FormatHTML && f != FormatCSV {
+			return fmt.Errorf("unknown %q report format", f)
+		}
+	}
+	if c.CheckInterval <= 0 {
+		c.CheckInterval = defCheckInterval
+	}
+	return nil
+}
+
+// Scheduler generates the monthly report of the previous month of the service
+// levels handled by the operator. The missing reports are checked periodically,
+// so a report missed while the operator was down is generated when it starts.
+// The reports are UTC months and only have the service levels that are being
+// handled when they are generated.
+type Scheduler struct {
+	cfg    SchedulerCfg
+	cli    promv1.API
+	reader status.Reader
+	logger log.Logger
+}
+
+// NewScheduler returns a new report scheduler.
+func NewScheduler(cfg SchedulerCfg, cli promv1.API, reader status.Reader, logger log.Logger) (*Scheduler, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, err
+	}
+	return &Scheduler{
+		cfg:    cfg,
+		cli:    cli,
+		reader: reader,
+		logger: logger,
+	}, nil
+}
+
+// Run runs the scheduler until stopped. The first check is done after the
+// check interval, so the operator has handled the service levels.
+func (s *Scheduler) Run(stopC <-chan struct{}) error {
+	t := time.NewTicker(s.cfg.CheckInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			ctx, cancel := context.WithTimeout(context.Background(), generationTimeout)
+			if err := s.Generate(ctx, time.Now()); err != nil {
+				s.logger.Errorf("could not generate the SLO report: %s", err)
+			}
+			cancel()
+		case <-stopC:
+			return nil
+		}
+	}
+}
+
+// Generate generates the report of the month previous to now if it has not
+// been generated yet.
+func (s *Scheduler) Generate(ctx context.Context, now time.Time) error {
+	thisMonth, _ := Month(now.UTC())
+	start, end := Month(thisMonth.AddDate(0, -1, 0))
+
+	missing := []string{}
+	for _, f := range s.cfg.Formats {
+		if _, err := os.Stat(s.path(start, f)); os.IsNotExist(err) {
+			missing = append(missing, f)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	sls := s.serviceLevels()
+	if len(sls) == 0 {
+		s.logger.Debugf("no service levels to report")
+		return nil
+	}
+
+	r, err := Generate(ctx, s.cli, sls, Config{Start: start, End: end, WorstDays: s.cfg.WorstDays})
+	if err != nil {
+		return err
+	}
+	for _, f := range missing {
+		var b bytes.Buffer
+		if err := Write(&b, r, f); err != nil {
+			return err
+		}
+		if err := writeFile(s.path(start, f), b.Bytes()); err != nil {
+			return err
+		}
+		s.logger.Infof("SLO report %s generated", s.path(start, f))
+	}
+	return nil
+}
+
+func (s *Scheduler) path(month time.Time, format string) string {
+	return filepath.Join(s.cfg.Dir, filePrefix+month.Format("2006-01")+Extension(format))
+}
+
+// serviceLevels returns the handled service levels with their SLOs, only the
+// SLO fields used by the reports are set.
+func (s *Scheduler) serviceLevels() []ServiceLevel {
+	var sls []ServiceLevel
+	for _, st := range s.reader.ListServiceLevels() {
+		sl := ServiceLevel{
+			Cluster: st.Cluster,
+			ServiceLevel: &monitoringv1alpha1.ServiceLevel{
+				ObjectMeta: metav1.ObjectMeta{Namespace: st.Namespace, Name: st.Name},
+			},
+		}
+		for _, slo := range st.SLOs {
+			sl.Spec.ServiceLevelObjectives = append(sl.Spec.ServiceLevelObjectives, monitoringv1alpha1.SLO{
+				Name:                         slo.Name,
+				Description:                  slo.Description,
+				Disable:                      slo.Disabled,
+				AvailabilityObjectivePercent: slo.AvailabilityObjectivePercent,
+			})
+		}
+		sls = append(sls, sl)
+	}
+	return sls
+}
+
+// writeFile writes a file atomically, so incomplete reports are not found as
+// generated.
+func writeFile(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	// Temp files are only readable by the owner.
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,55 @@
+package report_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mpromv1 "github.com/spotahome/service-level-operator/mocks/github.com/prometheus/client_golang/api/prometheus/v1"
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/report"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+)
+
+func TestSchedulerGenerate(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "slo-reports")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	store := status.NewMemory(time.Minute)
+	mapi := &mpromv1.API{}
+	s, err := report.NewScheduler(report.SchedulerCfg{Dir: dir, Formats: []string{report.FormatMarkdown, report.FormatCSV}}, mapi, store, log.Dummy)
+	require.NoError(err)
+	now := time.Date(2026, 10, 5, 10, 0, 0, 0, time.UTC)
+
+	// Without service levels there is nothing to report.
+	require.NoError(s.Generate(context.Background(), now))
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(err)
+	assert.Empty(files)
+
+	// The report of the previous month should be generated once.
+	store.SetServiceLevel("", newServiceLevel(monitoringv1alpha1.SLO{Name: "availability", AvailabilityObjectivePercent: 99}))
+	mapi.On("QueryRange", mock.Anything, mock.Anything, mock.Anything).Return(dailyMatrix(0, 1), nil, nil)
+	require.NoError(s.Generate(context.Background(), now))
+	require.NoError(s.Generate(context.Background(), now.Add(time.Hour)))
+	mapi.AssertNumberOfCalls(t, "QueryRange", 2)
+
+	md, err := ioutil.ReadFile(filepath.Join(dir, "slo-report-2026-09.md"))
+	require.NoError(err)
+	assert.Contains(string(md), "Period: 2026-09-01 to 2026-09-30 (UTC)")
+	assert.Contains(string(md), "| shop | checkout | availability |")
+	_, err = os.Stat(filepath.Join(dir, "slo-report-2026-09.csv"))
+	assert.NoError(err)
+}
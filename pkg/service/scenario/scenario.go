@@ -0,0 +1,227 @@
+package scenario
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	kubernetesclifactory "github.com/spotahome/service-level-operator/pkg/service/client/kubernetes"
+	promclifactory "github.com/spotahome/service-level-operator/pkg/service/client/prometheus"
+)
+
+const (
+	defNamespace      = "default"
+	defRandomInterval = 30 * time.Second
+)
+
+// SeriesType is the type of a value series.
+type SeriesType string
+
+const (
+	// ConstantSeries always has the same value.
+	ConstantSeries SeriesType = "constant"
+	// RampSeries changes linearly from one value to another.
+	RampSeries SeriesType = "ramp"
+	// StepSeries has a value that changes to the outage value during the outage,
+	// the outage can be repeated.
+	StepSeries SeriesType = "step"
+	// RandomSeries has random values between a minimum and a maximum, the values
+	// are the same for the same seed.
+	RandomSeries SeriesType = "random"
+)
+
+// Scenario is a faked environment with the service levels and the values of
+// their queries over time, the time of the series is relative to the start of
+// the scenario.
+type Scenario struct {
+	// ServiceLevels are the faked service levels.
+	ServiceLevels []monitoringv1alpha1.ServiceLevel `json:"serviceLevels"`
+	// Queries are the value series of the faked queries by the query.
+	Queries map[string]Series `json:"queries"`
+}
+
+// Series is a value series that depends on the time.
+type Series struct {
+	// Type is the type of the series.
+	Type SeriesType `json:"type"`
+	// Value is the value of constant series and the regular value of the step series.
+	Value float64 `json:"value,omitempty"`
+	// From is the value of the ramp series at the start of the ramp.
+	From float64 `json:"from,omitempty"`
+	// To is the value of the ramp series at the end of the ramp.
+	To float64 `json:"to,omitempty"`
+	// OutageValue is the value of the step series during the outage.
+	OutageValue float64 `json:"outageValue,omitempty"`
+	// Start is when the ramp or the (first) outage of the step series starts.
+	Start metav1.Duration `json:"start,omitempty"`
+	// Duration is the duration of the ramp or the outage of the step series.
+	Duration metav1.Duration `json:"duration,omitempty"`
+	// Every is the period of the step series outages, by default the outage happens once.
+	Every metav1.Duration `json:"every,omitempty"`
+	// Min is the minimum value of the random series.
+	Min float64 `json:"min,omitempty"`
+	// Max is the maximum value of the random series.
+	Max float64 `json:"max,omitempty"`
+	// Seed is the seed of the random series.
+	Seed int64 `json:"seed,omitempty"`
+	// Interval is the time a value of the random series lasts, by default 30s.
+	Interval metav1.Duration `json:"interval,omitempty"`
+}
+
+// Load loads a scenario in YAML (or JSON) format, unknown fields are not allowed.
+func Load(r io.Reader) (*Scenario, error) {
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Scenario{}
+	err = yaml.UnmarshalStrict(bs, s)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid scenario: %s", err)
+	}
+
+	return s, nil
+}
+
+// Validate validates the scenario and sets the defaults.
+func (s *Scenario) Validate() error {
+	if len(s.ServiceLevels) == 0 {
+		return fmt.Errorf("at least one service level is required")
+	}
+
+	// The service levels are not validated so the scenarios can have invalid
+	// service levels like the real clusters.
+	names := map[string]bool{}
+	for i := range s.ServiceLevels {
+		sl := &s.ServiceLevels[i]
+		if sl.Name == "" {
+			return fmt.Errorf("service levels require a name")
+		}
+		if sl.Namespace == "" {
+			sl.Namespace = defNamespace
+		}
+		id := sl.Namespace + "/" + sl.Name
+		if names[id] {
+			return fmt.Errorf("%s service level is duplicated", id)
+		}
+		names[id] = true
+	}
+
+	for q, series := range s.Queries {
+		err := series.Validate()
+		if err != nil {
+			return fmt.Errorf("%q query: %s", q, err)
+		}
+		s.Queries[q] = series
+	}
+
+	return nil
+}
+
+// Validate validates the series and sets the defaults.
+func (s *Series) Validate() error {
+	if s.Start.Duration < 0 || s.Duration.Duration < 0 || s.Every.Duration < 0 || s.Interval.Duration < 0 {
+		return fmt.Errorf("durations can't be negative")
+	}
+
+	switch s.Type {
+	case ConstantSeries:
+	case RampSeries:
+		if s.Duration.Duration == 0 {
+			return fmt.Errorf("ramp series require a duration")
+		}
+	case StepSeries:
+		if s.Duration.Duration == 0 {
+			return fmt.Errorf("step series require an outage duration")
+		}
+		if s.Every.Duration != 0 && s.Every.Duration <= s.Duration.Duration {
+			return fmt.Errorf("step series outage period must be greater than the outage duration")
+		}
+	case RandomSeries:
+		if s.Max < s.Min {
+			return fmt.Errorf("random series max can't be less than min")
+		}
+		if s.Interval.Duration == 0 {
+			s.Interval.Duration = defRandomInterval
+		}
+	default:
+		return fmt.Errorf("unknown %q series type, should be one of: %s, %s, %s, %s", s.Type, ConstantSeries, RampSeries, StepSeries, RandomSeries)
+	}
+
+	return nil
+}
+
+// ValueAt returns the value of the series at the elapsed time since the start
+// of the scenario.
+func (s Series) ValueAt(elapsed time.Duration) float64 {
+	switch s.Type {
+	case RampSeries:
+		switch {
+		case elapsed <= s.Start.Duration:
+			return s.From
+		case elapsed >= s.Start.Duration+s.Duration.Duration:
+			return s.To
+		}
+		progress := float64(elapsed-s.Start.Duration) / float64(s.Duration.Duration)
+		return s.From + (s.To-s.From)*progress
+	case StepSeries:
+		if elapsed < s.Start.Duration {
+			return s.Value
+		}
+		sinceOutage := elapsed - s.Start.Duration
+		if s.Every.Duration > 0 {
+			sinceOutage = sinceOutage % s.Every.Duration
+		}
+		if sinceOutage < s.Duration.Duration {
+			return s.OutageValue
+		}
+		return s.Value
+	case RandomSeries:
+		// Every interval has its own source so the values don't depend on how
+		// many times the series has been queried.
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		interval := int64(elapsed / s.Interval.Duration)
+		r := rand.New(rand.NewSource(s.Seed + interval))
+		return s.Min + r.Float64()*(s.Max-s.Min)
+	default:
+		return s.Value
+	}
+}
+
+// KubernetesFactory returns a faked Kubernetes clients factory with the service
+// levels of the scenario.
+func (s *Scenario) KubernetesFactory() kubernetesclifactory.ClientFactory {
+	sls := make([]*monitoringv1alpha1.ServiceLevel, 0, len(s.ServiceLevels))
+	for i := range s.ServiceLevels {
+		sls = append(sls, &s.ServiceLevels[i])
+	}
+	return kubernetesclifactory.NewFakeWithServiceLevels(sls)
+}
+
+// PrometheusFactory returns a faked Prometheus clients factory that answers the
+// queries of the scenario, the series start at the start time.
+func (s *Scenario) PrometheusFactory(start time.Time) promclifactory.ClientFactory {
+	queryFuncs := map[string]promclifactory.FakeQueryFunc{}
+	for q, series := range s.Queries {
+		series := series
+		queryFuncs[strings.TrimSpace(q)] = func(ts time.Time) float64 {
+			return series.ValueAt(ts.Sub(start))
+		}
+	}
+	return promclifactory.NewFakeFactoryWithQueries(queryFuncs)
+}
@@ -0,0 +1,186 @@
+package scenario_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/spotahome/service-level-operator/pkg/service/scenario"
+)
+
+func duration(d time.Duration) metav1.Duration { return metav1.Duration{Duration: d} }
+
+func TestSeriesValueAt(t *testing.T) {
+	tests := map[string]struct {
+		series    scenario.Series
+		expValues map[time.Duration]float64
+	}{
+		"A constant series should always return the same value.": {
+			series:    scenario.Series{Type: scenario.ConstantSeries, Value: 10},
+			expValues: map[time.Duration]float64{0: 10, time.Hour: 10},
+		},
+		"A ramp series should change linearly during the ramp.": {
+			series: scenario.Series{Type: scenario.RampSeries, From: 10, To: 30, Start: duration(time.Minute), Duration: duration(10 * time.Minute)},
+			expValues: map[time.Duration]float64{
+				0:                10,
+				time.Minute:      10,
+				6 * time.Minute:  20,
+				11 * time.Minute: 30,
+				time.Hour:        30,
+			},
+		},
+		"A step series should return the outage value during the outage.": {
+			series: scenario.Series{Type: scenario.StepSeries, Value: 1, OutageValue: 100, Start: duration(time.Minute), Duration: duration(2 * time.Minute)},
+			expValues: map[time.Duration]float64{
+				0:                    1,
+				time.Minute:          100,
+				2*time.Minute + 59e9: 100,
+				3 * time.Minute:      1,
+				11 * time.Minute:     1,
+			},
+		},
+		"A repeated step series should return the outage value on every outage.": {
+			series: scenario.Series{Type: scenario.StepSeries, Value: 1, OutageValue: 100, Start: duration(time.Minute), Duration: duration(2 * time.Minute), Every: duration(10 * time.Minute)},
+			expValues: map[time.Duration]float64{
+				0:                1,
+				time.Minute:      100,
+				3 * time.Minute:  1,
+				11 * time.Minute: 100,
+				13 * time.Minute: 1,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require.NoError(t, test.series.Validate())
+
+			for elapsed, exp := range test.expValues {
+				assert.InDelta(exp, test.series.ValueAt(elapsed), 1e-9, "at %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestRandomSeriesIsDeterministic(t *testing.T) {
+	assert := assert.New(t)
+
+	s := scenario.Series{Type: scenario.RandomSeries, Min: 5, Max: 10, Seed: 42}
+	assert.NoError(s.Validate())
+
+	// Same values for the same interval, seed and time independently of the query order.
+	v0 := s.ValueAt(90 * time.Second)
+	v1 := s.ValueAt(time.Hour)
+	assert.Equal(v0, s.ValueAt(95*time.Second))
+	assert.Equal(v1, s.ValueAt(time.Hour))
+	assert.True(v0 >= 5 && v0 <= 10)
+
+	s2 := s
+	s2.Seed = 43
+	assert.NotEqual(v0, s2.ValueAt(90*time.Second))
+}
+
+func TestLoad(t *testing.T) {
+	tests := map[string]struct {
+		scenario string
+		expErr   string
+	}{
+		"A valid scenario should load.": {
+			scenario: `
+serviceLevels:
+  - metadata: {name: sl0}
+    spec:
+      serviceLevelObjectives:
+        - name: slo0
+queries:
+  q0: {type: constant, value: 1}
+`,
+		},
+		"Unknown fields should fail.": {
+			scenario: `
+serviceLevels:
+  - metadata: {name: sl0}
+queries:
+  q0: {type: constant, valeu: 1}
+`,
+			expErr: "valeu",
+		},
+		"Unknown series types should fail.": {
+			scenario: `
+serviceLevels:
+  - metadata: {name: sl0}
+queries:
+  q0: {type: sine}
+`,
+			expErr: `unknown "sine" series type`,
+		},
+		"Duplicated service levels should fail.": {
+			scenario: `
+serviceLevels:
+  - metadata: {name: sl0}
+  - metadata: {name: sl0, namespace: default}
+`,
+			expErr: "default/sl0 service level is duplicated",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := scenario.Load(strings.NewReader(test.scenario))
+			if test.expErr != "" {
+				if assert.Error(err) {
+					assert.Contains(err.Error(), test.expErr)
+				}
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestScenarioFactories(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	f, err := os.Open("../../../test/manual/fake-scenario.yaml")
+	require.NoError(err)
+	defer f.Close()
+	s, err := scenario.Load(f)
+	require.NoError(err)
+
+	// The Kubernetes clients should have the service levels of the scenario.
+	crdcli, err := s.KubernetesFactory().GetCRDClient()
+	require.NoError(err)
+	sls, err := crdcli.MonitoringV1alpha1().ServiceLevels("").List(metav1.ListOptions{})
+	require.NoError(err)
+	assert.Len(sls.Items, 2)
+
+	// The Prometheus clients should answer with the series values at the query time.
+	start := time.Now()
+	promcli, err := s.PrometheusFactory(start).GetV1APIClient("")
+	require.NoError(err)
+
+	res, _, err := promcli.Query(context.Background(), "checkout_requests_error", start.Add(90*time.Second))
+	require.NoError(err)
+	assert.Equal(model.SampleValue(250), res.(model.Vector)[0].Value)
+	res, _, err = promcli.Query(context.Background(), "checkout_requests_error", start.Add(4*time.Minute))
+	require.NoError(err)
+	assert.Equal(model.SampleValue(0), res.(model.Vector)[0].Value)
+
+	// Unknown queries should fail listing the known ones.
+	_, _, err = promcli.Query(context.Background(), "missing", start)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), `not faked "missing" query`)
+		assert.Contains(err.Error(), "checkout_requests_error, checkout_requests_slow, checkout_requests_total")
+	}
+}
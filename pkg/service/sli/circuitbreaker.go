@@ -0,0 +1,238 @@
+package sli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/metrics"
+)
+
+const (
+	// DefCircuitBreakerFailureThreshold is the default number of consecutive
+	// failures of an SLI source that will open its circuit.
+	DefCircuitBreakerFailureThreshold = 5
+	// DefCircuitBreakerOpenDuration is the default time a circuit will be open.
+	DefCircuitBreakerOpenDuration = 30 * time.Second
+)
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState string
+
+const (
+	// CircuitClosed is the regular state, the calls go to the backend.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen is the state where the calls fail fast without going to the backend.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen is the state where a single probe call is allowed to
+	// go to the backend to check if it's healthy again.
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// CircuitOpenError is the error returned when the SLI source
+// circuit is open and the retrieval has not been made.
+type CircuitOpenError struct {
+	// Address is the address of the SLI source.
+	Address string
+}
+
+func (c *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit open for SLI source %q, retrieval skipped", c.Address)
+}
+
+// IsCircuitOpenError returns true if the error was caused by an open circuit.
+func IsCircuitOpenError(err error) bool {
+	_, ok := err.(*CircuitOpenError)
+	return ok
+}
+
+// CircuitBreakerCfg is the configuration of the SLI source circuit breaker.
+type CircuitBreakerCfg struct {
+	// Disable disables the circuit breaker.
+	Disable bool
+	// FailureThreshold is the number of consecutive failures of an SLI source
+	// that will open the circuit, only the transport, timeout and server errors
+	// are failures of the SLI source.
+	FailureThreshold int
+	// OpenDuration is the time the circuit will be open before letting
+	// a probe retrieval go to the SLI source (half-open).
+	OpenDuration time.Duration
+}
+
+// Validate will validate the cfg setting safe defaults.
+func (c *CircuitBreakerCfg) Validate() {
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = DefCircuitBreakerFailureThreshold
+	}
+	if c.OpenDuration <= 0 {
+		c.OpenDuration = DefCircuitBreakerOpenDuration
+	}
+}
+
+// circuit is the state of the circuit of a single SLI source.
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// circuitBreakerMiddleware will stop calling the SLI sources that are failing
+// consecutively, the circuits are per SLI source address so an unhealthy
+// backend doesn't affect the SLOs of the healthy ones.
+type circuitBreakerMiddleware struct {
+	cfg        CircuitBreakerCfg
+	kind       string
+	metricssvc metrics.Service
+	next       Retriever
+	logger     log.Logger
+
+	circuitsMu sync.Mutex
+	circuits   map[string]*circuit
+}
+
+// NewCircuitBreakerMiddleware returns a new circuit breaker middleware that wraps a
+// Retriever SLI service and fails fast when the SLI source is unhealthy.
+func NewCircuitBreakerMiddleware(cfg CircuitBreakerCfg, metricssvc metrics.Service, kind string, next Retriever, logger log.Logger) Retriever {
+	if cfg.Disable {
+		return next
+	}
+	cfg.Validate()
+
+	return &circuitBreakerMiddleware{
+		cfg:        cfg,
+		kind:       kind,
+		metricssvc: metricssvc,
+		next:       next,
+		logger:     logger,
+		circuits:   map[string]*circuit{},
+	}
+}
+
+// Retrieve satisfies sli.Retriever interface.
+func (c *circuitBreakerMiddleware) Retrieve(ctx context.Context, sli *monitoringv1alpha1.SLI) (Result, error) {
+	address := sliSourceAddress(sli)
+
+	if !c.allow(address) {
+		c.metricssvc.IncSLIRetrieveCircuitOpen(sli, c.kind)
+		return Result{}, &CircuitOpenError{Address: address}
+	}
+
+	res, err := c.next.Retrieve(ctx, sli)
+	c.report(address, err)
+
+	return res, err
+}
+
+// allow returns if the retrieval can be made on the SLI source.
+func (c *circuitBreakerMiddleware) allow(address string) bool {
+	c.circuitsMu.Lock()
+	defer c.circuitsMu.Unlock()
+
+	cc := c.getCircuit(address)
+	switch cc.state {
+	case CircuitOpen:
+		if time.Since(cc.openedAt) < c.cfg.OpenDuration {
+			return false
+		}
+		// Time to check if the SLI source is healthy again.
+		c.setState(address, cc, CircuitHalfOpen)
+		cc.probing = true
+		return true
+	case CircuitHalfOpen:
+		// Only one probe at a time.
+		if cc.probing {
+			return false
+		}
+		cc.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// report registers the result of a retrieval on the SLI source circuit. The
+// errors of the queries or their results are not failures of the SLI source,
+// it answered, so they don't open the circuit.
+func (c *circuitBreakerMiddleware) report(address string, err error) {
+	c.circuitsMu.Lock()
+	defer c.circuitsMu.Unlock()
+
+	cc := c.getCircuit(address)
+	cc.probing = false
+
+	if !isSourceFailure(err) {
+		cc.failures = 0
+		if cc.state != CircuitClosed {
+			c.setState(address, cc, CircuitClosed)
+		}
+		return
+	}
+
+	cc.failures++
+	if cc.state == CircuitHalfOpen || cc.failures >= c.cfg.FailureThreshold {
+		cc.openedAt = time.Now()
+		if cc.state != CircuitOpen {
+			c.setState(address, cc, CircuitOpen)
+		}
+	}
+}
+
+// isSourceFailure returns true if the error is a failure of the SLI source
+// itself: a transport error, a timeout or a server error.
+func isSourceFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var apiErr *promv1.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Type == promv1.ErrServer || apiErr.Type == promv1.ErrTimeout
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func (c *circuitBreakerMiddleware) getCircuit(address string) *circuit {
+	cc, ok := c.circuits[address]
+	if !ok {
+		cc = &circuit{state: CircuitClosed}
+		c.circuits[address] = cc
+		c.metricssvc.SetSLISourceCircuitState(address, c.kind, string(CircuitClosed))
+	}
+	return cc
+}
+
+func (c *circuitBreakerMiddleware) setState(address string, cc *circuit, state CircuitState) {
+	logger := c.logger.With("sli-source", address).With("failures", cc.failures)
+	switch state {
+	case CircuitOpen:
+		logger.Warnf("SLI source circuit opened, retrievals will be skipped for %s", c.cfg.OpenDuration)
+	case CircuitHalfOpen:
+		logger.Infof("SLI source circuit half-opened, probing SLI source")
+	case CircuitClosed:
+		logger.Infof("SLI source circuit closed, SLI source recovered")
+	}
+
+	cc.state = state
+	c.metricssvc.SetSLISourceCircuitState(address, c.kind, string(state))
+}
+
+// sliSourceAddress returns the address that identifies the SLI source. An empty
+// address is the default SLI source.
+func sliSourceAddress(sli *monitoringv1alpha1.SLI) string {
+	if sli.Prometheus != nil {
+		return sli.Prometheus.Address
+	}
+	return ""
+}
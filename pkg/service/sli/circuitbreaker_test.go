@@ -0,0 +1,204 @@
+package sli_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	msli "github.com/spotahome/service-level-operator/mocks/service/sli"
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/metrics"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
+)
+
+func TestCircuitBreakerMiddleware(t *testing.T) {
+	sliProm0 := &monitoringv1alpha1.SLI{
+		SLISource: monitoringv1alpha1.SLISource{
+			Prometheus: &monitoringv1alpha1.PrometheusSLISource{Address: "http://prom0:9090"},
+		},
+	}
+	sliProm1 := &monitoringv1alpha1.SLI{
+		SLISource: monitoringv1alpha1.SLISource{
+			Prometheus: &monitoringv1alpha1.PrometheusSLISource{Address: "http://prom1:9090"},
+		},
+	}
+	errWanted := &promv1.Error{Type: promv1.ErrServer, Msg: "server error: 503"}
+	errQuery := &promv1.Error{Type: promv1.ErrBadData, Msg: "parse error"}
+	errResult := errors.New("wrong samples length, should not be more than 1, got: 2")
+
+	type call struct {
+		sli          *monitoringv1alpha1.SLI
+		sleep        time.Duration
+		expCalled    bool
+		expErr       bool
+		expErrIsOpen bool
+	}
+
+	tests := map[string]struct {
+		cfg      sli.CircuitBreakerCfg
+		nextErrs map[*monitoringv1alpha1.SLI][]error
+		calls    []call
+	}{
+		"Consecutive failures below the threshold should not open the circuit.": {
+			cfg: sli.CircuitBreakerCfg{FailureThreshold: 3, OpenDuration: time.Hour},
+			nextErrs: map[*monitoringv1alpha1.SLI][]error{
+				sliProm0: {errWanted, errWanted, nil, errWanted, errWanted, nil},
+			},
+			calls: []call{
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expCalled: true},
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expCalled: true},
+			},
+		},
+
+		"The query and result errors should not open the circuit.": {
+			cfg: sli.CircuitBreakerCfg{FailureThreshold: 1, OpenDuration: time.Hour},
+			nextErrs: map[*monitoringv1alpha1.SLI][]error{
+				sliProm0: {errQuery, errResult, &promv1.Error{Type: promv1.ErrClient, Msg: "client error: 404"}},
+			},
+			calls: []call{
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expCalled: true, expErr: true},
+			},
+		},
+
+		"The query errors should reset the consecutive failures.": {
+			cfg: sli.CircuitBreakerCfg{FailureThreshold: 2, OpenDuration: time.Hour},
+			nextErrs: map[*monitoringv1alpha1.SLI][]error{
+				sliProm0: {errWanted, errQuery, errWanted, errWanted},
+			},
+			calls: []call{
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expErr: true, expErrIsOpen: true},
+			},
+		},
+
+		"The transport and timeout errors should open the circuit.": {
+			cfg: sli.CircuitBreakerCfg{FailureThreshold: 3, OpenDuration: time.Hour},
+			nextErrs: map[*monitoringv1alpha1.SLI][]error{
+				sliProm0: {
+					&url.Error{Op: "Post", URL: "http://prom0:9090/api/v1/query", Err: errors.New("connection refused")},
+					context.DeadlineExceeded,
+					&promv1.Error{Type: promv1.ErrTimeout, Msg: "query timed out"},
+				},
+			},
+			calls: []call{
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expErr: true, expErrIsOpen: true},
+			},
+		},
+
+		"Consecutive failures reaching the threshold should open the circuit and fail fast.": {
+			cfg: sli.CircuitBreakerCfg{FailureThreshold: 2, OpenDuration: time.Hour},
+			nextErrs: map[*monitoringv1alpha1.SLI][]error{
+				sliProm0: {errWanted, errWanted},
+			},
+			calls: []call{
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expErr: true, expErrIsOpen: true},
+				{sli: sliProm0, expErr: true, expErrIsOpen: true},
+			},
+		},
+
+		"An open circuit should not affect other SLI sources.": {
+			cfg: sli.CircuitBreakerCfg{FailureThreshold: 1, OpenDuration: time.Hour},
+			nextErrs: map[*monitoringv1alpha1.SLI][]error{
+				sliProm0: {errWanted},
+				sliProm1: {nil, nil},
+			},
+			calls: []call{
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm1, expCalled: true},
+				{sli: sliProm0, expErr: true, expErrIsOpen: true},
+				{sli: sliProm1, expCalled: true},
+			},
+		},
+
+		"After the open duration a successful probe should close the circuit.": {
+			cfg: sli.CircuitBreakerCfg{FailureThreshold: 1, OpenDuration: 5 * time.Millisecond},
+			nextErrs: map[*monitoringv1alpha1.SLI][]error{
+				sliProm0: {errWanted, nil, nil},
+			},
+			calls: []call{
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expErr: true, expErrIsOpen: true},
+				{sli: sliProm0, sleep: 10 * time.Millisecond, expCalled: true},
+				{sli: sliProm0, expCalled: true},
+			},
+		},
+
+		"After the open duration a failed probe should open the circuit again.": {
+			cfg: sli.CircuitBreakerCfg{FailureThreshold: 3, OpenDuration: 5 * time.Millisecond},
+			nextErrs: map[*monitoringv1alpha1.SLI][]error{
+				sliProm0: {errWanted, errWanted, errWanted, errWanted},
+			},
+			calls: []call{
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, sleep: 10 * time.Millisecond, expCalled: true, expErr: true},
+				{sli: sliProm0, expErr: true, expErrIsOpen: true},
+			},
+		},
+
+		"A disabled circuit breaker should never open the circuit.": {
+			cfg: sli.CircuitBreakerCfg{Disable: true, FailureThreshold: 1},
+			nextErrs: map[*monitoringv1alpha1.SLI][]error{
+				sliProm0: {errWanted, errWanted, errWanted},
+			},
+			calls: []call{
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expCalled: true, expErr: true},
+				{sli: sliProm0, expCalled: true, expErr: true},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mret := &msli.Retriever{}
+			for s, errs := range test.nextErrs {
+				for _, err := range errs {
+					mret.On("Retrieve", mock.Anything, s).Once().Return(sli.Result{}, err)
+				}
+			}
+
+			cb := sli.NewCircuitBreakerMiddleware(test.cfg, metrics.Dummy, "test", mret, log.Dummy)
+			for _, c := range test.calls {
+				time.Sleep(c.sleep)
+				calls := len(mret.Calls)
+				_, err := cb.Retrieve(context.Background(), c.sli)
+
+				assert.Equal(c.expCalled, len(mret.Calls) > calls)
+				if c.expErr {
+					assert.Error(err)
+				} else {
+					assert.NoError(err)
+				}
+				assert.Equal(c.expErrIsOpen, sli.IsCircuitOpenError(err))
+			}
+
+			mret.AssertExpectations(t)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package sli
 
 import (
+	"context"
 	"time"
 
 	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
@@ -25,12 +26,12 @@ func NewMetricsMiddleware(metricssvc metrics.Service, kind string, next Retrieve
 }
 
 // Retrieve satisfies sli.Retriever interface.
-func (m metricsMiddleware) Retrieve(sli *monitoringv1alpha1.SLI) (result Result, err error) {
+func (m metricsMiddleware) Retrieve(ctx context.Context, sli *monitoringv1alpha1.SLI) (result Result, err error) {
 	defer func(t time.Time) {
 		m.metricssvc.ObserveSLIRetrieveDuration(sli, m.kind, t)
 		if err != nil {
 			m.metricssvc.IncSLIRetrieveError(sli, m.kind)
 		}
 	}(time.Now())
-	return m.next.Retrieve(sli)
+	return m.next.Retrieve(ctx, sli)
 }
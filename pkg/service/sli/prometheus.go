@@ -9,30 +9,51 @@ import (
 
 	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
 	"github.com/spotahome/service-level-operator/pkg/log"
 	promcli "github.com/spotahome/service-level-operator/pkg/service/client/prometheus"
 )
 
-const promCliTimeout = 2 * time.Second
+const (
+	promCliTimeout      = 2 * time.Second
+	promRangeCliTimeout = 30 * time.Second
+	// maxRangeQueryPoints is the maximum number of points of a range query, Prometheus
+	// doesn't allow more than 11000 points per series.
+	maxRangeQueryPoints = 10000
+)
 
 // prometheus knows how to get SLIs from a prometheus backend.
 type prometheus struct {
 	cliFactory promcli.ClientFactory
+	tracer     trace.Tracer
 	logger     log.Logger
 }
 
 // NewPrometheus returns a new prometheus SLI service.
-func NewPrometheus(promCliFactory promcli.ClientFactory, logger log.Logger) Retriever {
+func NewPrometheus(promCliFactory promcli.ClientFactory, tracer trace.Tracer, logger log.Logger) Retriever {
+	return &prometheus{
+		cliFactory: promCliFactory,
+		tracer:     tracer,
+		logger:     logger,
+	}
+}
+
+// NewPrometheusRange returns a new prometheus SLI service that retrieves the SLIs
+// of past time ranges.
+func NewPrometheusRange(promCliFactory promcli.ClientFactory, tracer trace.Tracer, logger log.Logger) RangeRetriever {
 	return &prometheus{
 		cliFactory: promCliFactory,
+		tracer:     tracer,
 		logger:     logger,
 	}
 }
 
 // Retrieve satisfies Service interface..
-func (p *prometheus) Retrieve(sli *monitoringv1alpha1.SLI) (Result, error) {
+func (p *prometheus) Retrieve(ctx context.Context, sli *monitoringv1alpha1.SLI) (Result, error) {
 	cli, err := p.cliFactory.GetV1APIClient(sli.Prometheus.Address)
 	if err != nil {
 		return Result{}, err
@@ -41,17 +62,18 @@ func (p *prometheus) Retrieve(sli *monitoringv1alpha1.SLI) (Result, error) {
 	// Get both metrics.
 	res := Result{}
 
-	promclictx, cancel := context.WithTimeout(context.Background(), promCliTimeout)
+	promclictx, cancel := context.WithTimeout(ctx, promCliTimeout)
 	defer cancel()
 
 	// Make queries concurrently.
-	g, ctx := errgroup.WithContext(promclictx)
-	g.Go(func() error {
-		res.TotalQ, err = p.getVectorMetric(ctx, cli, sli.Prometheus.TotalQuery)
+	var totalWarns, errorWarns []string
+	g, gctx := errgroup.WithContext(promclictx)
+	g.Go(func() (err error) {
+		res.TotalQ, res.TotalQSamples, totalWarns, err = p.getVectorMetric(gctx, cli, sli.Prometheus.Address, sli.Prometheus.TotalQuery)
 		return err
 	})
-	g.Go(func() error {
-		res.ErrorQ, err = p.getVectorMetric(ctx, cli, sli.Prometheus.ErrorQuery)
+	g.Go(func() (err error) {
+		res.ErrorQ, res.ErrorQSamples, errorWarns, err = p.getVectorMetric(gctx, cli, sli.Prometheus.Address, sli.Prometheus.ErrorQuery)
 		return err
 	})
 
@@ -60,36 +82,192 @@ func (p *prometheus) Retrieve(sli *monitoringv1alpha1.SLI) (Result, error) {
 	if err != nil {
 		return Result{}, err
 	}
+	res.Warnings = append(totalWarns, errorWarns...)
 
 	return res, nil
 }
 
-func (p *prometheus) getVectorMetric(ctx context.Context, cli promv1.API, query string) (float64, error) {
+func (p *prometheus) getVectorMetric(ctx context.Context, cli promv1.API, address, query string) (_ float64, samples int, warnings []string, err error) {
+	ctx, span := p.tracer.Start(ctx, "prometheus.Query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("prometheus.address", address),
+			attribute.String("prometheus.query", query),
+		))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
 	// Make the query.
-	val, _, err := cli.Query(ctx, query, time.Now())
+	val, warns, err := cli.Query(ctx, query, time.Now())
 	if err != nil {
-		return 0, err
+		return 0, 0, nil, err
+	}
+	if len(warns) > 0 {
+		warnings = []string(warns)
+		span.SetAttributes(attribute.StringSlice("prometheus.warnings", warnings))
 	}
 
 	if val == nil {
-		return 0, fmt.Errorf("nil value received from prometheus")
+		return 0, 0, warnings, fmt.Errorf("nil value received from prometheus")
 	}
+	span.SetAttributes(attribute.String("prometheus.result_type", val.Type().String()))
 
 	// Only vectors are valid metrics.
 	if val.Type() != model.ValVector {
-		return 0, fmt.Errorf("received metric needs to be a vector, received: %s", val.Type())
+		return 0, 0, warnings, fmt.Errorf("received metric needs to be a vector, received: %s", val.Type())
 	}
 	mtr := val.(model.Vector)
 
 	// If we obtain no metric then for us is 0.
 	if len(mtr) == 0 {
-		return 0, nil
+		return 0, 0, warnings, nil
 	}
 
 	// More than one metric should be an error.
 	if len(mtr) != 1 {
-		return 0, fmt.Errorf("wrong samples length, should not be more than 1, got: %d", len(mtr))
+		return 0, len(mtr), warnings, fmt.Errorf("wrong samples length, should not be more than 1, got: %d", len(mtr))
+	}
+
+	return float64(mtr[0].Value), len(mtr), warnings, nil
+}
+
+// RetrieveRange satisfies RangeRetriever interface.
+func (p *prometheus) RetrieveRange(ctx context.Context, sli *monitoringv1alpha1.SLI, start, end time.Time, step time.Duration) ([]TimedResult, error) {
+	if step <= 0 {
+		return nil, fmt.Errorf("step must be positive")
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end can't be before start")
+	}
+
+	cli, err := p.cliFactory.GetV1APIClient(sli.Prometheus.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prometheus has millisecond precision.
+	start = start.Truncate(time.Millisecond)
+	end = end.Truncate(time.Millisecond)
+
+	// Make queries concurrently.
+	var totals, errs map[int64]float64
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() (err error) {
+		totals, err = p.getMatrixMetric(gctx, cli, sli.Prometheus.Address, sli.Prometheus.TotalQuery, start, end, step)
+		return err
+	})
+	g.Go(func() (err error) {
+		errs, err = p.getMatrixMetric(gctx, cli, sli.Prometheus.Address, sli.Prometheus.ErrorQuery, start, end, step)
+		return err
+	})
+	err = g.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	// Missing points are treated like queries without metrics.
+	var res []TimedResult
+	for ts := start; !ts.After(end); ts = ts.Add(step) {
+		r := TimedResult{Time: ts}
+		key := timestampKey(ts)
+		if v, ok := totals[key]; ok {
+			r.TotalQ = v
+			r.TotalQSamples = 1
+		}
+		if v, ok := errs[key]; ok {
+			r.ErrorQ = v
+			r.ErrorQSamples = 1
+		}
+		res = append(res, r)
 	}
 
-	return float64(mtr[0].Value), nil
+	return res, nil
+}
+
+// getMatrixMetric returns the values of a single sample per step query by timestamp
+// key, the range is split in multiple queries if it has too many points.
+func (p *prometheus) getMatrixMetric(ctx context.Context, cli promv1.API, address, query string, start, end time.Time, step time.Duration) (map[int64]float64, error) {
+	values := map[int64]float64{}
+	for chunkStart := start; !chunkStart.After(end); {
+		chunkEnd := chunkStart.Add(step * (maxRangeQueryPoints - 1))
+		if chunkEnd.After(end) {
+			chunkEnd = end
+		}
+
+		err := p.getMatrixMetricChunk(ctx, cli, address, query, promv1.Range{Start: chunkStart, End: chunkEnd, Step: step}, values)
+		if err != nil {
+			return nil, err
+		}
+
+		chunkStart = chunkEnd.Add(step)
+	}
+
+	return values, nil
+}
+
+func (p *prometheus) getMatrixMetricChunk(ctx context.Context, cli promv1.API, address, query string, r promv1.Range, values map[int64]float64) (err error) {
+	ctx, span := p.tracer.Start(ctx, "prometheus.QueryRange",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("prometheus.address", address),
+			attribute.String("prometheus.query", query),
+			attribute.String("prometheus.start", r.Start.Format(time.RFC3339)),
+			attribute.String("prometheus.end", r.End.Format(time.RFC3339)),
+			attribute.String("prometheus.step", r.Step.String()),
+		))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, promRangeCliTimeout)
+	defer cancel()
+
+	val, _, err := cli.QueryRange(ctx, query, r)
+	if err != nil {
+		return err
+	}
+
+	if val == nil {
+		return fmt.Errorf("nil value received from prometheus")
+	}
+	span.SetAttributes(attribute.String("prometheus.result_type", val.Type().String()))
+
+	// Only matrixes are valid range metrics.
+	if val.Type() != model.ValMatrix {
+		return fmt.Errorf("received metric needs to be a matrix, received: %s", val.Type())
+	}
+	mtr := val.(model.Matrix)
+
+	// If we obtain no metric then for us is 0.
+	if len(mtr) == 0 {
+		return nil
+	}
+
+	// The series can change on the range (e.g. a label value of the query changes),
+	// like on the instant queries more than one sample per step should be an error.
+	for _, ss := range mtr {
+		for _, v := range ss.Values {
+			key := int64(v.Timestamp)
+			if _, ok := values[key]; ok {
+				return fmt.Errorf("%q query returned more than 1 sample at %s", query, v.Timestamp.Time().UTC().Format(time.RFC3339))
+			}
+			values[key] = float64(v.Value)
+		}
+	}
+
+	return nil
+}
+
+// timestampKey returns the key of a time on the Prometheus results.
+func timestampKey(t time.Time) int64 {
+	return int64(model.TimeFromUnixNano(t.UnixNano()))
 }
@@ -1,12 +1,20 @@
 package sli_test
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/api"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 
 	mpromv1 "github.com/spotahome/service-level-operator/mocks/github.com/prometheus/client_golang/api/prometheus/v1"
 	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
@@ -41,10 +49,12 @@ func TestPrometheusRetrieve(t *testing.T) {
 		name string
 		sli  *monitoringv1alpha1.SLI
 
-		totalQueryResult model.Value
-		totalQueryErr    error
-		errorQueryResult model.Value
-		errorQueryErr    error
+		totalQueryResult   model.Value
+		totalQueryErr      error
+		totalQueryWarnings api.Warnings
+		errorQueryResult   model.Value
+		errorQueryErr      error
+		errorQueryWarnings api.Warnings
 
 		expResult sli.Result
 		expErr    bool
@@ -100,8 +110,10 @@ func TestPrometheusRetrieve(t *testing.T) {
 			errorQueryResult: model.Vector{},
 			expErr:           false,
 			expResult: sli.Result{
-				TotalQ: 2,
-				ErrorQ: 0,
+				TotalQ:        2,
+				ErrorQ:        0,
+				TotalQSamples: 1,
+				ErrorQSamples: 0,
 			},
 		},
 		{
@@ -110,8 +122,25 @@ func TestPrometheusRetrieve(t *testing.T) {
 			totalQueryResult: vector100,
 			errorQueryResult: vector2,
 			expResult: sli.Result{
-				TotalQ: 100,
-				ErrorQ: 2,
+				TotalQ:        100,
+				ErrorQ:        2,
+				TotalQSamples: 1,
+				ErrorQSamples: 1,
+			},
+		},
+		{
+			name:               "The warnings of the queries should be returned with the result.",
+			sli:                sli0,
+			totalQueryResult:   vector100,
+			totalQueryWarnings: api.Warnings{"total warning"},
+			errorQueryResult:   vector2,
+			errorQueryWarnings: api.Warnings{"error warning"},
+			expResult: sli.Result{
+				TotalQ:        100,
+				ErrorQ:        2,
+				TotalQSamples: 1,
+				ErrorQSamples: 1,
+				Warnings:      []string{"total warning", "error warning"},
 			},
 		},
 	}
@@ -123,11 +152,11 @@ func TestPrometheusRetrieve(t *testing.T) {
 			// Mocks.
 			mapi := &mpromv1.API{}
 			mpromfactory := &prometheusvc.MockFactory{Cli: mapi}
-			mapi.On("Query", mock.Anything, test.sli.Prometheus.TotalQuery, mock.Anything).Return(test.totalQueryResult, nil, test.errorQueryErr)
-			mapi.On("Query", mock.Anything, test.sli.Prometheus.ErrorQuery, mock.Anything).Return(test.errorQueryResult, nil, test.totalQueryErr)
+			mapi.On("Query", mock.Anything, test.sli.Prometheus.TotalQuery, mock.Anything).Return(test.totalQueryResult, test.totalQueryWarnings, test.errorQueryErr)
+			mapi.On("Query", mock.Anything, test.sli.Prometheus.ErrorQuery, mock.Anything).Return(test.errorQueryResult, test.errorQueryWarnings, test.totalQueryErr)
 
-			retriever := sli.NewPrometheus(mpromfactory, log.Dummy)
-			res, err := retriever.Retrieve(test.sli)
+			retriever := sli.NewPrometheus(mpromfactory, noop.NewTracerProvider().Tracer(""), log.Dummy)
+			res, err := retriever.Retrieve(context.Background(), test.sli)
 
 			if test.expErr {
 				assert.Error(err)
@@ -137,3 +166,172 @@ func TestPrometheusRetrieve(t *testing.T) {
 		})
 	}
 }
+
+func TestPrometheusRetrieveTracing(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sli0 := &monitoringv1alpha1.SLI{
+		SLISource: monitoringv1alpha1.SLISource{
+			Prometheus: &monitoringv1alpha1.PrometheusSLISource{
+				Address:    "http://127.0.0.1:9090",
+				TotalQuery: "test_total_query",
+				ErrorQuery: "test_error_query",
+			},
+		},
+	}
+
+	// Mocks.
+	mapi := &mpromv1.API{}
+	mpromfactory := &prometheusvc.MockFactory{Cli: mapi}
+	mapi.On("Query", mock.Anything, "test_total_query", mock.Anything).Return(model.Vector{}, nil, nil)
+	mapi.On("Query", mock.Anything, "test_error_query", mock.Anything).Return(&model.Scalar{}, nil, nil)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	retriever := sli.NewPrometheus(mpromfactory, tp.Tracer("test"), log.Dummy)
+	_, err := retriever.Retrieve(context.Background(), sli0)
+	require.Error(err)
+
+	// Every query should have its own span.
+	spans := exporter.GetSpans()
+	require.Len(spans, 2)
+	got := map[string]map[attribute.Key]string{}
+	for _, span := range spans {
+		assert.Equal("prometheus.Query", span.Name)
+		attrs := map[attribute.Key]string{}
+		for _, attr := range span.Attributes {
+			attrs[attr.Key] = attr.Value.Emit()
+		}
+		got[attrs["prometheus.query"]] = attrs
+	}
+
+	assert.Equal("vector", got["test_total_query"]["prometheus.result_type"])
+	assert.Equal("http://127.0.0.1:9090", got["test_total_query"]["prometheus.address"])
+	assert.Equal("scalar", got["test_error_query"]["prometheus.result_type"])
+}
+
+func TestPrometheusRetrieveRange(t *testing.T) {
+	sli0 := &monitoringv1alpha1.SLI{
+		SLISource: monitoringv1alpha1.SLISource{
+			Prometheus: &monitoringv1alpha1.PrometheusSLISource{
+				TotalQuery: "test_total_query",
+				ErrorQuery: "test_error_query",
+			},
+		},
+	}
+	start := time.Unix(1000, 0)
+	matrix := func(values ...float64) model.Matrix {
+		ss := &model.SampleStream{}
+		for i, v := range values {
+			// Negative values are missing points.
+			if v < 0 {
+				continue
+			}
+			ss.Values = append(ss.Values, model.SamplePair{
+				Timestamp: model.TimeFromUnixNano(start.Add(time.Duration(i) * time.Minute).UnixNano()),
+				Value:     model.SampleValue(v),
+			})
+		}
+		return model.Matrix{ss}
+	}
+
+	tests := map[string]struct {
+		totalQueryResult model.Value
+		errorQueryResult model.Value
+		expResults       []sli.TimedResult
+		expErr           bool
+		expErrMsg        string
+	}{
+		"Range results should be returned on every step.": {
+			totalQueryResult: matrix(100, 200, 300),
+			errorQueryResult: matrix(1, -1, 3),
+			expResults: []sli.TimedResult{
+				{Time: start, Result: sli.Result{TotalQ: 100, ErrorQ: 1, TotalQSamples: 1, ErrorQSamples: 1}},
+				{Time: start.Add(time.Minute), Result: sli.Result{TotalQ: 200, TotalQSamples: 1}},
+				{Time: start.Add(2 * time.Minute), Result: sli.Result{TotalQ: 300, ErrorQ: 3, TotalQSamples: 1, ErrorQSamples: 1}},
+			},
+		},
+		"Queries without series should be treated as 0 values.": {
+			totalQueryResult: model.Matrix{},
+			errorQueryResult: model.Matrix{},
+			expResults: []sli.TimedResult{
+				{Time: start},
+				{Time: start.Add(time.Minute)},
+				{Time: start.Add(2 * time.Minute)},
+			},
+		},
+		"If the query doesn't return a matrix it should fail.": {
+			totalQueryResult: model.Vector{},
+			errorQueryResult: model.Matrix{},
+			expErr:           true,
+		},
+		"Queries with multiple series on different steps should be merged.": {
+			totalQueryResult: append(matrix(100, -1, -1), matrix(-1, 200, 300)...),
+			errorQueryResult: matrix(1, -1, 3),
+			expResults: []sli.TimedResult{
+				{Time: start, Result: sli.Result{TotalQ: 100, ErrorQ: 1, TotalQSamples: 1, ErrorQSamples: 1}},
+				{Time: start.Add(time.Minute), Result: sli.Result{TotalQ: 200, TotalQSamples: 1}},
+				{Time: start.Add(2 * time.Minute), Result: sli.Result{TotalQ: 300, ErrorQ: 3, TotalQSamples: 1, ErrorQSamples: 1}},
+			},
+		},
+		"If the query returns more than one sample on a step it should fail.": {
+			totalQueryResult: append(matrix(100, 200), matrix(-1, 200)...),
+			errorQueryResult: model.Matrix{},
+			expErr:           true,
+			expErrMsg:        `"test_total_query" query returned more than 1 sample at 1970-01-01T00:17:40Z`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mapi := &mpromv1.API{}
+			mpromfactory := &prometheusvc.MockFactory{Cli: mapi}
+			mapi.On("QueryRange", mock.Anything, "test_total_query", mock.Anything).Return(test.totalQueryResult, nil, nil)
+			mapi.On("QueryRange", mock.Anything, "test_error_query", mock.Anything).Return(test.errorQueryResult, nil, nil)
+
+			retriever := sli.NewPrometheusRange(mpromfactory, noop.NewTracerProvider().Tracer(""), log.Dummy)
+			res, err := retriever.RetrieveRange(context.Background(), sli0, start, start.Add(2*time.Minute), time.Minute)
+
+			if test.expErr {
+				if assert.Error(err) && test.expErrMsg != "" {
+					assert.Equal(test.expErrMsg, err.Error())
+				}
+			} else if assert.NoError(err) {
+				assert.Equal(test.expResults, res)
+			}
+		})
+	}
+}
+
+func TestPrometheusRetrieveRangeSplitsLongRanges(t *testing.T) {
+	assert := assert.New(t)
+
+	sli0 := &monitoringv1alpha1.SLI{
+		SLISource: monitoringv1alpha1.SLISource{
+			Prometheus: &monitoringv1alpha1.PrometheusSLISource{
+				TotalQuery: "test_total_query",
+				ErrorQuery: "test_error_query",
+			},
+		},
+	}
+
+	// Mocks.
+	mapi := &mpromv1.API{}
+	mpromfactory := &prometheusvc.MockFactory{Cli: mapi}
+	mapi.On("QueryRange", mock.Anything, mock.Anything, mock.Anything).Times(4).Return(model.Matrix{}, nil, nil)
+
+	// 15001 points need 2 queries per SLI query.
+	start := time.Unix(1000, 0)
+	retriever := sli.NewPrometheusRange(mpromfactory, noop.NewTracerProvider().Tracer(""), log.Dummy)
+	res, err := retriever.RetrieveRange(context.Background(), sli0, start, start.Add(15000*time.Second), time.Second)
+
+	if assert.NoError(err) {
+		assert.Len(res, 15001)
+		mapi.AssertExpectations(t)
+	}
+}
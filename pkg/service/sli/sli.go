@@ -1,7 +1,9 @@
 package sli
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
 )
@@ -12,6 +14,12 @@ type Result struct {
 	TotalQ float64
 	// ErrorQ is the result of applying  the error query.
 	ErrorQ float64
+	// TotalQSamples is the number of samples returned by the total query.
+	TotalQSamples int
+	// ErrorQSamples is the number of samples returned by the error query.
+	ErrorQSamples int
+	// Warnings are the warnings returned by the backend with the results.
+	Warnings []string
 }
 
 // AvailabilityRatio returns the availability of an SLI result in
@@ -52,5 +60,19 @@ func (r *Result) ErrorRatio() (float64, error) {
 // Retriever knows how to get SLIs from different backends.
 type Retriever interface {
 	// Retrieve returns the result of a SLI retrieved from the implemented backend.
-	Retrieve(*monitoringv1alpha1.SLI) (Result, error)
+	Retrieve(context.Context, *monitoringv1alpha1.SLI) (Result, error)
+}
+
+// TimedResult is the result of a SLI at a point in time.
+type TimedResult struct {
+	// Time is the time of the result.
+	Time time.Time
+	Result
+}
+
+// RangeRetriever knows how to get the SLIs of a past time range from different backends.
+type RangeRetriever interface {
+	// RetrieveRange returns the results of a SLI at every step of the time range
+	// (start and end included), like the SLI would have been retrieved at that time.
+	RetrieveRange(ctx context.Context, sli *monitoringv1alpha1.SLI, start, end time.Time, step time.Duration) ([]TimedResult, error)
 }
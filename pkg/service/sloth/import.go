@@ -0,0 +1,187 @@
+package sloth
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+const maxNameLength = 63
+
+var (
+	windowTemplate      = regexp.MustCompile(`\{\{\s*\.window\s*\}\}`)
+	invalidNameChars    = regexp.MustCompile(`[^a-z0-9-]+`)
+	invalidSLONameChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+)
+
+// ImportConfig is the configuration of the import.
+type ImportConfig struct {
+	// Namespace is the namespace of the service levels without one, the
+	// Kubernetes CRDs keep their namespace.
+	Namespace string
+	// Window replaces the templated window of the Sloth queries ({{.window}}),
+	// it should be the range that gets the events of every operator evaluation.
+	Window string
+}
+
+func (c *ImportConfig) defaults() error {
+	if c.Window == "" {
+		return nil
+	}
+	if _, err := model.ParseDuration(c.Window); err != nil {
+		return fmt.Errorf("invalid window: %s", err)
+	}
+	return nil
+}
+
+// Issue is something of a Sloth spec that is not imported.
+type Issue struct {
+	// Object is the service or SLO with the issue.
+	Object string
+	// Message is the description of the issue.
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s", i.Object, i.Message)
+}
+
+// Import converts the Sloth specs to service levels, every spec is a service
+// level with its SLOs. The events SLIs are the total and error queries, and the
+// raw SLIs the error ratio query against a total of 1.
+func Import(specs []Spec, cfg ImportConfig) ([]*monitoringv1alpha1.ServiceLevel, []Issue, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		sls    []*monitoringv1alpha1.ServiceLevel
+		issues []Issue
+	)
+	issuef := func(object, format string, args ...interface{}) {
+		issues = append(issues, Issue{Object: object, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, spec := range specs {
+		name := spec.Name
+		if name == "" {
+			name = sanitizeName(spec.Service)
+			if name != spec.Service {
+				issuef(spec.Service, "%q is not a valid service level name, renamed to %q", spec.Service, name)
+			}
+		}
+		ns := spec.Namespace
+		if ns == "" {
+			ns = cfg.Namespace
+		}
+
+		sl := &monitoringv1alpha1.ServiceLevel{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: monitoringv1alpha1.SchemeGroupVersion.String(),
+				Kind:       monitoringv1alpha1.ServiceLevelKind,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: ns,
+				Labels:    spec.ObjectLabels,
+			},
+		}
+
+		for _, slo := range spec.SLOs {
+			object := spec.Service + "/" + slo.Name
+			src, err := sliSource(slo.SLI, cfg.Window)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %s", object, err)
+			}
+			if slo.Alerting != nil {
+				issuef(object, "alerting is not supported, ignored")
+			}
+			sloName := strings.Trim(invalidSLONameChars.ReplaceAllString(slo.Name, "_"), "_")
+			if sloName != slo.Name {
+				issuef(object, "%q is not a valid SLO name, renamed to %q", slo.Name, sloName)
+			}
+
+			// The Sloth labels are set on all the SLO metrics like the output ones.
+			labels := map[string]string{}
+			for k, v := range spec.Labels {
+				labels[k] = v
+			}
+			for k, v := range slo.Labels {
+				labels[k] = v
+			}
+			if len(labels) == 0 {
+				labels = nil
+			}
+
+			sl.Spec.ServiceLevelObjectives = append(sl.Spec.ServiceLevelObjectives, monitoringv1alpha1.SLO{
+				Name:                         sloName,
+				Description:                  slo.Description,
+				AvailabilityObjectivePercent: slo.Objective,
+				ServiceLevelIndicator:        monitoringv1alpha1.SLI{SLISource: monitoringv1alpha1.SLISource{Prometheus: src}},
+				Output: monitoringv1alpha1.Output{
+					Prometheus: &monitoringv1alpha1.PrometheusOutputSource{Labels: labels},
+				},
+			})
+		}
+
+		if err := sl.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s service level: %s", spec.Service, err)
+		}
+		sls = append(sls, sl)
+	}
+
+	return sls, issues, nil
+}
+
+// sliSource returns the Prometheus SLI source of a Sloth SLI.
+func sliSource(sli SLI, window string) (*monitoringv1alpha1.PrometheusSLISource, error) {
+	var total, errs string
+	switch {
+	case sli.Plugin != nil:
+		return nil, fmt.Errorf("the SLI plugins are not supported, got %q plugin", sli.Plugin.ID)
+	case sli.Events != nil:
+		total, errs = sli.Events.TotalQuery, sli.Events.ErrorQuery
+	case sli.Raw != nil:
+		// The error ratio is the error of a single event.
+		total, errs = "vector(1)", sli.Raw.ErrorRatioQuery
+	default:
+		return nil, fmt.Errorf("the SLI requires events or raw queries")
+	}
+
+	var err error
+	if total, err = renderQuery(total, window); err != nil {
+		return nil, err
+	}
+	if errs, err = renderQuery(errs, window); err != nil {
+		return nil, err
+	}
+	return &monitoringv1alpha1.PrometheusSLISource{TotalQuery: total, ErrorQuery: errs}, nil
+}
+
+// renderQuery replaces the templated window of a query.
+func renderQuery(query, window string) (string, error) {
+	if windowTemplate.MatchString(query) {
+		if window == "" {
+			return "", fmt.Errorf("templated windows ({{.window}}) require a window to replace them: %s", query)
+		}
+		query = windowTemplate.ReplaceAllLiteralString(query, window)
+	}
+	if strings.Contains(query, "{{") {
+		return "", fmt.Errorf("only the {{.window}} template is supported: %s", query)
+	}
+	return strings.TrimSpace(query), nil
+}
+
+// sanitizeName returns a valid RFC 1123 label.
+func sanitizeName(name string) string {
+	n := invalidNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	if len(n) > maxNameLength {
+		n = n[:maxNameLength]
+	}
+	return strings.Trim(n, "-")
+}
@@ -0,0 +1,130 @@
+package sloth_test
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spotahome/service-level-operator/pkg/service/manifest"
+	"github.com/spotahome/service-level-operator/pkg/service/sloth"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestImport(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		golden string
+		cfg    sloth.ImportConfig
+	}{
+		"Sloth raw specs and CRDs should be imported with the templated windows replaced.": {
+			input:  "specs.yaml",
+			golden: "specs.golden",
+			cfg:    sloth.ImportConfig{Namespace: "default", Window: "2m"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			f, err := os.Open(filepath.Join("testdata", test.input))
+			require.NoError(err)
+			defer f.Close()
+			specs, err := sloth.Read(f)
+			require.NoError(err)
+
+			sls, issues, err := sloth.Import(specs, test.cfg)
+			require.NoError(err)
+
+			var b bytes.Buffer
+			for _, i := range issues {
+				fmt.Fprintf(&b, "# %s\n", i)
+			}
+			require.NoError(manifest.Write(&b, sls))
+
+			golden := filepath.Join("testdata", test.golden)
+			if *update {
+				require.NoError(ioutil.WriteFile(golden, b.Bytes(), 0644))
+			}
+			exp, err := ioutil.ReadFile(golden)
+			require.NoError(err)
+			assert.Equal(string(exp), b.String())
+		})
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	const spec = `
+version: prometheus/v1
+service: svc
+slos:
+  - name: slo
+    objective: 99
+    sli:
+      events:
+        error_query: sum(rate(errors[{{.window}}]))
+        total_query: sum(rate(total[{{.window}}]))
+`
+
+	tests := map[string]struct {
+		spec   string
+		cfg    sloth.ImportConfig
+		expErr string
+	}{
+		"Templated windows without window should fail.": {
+			spec:   spec,
+			expErr: "templated windows ({{.window}}) require a window",
+		},
+		"Unsupported templates should fail.": {
+			spec:   strings.Replace(spec, "{{.window}}]))\n        total", "{{.window}}])) / {{.service}}\n        total", 1),
+			cfg:    sloth.ImportConfig{Window: "5m"},
+			expErr: "only the {{.window}} template is supported",
+		},
+		"SLI plugins should fail.": {
+			spec: `
+version: prometheus/v1
+service: svc
+slos:
+  - name: slo
+    objective: 99
+    sli:
+      plugin:
+        id: sloth-common/kubernetes/apiserver/availability
+`,
+			expErr: `the SLI plugins are not supported, got "sloth-common/kubernetes/apiserver/availability" plugin`,
+		},
+		"Invalid windows should fail.": {
+			spec:   spec,
+			cfg:    sloth.ImportConfig{Window: "5 minutes"},
+			expErr: "invalid window",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			specs, err := sloth.Read(strings.NewReader(test.spec))
+			require.NoError(t, err)
+			_, _, err = sloth.Import(specs, test.cfg)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), test.expErr)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	_, err := sloth.Read(strings.NewReader("---\nversion: prometheus/v2\nservice: svc\n"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "line 2: unsupported Sloth spec")
+	}
+}
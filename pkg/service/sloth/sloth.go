@@ -0,0 +1,174 @@
+package sloth
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Sloth spec versions.
+const (
+	// SpecVersion is the version of the Sloth raw spec.
+	SpecVersion = "prometheus/v1"
+	// KubernetesAPIVersion is the API version of the Sloth Kubernetes CRD.
+	KubernetesAPIVersion = "sloth.slok.dev/v1"
+	// KubernetesKind is the kind of the Sloth Kubernetes CRD.
+	KubernetesKind = "PrometheusServiceLevel"
+)
+
+// Spec is a Sloth service SLOs spec, the raw spec or the spec of the Kubernetes
+// CRD.
+type Spec struct {
+	Version string            `yaml:"version"`
+	Service string            `yaml:"service"`
+	Labels  map[string]string `yaml:"labels,omitempty"`
+	SLOs    []SLO             `yaml:"slos"`
+
+	// Name, Namespace and ObjectLabels are the metadata of the Kubernetes CRD,
+	// empty on the raw specs.
+	Name         string            `yaml:"-"`
+	Namespace    string            `yaml:"-"`
+	ObjectLabels map[string]string `yaml:"-"`
+}
+
+// SLO is a Sloth SLO.
+type SLO struct {
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description,omitempty"`
+	Objective   float64           `yaml:"objective"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	SLI         SLI               `yaml:"sli"`
+	// Alerting is not supported, only kept to report it.
+	Alerting *yaml.Node `yaml:"alerting,omitempty"`
+}
+
+// SLI is a Sloth SLI, only one of the types is set.
+type SLI struct {
+	Raw    *SLIRaw    `yaml:"raw,omitempty"`
+	Events *SLIEvents `yaml:"events,omitempty"`
+	Plugin *SLIPlugin `yaml:"plugin,omitempty"`
+}
+
+// SLIRaw is an SLI with a query that returns the error ratio.
+type SLIRaw struct {
+	ErrorRatioQuery string `yaml:"error_ratio_query"`
+}
+
+// SLIEvents is an SLI with the error and total events queries.
+type SLIEvents struct {
+	ErrorQuery string `yaml:"error_query"`
+	TotalQuery string `yaml:"total_query"`
+}
+
+// SLIPlugin is an SLI generated by a Sloth plugin.
+type SLIPlugin struct {
+	ID string `yaml:"id"`
+}
+
+// kubernetesSpec is the Sloth Kubernetes CRD, the fields are camel cased.
+type kubernetesSpec struct {
+	Metadata struct {
+		Name      string            `yaml:"name"`
+		Namespace string            `yaml:"namespace"`
+		Labels    map[string]string `yaml:"labels"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Service string            `yaml:"service"`
+		Labels  map[string]string `yaml:"labels"`
+		SLOs    []struct {
+			Name        string            `yaml:"name"`
+			Description string            `yaml:"description"`
+			Objective   float64           `yaml:"objective"`
+			Labels      map[string]string `yaml:"labels"`
+			SLI         struct {
+				Raw *struct {
+					ErrorRatioQuery string `yaml:"errorRatioQuery"`
+				} `yaml:"raw"`
+				Events *struct {
+					ErrorQuery string `yaml:"errorQuery"`
+					TotalQuery string `yaml:"totalQuery"`
+				} `yaml:"events"`
+				Plugin *SLIPlugin `yaml:"plugin"`
+			} `yaml:"sli"`
+			Alerting *yaml.Node `yaml:"alerting"`
+		} `yaml:"slos"`
+	} `yaml:"spec"`
+}
+
+func (k kubernetesSpec) toSpec() Spec {
+	spec := Spec{
+		Version:      SpecVersion,
+		Service:      k.Spec.Service,
+		Labels:       k.Spec.Labels,
+		Name:         k.Metadata.Name,
+		Namespace:    k.Metadata.Namespace,
+		ObjectLabels: k.Metadata.Labels,
+	}
+	for _, kslo := range k.Spec.SLOs {
+		slo := SLO{
+			Name:        kslo.Name,
+			Description: kslo.Description,
+			Objective:   kslo.Objective,
+			Labels:      kslo.Labels,
+			Alerting:    kslo.Alerting,
+		}
+		switch {
+		case kslo.SLI.Raw != nil:
+			slo.SLI.Raw = &SLIRaw{ErrorRatioQuery: kslo.SLI.Raw.ErrorRatioQuery}
+		case kslo.SLI.Events != nil:
+			slo.SLI.Events = &SLIEvents{ErrorQuery: kslo.SLI.Events.ErrorQuery, TotalQuery: kslo.SLI.Events.TotalQuery}
+		}
+		slo.SLI.Plugin = kslo.SLI.Plugin
+		spec.SLOs = append(spec.SLOs, slo)
+	}
+	return spec
+}
+
+// Read reads the Sloth specs of a multi document YAML stream, the raw specs and
+// the Kubernetes CRDs.
+func Read(r io.Reader) ([]Spec, error) {
+	var specs []Spec
+	dec := yaml.NewDecoder(r)
+	for {
+		node := &yaml.Node{}
+		err := dec.Decode(node)
+		if err == io.EOF {
+			return specs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Empty documents.
+		if len(node.Content) == 0 || node.Content[0].Kind == yaml.ScalarNode && node.Content[0].Tag == "!!null" {
+			continue
+		}
+		line := node.Content[0].Line
+
+		var header struct {
+			Version    string `yaml:"version"`
+			APIVersion string `yaml:"apiVersion"`
+			Kind       string `yaml:"kind"`
+		}
+		if err := node.Decode(&header); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+
+		var spec Spec
+		switch {
+		case header.Version == SpecVersion:
+			if err := node.Decode(&spec); err != nil {
+				return nil, fmt.Errorf("line %d: invalid Sloth spec: %s", line, err)
+			}
+		case header.APIVersion == KubernetesAPIVersion && header.Kind == KubernetesKind:
+			var k kubernetesSpec
+			if err := node.Decode(&k); err != nil {
+				return nil, fmt.Errorf("line %d: invalid Sloth %s: %s", line, KubernetesKind, err)
+			}
+			spec = k.toSpec()
+		default:
+			return nil, fmt.Errorf("line %d: unsupported Sloth spec, should be a %q spec or a %s %s", line, SpecVersion, KubernetesAPIVersion, KubernetesKind)
+		}
+		specs = append(specs, spec)
+	}
+}
@@ -0,0 +1,233 @@
+package status
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+type serviceLevelKey struct {
+	cluster   string
+	namespace string
+	name      string
+}
+
+// historySize is the number of evaluations kept on the SLO history.
+const historySize = 120
+
+type serviceLevelState struct {
+	key    serviceLevelKey
+	labels map[string]string
+	slos   []*SLOStatus
+}
+
+// memory is a Store that has the state in memory.
+type memory struct {
+	resyncPeriod time.Duration
+
+	mu            sync.Mutex
+	serviceLevels map[serviceLevelKey]*serviceLevelState
+	slos          map[string]*SLOStatus
+}
+
+// NewMemory returns a new Store that has the state in memory. The resync period
+// is used to know when the SLOs will be evaluated again.
+func NewMemory(resyncPeriod time.Duration) Store {
+	return &memory{
+		resyncPeriod:  resyncPeriod,
+		serviceLevels: map[serviceLevelKey]*serviceLevelState{},
+		slos:          map[string]*SLOStatus{},
+	}
+}
+
+// SetServiceLevel satisfies Recorder interface.
+func (m *memory) SetServiceLevel(cluster string, sl *monitoringv1alpha1.ServiceLevel) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := serviceLevelKey{cluster: cluster, namespace: sl.Namespace, name: sl.Name}
+	state := &serviceLevelState{key: key, labels: map[string]string{}}
+	for k, v := range sl.Labels {
+		state.labels[k] = v
+	}
+
+	// Keep the state of the SLOs that are still present.
+	current := map[string]bool{}
+	for i := range sl.Spec.ServiceLevelObjectives {
+		slo := &sl.Spec.ServiceLevelObjectives[i]
+		id := SLOID(cluster, sl, slo)
+		current[id] = true
+
+		st, ok := m.slos[id]
+		if !ok {
+			st = &SLOStatus{
+				ID:           id,
+				Cluster:      cluster,
+				Namespace:    sl.Namespace,
+				ServiceLevel: sl.Name,
+				Name:         slo.Name,
+			}
+			m.slos[id] = st
+		}
+		st.Description = slo.Description
+		st.Disabled = slo.Disable
+		st.AvailabilityObjectivePercent = slo.AvailabilityObjectivePercent
+		state.slos = append(state.slos, st)
+	}
+
+	if prev, ok := m.serviceLevels[key]; ok {
+		for _, st := range prev.slos {
+			if !current[st.ID] {
+				delete(m.slos, st.ID)
+			}
+		}
+	}
+	m.serviceLevels[key] = state
+}
+
+// DeleteServiceLevel satisfies Recorder interface.
+func (m *memory) DeleteServiceLevel(cluster, namespace, name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := serviceLevelKey{cluster: cluster, namespace: namespace, name: name}
+	state, ok := m.serviceLevels[key]
+	if !ok {
+		return
+	}
+
+	for _, st := range state.slos {
+		delete(m.slos, st.ID)
+	}
+	delete(m.serviceLevels, key)
+}
+
+// RecordSLOEvaluation satisfies Recorder interface.
+func (m *memory) RecordSLOEvaluation(e SLOEvaluation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Only the evaluations of the handled service levels are recorded.
+	st, ok := m.slos[SLOID(e.Cluster, e.ServiceLevel, e.SLO)]
+	if !ok {
+		return
+	}
+
+	evalTime := e.Time
+	next := evalTime.Add(m.resyncPeriod)
+	st.LastEvaluation = &evalTime
+	st.LastEvaluationDuration = e.Duration.String()
+	st.NextEvaluation = &next
+	st.LastSkipped = e.Skipped
+	if e.Counters != nil {
+		counters := *e.Counters
+		st.OutputCounters = &counters
+	}
+
+	switch {
+	case e.Skipped:
+		st.LastError = ""
+		if e.Err != nil {
+			st.LastError = e.Err.Error()
+		}
+	case e.Err != nil:
+		st.Evaluations++
+		st.Failures++
+		st.ConsecutiveFailures++
+		st.LastError = e.Err.Error()
+	default:
+		st.Evaluations++
+		st.ConsecutiveFailures = 0
+		st.LastError = ""
+		st.LastSuccess = &evalTime
+	}
+
+	if e.Result != nil {
+		st.LastResult = newSLIResult(e.Result)
+	}
+
+	if e.Err == nil && !e.Skipped && st.LastResult != nil {
+		st.History = append(st.History, SLOHistoryPoint{Time: evalTime, AvailabilityRatio: st.LastResult.AvailabilityRatio})
+		if len(st.History) > historySize {
+			st.History = append([]SLOHistoryPoint{}, st.History[len(st.History)-historySize:]...)
+		}
+	}
+}
+
+// ListServiceLevels satisfies Reader interface.
+func (m *memory) ListServiceLevels() []ServiceLevelStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := []ServiceLevelStatus{}
+	for _, state := range m.serviceLevels {
+		res = append(res, state.status())
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	return res
+}
+
+// GetServiceLevel satisfies Reader interface.
+func (m *memory) GetServiceLevel(cluster, namespace, name string) (ServiceLevelStatus, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.serviceLevels[serviceLevelKey{cluster: cluster, namespace: namespace, name: name}]
+	if !ok {
+		return ServiceLevelStatus{}, false
+	}
+
+	return state.status(), true
+}
+
+// GetSLO satisfies Reader interface.
+func (m *memory) GetSLO(id string) (SLOStatus, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.slos[id]
+	if !ok {
+		return SLOStatus{}, false
+	}
+
+	return st.copy(), true
+}
+
+// status returns a copy of the state, needs to be called with the lock held.
+func (s *serviceLevelState) status() ServiceLevelStatus {
+	res := ServiceLevelStatus{
+		Cluster:   s.key.cluster,
+		Namespace: s.key.namespace,
+		Name:      s.key.name,
+		Labels:    map[string]string{},
+		SLOs:      []SLOStatus{},
+	}
+	for k, v := range s.labels {
+		res.Labels[k] = v
+	}
+	for _, st := range s.slos {
+		res.SLOs = append(res.SLOs, st.copy())
+	}
+
+	return res
+}
+
+// copy returns a copy of the SLO state that doesn't share the history.
+func (s *SLOStatus) copy() SLOStatus {
+	c := *s
+	c.History = append([]SLOHistoryPoint(nil), s.History...)
+	return c
+}
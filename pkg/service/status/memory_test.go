@@ -0,0 +1,202 @@
+package status_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+)
+
+var (
+	sl0 = &monitoringv1alpha1.ServiceLevel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns0", Name: "sl0"},
+		Spec: monitoringv1alpha1.ServiceLevelSpec{
+			ServiceLevelObjectives: []monitoringv1alpha1.SLO{
+				{Name: "slo0", AvailabilityObjectivePercent: 99.9},
+				{Name: "slo1", AvailabilityObjectivePercent: 99.9},
+			},
+		},
+	}
+	sl0WithoutSLO1 = &monitoringv1alpha1.ServiceLevel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns0", Name: "sl0"},
+		Spec: monitoringv1alpha1.ServiceLevelSpec{
+			ServiceLevelObjectives: []monitoringv1alpha1.SLO{
+				{Name: "slo0", AvailabilityObjectivePercent: 99.9},
+			},
+		},
+	}
+	sl0WithLabels = &monitoringv1alpha1.ServiceLevel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns0", Name: "sl0", Labels: map[string]string{"team": "team0"}},
+		Spec: monitoringv1alpha1.ServiceLevelSpec{
+			ServiceLevelObjectives: []monitoringv1alpha1.SLO{
+				{Name: "slo0", AvailabilityObjectivePercent: 99.9},
+			},
+		},
+	}
+	sl1 = &monitoringv1alpha1.ServiceLevel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "sl1"},
+	}
+)
+
+func TestMemoryStore(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	store := status.NewMemory(30 * time.Second)
+	store.SetServiceLevel("", sl0)
+	store.SetServiceLevel("cluster1", sl0WithoutSLO1)
+
+	// Record evaluations.
+	now := time.Now()
+	slo0 := &sl0.Spec.ServiceLevelObjectives[0]
+	store.RecordSLOEvaluation(status.SLOEvaluation{ServiceLevel: sl0, SLO: slo0, Time: now, Err: errors.New("wanted error")})
+	store.RecordSLOEvaluation(status.SLOEvaluation{ServiceLevel: sl0, SLO: slo0, Time: now, Err: errors.New("wanted error")})
+	store.RecordSLOEvaluation(status.SLOEvaluation{
+		Cluster:      "cluster1",
+		ServiceLevel: sl0WithoutSLO1,
+		SLO:          &sl0WithoutSLO1.Spec.ServiceLevelObjectives[0],
+		Time:         now,
+		Result:       &sli.Result{TotalQ: 100, ErrorQ: 1},
+		Counters:     &output.SLOCounters{ErrorRatioSum: 0.01, Count: 1, Objective: 0.999},
+	})
+	// Not handled service levels are ignored.
+	store.RecordSLOEvaluation(status.SLOEvaluation{ServiceLevel: sl1, SLO: slo0, Time: now})
+
+	sls := store.ListServiceLevels()
+	require.Len(sls, 2)
+	assert.Equal("", sls[0].Cluster)
+	assert.Equal("cluster1", sls[1].Cluster)
+
+	// Check the failed SLO.
+	st, ok := store.GetSLO("ns0:sl0:slo0")
+	require.True(ok)
+	assert.Equal(2, st.Evaluations)
+	assert.Equal(2, st.Failures)
+	assert.Equal(2, st.ConsecutiveFailures)
+	assert.Equal("wanted error", st.LastError)
+	assert.Nil(st.LastSuccess)
+	assert.Nil(st.LastResult)
+	assert.Equal(now.Add(30*time.Second), *st.NextEvaluation)
+
+	// Check the successful SLO.
+	st, ok = store.GetSLO("cluster1:ns0:sl0:slo0")
+	require.True(ok)
+	assert.Equal(1, st.Evaluations)
+	assert.Equal(0, st.ConsecutiveFailures)
+	assert.Equal(now, *st.LastSuccess)
+	assert.Equal(0.01, st.LastResult.ErrorRatio)
+	assert.Equal(0.99, st.LastResult.AvailabilityRatio)
+	assert.Equal(1.0, st.OutputCounters.Count)
+	assert.Equal([]status.SLOHistoryPoint{{Time: now, AvailabilityRatio: 0.99}}, st.History)
+
+	// Not evaluated SLOs are present.
+	st, ok = store.GetSLO("ns0:sl0:slo1")
+	require.True(ok)
+	assert.Nil(st.LastEvaluation)
+
+	// Removed SLOs should be removed.
+	store.SetServiceLevel("", sl0WithoutSLO1)
+	_, ok = store.GetSLO("ns0:sl0:slo1")
+	assert.False(ok)
+	st, ok = store.GetSLO("ns0:sl0:slo0")
+	require.True(ok)
+	assert.Equal(2, st.Failures, "the state of the present SLOs should be kept")
+	assert.Empty(st.History, "failed evaluations should not be on the history")
+
+	// Deleted service levels should be removed.
+	store.DeleteServiceLevel("cluster1", "ns0", "sl0")
+	_, ok = store.GetServiceLevel("cluster1", "ns0", "sl0")
+	assert.False(ok)
+	_, ok = store.GetSLO("cluster1:ns0:sl0:slo0")
+	assert.False(ok)
+	_, ok = store.GetServiceLevel("", "ns0", "sl0")
+	assert.True(ok)
+}
+
+func TestMemoryStoreHistoryAndLabels(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	store := status.NewMemory(30 * time.Second)
+	store.SetServiceLevel("", sl0WithLabels)
+
+	// Record more evaluations than the history can hold.
+	start := time.Now()
+	for i := 0; i < 200; i++ {
+		store.RecordSLOEvaluation(status.SLOEvaluation{
+			ServiceLevel: sl0WithLabels,
+			SLO:          &sl0WithLabels.Spec.ServiceLevelObjectives[0],
+			Time:         start.Add(time.Duration(i) * time.Second),
+			Result:       &sli.Result{TotalQ: 100, ErrorQ: float64(i % 2)},
+		})
+	}
+
+	got, ok := store.GetServiceLevel("", "ns0", "sl0")
+	require.True(ok)
+	assert.Equal(map[string]string{"team": "team0"}, got.Labels)
+	require.Len(got.SLOs, 1)
+	history := got.SLOs[0].History
+	require.Len(history, 120)
+	assert.Equal(start.Add(80*time.Second), history[0].Time, "the oldest evaluations should be removed")
+	assert.Equal(start.Add(199*time.Second), history[119].Time)
+	assert.Equal(0.99, history[119].AvailabilityRatio)
+
+	// The returned history should be a copy.
+	history[0].AvailabilityRatio = 0
+	st, _ := store.GetSLO("ns0:sl0:slo0")
+	assert.Equal(1.0, st.History[0].AvailabilityRatio)
+}
+
+func TestSLOStatusErrorBudget(t *testing.T) {
+	tests := map[string]struct {
+		counters        *output.SLOCounters
+		expAvailability float64
+		expRemaining    float64
+		expOK           bool
+	}{
+		"Without counters there shouldn't be error budget.": {
+			expOK: false,
+		},
+		"Without evaluations there shouldn't be error budget.": {
+			counters: &output.SLOCounters{Objective: 0.99},
+			expOK:    false,
+		},
+		"Having consumed part of the budget should return the remaining budget.": {
+			counters:        &output.SLOCounters{ErrorRatioSum: 0.01, Count: 4, Objective: 0.99},
+			expAvailability: 0.9975,
+			expRemaining:    0.75,
+			expOK:           true,
+		},
+		"Having consumed more than the budget should return a negative remaining budget.": {
+			counters:        &output.SLOCounters{ErrorRatioSum: 0.04, Count: 2, Objective: 0.99},
+			expAvailability: 0.98,
+			expRemaining:    -1,
+			expOK:           true,
+		},
+		"Having a 100% objective with errors should exhaust the budget.": {
+			counters:        &output.SLOCounters{ErrorRatioSum: 0.01, Count: 1, Objective: 1},
+			expAvailability: 0.99,
+			expRemaining:    -1,
+			expOK:           true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			availability, remaining, ok := status.SLOStatus{OutputCounters: test.counters}.ErrorBudget()
+			assert.Equal(test.expOK, ok)
+			assert.InDelta(test.expAvailability, availability, 1e-9)
+			assert.InDelta(test.expRemaining, remaining, 1e-9)
+		})
+	}
+}
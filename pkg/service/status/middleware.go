@@ -0,0 +1,30 @@
+package status
+
+import (
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+)
+
+// countersMiddleware will add the output counters to the recorded SLO evaluations.
+type countersMiddleware struct {
+	Recorder
+	counters output.CounterGetter
+}
+
+// NewCountersMiddleware returns a new recorder middleware that wraps a Recorder and
+// adds the SLO counters held by the output to the recorded SLO evaluations.
+func NewCountersMiddleware(counters output.CounterGetter, next Recorder) Recorder {
+	return countersMiddleware{
+		Recorder: next,
+		counters: counters,
+	}
+}
+
+// RecordSLOEvaluation satisfies status.Recorder interface.
+func (c countersMiddleware) RecordSLOEvaluation(e SLOEvaluation) {
+	if e.Counters == nil {
+		if counters, ok := c.counters.GetSLOCounters(e.Cluster, e.ServiceLevel, e.SLO); ok {
+			e.Counters = &counters
+		}
+	}
+	c.Recorder.RecordSLOEvaluation(e)
+}
@@ -0,0 +1,164 @@
+package status
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
+)
+
+// SLOEvaluation is the evaluation of an SLO.
+type SLOEvaluation struct {
+	// Cluster is the cluster of the service level, empty on the default cluster.
+	Cluster string
+	// ServiceLevel is the service level of the SLO.
+	ServiceLevel *monitoringv1alpha1.ServiceLevel
+	// SLO is the evaluated SLO.
+	SLO *monitoringv1alpha1.SLO
+	// Time is when the evaluation started.
+	Time time.Time
+	// Duration is the time the evaluation took.
+	Duration time.Duration
+	// Result is the SLI result, nil if the SLI could not be retrieved.
+	Result *sli.Result
+	// Err is the error of the evaluation.
+	Err error
+	// Skipped is true when the SLO was not evaluated (e.g the SLI source circuit was open).
+	Skipped bool
+	// Counters are the counters held by the output of the SLO after the evaluation.
+	Counters *output.SLOCounters
+}
+
+// Recorder knows how to record the state of the service levels that are being handled.
+type Recorder interface {
+	// SetServiceLevel sets the current service level of a cluster that is being handled.
+	SetServiceLevel(cluster string, sl *monitoringv1alpha1.ServiceLevel)
+	// DeleteServiceLevel deletes a service level that is not being handled anymore.
+	DeleteServiceLevel(cluster, namespace, name string)
+	// RecordSLOEvaluation records the evaluation of an SLO.
+	RecordSLOEvaluation(e SLOEvaluation)
+}
+
+// ValidationRecorder knows how to record the service levels that are not valid, the
+// recorders that implement it are notified of the service levels that are not handled.
+type ValidationRecorder interface {
+	// RecordValidationError records the validation error of a service level of a cluster.
+	RecordValidationError(cluster string, sl *monitoringv1alpha1.ServiceLevel, err error)
+}
+
+// Reader knows how to read the state of the service levels that are being handled.
+type Reader interface {
+	// ListServiceLevels lists the state of all the service levels.
+	ListServiceLevels() []ServiceLevelStatus
+	// GetServiceLevel gets the state of a service level.
+	GetServiceLevel(cluster, namespace, name string) (ServiceLevelStatus, bool)
+	// GetSLO gets the state of an SLO by its ID.
+	GetSLO(id string) (SLOStatus, bool)
+}
+
+// Store knows how to record and read the state of the service levels.
+type Store interface {
+	Recorder
+	Reader
+}
+
+// ServiceLevelStatus is the state of a service level.
+type ServiceLevelStatus struct {
+	Cluster   string            `json:"cluster,omitempty"`
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	SLOs      []SLOStatus       `json:"slos"`
+}
+
+// SLOStatus is the state of an SLO.
+type SLOStatus struct {
+	ID                           string              `json:"id"`
+	Cluster                      string              `json:"cluster,omitempty"`
+	Namespace                    string              `json:"namespace"`
+	ServiceLevel                 string              `json:"serviceLevel"`
+	Name                         string              `json:"name"`
+	Description                  string              `json:"description,omitempty"`
+	Disabled                     bool                `json:"disabled"`
+	AvailabilityObjectivePercent float64             `json:"availabilityObjectivePercent"`
+	Evaluations                  int                 `json:"evaluations"`
+	Failures                     int                 `json:"failures"`
+	ConsecutiveFailures          int                 `json:"consecutiveFailures"`
+	LastEvaluation               *time.Time          `json:"lastEvaluation,omitempty"`
+	LastEvaluationDuration       string              `json:"lastEvaluationDuration,omitempty"`
+	LastSuccess                  *time.Time          `json:"lastSuccess,omitempty"`
+	LastSkipped                  bool                `json:"lastSkipped"`
+	LastError                    string              `json:"lastError,omitempty"`
+	LastResult                   *SLIResult          `json:"lastResult,omitempty"`
+	OutputCounters               *output.SLOCounters `json:"outputCounters,omitempty"`
+	NextEvaluation               *time.Time          `json:"nextEvaluation,omitempty"`
+	History                      []SLOHistoryPoint   `json:"history,omitempty"`
+}
+
+// SLOHistoryPoint is the result of a successful SLO evaluation.
+type SLOHistoryPoint struct {
+	Time              time.Time `json:"time"`
+	AvailabilityRatio float64   `json:"availabilityRatio"`
+}
+
+// ErrorBudget returns the availability of the SLO and the remaining error budget
+// (both in ratio unit) based on the output counters, false if the SLO doesn't have
+// output counters. The remaining error budget is negative when the budget has
+// been exhausted.
+func (s SLOStatus) ErrorBudget() (availability, remaining float64, ok bool) {
+	if s.OutputCounters == nil {
+		return 0, 0, false
+	}
+	return s.OutputCounters.ErrorBudget()
+}
+
+// SLIResult is the result of an SLI.
+type SLIResult struct {
+	TotalQ            float64 `json:"total"`
+	ErrorQ            float64 `json:"error"`
+	TotalQSamples     int     `json:"totalSamples"`
+	ErrorQSamples     int     `json:"errorSamples"`
+	ErrorRatio        float64 `json:"errorRatio"`
+	AvailabilityRatio float64 `json:"availabilityRatio"`
+}
+
+func newSLIResult(r *sli.Result) *SLIResult {
+	// Invalid results are returned as errors on the evaluation.
+	errRat, _ := r.ErrorRatio()
+	avRat, _ := r.AvailabilityRatio()
+	return &SLIResult{
+		TotalQ:            r.TotalQ,
+		ErrorQ:            r.ErrorQ,
+		TotalQSamples:     r.TotalQSamples,
+		ErrorQSamples:     r.ErrorQSamples,
+		ErrorRatio:        errRat,
+		AvailabilityRatio: avRat,
+	}
+}
+
+// SLOID returns the ID of an SLO of a cluster, the ID is unique across clusters
+// and is safe to use as an URL path segment.
+func SLOID(cluster string, sl *monitoringv1alpha1.ServiceLevel, slo *monitoringv1alpha1.SLO) string {
+	id := fmt.Sprintf("%s:%s:%s", sl.Namespace, sl.Name, slo.Name)
+	if cluster != "" {
+		id = cluster + ":" + id
+	}
+	return id
+}
+
+// ServiceLevelKey returns the key of a service level of a cluster, the key is
+// unique across clusters.
+func ServiceLevelKey(cluster, namespace, name string) string {
+	return cluster + "/" + namespace + "/" + name
+}
+
+// FormatPercent formats a ratio as a percent with 3 decimals at most.
+func FormatPercent(ratio float64) string {
+	s := strconv.FormatFloat(ratio*100, 'f', 3, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	return s + "%"
+}
@@ -0,0 +1,154 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+)
+
+const (
+	// APIPrefix is the path prefix of the API.
+	APIPrefix = "/api/v1/"
+)
+
+// LastResult is the last result of an SLO evaluation.
+type LastResult struct {
+	ID             string              `json:"id"`
+	LastEvaluation *time.Time          `json:"lastEvaluation,omitempty"`
+	LastSkipped    bool                `json:"lastSkipped"`
+	LastError      string              `json:"lastError,omitempty"`
+	LastResult     *status.SLIResult   `json:"lastResult,omitempty"`
+	OutputCounters *output.SLOCounters `json:"outputCounters,omitempty"`
+	NextEvaluation *time.Time          `json:"nextEvaluation,omitempty"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// api is a read only JSON API of the state of the service levels.
+type api struct {
+	reader status.Reader
+	logger log.Logger
+}
+
+// NewAPIHandler returns a read only JSON API handler with the state of the handled
+// service levels, it should be served on the APIPrefix path. The routes are:
+//
+// - /api/v1/servicelevels: Lists the service levels, can be filtered with the `cluster` query param.
+// - /api/v1/servicelevels/{ns}/{name}: Gets a service level, the cluster is set with the `cluster` query param.
+// - /api/v1/slos/{id}: Gets an SLO.
+// - /api/v1/slos/{id}/last-result: Gets the last evaluation result of an SLO.
+func NewAPIHandler(reader status.Reader, logger log.Logger) http.Handler {
+	return &api{
+		reader: reader,
+		logger: logger,
+	}
+}
+
+// ServeHTTP satisfies http.Handler interface.
+func (a *api) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		a.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	// Use the escaped path so the path segments can have escaped slashes.
+	path := strings.TrimPrefix(r.URL.EscapedPath(), APIPrefix)
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, p := range parts {
+		up, err := url.PathUnescape(p)
+		if err != nil {
+			a.writeError(w, http.StatusBadRequest, "invalid path")
+			return
+		}
+		parts[i] = up
+	}
+
+	cluster := r.URL.Query().Get("cluster")
+	switch {
+	case len(parts) == 1 && parts[0] == "servicelevels":
+		a.listServiceLevels(w, cluster)
+	case len(parts) == 3 && parts[0] == "servicelevels":
+		a.getServiceLevel(w, cluster, parts[1], parts[2])
+	case len(parts) == 2 && parts[0] == "slos":
+		a.getSLO(w, parts[1])
+	case len(parts) == 3 && parts[0] == "slos" && parts[2] == "last-result":
+		a.getSLOLastResult(w, parts[1])
+	default:
+		a.writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (a *api) listServiceLevels(w http.ResponseWriter, cluster string) {
+	sls := a.reader.ListServiceLevels()
+	if cluster != "" {
+		filtered := []status.ServiceLevelStatus{}
+		for _, sl := range sls {
+			if sl.Cluster == cluster {
+				filtered = append(filtered, sl)
+			}
+		}
+		sls = filtered
+	}
+
+	a.writeJSON(w, http.StatusOK, sls)
+}
+
+func (a *api) getServiceLevel(w http.ResponseWriter, cluster, ns, name string) {
+	sl, ok := a.reader.GetServiceLevel(cluster, ns, name)
+	if !ok {
+		a.writeError(w, http.StatusNotFound, "service level not found")
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, sl)
+}
+
+func (a *api) getSLO(w http.ResponseWriter, id string) {
+	slo, ok := a.reader.GetSLO(id)
+	if !ok {
+		a.writeError(w, http.StatusNotFound, "SLO not found")
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, slo)
+}
+
+func (a *api) getSLOLastResult(w http.ResponseWriter, id string) {
+	slo, ok := a.reader.GetSLO(id)
+	if !ok {
+		a.writeError(w, http.StatusNotFound, "SLO not found")
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, LastResult{
+		ID:             slo.ID,
+		LastEvaluation: slo.LastEvaluation,
+		LastSkipped:    slo.LastSkipped,
+		LastError:      slo.LastError,
+		LastResult:     slo.LastResult,
+		OutputCounters: slo.OutputCounters,
+		NextEvaluation: slo.NextEvaluation,
+	})
+}
+
+func (a *api) writeError(w http.ResponseWriter, code int, msg string) {
+	a.writeJSON(w, code, apiError{Error: msg})
+}
+
+func (a *api) writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		a.logger.Errorf("error writing API response: %s", err)
+	}
+}
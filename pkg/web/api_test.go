@@ -0,0 +1,128 @@
+package web_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+	"github.com/spotahome/service-level-operator/pkg/web"
+)
+
+func TestAPI(t *testing.T) {
+	sl := &monitoringv1alpha1.ServiceLevel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns0", Name: "sl0"},
+		Spec: monitoringv1alpha1.ServiceLevelSpec{
+			ServiceLevelObjectives: []monitoringv1alpha1.SLO{
+				{Name: "slo0", AvailabilityObjectivePercent: 99.9},
+			},
+		},
+	}
+	store := status.NewMemory(time.Minute)
+	store.SetServiceLevel("", sl)
+	store.RecordSLOEvaluation(status.SLOEvaluation{
+		ServiceLevel: sl,
+		SLO:          &sl.Spec.ServiceLevelObjectives[0],
+		Time:         time.Now(),
+		Result:       &sli.Result{TotalQ: 10, ErrorQ: 1},
+	})
+
+	tests := map[string]struct {
+		method  string
+		path    string
+		expCode int
+		expBody func(t *testing.T, body []byte)
+	}{
+		"Listing the service levels should return all the service levels.": {
+			path:    "/api/v1/servicelevels",
+			expCode: http.StatusOK,
+			expBody: func(t *testing.T, body []byte) {
+				sls := []status.ServiceLevelStatus{}
+				assert.NoError(t, json.Unmarshal(body, &sls))
+				if assert.Len(t, sls, 1) && assert.Len(t, sls[0].SLOs, 1) {
+					assert.Equal(t, "ns0:sl0:slo0", sls[0].SLOs[0].ID)
+				}
+			},
+		},
+
+		"Listing the service levels of a cluster should filter the service levels.": {
+			path:    "/api/v1/servicelevels?cluster=cluster1",
+			expCode: http.StatusOK,
+			expBody: func(t *testing.T, body []byte) {
+				assert.JSONEq(t, `[]`, string(body))
+			},
+		},
+
+		"Getting a service level should return the service level.": {
+			path:    "/api/v1/servicelevels/ns0/sl0",
+			expCode: http.StatusOK,
+			expBody: func(t *testing.T, body []byte) {
+				sl := status.ServiceLevelStatus{}
+				assert.NoError(t, json.Unmarshal(body, &sl))
+				assert.Equal(t, "sl0", sl.Name)
+			},
+		},
+
+		"Getting a missing service level should return not found.": {
+			path:    "/api/v1/servicelevels/ns0/sl1",
+			expCode: http.StatusNotFound,
+		},
+
+		"Getting the last result of an SLO should return the last result.": {
+			path:    "/api/v1/slos/ns0:sl0:slo0/last-result",
+			expCode: http.StatusOK,
+			expBody: func(t *testing.T, body []byte) {
+				res := web.LastResult{}
+				assert.NoError(t, json.Unmarshal(body, &res))
+				assert.Equal(t, "ns0:sl0:slo0", res.ID)
+				if assert.NotNil(t, res.LastResult) {
+					assert.Equal(t, 0.1, res.LastResult.ErrorRatio)
+				}
+				assert.NotNil(t, res.NextEvaluation)
+			},
+		},
+
+		"Getting a missing SLO should return not found.": {
+			path:    "/api/v1/slos/ns0:sl0:slo1",
+			expCode: http.StatusNotFound,
+		},
+
+		"Unknown routes should return not found.": {
+			path:    "/api/v1/unknown",
+			expCode: http.StatusNotFound,
+		},
+
+		"Not read methods should not be allowed.": {
+			method:  http.MethodPost,
+			path:    "/api/v1/servicelevels",
+			expCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			method := test.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			h := web.NewAPIHandler(store, log.Dummy)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(method, test.path, nil))
+
+			assert.Equal(t, test.expCode, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			if test.expBody != nil {
+				test.expBody(t, w.Body.Bytes())
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spotahome/service-level-operator/pkg/service/gate"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+)
+
+const defAPIClientTimeout = 10 * time.Second
+
+// APIClient is a client of the operator API.
+type APIClient struct {
+	address string
+	cli     *http.Client
+}
+
+// NewAPIClient returns a new client of the operator API served on the address
+// (e.g. http://127.0.0.1:8080), if the HTTP client is nil a default one is used.
+func NewAPIClient(address string, cli *http.Client) *APIClient {
+	if cli == nil {
+		cli = &http.Client{Timeout: defAPIClientTimeout}
+	}
+	return &APIClient{
+		address: strings.TrimSuffix(address, "/"),
+		cli:     cli,
+	}
+}
+
+// ListServiceLevels lists the state of the service levels handled by the operator,
+// if the cluster is set only the ones of that cluster.
+func (a *APIClient) ListServiceLevels(ctx context.Context, cluster string) ([]status.ServiceLevelStatus, error) {
+	sls := []status.ServiceLevelStatus{}
+	err := a.get(ctx, "servicelevels", cluster, &sls)
+	if err != nil {
+		return nil, err
+	}
+	return sls, nil
+}
+
+// GetServiceLevel gets the state of a service level handled by the operator.
+func (a *APIClient) GetServiceLevel(ctx context.Context, cluster, namespace, name string) (status.ServiceLevelStatus, error) {
+	sl := status.ServiceLevelStatus{}
+	path := fmt.Sprintf("servicelevels/%s/%s", url.PathEscape(namespace), url.PathEscape(name))
+	err := a.get(ctx, path, cluster, &sl)
+	if err != nil {
+		return status.ServiceLevelStatus{}, err
+	}
+	return sl, nil
+}
+
+// Gate gets the error budget gate decision of a service level handled by the operator.
+func (a *APIClient) Gate(ctx context.Context, cluster, namespace, name string) (gate.Decision, error) {
+	d := gate.Decision{}
+	path := fmt.Sprintf("gate/%s/%s", url.PathEscape(namespace), url.PathEscape(name))
+	err := a.get(ctx, path, cluster, &d)
+	if err != nil {
+		return gate.Decision{}, err
+	}
+	return d, nil
+}
+
+func (a *APIClient) get(ctx context.Context, path, cluster string, v interface{}) error {
+	u := a.address + APIPrefix + path
+	if cluster != "" {
+		u += "?cluster=" + url.QueryEscape(cluster)
+	}
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.cli.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := apiError{}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = resp.Status
+		}
+		return fmt.Errorf("operator API error: %s", apiErr.Error)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
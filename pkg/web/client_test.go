@@ -0,0 +1,83 @@
+package web_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+	"github.com/spotahome/service-level-operator/pkg/web"
+)
+
+func TestAPIClient(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sl := &monitoringv1alpha1.ServiceLevel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns0", Name: "sl0"},
+		Spec: monitoringv1alpha1.ServiceLevelSpec{
+			ServiceLevelObjectives: []monitoringv1alpha1.SLO{
+				{Name: "slo0", AvailabilityObjectivePercent: 99.9},
+			},
+		},
+	}
+	store := status.NewMemory(time.Minute)
+	store.SetServiceLevel("", sl)
+	store.RecordSLOEvaluation(status.SLOEvaluation{
+		ServiceLevel: sl,
+		SLO:          &sl.Spec.ServiceLevelObjectives[0],
+		Time:         time.Now(),
+		Result:       &sli.Result{TotalQ: 10, ErrorQ: 1},
+	})
+
+	srv := httptest.NewServer(web.NewAPIHandler(store, log.Dummy))
+	defer srv.Close()
+	cli := web.NewAPIClient(srv.URL+"/", nil)
+
+	sls, err := cli.ListServiceLevels(context.Background(), "")
+	require.NoError(err)
+	require.Len(sls, 1)
+	assert.Equal("sl0", sls[0].Name)
+
+	sls, err = cli.ListServiceLevels(context.Background(), "cluster1")
+	require.NoError(err)
+	assert.Len(sls, 0)
+
+	got, err := cli.GetServiceLevel(context.Background(), "", "ns0", "sl0")
+	require.NoError(err)
+	require.Len(got.SLOs, 1)
+	if assert.NotNil(got.SLOs[0].LastResult) {
+		assert.Equal(0.9, got.SLOs[0].LastResult.AvailabilityRatio)
+	}
+
+	_, err = cli.GetServiceLevel(context.Background(), "", "ns0", "sl1")
+	assert.EqualError(err, "operator API error: service level not found")
+}
+
+func TestAPIClientGate(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	srv := httptest.NewServer(web.NewGateHandler(newGate(t), log.Dummy))
+	defer srv.Close()
+	cli := web.NewAPIClient(srv.URL, nil)
+
+	d, err := cli.Gate(context.Background(), "", "ns0", "sl0")
+	require.NoError(err)
+	assert.True(d.Allowed)
+
+	d, err = cli.Gate(context.Background(), "", "ns0", "sl1")
+	require.NoError(err)
+	assert.False(d.Allowed)
+
+	_, err = cli.Gate(context.Background(), "", "ns0", "sl3")
+	assert.EqualError(err, "operator API error: service level not found")
+}
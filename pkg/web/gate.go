@@ -0,0 +1,73 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/gate"
+)
+
+const (
+	// GatePrefix is the path prefix of the error budget gate API.
+	GatePrefix = APIPrefix + "gate/"
+)
+
+// Decider knows how to decide if the changes of the service levels are allowed.
+type Decider interface {
+	// Decide decides if a service level allows changes.
+	Decide(cluster, namespace, name string) (gate.Decision, error)
+}
+
+// gateAPI is the JSON API of the error budget gate.
+type gateAPI struct {
+	api
+	decider Decider
+}
+
+// NewGateHandler returns the error budget gate JSON API handler, it should be
+// served on the GatePrefix path. The route is:
+//
+// - /api/v1/gate/{ns}/{name}: Decides if a service level allows changes, the cluster is set with the `cluster` query param.
+func NewGateHandler(decider Decider, logger log.Logger) http.Handler {
+	return &gateAPI{
+		api:     api{logger: logger},
+		decider: decider,
+	}
+}
+
+// ServeHTTP satisfies http.Handler interface.
+func (g *gateAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		g.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.EscapedPath(), GatePrefix)
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != 2 {
+		g.writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	for i, p := range parts {
+		up, err := url.PathUnescape(p)
+		if err != nil {
+			g.writeError(w, http.StatusBadRequest, "invalid path")
+			return
+		}
+		parts[i] = up
+	}
+
+	d, err := g.decider.Decide(r.URL.Query().Get("cluster"), parts[0], parts[1])
+	switch {
+	case err == gate.ErrNotFound:
+		g.writeError(w, http.StatusNotFound, err.Error())
+	case err != nil:
+		// The policy of the service level is not valid.
+		g.writeError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		g.writeJSON(w, http.StatusOK, d)
+	}
+}
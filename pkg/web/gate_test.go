@@ -0,0 +1,132 @@
+package web_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/gate"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+	"github.com/spotahome/service-level-operator/pkg/web"
+)
+
+func newGate(t *testing.T) *gate.Gate {
+	newSL := func(name string, annotations map[string]string) *monitoringv1alpha1.ServiceLevel {
+		return &monitoringv1alpha1.ServiceLevel{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns0", Name: name, Annotations: annotations},
+			Spec: monitoringv1alpha1.ServiceLevelSpec{
+				ServiceLevelObjectives: []monitoringv1alpha1.SLO{
+					{Name: "slo0", AvailabilityObjectivePercent: 99},
+				},
+			},
+		}
+	}
+
+	store := status.NewMemory(time.Minute)
+	policies := gate.NewPolicies(store)
+	for _, sl := range []*monitoringv1alpha1.ServiceLevel{
+		newSL("sl0", nil),
+		newSL("sl1", map[string]string{gate.AnnotationPolicy: `{"minErrorBudgetPercent": 90}`}),
+		newSL("sl2", map[string]string{gate.AnnotationPolicy: `{`}),
+	} {
+		policies.SetServiceLevel("", sl)
+		policies.RecordSLOEvaluation(status.SLOEvaluation{
+			ServiceLevel: sl,
+			SLO:          &sl.Spec.ServiceLevelObjectives[0],
+			Time:         time.Now(),
+			// 50% of the error budget remaining.
+			Counters: &output.SLOCounters{ErrorRatioSum: 0.5, Count: 100, Objective: 0.99},
+		})
+	}
+
+	g, err := gate.NewGate(gate.Policy{MinErrorBudgetPercent: 10}, store, policies)
+	require.NoError(t, err)
+	return g
+}
+
+func TestGateHandler(t *testing.T) {
+	g := newGate(t)
+
+	tests := map[string]struct {
+		method  string
+		path    string
+		expCode int
+		expBody func(t *testing.T, body []byte)
+	}{
+		"A service level with error budget should allow.": {
+			path:    "/api/v1/gate/ns0/sl0",
+			expCode: http.StatusOK,
+			expBody: func(t *testing.T, body []byte) {
+				d := gate.Decision{}
+				assert.NoError(t, json.Unmarshal(body, &d))
+				assert.True(t, d.Allowed)
+				assert.Len(t, d.SLOs, 1)
+			},
+		},
+
+		"A service level below its annotation policy minimum should deny.": {
+			path:    "/api/v1/gate/ns0/sl1",
+			expCode: http.StatusOK,
+			expBody: func(t *testing.T, body []byte) {
+				d := gate.Decision{}
+				assert.NoError(t, json.Unmarshal(body, &d))
+				assert.False(t, d.Allowed)
+				assert.Equal(t, []string{"slo0 SLO: the error budget remaining is 50%, below the 90% minimum"}, d.Reasons)
+			},
+		},
+
+		"A service level with an invalid policy should fail.": {
+			path:    "/api/v1/gate/ns0/sl2",
+			expCode: http.StatusUnprocessableEntity,
+		},
+
+		"A missing service level should return not found.": {
+			path:    "/api/v1/gate/ns0/sl3",
+			expCode: http.StatusNotFound,
+		},
+
+		"A service level of another cluster should return not found.": {
+			path:    "/api/v1/gate/ns0/sl0?cluster=cluster1",
+			expCode: http.StatusNotFound,
+		},
+
+		"Invalid routes should return not found.": {
+			path:    "/api/v1/gate/ns0",
+			expCode: http.StatusNotFound,
+		},
+
+		"Not read methods should not be allowed.": {
+			method:  http.MethodPost,
+			path:    "/api/v1/gate/ns0/sl0",
+			expCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			method := test.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			h := web.NewGateHandler(g, log.Dummy)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(method, test.path, nil))
+
+			assert.Equal(t, test.expCode, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			if test.expBody != nil {
+				test.expBody(t, w.Body.Bytes())
+			}
+		})
+	}
+}
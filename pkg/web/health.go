@@ -0,0 +1,78 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/health"
+)
+
+const (
+	// ReadyPath is the path of the readiness check.
+	ReadyPath = "/healthz/ready"
+	// LivePath is the path of the liveness check.
+	LivePath = "/healthz/live"
+
+	healthCheckTimeout = 5 * time.Second
+)
+
+// healthHandler serves the result of a set of health checks.
+type healthHandler struct {
+	check  func(ctx context.Context) health.Result
+	state  string
+	logger log.Logger
+}
+
+// NewReadyHandler returns a handler that serves the result of the readiness checks,
+// it should be served on the ReadyPath.
+func NewReadyHandler(reporter health.Reporter, logger log.Logger) http.Handler {
+	return &healthHandler{check: reporter.Readiness, state: "ready", logger: logger}
+}
+
+// NewLiveHandler returns a handler that serves the result of the liveness checks,
+// it should be served on the LivePath.
+func NewLiveHandler(reporter health.Reporter, logger log.Logger) http.Handler {
+	return &healthHandler{check: reporter.Liveness, state: "live", logger: logger}
+}
+
+// ServeHTTP satisfies http.Handler interface. The response is unhealthy (503) if any
+// of the checks fails, with the `verbose` query param the result of every check is
+// returned in JSON.
+func (h *healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+	res := h.check(ctx)
+
+	code := http.StatusOK
+	if !res.Healthy {
+		code = http.StatusServiceUnavailable
+	}
+
+	if _, verbose := r.URL.Query()["verbose"]; verbose {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		err := json.NewEncoder(w).Encode(res)
+		if err != nil {
+			h.logger.Errorf("error writing health check response: %s", err)
+		}
+		return
+	}
+
+	msg := h.state
+	if !res.Healthy {
+		var failed []string
+		for _, c := range res.Checks {
+			if !c.Healthy {
+				failed = append(failed, c.Name)
+			}
+		}
+		msg = "not " + h.state + ": " + strings.Join(failed, ", ")
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(code)
+	w.Write([]byte(msg))
+}
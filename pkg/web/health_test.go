@@ -0,0 +1,71 @@
+package web_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/health"
+	"github.com/spotahome/service-level-operator/pkg/web"
+)
+
+func TestHealthHandlers(t *testing.T) {
+	checks := health.NewChecks()
+	checks.RegisterReadiness("check0", health.CheckerFunc(func(_ context.Context) error { return nil }))
+	checks.RegisterReadiness("check1", health.CheckerFunc(func(_ context.Context) error { return errors.New("wanted error") }))
+	checks.RegisterLiveness("check2", health.CheckerFunc(func(_ context.Context) error { return nil }))
+
+	tests := map[string]struct {
+		handler http.Handler
+		path    string
+		expCode int
+		expBody string
+		expJSON *health.Result
+	}{
+		"A failing readiness check should make the operator not ready.": {
+			handler: web.NewReadyHandler(checks, log.Dummy),
+			path:    "/healthz/ready",
+			expCode: http.StatusServiceUnavailable,
+			expBody: "not ready: check1",
+		},
+		"A verbose readiness check should return every check result.": {
+			handler: web.NewReadyHandler(checks, log.Dummy),
+			path:    "/healthz/ready?verbose",
+			expCode: http.StatusServiceUnavailable,
+			expJSON: &health.Result{Healthy: false, Checks: []health.CheckResult{
+				{Name: "check0", Healthy: true},
+				{Name: "check1", Healthy: false, Error: "wanted error"},
+			}},
+		},
+		"Healthy liveness checks should make the operator alive.": {
+			handler: web.NewLiveHandler(checks, log.Dummy),
+			path:    "/healthz/live",
+			expCode: http.StatusOK,
+			expBody: "live",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			w := httptest.NewRecorder()
+			test.handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+			assert.Equal(test.expCode, w.Code)
+			if test.expJSON != nil {
+				got := health.Result{}
+				assert.NoError(json.Unmarshal(w.Body.Bytes(), &got))
+				assert.Equal(*test.expJSON, got)
+			} else {
+				assert.Equal(test.expBody, w.Body.String())
+			}
+		})
+	}
+}
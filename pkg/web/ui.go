@@ -0,0 +1,308 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+)
+
+const (
+	sparklineWidth  = 120
+	sparklineHeight = 24
+	// budgetWarningRatio is the remaining error budget ratio below which the budget
+	// is shown as a warning.
+	budgetWarningRatio = 0.25
+)
+
+// ui is an HTML UI of the state of the service levels.
+type ui struct {
+	reader      status.Reader
+	metricsPath string
+	tpl         *template.Template
+	logger      log.Logger
+}
+
+// NewUIHandler returns an HTML UI handler that lists the handled service levels with
+// the objective, availability, remaining error budget, last error and the recent
+// availability of every SLO. It should be served on the root path. The service levels
+// can be filtered with the `namespace` and `selector` (label selector) query params.
+// The UI doesn't need any external asset.
+func NewUIHandler(reader status.Reader, metricsPath string, logger log.Logger) http.Handler {
+	return &ui{
+		reader:      reader,
+		metricsPath: metricsPath,
+		tpl:         template.Must(template.New("ui").Parse(uiTemplate)),
+		logger:      logger,
+	}
+}
+
+type uiPage struct {
+	MetricsPath   string
+	APIPath       string
+	Namespaces    []string
+	Namespace     string
+	Selector      string
+	Error         string
+	Total         int
+	ServiceLevels []uiServiceLevel
+}
+
+type uiServiceLevel struct {
+	Cluster   string
+	Namespace string
+	Name      string
+	Labels    []string
+	SLOs      []uiSLO
+}
+
+type uiSLO struct {
+	ID               string
+	Name             string
+	Description      string
+	Disabled         bool
+	Objective        string
+	Availability     string
+	LastAvailability string
+	Budget           string
+	BudgetClass      string
+	LastEvaluation   string
+	LastError        string
+	Sparkline        string
+	ObjectiveLine    string
+}
+
+// ServeHTTP satisfies http.Handler interface.
+func (u *ui) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	page := uiPage{
+		MetricsPath: u.metricsPath,
+		APIPath:     APIPrefix + "servicelevels",
+		Namespace:   q.Get("namespace"),
+		Selector:    q.Get("selector"),
+	}
+
+	code := http.StatusOK
+	selector, err := labels.Parse(page.Selector)
+	if err != nil {
+		code = http.StatusBadRequest
+		page.Error = fmt.Sprintf("invalid label selector: %s", err)
+		selector = labels.Nothing()
+	}
+
+	now := time.Now()
+	nss := map[string]bool{}
+	for _, sl := range u.reader.ListServiceLevels() {
+		page.Total++
+		nss[sl.Namespace] = true
+		if page.Namespace != "" && sl.Namespace != page.Namespace {
+			continue
+		}
+		if !selector.Matches(labels.Set(sl.Labels)) {
+			continue
+		}
+		page.ServiceLevels = append(page.ServiceLevels, newUIServiceLevel(sl, now))
+	}
+	for ns := range nss {
+		page.Namespaces = append(page.Namespaces, ns)
+	}
+	sort.Strings(page.Namespaces)
+
+	// Render first so a template error doesn't end on a half written response.
+	var b bytes.Buffer
+	if err := u.tpl.Execute(&b, page); err != nil {
+		u.logger.Errorf("error rendering UI: %s", err)
+		http.Error(w, "error rendering the page", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	w.Write(b.Bytes())
+}
+
+func newUIServiceLevel(sl status.ServiceLevelStatus, now time.Time) uiServiceLevel {
+	res := uiServiceLevel{
+		Cluster:   sl.Cluster,
+		Namespace: sl.Namespace,
+		Name:      sl.Name,
+	}
+	for k, v := range sl.Labels {
+		res.Labels = append(res.Labels, k+"="+v)
+	}
+	sort.Strings(res.Labels)
+
+	for _, slo := range sl.SLOs {
+		res.SLOs = append(res.SLOs, newUISLO(slo, now))
+	}
+
+	return res
+}
+
+func newUISLO(slo status.SLOStatus, now time.Time) uiSLO {
+	res := uiSLO{
+		ID:               slo.ID,
+		Name:             slo.Name,
+		Description:      slo.Description,
+		Disabled:         slo.Disabled,
+		Objective:        strconv.FormatFloat(slo.AvailabilityObjectivePercent, 'f', -1, 64) + "%",
+		Availability:     "-",
+		LastAvailability: "-",
+		Budget:           "-",
+		LastEvaluation:   "never",
+		LastError:        slo.LastError,
+	}
+
+	if availability, remaining, ok := slo.ErrorBudget(); ok {
+		res.Availability = formatPercent(availability)
+		res.Budget = formatPercent(remaining)
+		switch {
+		case output.ErrorBudgetExhausted(remaining):
+			res.BudgetClass = "exhausted"
+		case remaining < budgetWarningRatio:
+			res.BudgetClass = "warning"
+		default:
+			res.BudgetClass = "ok"
+		}
+	}
+	if slo.LastResult != nil {
+		res.LastAvailability = formatPercent(slo.LastResult.AvailabilityRatio)
+	}
+	if slo.LastEvaluation != nil {
+		res.LastEvaluation = now.Sub(*slo.LastEvaluation).Round(time.Second).String() + " ago"
+	}
+
+	res.Sparkline, res.ObjectiveLine = sparkline(slo.History, slo.AvailabilityObjectivePercent/100)
+
+	return res
+}
+
+// sparkline returns the SVG polyline points of the availability history and the
+// objective, empty if there aren't enough points to draw a line. The vertical scale
+// goes from the lowest availability (or the objective) to 100%.
+func sparkline(history []status.SLOHistoryPoint, objective float64) (line, objectiveLine string) {
+	if len(history) < 2 {
+		return "", ""
+	}
+
+	const hi = 1.0
+	lo := objective
+	for _, p := range history {
+		if p.AvailabilityRatio < lo {
+			lo = p.AvailabilityRatio
+		}
+	}
+	if hi-lo < 1e-9 {
+		lo = hi - 1e-3
+	}
+
+	y := func(v float64) float64 {
+		// Leave a pixel on each side so the line is not clipped.
+		return 1 + (hi-v)/(hi-lo)*(sparklineHeight-2)
+	}
+
+	points := make([]string, 0, len(history))
+	step := float64(sparklineWidth) / float64(len(history)-1)
+	for i, p := range history {
+		points = append(points, fmt.Sprintf("%.1f,%.1f", float64(i)*step, y(p.AvailabilityRatio)))
+	}
+	oy := y(objective)
+
+	return strings.Join(points, " "), fmt.Sprintf("0,%.1f %d,%.1f", oy, sparklineWidth, oy)
+}
+
+func formatPercent(ratio float64) string {
+	return strconv.FormatFloat(ratio*100, 'f', 3, 64) + "%"
+}
+
+const uiTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Service level operator</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.5em; }
+h2 { font-size: 1.1em; margin: 1.5em 0 0.3em 0; }
+a { color: #1f6feb; }
+form { margin: 1em 0; }
+input, select, button { font-size: 0.9em; padding: 0.2em 0.4em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 0.3em 0.6em; border-bottom: 1px solid #ddd; font-size: 0.9em; }
+th { background: #f5f5f5; }
+.labels span { display: inline-block; background: #eef; border-radius: 3px; padding: 0 0.4em; margin-right: 0.3em; font-size: 0.8em; }
+.muted { color: #888; }
+.error { color: #c62828; }
+.ok { color: #2e7d32; font-weight: bold; }
+.warning { color: #ef6c00; font-weight: bold; }
+.exhausted { color: #c62828; font-weight: bold; }
+.disabled td { color: #aaa; }
+svg polyline.availability { fill: none; stroke: #1f6feb; stroke-width: 1.5; }
+svg polyline.objective { fill: none; stroke: #c62828; stroke-width: 1; stroke-dasharray: 2,2; }
+</style>
+</head>
+<body>
+<h1>Service level operator</h1>
+<p><a href="{{ .MetricsPath }}">Metrics</a> | <a href="{{ .APIPath }}">API</a></p>
+<form method="get" action="/">
+<label>Namespace
+<select name="namespace">
+<option value="">all</option>
+{{- range .Namespaces }}
+<option value="{{ . }}"{{ if eq . $.Namespace }} selected{{ end }}>{{ . }}</option>
+{{- end }}
+</select>
+</label>
+<label>Labels <input type="text" name="selector" value="{{ .Selector }}" placeholder="team=team0,env!=dev"></label>
+<button type="submit">Filter</button>
+</form>
+{{- if .Error }}
+<p class="error">{{ .Error }}</p>
+{{- end }}
+<p class="muted">Showing {{ len .ServiceLevels }} of {{ .Total }} service levels.</p>
+{{- range .ServiceLevels }}
+<h2>{{ if .Cluster }}{{ .Cluster }}/{{ end }}{{ .Namespace }}/{{ .Name }}</h2>
+{{- if .Labels }}
+<div class="labels">{{ range .Labels }}<span>{{ . }}</span>{{ end }}</div>
+{{- end }}
+<table>
+<tr><th>SLO</th><th>Objective</th><th>Availability</th><th>Last availability</th><th>Remaining budget</th><th>History</th><th>Last evaluation</th><th>Last error</th></tr>
+{{- range .SLOs }}
+<tr id="{{ .ID }}"{{ if .Disabled }} class="disabled"{{ end }}>
+<td title="{{ .Description }}">{{ .Name }}{{ if .Disabled }} (disabled){{ end }}</td>
+<td>{{ .Objective }}</td>
+<td>{{ .Availability }}</td>
+<td>{{ .LastAvailability }}</td>
+<td class="{{ .BudgetClass }}">{{ .Budget }}</td>
+<td>{{ if .Sparkline }}<svg width="120" height="24" viewBox="0 0 120 24"><polyline class="objective" points="{{ .ObjectiveLine }}"/><polyline class="availability" points="{{ .Sparkline }}"/></svg>{{ else }}<span class="muted">-</span>{{ end }}</td>
+<td>{{ .LastEvaluation }}</td>
+<td class="error">{{ .LastError }}</td>
+</tr>
+{{- end }}
+</table>
+{{- else }}
+<p class="muted">No service levels.</p>
+{{- end }}
+</body>
+</html>
+`
@@ -0,0 +1,115 @@
+package web_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv1alpha1 "github.com/spotahome/service-level-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/spotahome/service-level-operator/pkg/log"
+	"github.com/spotahome/service-level-operator/pkg/service/output"
+	"github.com/spotahome/service-level-operator/pkg/service/sli"
+	"github.com/spotahome/service-level-operator/pkg/service/status"
+	"github.com/spotahome/service-level-operator/pkg/web"
+)
+
+func TestUI(t *testing.T) {
+	sl0 := &monitoringv1alpha1.ServiceLevel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns0", Name: "sl0", Labels: map[string]string{"team": "team0"}},
+		Spec: monitoringv1alpha1.ServiceLevelSpec{
+			ServiceLevelObjectives: []monitoringv1alpha1.SLO{
+				{Name: "slo0", AvailabilityObjectivePercent: 99},
+			},
+		},
+	}
+	sl1 := &monitoringv1alpha1.ServiceLevel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "sl1", Labels: map[string]string{"team": "team1"}},
+		Spec: monitoringv1alpha1.ServiceLevelSpec{
+			ServiceLevelObjectives: []monitoringv1alpha1.SLO{
+				{Name: "slo1", AvailabilityObjectivePercent: 99.9},
+			},
+		},
+	}
+	store := status.NewMemory(time.Minute)
+	store.SetServiceLevel("", sl0)
+	store.SetServiceLevel("", sl1)
+	for i := 0; i < 3; i++ {
+		store.RecordSLOEvaluation(status.SLOEvaluation{
+			ServiceLevel: sl0,
+			SLO:          &sl0.Spec.ServiceLevelObjectives[0],
+			Time:         time.Now(),
+			Result:       &sli.Result{TotalQ: 100, ErrorQ: 1},
+			Counters:     &output.SLOCounters{ErrorRatioSum: 0.01, Count: 4, Objective: 0.99},
+		})
+	}
+	store.RecordSLOEvaluation(status.SLOEvaluation{
+		ServiceLevel: sl1,
+		SLO:          &sl1.Spec.ServiceLevelObjectives[0],
+		Time:         time.Now(),
+		Err:          assert.AnError,
+	})
+
+	tests := map[string]struct {
+		path          string
+		expCode       int
+		expContains   []string
+		expNotContain []string
+	}{
+		"Without filters all the service levels should be shown.": {
+			path:    "/",
+			expCode: http.StatusOK,
+			expContains: []string{
+				"ns0/sl0", "ns1/sl1", "<span>team=team0</span>",
+				"99%", "99.750%", "99.000%", "75.000%", `class="ok"`,
+				`<polyline class="availability"`,
+				assert.AnError.Error(),
+			},
+		},
+		"Filtering by namespace should show only the service levels of the namespace.": {
+			path:          "/?namespace=ns1",
+			expCode:       http.StatusOK,
+			expContains:   []string{"ns1/sl1", "Showing 1 of 2 service levels."},
+			expNotContain: []string{"ns0/sl0"},
+		},
+		"Filtering by label should show only the service levels that match the selector.": {
+			path:          "/?selector=team%3Dteam0",
+			expCode:       http.StatusOK,
+			expContains:   []string{"ns0/sl0"},
+			expNotContain: []string{"ns1/sl1"},
+		},
+		"An invalid selector should return an error.": {
+			path:          "/?selector=team%3D%3D%3D",
+			expCode:       http.StatusBadRequest,
+			expContains:   []string{"invalid label selector"},
+			expNotContain: []string{"ns0/sl0", "ns1/sl1"},
+		},
+		"Other paths should not be found.": {
+			path:    "/missing",
+			expCode: http.StatusNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			h := web.NewUIHandler(store, "/metrics", log.Dummy)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+			assert.Equal(test.expCode, w.Code)
+			body, _ := ioutil.ReadAll(w.Result().Body)
+			for _, exp := range test.expContains {
+				assert.Contains(string(body), exp)
+			}
+			for _, exp := range test.expNotContain {
+				assert.NotContains(string(body), exp)
+			}
+		})
+	}
+}